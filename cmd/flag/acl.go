@@ -0,0 +1,41 @@
+package flag
+
+import (
+	"github.com/cyverse/gocommands/commons/config"
+	"github.com/spf13/cobra"
+)
+
+type ACLAuditFlagValues struct {
+	DeviationOnly bool
+}
+
+type ACLApplyFlagValues struct {
+	ThreadNumber int
+	StopOnError  bool
+}
+
+var (
+	aclAuditFlagValues ACLAuditFlagValues
+	aclApplyFlagValues ACLApplyFlagValues
+)
+
+func SetACLAuditFlags(command *cobra.Command) {
+	command.Flags().BoolVar(&aclAuditFlagValues.DeviationOnly, "deviation_only", false, "Display only accesses deviating from the parent collection")
+}
+
+func GetACLAuditFlagValues() *ACLAuditFlagValues {
+	return &aclAuditFlagValues
+}
+
+func SetACLApplyFlags(command *cobra.Command) {
+	command.Flags().IntVar(&aclApplyFlagValues.ThreadNumber, "thread_num", config.GetDefaultTransferThreadNum(), "Set the number of threads to apply changes")
+	command.Flags().BoolVar(&aclApplyFlagValues.StopOnError, "stop_on_error", false, "Stop applying changes immediately when an error occurs")
+}
+
+func GetACLApplyFlagValues() *ACLApplyFlagValues {
+	if aclApplyFlagValues.ThreadNumber < 1 {
+		aclApplyFlagValues.ThreadNumber = 1
+	}
+
+	return &aclApplyFlagValues
+}
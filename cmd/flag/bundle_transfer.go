@@ -17,6 +17,7 @@ type BundleTransferFlagValues struct {
 	MaxFileNumInBundle     int
 	MaxBundleFileSize      int64
 	NoBulkRegistration     bool
+	AdaptiveBundles        bool
 	maxBundleFileSizeInput string
 }
 
@@ -33,6 +34,7 @@ func SetBundleTransferFlags(command *cobra.Command, hideTempPathConfig bool, hid
 	command.Flags().StringVar(&bundleTransferFlagValues.maxBundleFileSizeInput, "max_bundle_size", strconv.FormatInt(config.MaxBundleFileSizeDefault, 10), "Maximum size limit for a single bundle file")
 
 	command.Flags().BoolVar(&bundleTransferFlagValues.NoBulkRegistration, "no_bulk_reg", false, "Disable bulk registration of bundle files")
+	command.Flags().BoolVar(&bundleTransferFlagValues.AdaptiveBundles, "adaptive_bundles", false, "Dynamically adjust bundle size and concurrency based on observed throughput")
 
 	if hideTempPathConfig {
 		command.Flags().MarkHidden("local_temp")
@@ -45,6 +47,7 @@ func SetBundleTransferFlags(command *cobra.Command, hideTempPathConfig bool, hid
 		command.Flags().MarkHidden("max_file_num")
 		command.Flags().MarkHidden("max_bundle_size")
 		command.Flags().MarkHidden("no_bulk_reg")
+		command.Flags().MarkHidden("adaptive_bundles")
 	}
 }
 
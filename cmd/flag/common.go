@@ -1,8 +1,11 @@
 package flag
 
 import (
+	"context"
 	"io"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/cockroachdb/errors"
 	irodsclient_config "github.com/cyverse/go-irodsclient/config"
@@ -26,11 +29,13 @@ type CommonFlagValues struct {
 	LogLevelUpdated bool
 	LogFile         string
 	LogTerminal     bool
+	LogFormat       string
 	SessionID       int
 	Resource        string
 	ResourceUpdated bool
 	Timeout         int
 	TimeoutUpdated  bool
+	Deadline        time.Duration
 	YesAll          bool
 	NoAll           bool
 }
@@ -48,6 +53,7 @@ func SetCommonFlags(command *cobra.Command, hideResource bool) {
 	command.Flags().StringVar(&commonFlagValues.logLevelInput, "log_level", "", "Set logging verbosity level (e.g., INFO, WARN, ERROR, DEBUG)")
 	command.Flags().StringVar(&commonFlagValues.LogFile, "log_file", "", "Specify file path for logging output")
 	command.Flags().BoolVarP(&commonFlagValues.LogTerminal, "log_terminal", "", false, "Enable logging to terminal")
+	command.Flags().StringVar(&commonFlagValues.LogFormat, "log_format", "text", "Set log format (text, json)")
 	command.Flags().IntVarP(&commonFlagValues.SessionID, "session", "s", os.Getppid(), "Specify session identifier for tracking operations")
 	command.Flags().StringVarP(&commonFlagValues.Resource, "resource", "R", "", "Target specific iRODS resource server for operations")
 	command.Flags().IntVarP(&commonFlagValues.Timeout, "timeout", "", config.GetDefaultFilesystemTimeoutInSeconds(), "Specify timeout duration in seconds")
@@ -77,6 +83,7 @@ func SetCommonFlagsWithoutResource(command *cobra.Command) {
 	command.Flags().StringVar(&commonFlagValues.logLevelInput, "log_level", "", "Set log level")
 	command.Flags().StringVar(&commonFlagValues.LogFile, "log_file", "", "Specify file path for logging output")
 	command.Flags().BoolVarP(&commonFlagValues.LogTerminal, "log_terminal", "", false, "Enable logging to terminal")
+	command.Flags().StringVar(&commonFlagValues.LogFormat, "log_format", "text", "Set log format (text, json)")
 	command.Flags().IntVarP(&commonFlagValues.SessionID, "session", "s", os.Getppid(), "Set session ID")
 	command.Flags().IntVarP(&commonFlagValues.Timeout, "timeout", "", config.GetDefaultFilesystemTimeoutInSeconds(), "Specify timeout duration in seconds")
 	command.Flags().BoolVarP(&commonFlagValues.YesAll, "yes", "Y", false, "Yes to all questions")
@@ -90,6 +97,11 @@ func SetCommonFlagsWithoutResource(command *cobra.Command) {
 	command.MarkFlagsMutuallyExclusive("session", "version")
 }
 
+// SetDeadlineFlags sets the deadline flag, for commands running with the context of GetContext
+func SetDeadlineFlags(command *cobra.Command) {
+	command.Flags().DurationVar(&commonFlagValues.Deadline, "deadline", 0, "Abort transfers not completed within the duration (e.g., 2h), pending transfers are canceled and a partial result is reported")
+}
+
 func GetCommonFlagValues(command *cobra.Command) *CommonFlagValues {
 	if len(commonFlagValues.logLevelInput) > 0 {
 		lvl, err := log.ParseLevel(commonFlagValues.logLevelInput)
@@ -111,6 +123,15 @@ func GetCommonFlagValues(command *cobra.Command) *CommonFlagValues {
 	return &commonFlagValues
 }
 
+// GetContext returns a context done when the deadline set with SetDeadlineFlags passes, the context is not done by itself without a deadline
+func (c *CommonFlagValues) GetContext() (context.Context, context.CancelFunc) {
+	if c.Deadline > 0 {
+		return context.WithTimeout(context.Background(), c.Deadline)
+	}
+
+	return context.WithCancel(context.Background())
+}
+
 func getLogrusLogLevel(irodsLogLevel int) log.Level {
 	switch irodsLogLevel {
 	case 0:
@@ -164,6 +185,33 @@ func getLogWriter(logFile string) io.WriteCloser {
 	return nil
 }
 
+// correlationIDHook adds the correlation ID of the run to every log line
+type correlationIDHook struct{}
+
+func (hook correlationIDHook) Levels() []log.Level {
+	return log.AllLevels
+}
+
+func (hook correlationIDHook) Fire(entry *log.Entry) error {
+	entry.Data["correlation_id"] = config.GetCorrelationID()
+	return nil
+}
+
+func setLogFormat(logFormat string) error {
+	switch strings.ToLower(logFormat) {
+	case "", "text":
+		// keep the text formatter set in main
+	case "json":
+		log.SetFormatter(&log.JSONFormatter{
+			TimestampFormat: "2006-01-02T15:04:05.000Z07:00",
+		})
+	default:
+		return errors.Errorf("unknown log format %q, must be text or json", logFormat)
+	}
+
+	return nil
+}
+
 func ProcessCommonFlags(command *cobra.Command) (bool, error) {
 	logger := log.WithFields(log.Fields{
 		"command": command.Name(),
@@ -183,6 +231,14 @@ func ProcessCommonFlags(command *cobra.Command) (bool, error) {
 		return false, nil // stop here
 	}
 
+	err := setLogFormat(myCommonFlagValues.LogFormat)
+	if err != nil {
+		return false, err
+	}
+
+	log.StandardLogger().ReplaceHooks(log.LevelHooks{})
+	log.AddHook(correlationIDHook{})
+
 	if len(myCommonFlagValues.LogFile) > 0 {
 		fileLogWriter := getLogWriter(myCommonFlagValues.LogFile)
 
@@ -196,8 +252,10 @@ func ProcessCommonFlags(command *cobra.Command) (bool, error) {
 		}
 	}
 
+	logger.Debugf("use correlation ID - %s", config.GetCorrelationID())
+
 	// init config
-	err := config.InitEnvironmentManagerFromSystemConfig()
+	err = config.InitEnvironmentManagerFromSystemConfig()
 	if err != nil {
 		return false, errors.Wrapf(err, "failed to init environment manager")
 	}
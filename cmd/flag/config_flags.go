@@ -0,0 +1,273 @@
+package flag
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/cyverse/gocommands/commons/config"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// ConfigFlagSource determines where a flag value comes from
+type ConfigFlagSource string
+
+const (
+	// ConfigFlagSourceDefault is for default values of flags
+	ConfigFlagSourceDefault ConfigFlagSource = "default"
+	// ConfigFlagSourceSystem is for command_flags in the system config
+	ConfigFlagSourceSystem ConfigFlagSource = "system"
+	// ConfigFlagSourceUser is for the user config
+	ConfigFlagSourceUser ConfigFlagSource = "user"
+	// ConfigFlagSourceEnv is for GOCMD_<COMMAND>_<FLAG> environment variables
+	ConfigFlagSourceEnv ConfigFlagSource = "env"
+
+	configFlagEnvPrefix string = "GOCMD_"
+
+	// cobra stores mutually exclusive flag groups in flag annotations
+	mutuallyExclusiveAnnotation string = "cobra_annotation_mutually_exclusive"
+)
+
+// ConfigFlagValue is a default flag value given in config files or environment variables
+type ConfigFlagValue struct {
+	Flag   string
+	Value  interface{} // string, bool, number, or list of them
+	Source ConfigFlagSource
+	Origin string // config file path or environment variable name
+}
+
+// GetConfigCommandName returns the name of the command used in config files, subcommand names are joined with '_' (e.g., meta_import)
+func GetConfigCommandName(command *cobra.Command) string {
+	names := []string{}
+	for cmd := command; cmd != nil && cmd.HasParent(); cmd = cmd.Parent() {
+		names = append([]string{cmd.Name()}, names...)
+	}
+
+	return strings.Join(names, "_")
+}
+
+// GetConfigFlagEnvName returns the environment variable name giving a default value of the flag
+func GetConfigFlagEnvName(commandName string, flagName string) string {
+	name := configFlagEnvPrefix + commandName + "_" + flagName
+	name = strings.ReplaceAll(name, "-", "_")
+	return strings.ToUpper(name)
+}
+
+// configFlagFile has default flag values of commands given in a config file
+type configFlagFile struct {
+	commandFlags config.CommandFlags
+	source       ConfigFlagSource
+	origin       string
+}
+
+// getConfigFlagFiles returns the system and user configs loaded, lowest priority first
+func getConfigFlagFiles() []configFlagFile {
+	files := []configFlagFile{}
+
+	systemConfig := config.GetSystemConfig()
+	if systemConfig != nil {
+		systemConfigPath, _ := config.GetSystemConfigPath()
+		files = append(files, configFlagFile{systemConfig.CommandFlags, ConfigFlagSourceSystem, systemConfigPath})
+	}
+
+	userConfig := config.GetUserConfig()
+	if userConfig != nil {
+		files = append(files, configFlagFile{userConfig.CommandFlags, ConfigFlagSourceUser, userConfig.Path})
+	}
+
+	return files
+}
+
+// GetConfigFlagValues returns default flag values of the command given in config files or environment variables
+// a value of higher priority (env > user > system) is returned for each flag, in the order of priority
+func GetConfigFlagValues(command *cobra.Command) ([]ConfigFlagValue, error) {
+	return getConfigFlagValues(command, getConfigFlagFiles())
+}
+
+// getConfigFlagValues returns default flag values of the command given in the config files or environment variables, files are given in the order of priority, lowest first
+func getConfigFlagValues(command *cobra.Command, files []configFlagFile) ([]ConfigFlagValue, error) {
+	commandName := GetConfigCommandName(command)
+	if len(commandName) == 0 {
+		return nil, nil
+	}
+
+	values := map[string]ConfigFlagValue{}
+
+	// lowest priority first
+	for _, file := range files {
+		err := addConfigFlagValues(values, command, file.commandFlags[commandName], file.source, file.origin)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	command.Flags().VisitAll(func(f *pflag.Flag) {
+		envName := GetConfigFlagEnvName(commandName, f.Name)
+		if envValue, ok := os.LookupEnv(envName); ok {
+			values[f.Name] = ConfigFlagValue{
+				Flag:   f.Name,
+				Value:  envValue,
+				Source: ConfigFlagSourceEnv,
+				Origin: envName,
+			}
+		}
+	})
+
+	sortedValues := make([]ConfigFlagValue, 0, len(values))
+	for _, value := range values {
+		sortedValues = append(sortedValues, value)
+	}
+
+	sort.SliceStable(sortedValues, func(i int, j int) bool {
+		if sortedValues[i].Source != sortedValues[j].Source {
+			return getConfigFlagSourcePriority(sortedValues[i].Source) > getConfigFlagSourcePriority(sortedValues[j].Source)
+		}
+
+		return sortedValues[i].Flag < sortedValues[j].Flag
+	})
+
+	return sortedValues, nil
+}
+
+func addConfigFlagValues(values map[string]ConfigFlagValue, command *cobra.Command, flagValues map[string]interface{}, source ConfigFlagSource, origin string) error {
+	for flagName, value := range flagValues {
+		if command.Flags().Lookup(flagName) == nil {
+			return errors.Errorf("unknown flag %q for command %q in %q", flagName, GetConfigCommandName(command), origin)
+		}
+
+		if value == nil {
+			continue
+		}
+
+		values[flagName] = ConfigFlagValue{
+			Flag:   flagName,
+			Value:  value,
+			Source: source,
+			Origin: origin,
+		}
+	}
+
+	return nil
+}
+
+func getConfigFlagSourcePriority(source ConfigFlagSource) int {
+	switch source {
+	case ConfigFlagSourceEnv:
+		return 3
+	case ConfigFlagSourceUser:
+		return 2
+	case ConfigFlagSourceSystem:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// FormatConfigFlagValue returns the value as given in the command-line
+func FormatConfigFlagValue(value interface{}) string {
+	if list, ok := value.([]interface{}); ok {
+		return strings.Join(getConfigFlagListValue(list), ",")
+	}
+
+	return fmt.Sprintf("%v", value)
+}
+
+func getConfigFlagListValue(list []interface{}) []string {
+	strs := make([]string, 0, len(list))
+	for _, item := range list {
+		strs = append(strs, fmt.Sprintf("%v", item))
+	}
+
+	return strs
+}
+
+// ApplyConfigFlags sets flags not given in the command-line to default values given in config files or environment variables
+// the merge order is system < user < env < command-line, returns an error if the user config failed to load
+func ApplyConfigFlags(command *cobra.Command) error {
+	if len(GetConfigCommandName(command)) == 0 {
+		return nil
+	}
+
+	err := config.GetUserConfigError()
+	if err != nil {
+		return errors.Wrapf(err, "failed to load user config")
+	}
+
+	return applyConfigFlags(command, getConfigFlagFiles())
+}
+
+// applyConfigFlags sets flags not given in the command-line to default values given in the config files or environment variables
+func applyConfigFlags(command *cobra.Command, files []configFlagFile) error {
+	logger := log.WithFields(log.Fields{
+		"command": command.Name(),
+	})
+
+	configFlagValues, err := getConfigFlagValues(command, files)
+	if err != nil {
+		return err
+	}
+
+	for _, configFlagValue := range configFlagValues {
+		f := command.Flags().Lookup(configFlagValue.Flag)
+		if f.Changed {
+			// given in the command-line
+			continue
+		}
+
+		if conflictFlag, ok := getChangedExclusiveFlag(command, f); ok {
+			logger.Debugf("ignore flag %q in %q as it conflicts with flag %q", f.Name, configFlagValue.Origin, conflictFlag)
+			continue
+		}
+
+		err := setConfigFlagValue(f, configFlagValue.Value)
+		if err != nil {
+			return errors.Wrapf(err, "failed to set flag %q to %q given in %q", f.Name, FormatConfigFlagValue(configFlagValue.Value), configFlagValue.Origin)
+		}
+	}
+
+	return nil
+}
+
+// getChangedExclusiveFlag returns a flag mutually exclusive with the flag that is already set
+func getChangedExclusiveFlag(command *cobra.Command, f *pflag.Flag) (string, bool) {
+	for _, group := range f.Annotations[mutuallyExclusiveAnnotation] {
+		for _, flagName := range strings.Split(group, " ") {
+			if flagName == f.Name {
+				continue
+			}
+
+			if command.Flags().Changed(flagName) {
+				return flagName, true
+			}
+		}
+	}
+
+	return "", false
+}
+
+func setConfigFlagValue(f *pflag.Flag, value interface{}) error {
+	if list, ok := value.([]interface{}); ok {
+		sliceValue, ok := f.Value.(pflag.SliceValue)
+		if !ok {
+			return errors.Errorf("flag %q does not accept a list", f.Name)
+		}
+
+		err := sliceValue.Replace(getConfigFlagListValue(list))
+		if err != nil {
+			return err
+		}
+	} else {
+		err := f.Value.Set(fmt.Sprintf("%v", value))
+		if err != nil {
+			return err
+		}
+	}
+
+	// treat as given in the command-line
+	f.Changed = true
+	return nil
+}
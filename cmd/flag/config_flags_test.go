@@ -0,0 +1,368 @@
+package flag
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cyverse/gocommands/commons/config"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfigFlags(t *testing.T) {
+	t.Run("test GetConfigCommandName", testGetConfigCommandName)
+	t.Run("test GetConfigFlagEnvName", testGetConfigFlagEnvName)
+	t.Run("test GetConfigFlagValues", testGetConfigFlagValues)
+	t.Run("test ApplyConfigFlags", testApplyConfigFlags)
+	t.Run("test ApplyConfigFlagsWithUserConfigError", testApplyConfigFlagsWithUserConfigError)
+	t.Run("test SetConfigFlagValue", testSetConfigFlagValue)
+}
+
+// testConfigFlagValues has values of flags of the throwaway command
+type testConfigFlagValues struct {
+	retry    int
+	progress bool
+	debug    bool
+	quiet    bool
+	priority []string
+	exclude  []string
+}
+
+// newTestConfigCommand returns a throwaway "put" command under a root command, with flags like the real one
+func newTestConfigCommand() (*cobra.Command, *testConfigFlagValues) {
+	values := &testConfigFlagValues{}
+
+	rootCmd := &cobra.Command{Use: "gocmd"}
+	putCmd := &cobra.Command{Use: "put", Run: func(command *cobra.Command, args []string) {}}
+
+	putCmd.Flags().IntVar(&values.retry, "retry", 1, "")
+	putCmd.Flags().BoolVar(&values.progress, "progress", false, "")
+	putCmd.Flags().BoolVar(&values.debug, "debug", false, "")
+	putCmd.Flags().BoolVar(&values.quiet, "quiet", false, "")
+	putCmd.Flags().StringArrayVar(&values.priority, "priority", []string{"*.default"}, "")
+	putCmd.Flags().StringSliceVar(&values.exclude, "exclude", []string{}, "")
+	putCmd.MarkFlagsMutuallyExclusive("debug", "quiet")
+
+	rootCmd.AddCommand(putCmd)
+
+	return putCmd, values
+}
+
+func testGetConfigCommandName(t *testing.T) {
+	rootCmd := &cobra.Command{Use: "gocmd"}
+	metaCmd := &cobra.Command{Use: "meta"}
+	importCmd := &cobra.Command{Use: "import"}
+	putCmd := &cobra.Command{Use: "put"}
+
+	metaCmd.AddCommand(importCmd)
+	rootCmd.AddCommand(metaCmd, putCmd)
+
+	tests := []struct {
+		command  *cobra.Command
+		expected string
+	}{
+		{rootCmd, ""},
+		{putCmd, "put"},
+		{metaCmd, "meta"},
+		{importCmd, "meta_import"},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, GetConfigCommandName(test.command), test.command.Name())
+	}
+}
+
+func testGetConfigFlagEnvName(t *testing.T) {
+	tests := []struct {
+		commandName string
+		flagName    string
+		expected    string
+	}{
+		{"put", "retry", "GOCMD_PUT_RETRY"},
+		{"put", "verify_checksum", "GOCMD_PUT_VERIFY_CHECKSUM"},
+		{"meta_import", "thread-num", "GOCMD_META_IMPORT_THREAD_NUM"},
+		{"copy-sftp-id", "dry_run", "GOCMD_COPY_SFTP_ID_DRY_RUN"},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, GetConfigFlagEnvName(test.commandName, test.flagName), test.expected)
+	}
+}
+
+func testGetConfigFlagValues(t *testing.T) {
+	systemFile := configFlagFile{
+		commandFlags: config.CommandFlags{"put": {"retry": 3, "progress": true}, "get": {"retry": 10}},
+		source:       ConfigFlagSourceSystem,
+		origin:       "/etc/gocmd/config.json",
+	}
+
+	userFile := configFlagFile{
+		commandFlags: config.CommandFlags{"put": {"retry": 5, "priority": []interface{}{"*.json", "*.csv"}, "debug": nil}},
+		source:       ConfigFlagSourceUser,
+		origin:       "/home/user/.irods/gocmd.yaml",
+	}
+
+	tests := []struct {
+		name     string
+		files    []configFlagFile
+		env      map[string]string
+		expected []ConfigFlagValue
+		hasError bool
+	}{
+		{
+			name:  "system only",
+			files: []configFlagFile{systemFile},
+			expected: []ConfigFlagValue{
+				{"progress", true, ConfigFlagSourceSystem, "/etc/gocmd/config.json"},
+				{"retry", 3, ConfigFlagSourceSystem, "/etc/gocmd/config.json"},
+			},
+		},
+		{
+			name:  "user overrides system, sorted by priority then flag",
+			files: []configFlagFile{systemFile, userFile},
+			expected: []ConfigFlagValue{
+				{"priority", []interface{}{"*.json", "*.csv"}, ConfigFlagSourceUser, "/home/user/.irods/gocmd.yaml"},
+				{"retry", 5, ConfigFlagSourceUser, "/home/user/.irods/gocmd.yaml"},
+				{"progress", true, ConfigFlagSourceSystem, "/etc/gocmd/config.json"},
+			},
+		},
+		{
+			name:  "env overrides user and system",
+			files: []configFlagFile{systemFile, userFile},
+			env:   map[string]string{"GOCMD_PUT_RETRY": "7", "GOCMD_PUT_QUIET": "true", "GOCMD_GET_RETRY": "8"},
+			expected: []ConfigFlagValue{
+				{"quiet", "true", ConfigFlagSourceEnv, "GOCMD_PUT_QUIET"},
+				{"retry", "7", ConfigFlagSourceEnv, "GOCMD_PUT_RETRY"},
+				{"priority", []interface{}{"*.json", "*.csv"}, ConfigFlagSourceUser, "/home/user/.irods/gocmd.yaml"},
+				{"progress", true, ConfigFlagSourceSystem, "/etc/gocmd/config.json"},
+			},
+		},
+		{
+			name:     "no values",
+			files:    []configFlagFile{},
+			expected: []ConfigFlagValue{},
+		},
+		{
+			name:     "unknown flag",
+			files:    []configFlagFile{{config.CommandFlags{"put": {"retries": 5}}, ConfigFlagSourceUser, "gocmd.yaml"}},
+			hasError: true,
+		},
+	}
+
+	for _, test := range tests {
+		for envName, envValue := range test.env {
+			t.Setenv(envName, envValue)
+		}
+
+		command, _ := newTestConfigCommand()
+
+		values, err := getConfigFlagValues(command, test.files)
+		if test.hasError {
+			assert.Error(t, err, test.name)
+		} else {
+			assert.NoError(t, err, test.name)
+			assert.Equal(t, test.expected, values, test.name)
+		}
+
+		for envName := range test.env {
+			os.Unsetenv(envName)
+		}
+	}
+
+	// the root command has no default flag values
+	rootCmd := &cobra.Command{Use: "gocmd"}
+	rootCmd.Flags().IntVar(new(int), "retry", 1, "")
+
+	values, err := getConfigFlagValues(rootCmd, []configFlagFile{systemFile, userFile})
+	assert.NoError(t, err)
+	assert.Empty(t, values)
+}
+
+func testApplyConfigFlags(t *testing.T) {
+	systemFile := configFlagFile{
+		commandFlags: config.CommandFlags{"put": {"retry": 3, "progress": true}},
+		source:       ConfigFlagSourceSystem,
+		origin:       "/etc/gocmd/config.json",
+	}
+
+	userFile := configFlagFile{
+		commandFlags: config.CommandFlags{"put": {"retry": 5, "priority": []interface{}{"*.json", "*.csv"}, "debug": true}},
+		source:       ConfigFlagSourceUser,
+		origin:       "/home/user/.irods/gocmd.yaml",
+	}
+
+	tests := []struct {
+		name     string
+		files    []configFlagFile
+		env      map[string]string
+		args     []string
+		expected testConfigFlagValues
+		hasError bool
+	}{
+		{
+			name:     "defaults without configs",
+			files:    []configFlagFile{},
+			expected: testConfigFlagValues{retry: 1, priority: []string{"*.default"}, exclude: []string{}},
+		},
+		{
+			name:     "system",
+			files:    []configFlagFile{systemFile},
+			expected: testConfigFlagValues{retry: 3, progress: true, priority: []string{"*.default"}, exclude: []string{}},
+		},
+		{
+			name:     "user overrides system, list replaces the default",
+			files:    []configFlagFile{systemFile, userFile},
+			expected: testConfigFlagValues{retry: 5, progress: true, debug: true, priority: []string{"*.json", "*.csv"}, exclude: []string{}},
+		},
+		{
+			name:     "env overrides system, a value replaces the default list",
+			files:    []configFlagFile{systemFile},
+			env:      map[string]string{"GOCMD_PUT_RETRY": "7", "GOCMD_PUT_PRIORITY": "*.txt"},
+			expected: testConfigFlagValues{retry: 7, progress: true, priority: []string{"*.txt"}, exclude: []string{}},
+		},
+		{
+			name:     "command-line overrides env, lists are not merged",
+			files:    []configFlagFile{systemFile, userFile},
+			env:      map[string]string{"GOCMD_PUT_RETRY": "7"},
+			args:     []string{"--retry", "9", "--priority", "*.bin", "--priority", "*.dat", "--progress=false"},
+			expected: testConfigFlagValues{retry: 9, debug: true, priority: []string{"*.bin", "*.dat"}, exclude: []string{}},
+		},
+		{
+			name:     "exclusive flag given in the command-line is kept",
+			files:    []configFlagFile{userFile},
+			args:     []string{"--quiet"},
+			expected: testConfigFlagValues{retry: 5, quiet: true, priority: []string{"*.json", "*.csv"}, exclude: []string{}},
+		},
+		{
+			name:     "list for slice flag",
+			files:    []configFlagFile{{config.CommandFlags{"put": {"exclude": []interface{}{"a", "b"}}}, ConfigFlagSourceUser, "gocmd.yaml"}},
+			expected: testConfigFlagValues{retry: 1, priority: []string{"*.default"}, exclude: []string{"a", "b"}},
+		},
+		{
+			name:     "invalid value",
+			files:    []configFlagFile{{config.CommandFlags{"put": {"retry": "many"}}, ConfigFlagSourceUser, "gocmd.yaml"}},
+			hasError: true,
+		},
+		{
+			name:     "unknown flag",
+			files:    []configFlagFile{{config.CommandFlags{"put": {"retries": 5}}, ConfigFlagSourceUser, "gocmd.yaml"}},
+			hasError: true,
+		},
+	}
+
+	for _, test := range tests {
+		for envName, envValue := range test.env {
+			t.Setenv(envName, envValue)
+		}
+
+		command, values := newTestConfigCommand()
+
+		err := command.ParseFlags(test.args)
+		assert.NoError(t, err, test.name)
+
+		err = applyConfigFlags(command, test.files)
+		if test.hasError {
+			assert.Error(t, err, test.name)
+		} else {
+			assert.NoError(t, err, test.name)
+			assert.Equal(t, test.expected, *values, test.name)
+		}
+
+		for envName := range test.env {
+			os.Unsetenv(envName)
+		}
+	}
+}
+
+func testApplyConfigFlagsWithUserConfigError(t *testing.T) {
+	homeDir := t.TempDir()
+	t.Setenv("HOME", homeDir)
+
+	configDir := filepath.Join(homeDir, ".irods")
+	err := os.MkdirAll(configDir, 0700)
+	assert.NoError(t, err)
+
+	configPath := filepath.Join(configDir, "gocmd.yaml")
+	err = os.WriteFile(configPath, []byte("put:\n  retry: [\n"), 0600)
+	assert.NoError(t, err)
+
+	// the error is kept for ApplyConfigFlags
+	err = config.InitUserConfig()
+	assert.Error(t, err)
+	assert.Equal(t, err, config.GetUserConfigError())
+
+	command, values := newTestConfigCommand()
+
+	err = ApplyConfigFlags(command)
+	assert.ErrorIs(t, err, config.GetUserConfigError())
+	assert.Equal(t, 1, values.retry)
+
+	// the root command does not use configs
+	err = ApplyConfigFlags(command.Root())
+	assert.NoError(t, err)
+
+	err = os.WriteFile(configPath, []byte("put:\n  retry: 5\n"), 0600)
+	assert.NoError(t, err)
+
+	err = config.InitUserConfig()
+	assert.NoError(t, err)
+	assert.NoError(t, config.GetUserConfigError())
+
+	err = ApplyConfigFlags(command)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, values.retry)
+
+	// reset the user config for other tests
+	err = os.Remove(configPath)
+	assert.NoError(t, err)
+
+	err = config.InitUserConfig()
+	assert.NoError(t, err)
+	assert.Nil(t, config.GetUserConfig())
+}
+
+func testSetConfigFlagValue(t *testing.T) {
+	tests := []struct {
+		name     string
+		flag     string
+		value    interface{}
+		hasError bool
+	}{
+		{"int", "retry", 5, false},
+		{"int from string", "retry", "5", false},
+		{"invalid int", "retry", "five", true},
+		{"bool", "progress", true, false},
+		{"invalid bool", "progress", "maybe", true},
+		{"list for array", "priority", []interface{}{"*.json", 1}, false},
+		{"value for array", "priority", "*.json", false},
+		{"list for int", "retry", []interface{}{1, 2}, true},
+	}
+
+	for _, test := range tests {
+		command, _ := newTestConfigCommand()
+		f := command.Flags().Lookup(test.flag)
+
+		err := setConfigFlagValue(f, test.value)
+		if test.hasError {
+			assert.Error(t, err, test.name)
+			assert.False(t, f.Changed, test.name)
+		} else {
+			assert.NoError(t, err, test.name)
+			assert.True(t, f.Changed, test.name)
+		}
+	}
+
+	// a list or a value replaces the default list, not appended to it
+	command, values := newTestConfigCommand()
+
+	err := setConfigFlagValue(command.Flags().Lookup("priority"), []interface{}{"*.json", 1})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"*.json", "1"}, values.priority)
+
+	command, values = newTestConfigCommand()
+
+	err = setConfigFlagValue(command.Flags().Lookup("priority"), "*.csv")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"*.csv"}, values.priority)
+}
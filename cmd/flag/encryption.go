@@ -8,22 +8,25 @@ import (
 )
 
 type EncryptionFlagValues struct {
-	Encryption           bool
-	NoEncryption         bool
-	IgnoreMeta           bool
-	Mode                 encryption.EncryptionMode
-	modeInput            string
-	Key                  string
-	PublicPrivateKeyPath string
-	TempPath             string
+	Encryption                   bool
+	NoEncryption                 bool
+	IgnoreMeta                   bool
+	Mode                         encryption.EncryptionMode
+	modeInput                    string
+	Key                          string
+	PublicPrivateKeyPaths        []string
+	PublicPrivateKeyPathsUpdated bool
+	AgeRecipients                []string
+	TempPath                     string
 }
 
 type DecryptionFlagValues struct {
-	Decryption     bool
-	NoDecryption   bool
-	Key            string
-	PrivateKeyPath string
-	TempPath       string
+	Decryption      bool
+	NoDecryption    bool
+	Key             string
+	PrivateKeyPath  string
+	AgeIdentityPath string
+	TempPath        string
 }
 
 var (
@@ -35,20 +38,31 @@ func SetEncryptionFlags(command *cobra.Command) {
 	command.Flags().BoolVar(&encryptionFlagValues.Encryption, "encrypt", false, "Enable file encryption")
 	command.Flags().BoolVar(&encryptionFlagValues.NoEncryption, "no_encrypt", false, "Disable file encryption forcefully")
 	command.Flags().BoolVar(&encryptionFlagValues.IgnoreMeta, "ignore_meta", false, "Ignore encryption config via metadata")
-	command.Flags().StringVar(&encryptionFlagValues.modeInput, "encrypt_mode", "ssh", "Specify encryption mode ('winscp', 'pgp', or 'ssh')")
-	command.Flags().StringVar(&encryptionFlagValues.Key, "encrypt_key", "", "Specify the encryption key for 'winscp' and 'pgp' mode")
-	command.Flags().StringVar(&encryptionFlagValues.PublicPrivateKeyPath, "encrypt_pub_key", encryption.GetDefaultPublicKeyPath(), "Provide the encryption public (or private) key for 'ssh' mode")
+	command.Flags().StringVar(&encryptionFlagValues.modeInput, "encrypt_mode", "ssh", "Specify encryption mode ('winscp', 'aesgcm', 'pgp', 'ssh', or 'age')")
+	command.Flags().StringVar(&encryptionFlagValues.Key, "encrypt_key", "", "Specify the encryption key for 'winscp', 'aesgcm', and 'pgp' mode, and for filenames in 'age' mode")
+	command.Flags().StringArrayVar(&encryptionFlagValues.PublicPrivateKeyPaths, "encrypt_pub_key", []string{encryption.GetDefaultPublicKeyPath()}, "Provide the encryption public (or private) key or a key ring file for 'ssh' mode, can be given multiple times to encrypt for multiple recipients")
+	command.Flags().StringArrayVar(&encryptionFlagValues.AgeRecipients, "encrypt_recipient", defaultAgeRecipients(), "Add an age recipient ('age1...' or 'ssh-ed25519 ...') or a recipient file for 'age' mode, can be given multiple times")
 	command.Flags().StringVar(&encryptionFlagValues.TempPath, "encrypt_temp", os.TempDir(), "Set a temporary directory path for file encryption")
 }
 
 func SetDecryptionFlags(command *cobra.Command) {
 	command.Flags().BoolVar(&decryptionFlagValues.Decryption, "decrypt", true, "Enable file decryption")
 	command.Flags().BoolVar(&decryptionFlagValues.NoDecryption, "no_decrypt", false, "Disable file decryption forcefully")
-	command.Flags().StringVar(&decryptionFlagValues.Key, "decrypt_key", "", "Specify the decryption key for 'winscp' or 'pgp' modes")
+	command.Flags().StringVar(&decryptionFlagValues.Key, "decrypt_key", "", "Specify the decryption key for 'winscp', 'aesgcm', or 'pgp' modes, and for filenames in 'age' mode")
 	command.Flags().StringVar(&decryptionFlagValues.PrivateKeyPath, "decrypt_priv_key", encryption.GetDefaultPrivateKeyPath(), "Provide the decryption private key for 'ssh' mode")
+	command.Flags().StringVar(&decryptionFlagValues.AgeIdentityPath, "decrypt_identity", encryption.GetDefaultAgeIdentityPath(), "Provide the decryption identity file (age identity or SSH private key) for 'age' mode")
 	command.Flags().StringVar(&decryptionFlagValues.TempPath, "decrypt_temp", os.TempDir(), "Set a temporary directory for file decryption")
 }
 
+func defaultAgeRecipients() []string {
+	recipientPath := encryption.GetDefaultAgeRecipientPath()
+	if len(recipientPath) > 0 {
+		return []string{recipientPath}
+	}
+
+	return []string{}
+}
+
 func GetEncryptionFlagValues(command *cobra.Command) *EncryptionFlagValues {
 	encryptionFlagValues.Mode = encryption.GetEncryptionMode(encryptionFlagValues.modeInput)
 	if command.Flags().Changed("encrypt_key") && len(encryptionFlagValues.Key) > 0 {
@@ -59,6 +73,8 @@ func GetEncryptionFlagValues(command *cobra.Command) *EncryptionFlagValues {
 		encryptionFlagValues.Encryption = false
 	}
 
+	encryptionFlagValues.PublicPrivateKeyPathsUpdated = command.Flags().Changed("encrypt_pub_key")
+
 	return &encryptionFlagValues
 }
 
@@ -0,0 +1,28 @@
+package flag
+
+import (
+	"github.com/cyverse/gocommands/commons/config"
+	"github.com/spf13/cobra"
+)
+
+type MetadataBulkFlagValues struct {
+	ThreadNumber int
+	StopOnError  bool
+}
+
+var (
+	metadataBulkFlagValues MetadataBulkFlagValues
+)
+
+func SetMetadataBulkFlags(command *cobra.Command) {
+	command.Flags().IntVar(&metadataBulkFlagValues.ThreadNumber, "thread_num", config.GetDefaultTransferThreadNum(), "Set the number of threads to process metadata")
+	command.Flags().BoolVar(&metadataBulkFlagValues.StopOnError, "stop_on_error", false, "Stop processing metadata immediately when an error occurs")
+}
+
+func GetMetadataBulkFlagValues() *MetadataBulkFlagValues {
+	if metadataBulkFlagValues.ThreadNumber < 1 {
+		metadataBulkFlagValues.ThreadNumber = 1
+	}
+
+	return &metadataBulkFlagValues
+}
@@ -0,0 +1,39 @@
+package flag
+
+import (
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/spf13/cobra"
+)
+
+type MetadataTemplateFlagValues struct {
+	TemplatePath string
+	valueInput   []string
+	Values       map[string]string
+}
+
+var (
+	metadataTemplateFlagValues MetadataTemplateFlagValues
+)
+
+func SetMetadataTemplateFlags(command *cobra.Command) {
+	command.Flags().StringVar(&metadataTemplateFlagValues.TemplatePath, "meta_template", "", "Validate and apply metadata (AVUs) to uploaded data objects and collections with the template file (JSON)")
+	command.Flags().StringArrayVar(&metadataTemplateFlagValues.valueInput, "meta_value", []string{}, "Set a value of an attribute for the metadata template, in 'attribute=value' form")
+}
+
+func GetMetadataTemplateFlagValues() (*MetadataTemplateFlagValues, error) {
+	metadataTemplateFlagValues.Values = map[string]string{}
+
+	for _, input := range metadataTemplateFlagValues.valueInput {
+		attr, value, found := strings.Cut(input, "=")
+		attr = strings.TrimSpace(attr)
+		if !found || len(attr) == 0 {
+			return nil, errors.Errorf("invalid meta_value %q, must be in 'attribute=value' form", input)
+		}
+
+		metadataTemplateFlagValues.Values[attr] = value
+	}
+
+	return &metadataTemplateFlagValues, nil
+}
@@ -0,0 +1,40 @@
+package flag
+
+import (
+	"github.com/cyverse/gocommands/commons/metrics"
+	"github.com/spf13/cobra"
+)
+
+type MetricsFlagValues struct {
+	Listen       string
+	TextfilePath string
+}
+
+var (
+	metricsFlagValues MetricsFlagValues
+)
+
+func SetMetricsFlags(command *cobra.Command) {
+	command.Flags().StringVar(&metricsFlagValues.Listen, "metrics_listen", "", "Serve Prometheus metrics at /metrics of the address (e.g., ':9100')")
+	command.Flags().StringVar(&metricsFlagValues.TextfilePath, "metrics_textfile", "", "Write Prometheus metrics to the file periodically, for the node_exporter textfile collector (e.g., '/var/lib/node_exporter/gocmd.prom')")
+}
+
+func GetMetricsFlagValues() *MetricsFlagValues {
+	return &metricsFlagValues
+}
+
+// StartMetricsExporter returns metrics and an exporter exposing them, nil if neither '--metrics_listen' nor '--metrics_textfile' is given
+func (m *MetricsFlagValues) StartMetricsExporter() (*metrics.Metrics, *metrics.Exporter, error) {
+	if len(m.Listen) == 0 && len(m.TextfilePath) == 0 {
+		return nil, nil, nil
+	}
+
+	transferMetrics := metrics.NewMetrics()
+
+	exporter, err := metrics.NewExporter(transferMetrics, m.Listen, m.TextfilePath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return transferMetrics, exporter, nil
+}
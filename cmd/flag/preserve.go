@@ -0,0 +1,63 @@
+package flag
+
+import (
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+type PreserveFlagValues struct {
+	preserveInput []string
+	Metadata      bool
+	ACL           bool
+	ModifyTime    bool
+	Unknown       []string
+}
+
+type MetadataSidecarFlagValues struct {
+	MetadataSidecar bool
+}
+
+var (
+	preserveFlagValues        PreserveFlagValues
+	metadataSidecarFlagValues MetadataSidecarFlagValues
+)
+
+func SetPreserveFlags(command *cobra.Command) {
+	command.Flags().StringSliceVar(&preserveFlagValues.preserveInput, "preserve", []string{}, "Preserve attributes of the source, comma separated list of 'meta', 'acl', and 'mtime'")
+}
+
+func GetPreserveFlagValues() *PreserveFlagValues {
+	preserveFlagValues.Unknown = []string{}
+
+	for _, item := range preserveFlagValues.preserveInput {
+		switch strings.ToLower(strings.TrimSpace(item)) {
+		case "meta", "metadata":
+			preserveFlagValues.Metadata = true
+		case "acl", "acls":
+			preserveFlagValues.ACL = true
+		case "mtime":
+			preserveFlagValues.ModifyTime = true
+		case "all":
+			preserveFlagValues.Metadata = true
+			preserveFlagValues.ACL = true
+			preserveFlagValues.ModifyTime = true
+		default:
+			preserveFlagValues.Unknown = append(preserveFlagValues.Unknown, item)
+		}
+	}
+
+	return &preserveFlagValues
+}
+
+func SetMetadataSidecarFlags(command *cobra.Command, download bool) {
+	if download {
+		command.Flags().BoolVar(&metadataSidecarFlagValues.MetadataSidecar, "meta_sidecar", false, "Export metadata (AVUs) of downloaded data objects and collections to sidecar files (*.meta.json)")
+	} else {
+		command.Flags().BoolVar(&metadataSidecarFlagValues.MetadataSidecar, "meta_sidecar", false, "Import metadata (AVUs) from sidecar files (*.meta.json) to uploaded data objects and collections, sidecar files are not uploaded")
+	}
+}
+
+func GetMetadataSidecarFlagValues() *MetadataSidecarFlagValues {
+	return &metadataSidecarFlagValues
+}
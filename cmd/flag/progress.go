@@ -1,12 +1,16 @@
 package flag
 
 import (
+	"github.com/cyverse/gocommands/commons/parallel"
 	"github.com/spf13/cobra"
 )
 
 type ProgressFlagValues struct {
 	ShowProgress bool
 	ShowFullPath bool
+	modeInput    string
+	SlowFileNum  int
+	EventTarget  string
 }
 
 var (
@@ -18,6 +22,29 @@ func SetProgressFlags(command *cobra.Command) {
 	command.Flags().BoolVar(&progressFlagValues.ShowFullPath, "show_path", false, "Show full file paths in progress bars")
 }
 
+func SetProgressModeFlags(command *cobra.Command) {
+	command.Flags().StringVar(&progressFlagValues.modeInput, "progress_mode", string(parallel.ProgressModeFull), "Set how progress is shown with --progress, 'full' shows all files and 'compact' shows the total and the slowest files only")
+	command.Flags().IntVar(&progressFlagValues.SlowFileNum, "progress_slow_files", parallel.DefaultProgressSlowJobNum, "Set the number of the slowest files shown in 'compact' progress mode")
+}
+
+func SetProgressEventFlags(command *cobra.Command) {
+	command.Flags().StringVar(&progressFlagValues.EventTarget, "progress_json", "", "Write progress events as newline-delimited JSON to a file descriptor in 'fd:N' form (e.g., fd:3) or a file path")
+}
+
 func GetProgressFlagValues() *ProgressFlagValues {
 	return &progressFlagValues
 }
+
+// GetProgressMode returns the progress mode given
+func (p *ProgressFlagValues) GetProgressMode() (parallel.ProgressMode, error) {
+	return parallel.GetProgressMode(p.modeInput)
+}
+
+// OpenProgressEventWriter returns a writer for progress events given, nil if not given
+func (p *ProgressFlagValues) OpenProgressEventWriter() (*parallel.ProgressEventWriter, error) {
+	if len(p.EventTarget) == 0 {
+		return nil, nil
+	}
+
+	return parallel.OpenProgressEventWriter(p.EventTarget)
+}
@@ -0,0 +1,31 @@
+package flag
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+type ReencryptFlagValues struct {
+	FromKey     string
+	ToKeys      []string
+	FilenameKey string
+	TempPath    string
+}
+
+var (
+	reencryptFlagValues ReencryptFlagValues
+)
+
+func SetReencryptFlags(command *cobra.Command) {
+	command.Flags().StringVar(&reencryptFlagValues.FromKey, "from_key", "", "Specify the current key, the encryption key for 'winscp', 'aesgcm', and 'pgp' modes, the private key for 'ssh' mode, or the identity file for 'age' mode")
+	command.Flags().StringArrayVar(&reencryptFlagValues.ToKeys, "to_key", []string{}, "Specify the new key, the encryption key for 'winscp', 'aesgcm', and 'pgp' modes, the public key or key ring for 'ssh' mode, or the recipient for 'age' mode, can be given multiple times for 'ssh' and 'age' modes")
+	command.Flags().StringVar(&reencryptFlagValues.FilenameKey, "filename_key", "", "Specify the key for filenames in 'age' mode, same as 'encrypt_key' of put (default is the password)")
+	command.Flags().StringVar(&reencryptFlagValues.TempPath, "reencrypt_temp", os.TempDir(), "Set a temporary directory path for re-encryption")
+
+	command.MarkFlagRequired("to_key")
+}
+
+func GetReencryptFlagValues() *ReencryptFlagValues {
+	return &reencryptFlagValues
+}
@@ -0,0 +1,29 @@
+package flag
+
+import (
+	"github.com/cyverse/gocommands/commons/parallel"
+	"github.com/spf13/cobra"
+)
+
+type ScheduleFlagValues struct {
+	policyInput      string
+	PriorityPatterns []string
+}
+
+var (
+	scheduleFlagValues ScheduleFlagValues
+)
+
+func SetScheduleFlags(command *cobra.Command) {
+	command.Flags().StringVar(&scheduleFlagValues.policyInput, "schedule", string(parallel.SchedulePolicyFIFO), "Set the order files are transferred ('fifo', 'small_first', 'large_first', or 'interleave')")
+	command.Flags().StringArrayVar(&scheduleFlagValues.PriorityPatterns, "priority", []string{}, "Transfer files having names matching the pattern (e.g., '*.json') first, can be given multiple times in descending order of priority")
+}
+
+func GetScheduleFlagValues() *ScheduleFlagValues {
+	return &scheduleFlagValues
+}
+
+// GetSchedulePolicy returns the schedule policy given
+func (s *ScheduleFlagValues) GetSchedulePolicy() (parallel.SchedulePolicy, error) {
+	return parallel.GetSchedulePolicy(s.policyInput)
+}
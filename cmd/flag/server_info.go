@@ -0,0 +1,21 @@
+package flag
+
+import (
+	"github.com/spf13/cobra"
+)
+
+type ServerInfoFlagValues struct {
+	Full bool
+}
+
+var (
+	serverInfoFlagValues ServerInfoFlagValues
+)
+
+func SetServerInfoFlags(command *cobra.Command) {
+	command.Flags().BoolVar(&serverInfoFlagValues.Full, "full", false, "Display connection parameters, latency, server capabilities, and resource tree for troubleshooting")
+}
+
+func GetServerInfoFlagValues() *ServerInfoFlagValues {
+	return &serverInfoFlagValues
+}
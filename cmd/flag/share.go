@@ -0,0 +1,53 @@
+package flag
+
+import (
+	"time"
+
+	"github.com/cyverse/gocommands/commons/types"
+	"github.com/spf13/cobra"
+)
+
+type ShareFlagValues struct {
+	expiresInput   string
+	ExpirationTime time.Time
+	ExpiresUpdated bool
+	UseLimit       int64
+	Users          []string
+	Groups         []string
+	Hosts          []string
+	Write          bool
+}
+
+var (
+	shareFlagValues ShareFlagValues
+)
+
+func SetShareFlags(command *cobra.Command) {
+	command.Flags().StringVar(&shareFlagValues.expiresInput, "expires", "", "Set the expiration of the share, as a duration (e.g., 12h, 7d) or time [YYYY-MM-DD HH:mm:SS]")
+	command.Flags().Int64Var(&shareFlagValues.UseLimit, "uses", 0, "Set the number of uses allowed")
+	command.Flags().StringSliceVar(&shareFlagValues.Users, "users", []string{}, "Allow only the given users to use the share")
+	command.Flags().StringSliceVar(&shareFlagValues.Groups, "groups", []string{}, "Allow only the given groups to use the share")
+	command.Flags().StringSliceVar(&shareFlagValues.Hosts, "hosts", []string{}, "Allow only the given hosts to use the share")
+	command.Flags().BoolVar(&shareFlagValues.Write, "write", false, "Create a writable share")
+}
+
+func GetShareFlagValues(command *cobra.Command) *ShareFlagValues {
+	shareFlagValues.ExpirationTime = time.Time{}
+
+	if command.Flags().Changed("expires") && len(shareFlagValues.expiresInput) > 0 {
+		// duration first
+		seconds, err := types.ParseTime(shareFlagValues.expiresInput)
+		if err == nil {
+			shareFlagValues.ExpirationTime = time.Now().Add(time.Duration(seconds) * time.Second)
+			shareFlagValues.ExpiresUpdated = true
+		} else {
+			exp, err := types.MakeDateTimeFromString(shareFlagValues.expiresInput)
+			if err == nil {
+				shareFlagValues.ExpirationTime = exp
+				shareFlagValues.ExpiresUpdated = true
+			}
+		}
+	}
+
+	return &shareFlagValues
+}
@@ -11,7 +11,8 @@ import (
 )
 
 type TicketAccessFlagValues struct {
-	Name string
+	Name      string
+	Anonymous bool
 }
 
 type TicketFlagValues struct {
@@ -42,14 +43,22 @@ type TicketUpdateFlagValues struct {
 	RemoveAllowedHosts       []string
 }
 
+type TicketFilterFlagValues struct {
+	Expired   bool
+	Exhausted bool
+	Dangling  bool
+}
+
 var (
 	ticketAccessFlagValues TicketAccessFlagValues
 	ticketFlagValues       TicketFlagValues
 	ticketUpdateFlagValues TicketUpdateFlagValues
+	ticketFilterFlagValues TicketFilterFlagValues
 )
 
 func SetTicketAccessFlags(command *cobra.Command) {
 	command.Flags().StringVarP(&ticketAccessFlagValues.Name, "ticket", "T", "", "Specify the name of the ticket")
+	command.Flags().BoolVar(&ticketAccessFlagValues.Anonymous, "anonymous", false, "Access as anonymous user with the ticket, no iRODS account is required")
 }
 
 func GetTicketAccessFlagValues() *TicketAccessFlagValues {
@@ -142,3 +151,18 @@ func GetTicketUpdateFlagValues(command *cobra.Command) *TicketUpdateFlagValues {
 
 	return &ticketUpdateFlagValues
 }
+
+func SetTicketFilterFlags(command *cobra.Command) {
+	command.Flags().BoolVar(&ticketFilterFlagValues.Expired, "expired", false, "Select tickets past their expiration time")
+	command.Flags().BoolVar(&ticketFilterFlagValues.Exhausted, "exhausted", false, "Select tickets that reached their uses or write limits")
+	command.Flags().BoolVar(&ticketFilterFlagValues.Dangling, "dangling", false, "Select tickets pointing to deleted paths")
+}
+
+func GetTicketFilterFlagValues() *TicketFilterFlagValues {
+	return &ticketFilterFlagValues
+}
+
+// HasFilter returns true if any filter is set
+func (values *TicketFilterFlagValues) HasFilter() bool {
+	return values.Expired || values.Exhausted || values.Dangling
+}
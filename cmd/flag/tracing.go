@@ -0,0 +1,36 @@
+package flag
+
+import (
+	"github.com/cyverse/gocommands/commons/tracing"
+	"github.com/spf13/cobra"
+)
+
+type TracingFlagValues struct {
+	Target string
+}
+
+var (
+	tracingFlagValues TracingFlagValues
+)
+
+func SetTracingFlags(command *cobra.Command) {
+	command.Flags().StringVar(&tracingFlagValues.Target, "trace", "", "Export OpenTelemetry traces as OTLP/JSON to a file path, or to a collector at an http(s) URL (e.g., 'http://localhost:4318')")
+}
+
+func GetTracingFlagValues() *TracingFlagValues {
+	return &tracingFlagValues
+}
+
+// StartTracer returns a tracer exporting spans to the target given, nil if not given
+func (t *TracingFlagValues) StartTracer() (*tracing.Tracer, error) {
+	if len(t.Target) == 0 {
+		return nil, nil
+	}
+
+	exporter, err := tracing.NewExporter(t.Target)
+	if err != nil {
+		return nil, err
+	}
+
+	return tracing.NewTracer(exporter), nil
+}
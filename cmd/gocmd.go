@@ -8,6 +8,7 @@ import (
 	"github.com/cyverse/gocommands/cmd/flag"
 	"github.com/cyverse/gocommands/cmd/subcmd"
 	"github.com/cyverse/gocommands/commons/config"
+	"github.com/cyverse/gocommands/commons/interrupt"
 	"github.com/cyverse/gocommands/commons/terminal"
 	"github.com/cyverse/gocommands/commons/types"
 	log "github.com/sirupsen/logrus"
@@ -15,13 +16,19 @@ import (
 )
 
 // rootCmd represents the base command when called without any subcommands
+const (
+	// deadlineExceededExitCode is the exit code when transfers are aborted by --deadline, same as timeout(1)
+	deadlineExceededExitCode int = 124
+)
+
 var rootCmd = &cobra.Command{
-	Use:           "gocmd <subcommand> [flags]",
-	Short:         "GoCommands: A command-line interface for interacting with iRODS",
-	Long:          `Gocommands is a powerful command-line tool for interacting with iRODS (Integrated Rule-Oriented Data System). It allows users to manage data objects, collections, and more within iRODS from the terminal.`,
-	RunE:          processCommand,
-	SilenceUsage:  true,
-	SilenceErrors: true,
+	Use:               "gocmd <subcommand> [flags]",
+	Short:             "GoCommands: A command-line interface for interacting with iRODS",
+	Long:              `Gocommands is a powerful command-line tool for interacting with iRODS (Integrated Rule-Oriented Data System). It allows users to manage data objects, collections, and more within iRODS from the terminal.`,
+	RunE:              processCommand,
+	PersistentPreRunE: applyConfigFlags,
+	SilenceUsage:      true,
+	SilenceErrors:     true,
 	CompletionOptions: cobra.CompletionOptions{
 		DisableDefaultCmd:   true,
 		DisableNoDescFlag:   true,
@@ -34,6 +41,27 @@ func Execute() error {
 	return rootCmd.Execute()
 }
 
+// applyConfigFlags applies default flag values in config files and environment variables to all subcommands
+func applyConfigFlags(command *cobra.Command, args []string) error {
+	commonFlagValues := flag.GetCommonFlagValues(command)
+	if commonFlagValues.ShowVersion || commonFlagValues.ShowHelp {
+		// print version and help even if configs are broken
+		return nil
+	}
+
+	err := flag.ApplyConfigFlags(command)
+	if err != nil {
+		if config.GetUserConfigError() != nil && flag.GetConfigCommandName(command) == "config_show" {
+			// config show runs to report the broken user config
+			return nil
+		}
+
+		return errors.Wrapf(err, "failed to apply default flag values in config")
+	}
+
+	return nil
+}
+
 func processCommand(command *cobra.Command, args []string) error {
 	logger := log.WithFields(log.Fields{
 		"command": command.Name(),
@@ -74,6 +102,12 @@ func main() {
 		logger.Debugf("failed to init system config: %v", err)
 	}
 
+	err = config.InitUserConfig()
+	if err != nil {
+		// reported when default flag values are applied, so --version, --help, and config show still run
+		logger.Debugf("failed to init user config: %v", err)
+	}
+
 	// attach common flags
 	flag.SetCommonFlags(rootCmd, true)
 
@@ -101,20 +135,30 @@ func main() {
 	subcmd.AddRmdirCommand(rootCmd)
 	subcmd.AddBunCommand(rootCmd)
 	subcmd.AddBputCommand(rootCmd)
+	subcmd.AddReencryptCommand(rootCmd)
 	subcmd.AddSvrinfoCommand(rootCmd)
+	subcmd.AddLsrescCommand(rootCmd)
 	subcmd.AddPsCommand(rootCmd)
 	subcmd.AddLsmetaCommand(rootCmd)
 	subcmd.AddAddmetaCommand(rootCmd)
 	subcmd.AddRmmetaCommand(rootCmd)
+	subcmd.AddSetmetaCommand(rootCmd)
+	subcmd.AddModmetaCommand(rootCmd)
+	subcmd.AddMetadataCommand(rootCmd)
 	subcmd.AddCopySftpIdCommand(rootCmd)
 	subcmd.AddLsticketCommand(rootCmd)
 	subcmd.AddRmticketCommand(rootCmd)
 	subcmd.AddMkticketCommand(rootCmd)
 	subcmd.AddModticketCommand(rootCmd)
+	subcmd.AddShareCommand(rootCmd)
+	subcmd.AddDropboxCommand(rootCmd)
 	subcmd.AddBcleanCommand(rootCmd)
 	subcmd.AddChmodCommand(rootCmd)
 	subcmd.AddChmodinheritCommand(rootCmd)
+	subcmd.AddLsaclCommand(rootCmd)
+	subcmd.AddACLCommand(rootCmd)
 	subcmd.AddUpgradeCommand(rootCmd)
+	subcmd.AddConfigCommand(rootCmd)
 
 	err = Execute()
 	if err != nil {
@@ -124,7 +168,15 @@ func main() {
 			terminal.PrintErrorf("%+v\n", err)
 		}
 
-		if os.IsNotExist(err) {
+		if types.IsInterruptedError(err) {
+			terminal.PrintErrorf("Interrupted!\n")
+			os.Exit(interrupt.AbortExitCode)
+		} else if types.IsDeadlineExceededError(err) {
+			terminal.PrintErrorf("Deadline exceeded!\n")
+			os.Exit(deadlineExceededExitCode)
+		} else if userConfigErr := config.GetUserConfigError(); userConfigErr != nil && errors.Is(err, userConfigErr) {
+			terminal.PrintErrorf("Failed to load user config: %v\n", userConfigErr)
+		} else if os.IsNotExist(err) {
 			terminal.PrintErrorf("File or directory not found!\n")
 		} else if irodsclient_types.IsConnectionConfigError(err) {
 			var connectionConfigError *irodsclient_types.ConnectionConfigError
@@ -198,6 +250,13 @@ func main() {
 			} else {
 				terminal.PrintErrorf("WebDAV Error!\n")
 			}
+		} else if types.IsIntegrityError(err) {
+			var integrityError *types.IntegrityError
+			if errors.As(err, &integrityError) {
+				terminal.PrintErrorf("Integrity check failed for %q, the encrypted data may be tampered or truncated!\n", integrityError.Path)
+			} else {
+				terminal.PrintErrorf("Integrity check failed, the encrypted data may be tampered or truncated!\n")
+			}
 		} else if types.IsNotDirError(err) {
 			var notDirError *types.NotDirError
 			if errors.As(err, &notDirError) {
@@ -0,0 +1,361 @@
+package subcmd
+
+import (
+	"fmt"
+	"sort"
+	"sync/atomic"
+
+	"github.com/cockroachdb/errors"
+	irodsclient_fs "github.com/cyverse/go-irodsclient/fs"
+	irodsclient_types "github.com/cyverse/go-irodsclient/irods/types"
+	"github.com/cyverse/gocommands/cmd/flag"
+	"github.com/cyverse/gocommands/commons/config"
+	"github.com/cyverse/gocommands/commons/format"
+	"github.com/cyverse/gocommands/commons/irods"
+	"github.com/cyverse/gocommands/commons/parallel"
+	"github.com/cyverse/gocommands/commons/path"
+	"github.com/cyverse/gocommands/commons/terminal"
+	"github.com/jedib0t/go-pretty/v6/progress"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var aclCmd = &cobra.Command{
+	Use:   "acl",
+	Short: "Manage access control lists declaratively",
+	Long:  `This command manages access control lists of collections and data objects from a policy file.`,
+}
+
+var aclApplyCmd = &cobra.Command{
+	Use:   "apply <policy-file>",
+	Short: "Apply an ACL policy file",
+	Long: `This command applies an ACL policy file (YAML) listing paths, principals, access levels, and inheritance.
+It computes changes against the current ACLs, shows the plan, and applies the changes in parallel. Use --dry_run to show the plan only.`,
+	Example: `  gocmd acl apply policy.yaml --dry_run
+
+  # policy.yaml
+  rules:
+    - path: /zone/home/lab/project
+      recursive: true
+      inherit: true
+      exclusive: false
+      access:
+        - principal: g:lab-members
+          level: read
+        - principal: alice
+          level: write`,
+	RunE: processACLApplyCommand,
+	Args: cobra.ExactArgs(1),
+}
+
+func AddACLCommand(rootCmd *cobra.Command) {
+	// attach common flags
+	flag.SetCommonFlags(aclApplyCmd, true)
+
+	flag.SetOutputFormatFlags(aclApplyCmd, true)
+	flag.SetDryRunFlags(aclApplyCmd)
+	flag.SetACLApplyFlags(aclApplyCmd)
+
+	aclCmd.AddCommand(aclApplyCmd)
+
+	rootCmd.AddCommand(aclCmd)
+}
+
+func processACLApplyCommand(command *cobra.Command, args []string) error {
+	aclApply, err := NewACLApplyCommand(command, args)
+	if err != nil {
+		return err
+	}
+
+	return aclApply.Process()
+}
+
+type ACLApplyCommand struct {
+	command *cobra.Command
+
+	commonFlagValues       *flag.CommonFlagValues
+	outputFormatFlagValues *flag.OutputFormatFlagValues
+	dryRunFlagValues       *flag.DryRunFlagValues
+	aclApplyFlagValues     *flag.ACLApplyFlagValues
+
+	account    *irodsclient_types.IRODSAccount
+	filesystem *irodsclient_fs.FileSystem
+
+	policyPath string
+	policy     *irods.ACLPolicy
+
+	// desired state per path, paths are kept in order to make plan deterministic
+	desiredStates map[string]*irods.ACLDesiredState
+	entries       map[string]*irodsclient_fs.Entry
+	entryPaths    []string
+}
+
+func NewACLApplyCommand(command *cobra.Command, args []string) (*ACLApplyCommand, error) {
+	aclApply := &ACLApplyCommand{
+		command: command,
+
+		commonFlagValues:       flag.GetCommonFlagValues(command),
+		outputFormatFlagValues: flag.GetOutputFormatFlagValues(),
+		dryRunFlagValues:       flag.GetDryRunFlagValues(),
+		aclApplyFlagValues:     flag.GetACLApplyFlagValues(),
+
+		desiredStates: map[string]*irods.ACLDesiredState{},
+		entries:       map[string]*irodsclient_fs.Entry{},
+		entryPaths:    []string{},
+	}
+
+	aclApply.policyPath = args[0]
+
+	return aclApply, nil
+}
+
+func (aclApply *ACLApplyCommand) Process() error {
+	cont, err := flag.ProcessCommonFlags(aclApply.command)
+	if err != nil {
+		return errors.Wrapf(err, "failed to process common flags")
+	}
+
+	if !cont {
+		return nil
+	}
+
+	aclApply.policy, err = irods.NewACLPolicyFromFile(aclApply.policyPath)
+	if err != nil {
+		return err
+	}
+
+	// handle local flags
+	_, err = config.InputMissingFields()
+	if err != nil {
+		return errors.Wrapf(err, "failed to input missing fields")
+	}
+
+	// Create a file system
+	aclApply.account = config.GetSessionConfig().ToIRODSAccount()
+
+	timeout := 0
+	if aclApply.commonFlagValues.TimeoutUpdated {
+		timeout = aclApply.commonFlagValues.Timeout
+	}
+
+	aclApply.filesystem, err = irods.GetIRODSFSClient(aclApply.account, false, timeout)
+	if err != nil {
+		return errors.Wrapf(err, "failed to get iRODS FS Client")
+	}
+	defer aclApply.filesystem.Release()
+
+	// merge rules to desired states
+	for ruleIdx := range aclApply.policy.Rules {
+		err = aclApply.collectRule(&aclApply.policy.Rules[ruleIdx])
+		if err != nil {
+			return errors.Wrapf(err, "failed to process rule for %q", aclApply.policy.Rules[ruleIdx].Path)
+		}
+	}
+
+	// compute plan
+	actions, err := aclApply.makePlan()
+	if err != nil {
+		return errors.Wrapf(err, "failed to make a plan")
+	}
+
+	if aclApply.dryRunFlagValues.DryRun || len(actions) == 0 {
+		aclApply.printActions(actions, nil)
+		return nil
+	}
+
+	results, err := aclApply.applyActions(actions)
+	aclApply.printActions(actions, results)
+	if err != nil {
+		return errors.Wrapf(err, "failed to apply ACL policy")
+	}
+
+	return nil
+}
+
+func (aclApply *ACLApplyCommand) collectRule(rule *irods.ACLPolicyRule) error {
+	cwd := config.GetCWD()
+	home := config.GetHomeDir()
+	zone := aclApply.account.ClientZone
+	rulePath := path.MakeIRODSPath(cwd, home, zone, rule.Path)
+
+	entry, err := aclApply.filesystem.Stat(rulePath)
+	if err != nil {
+		return errors.Wrapf(err, "failed to stat %q", rulePath)
+	}
+
+	return aclApply.collectEntry(entry, rule)
+}
+
+func (aclApply *ACLApplyCommand) collectEntry(entry *irodsclient_fs.Entry, rule *irods.ACLPolicyRule) error {
+	if _, ok := aclApply.entries[entry.Path]; !ok {
+		aclApply.entries[entry.Path] = entry
+		aclApply.entryPaths = append(aclApply.entryPaths, entry.Path)
+	}
+
+	aclApply.desiredStates[entry.Path] = irods.MergeACLDesiredState(aclApply.desiredStates[entry.Path], rule, aclApply.account.ClientZone)
+
+	if !entry.IsDir() || !rule.Recursive {
+		return nil
+	}
+
+	childEntries, err := aclApply.filesystem.List(entry.Path)
+	if err != nil {
+		return errors.Wrapf(err, "failed to list collection %q", entry.Path)
+	}
+
+	for _, childEntry := range childEntries {
+		err = aclApply.collectEntry(childEntry, rule)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (aclApply *ACLApplyCommand) makePlan() ([]irods.ACLAction, error) {
+	actions := []irods.ACLAction{}
+	protectedUser := aclApply.account.ClientUser + "#" + aclApply.account.ClientZone
+
+	entryPaths := make([]string, len(aclApply.entryPaths))
+	copy(entryPaths, aclApply.entryPaths)
+	sort.Strings(entryPaths)
+
+	for _, entryPath := range entryPaths {
+		entry := aclApply.entries[entryPath]
+		state := aclApply.desiredStates[entryPath]
+
+		var currentAccesses []*irodsclient_types.IRODSAccess
+		var err error
+		currentInherit := false
+
+		if entry.IsDir() {
+			currentAccesses, err = aclApply.filesystem.ListDirACLs(entry.Path)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to list ACLs for collection %q", entry.Path)
+			}
+
+			if state.Inherit != nil {
+				inherit, err := aclApply.filesystem.GetDirACLInheritance(entry.Path)
+				if err != nil {
+					return nil, errors.Wrapf(err, "failed to get inheritance for collection %q", entry.Path)
+				}
+
+				currentInherit = inherit.Inheritance
+			}
+		} else {
+			currentAccesses, err = aclApply.filesystem.ListFileACLs(entry.Path)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to list ACLs for data object %q", entry.Path)
+			}
+		}
+
+		entryActions := irods.ComputeACLActions(entry.Path, entry.IsDir(), currentAccesses, currentInherit, state, protectedUser)
+		actions = append(actions, entryActions...)
+	}
+
+	return actions, nil
+}
+
+func (aclApply *ACLApplyCommand) applyActions(actions []irods.ACLAction) ([]string, error) {
+	results := make([]string, len(actions))
+
+	jobManager := parallel.NewParallelJobManager(aclApply.aclApplyFlagValues.ThreadNumber, false, false, aclApply.aclApplyFlagValues.StopOnError)
+
+	appliedCount := int64(0)
+
+	for actionIdx := range actions {
+		action := actions[actionIdx]
+		resultIdx := actionIdx
+
+		applyTask := func(job *parallel.ParallelJob) error {
+			if job.IsCanceled() {
+				results[resultIdx] = "canceled"
+				return nil
+			}
+
+			err := aclApply.applyAction(&action)
+			if err != nil {
+				results[resultIdx] = "failed"
+				return err
+			}
+
+			results[resultIdx] = "applied"
+			atomic.AddInt64(&appliedCount, 1)
+			return nil
+		}
+
+		jobManager.Schedule(action.Path, applyTask, 1, progress.UnitsDefault)
+	}
+
+	err := jobManager.Start()
+
+	log.Debugf("applied %d of %d ACL changes", appliedCount, len(actions))
+
+	return results, err
+}
+
+func (aclApply *ACLApplyCommand) applyAction(action *irods.ACLAction) error {
+	logger := log.WithFields(log.Fields{
+		"path":      action.Path,
+		"user_name": action.UserName,
+		"user_zone": action.UserZone,
+	})
+
+	if action.IsInheritanceChange() {
+		logger.Debugf("change inheritance to %t", *action.Inherit)
+	} else {
+		logger.Debugf("change access from %q to %q", action.CurrentAccess, action.DesiredAccess)
+	}
+
+	return irods.ApplyACLAction(aclApply.filesystem, action)
+}
+
+func (aclApply *ACLApplyCommand) printActions(actions []irods.ACLAction, results []string) {
+	if len(actions) == 0 {
+		terminal.Printf("no changes, ACLs already match the policy\n")
+		return
+	}
+
+	outputFormatter := format.NewOutputFormatter(terminal.GetTerminalWriter())
+	outputFormatterTable := outputFormatter.NewTable("ACL Plan")
+
+	outputFormatterTable.SetHeader([]string{
+		"Path",
+		"Principal",
+		"Current",
+		"Desired",
+		"Result",
+	})
+
+	for actionIdx, action := range actions {
+		principal := "(inheritance)"
+		current := string(action.CurrentAccess)
+		desired := string(action.DesiredAccess)
+
+		if action.IsInheritanceChange() {
+			current = fmt.Sprintf("inherit=%t", !*action.Inherit)
+			desired = fmt.Sprintf("inherit=%t", *action.Inherit)
+		} else {
+			principal = fmt.Sprintf("%s#%s", action.UserName, action.UserZone)
+		}
+
+		result := "dry run"
+		if results != nil {
+			result = results[actionIdx]
+		}
+
+		outputFormatterTable.AppendRow([]interface{}{
+			action.Path,
+			principal,
+			current,
+			desired,
+			result,
+		})
+	}
+
+	if aclApply.outputFormatFlagValues.Format == format.OutputFormatLegacy {
+		aclApply.outputFormatFlagValues.Format = format.OutputFormatTable
+	}
+	outputFormatter.Render(aclApply.outputFormatFlagValues.Format)
+}
@@ -1,6 +1,7 @@
 package subcmd
 
 import (
+	"context"
 	"path"
 	"strings"
 
@@ -115,7 +116,7 @@ func (bun *BunCommand) Process() error {
 
 	// Expand wildcards
 	if bun.wildcardSearchFlagValues.WildcardSearch {
-		bun.sourcePaths, err = wildcard.ExpandWildcards(bun.filesystem, bun.account, bun.sourcePaths, false, true)
+		bun.sourcePaths, err = wildcard.ExpandWildcards(context.Background(), bun.filesystem, bun.account, bun.sourcePaths, false, true)
 		if err != nil {
 			return errors.Wrapf(err, "failed to expand wildcards")
 		}
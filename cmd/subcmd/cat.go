@@ -81,6 +81,14 @@ func (cat *CatCommand) Process() error {
 	}
 
 	// handle local flags
+	if cat.ticketAccessFlagValues.Anonymous {
+		if len(cat.ticketAccessFlagValues.Name) == 0 {
+			return errors.New("failed to access as anonymous user, ticket is not given")
+		}
+
+		config.SetAnonymousAccess(cat.ticketAccessFlagValues.Name)
+	}
+
 	_, err = config.InputMissingFields()
 	if err != nil {
 		return errors.Wrapf(err, "failed to input missing fields")
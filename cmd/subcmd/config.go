@@ -0,0 +1,214 @@
+package subcmd
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/cyverse/gocommands/cmd/flag"
+	"github.com/cyverse/gocommands/commons/config"
+	"github.com/cyverse/gocommands/commons/format"
+	"github.com/cyverse/gocommands/commons/terminal"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Manage default flag values of commands",
+	Long:  `This command manages default flag values of commands given in the system config, the user config (~/.irods/gocmd.yaml), and GOCMD_<COMMAND>_<FLAG> environment variables.`,
+}
+
+var configShowCmd = &cobra.Command{
+	Use:   "show [command]...",
+	Short: "Print effective default flag values and their sources",
+	Long: `This command prints default flag values of commands and where they are given.
+Values are merged in the order of system config < user config < environment variables < command-line flags.
+Without a command, values given in configs and environment variables are printed for all commands.
+With a command (e.g., 'put' or 'meta import'), all flags of the command are printed.`,
+	Example: `  gocmd config show
+  gocmd config show put`,
+	RunE: processConfigShowCommand,
+	Args: cobra.ArbitraryArgs,
+}
+
+func AddConfigCommand(rootCmd *cobra.Command) {
+	// attach common flags
+	flag.SetCommonFlagsWithoutResource(configShowCmd)
+	flag.SetOutputFormatFlags(configShowCmd, true)
+
+	configCmd.AddCommand(configShowCmd)
+
+	rootCmd.AddCommand(configCmd)
+}
+
+func processConfigShowCommand(command *cobra.Command, args []string) error {
+	cfg, err := NewConfigCommand(command, args)
+	if err != nil {
+		return err
+	}
+
+	return cfg.ProcessShow()
+}
+
+type ConfigCommand struct {
+	command *cobra.Command
+
+	commonFlagValues       *flag.CommonFlagValues
+	outputFormatFlagValues *flag.OutputFormatFlagValues
+
+	commandNames []string
+}
+
+func NewConfigCommand(command *cobra.Command, args []string) (*ConfigCommand, error) {
+	cfg := &ConfigCommand{
+		command: command,
+
+		commonFlagValues:       flag.GetCommonFlagValues(command),
+		outputFormatFlagValues: flag.GetOutputFormatFlagValues(),
+
+		commandNames: args,
+	}
+
+	return cfg, nil
+}
+
+func (cfg *ConfigCommand) ProcessShow() error {
+	cont, err := flag.ProcessCommonFlags(cfg.command)
+	if err != nil {
+		return errors.Wrapf(err, "failed to process common flags")
+	}
+
+	if !cont {
+		return nil
+	}
+
+	userConfigErr := config.GetUserConfigError()
+	if userConfigErr != nil {
+		terminal.PrintErrorf("Failed to load user config, values in it are not shown: %v\n", userConfigErr)
+	}
+
+	outputFormatter := format.NewOutputFormatter(terminal.GetTerminalWriter())
+	outputFormatterTable := outputFormatter.NewTable("Default Flag Values")
+
+	outputFormatterTable.SetHeader([]string{"Command", "Flag", "Value", "Source", "Origin"})
+
+	if len(cfg.commandNames) > 0 {
+		targetCommand, remainingArgs, err := cfg.command.Root().Find(cfg.commandNames)
+		if err != nil || len(remainingArgs) > 0 || !targetCommand.HasParent() || !targetCommand.Runnable() {
+			return errors.Errorf("unknown command %q", strings.Join(cfg.commandNames, " "))
+		}
+
+		err = cfg.appendCommandFlagValues(outputFormatterTable, targetCommand, true)
+		if err != nil {
+			return err
+		}
+	} else {
+		commands := cfg.getRunnableCommands(cfg.command.Root())
+
+		err = cfg.checkCommandNames(commands)
+		if err != nil {
+			return err
+		}
+
+		for _, command := range commands {
+			err = cfg.appendCommandFlagValues(outputFormatterTable, command, false)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if cfg.outputFormatFlagValues.Format == format.OutputFormatLegacy {
+		cfg.outputFormatFlagValues.Format = format.OutputFormatTable
+	}
+	outputFormatter.Render(cfg.outputFormatFlagValues.Format)
+
+	return nil
+}
+
+// appendCommandFlagValues appends default flag values of the command, values of flags not given in configs are appended if allFlags is true
+func (cfg *ConfigCommand) appendCommandFlagValues(outputFormatterTable *format.OutputFormatterTable, command *cobra.Command, allFlags bool) error {
+	commandName := flag.GetConfigCommandName(command)
+
+	configFlagValues, err := flag.GetConfigFlagValues(command)
+	if err != nil {
+		return errors.Wrapf(err, "failed to get default flag values of command %q", commandName)
+	}
+
+	configFlagValueMap := map[string]flag.ConfigFlagValue{}
+	for _, configFlagValue := range configFlagValues {
+		configFlagValueMap[configFlagValue.Flag] = configFlagValue
+	}
+
+	command.Flags().VisitAll(func(f *pflag.Flag) {
+		if configFlagValue, ok := configFlagValueMap[f.Name]; ok {
+			outputFormatterTable.AppendRow([]interface{}{
+				commandName,
+				f.Name,
+				flag.FormatConfigFlagValue(configFlagValue.Value),
+				configFlagValue.Source,
+				configFlagValue.Origin,
+			})
+		} else if allFlags {
+			outputFormatterTable.AppendRow([]interface{}{
+				commandName,
+				f.Name,
+				f.DefValue,
+				flag.ConfigFlagSourceDefault,
+				"",
+			})
+		}
+	})
+
+	return nil
+}
+
+func (cfg *ConfigCommand) getRunnableCommands(command *cobra.Command) []*cobra.Command {
+	commands := []*cobra.Command{}
+
+	if command.HasParent() && command.Runnable() {
+		commands = append(commands, command)
+	}
+
+	for _, subCommand := range command.Commands() {
+		commands = append(commands, cfg.getRunnableCommands(subCommand)...)
+	}
+
+	sort.SliceStable(commands, func(i int, j int) bool {
+		return flag.GetConfigCommandName(commands[i]) < flag.GetConfigCommandName(commands[j])
+	})
+
+	return commands
+}
+
+// checkCommandNames checks if command names in configs are valid
+func (cfg *ConfigCommand) checkCommandNames(commands []*cobra.Command) error {
+	commandNames := map[string]bool{}
+	for _, command := range commands {
+		commandNames[flag.GetConfigCommandName(command)] = true
+	}
+
+	configCommandFlags := map[string]config.CommandFlags{}
+
+	systemConfig := config.GetSystemConfig()
+	if systemConfig != nil {
+		systemConfigPath, _ := config.GetSystemConfigPath()
+		configCommandFlags[systemConfigPath] = systemConfig.CommandFlags
+	}
+
+	userConfig := config.GetUserConfig()
+	if userConfig != nil {
+		configCommandFlags[userConfig.Path] = userConfig.CommandFlags
+	}
+
+	for configPath, commandFlags := range configCommandFlags {
+		for commandName := range commandFlags {
+			if !commandNames[commandName] {
+				return errors.Errorf("unknown command %q in %q", commandName, configPath)
+			}
+		}
+	}
+
+	return nil
+}
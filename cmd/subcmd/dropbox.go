@@ -0,0 +1,253 @@
+package subcmd
+
+import (
+	"fmt"
+
+	"github.com/cockroachdb/errors"
+	irodsclient_fs "github.com/cyverse/go-irodsclient/fs"
+	irodsclient_types "github.com/cyverse/go-irodsclient/irods/types"
+	"github.com/cyverse/gocommands/cmd/flag"
+	"github.com/cyverse/gocommands/commons/config"
+	"github.com/cyverse/gocommands/commons/irods"
+	"github.com/cyverse/gocommands/commons/path"
+	"github.com/cyverse/gocommands/commons/terminal"
+	"github.com/cyverse/gocommands/commons/types"
+	"github.com/cyverse/gocommands/commons/webdav"
+	"github.com/rs/xid"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var dropboxCmd = &cobra.Command{
+	Use:   "dropbox",
+	Short: "Manage drop boxes for anonymous uploads",
+	Long:  `This command manages drop boxes, collections that accept uploads from users without iRODS accounts through write tickets.`,
+}
+
+var dropboxCreateCmd = &cobra.Command{
+	Use:   "create <collection>",
+	Short: "Create a drop box",
+	Long: `This command creates a write ticket for the specified collection with limits, so that users without iRODS accounts can upload data to the collection.
+It prints the gocmd command and the WebDAV URL to upload data with the ticket.`,
+	Example: `  gocmd dropbox create i:/zone/home/user/inbox --wflimit 100 --wblimit 10737418240 --expiry +168h`,
+	RunE:    processDropboxCreateCommand,
+	Args:    cobra.ExactArgs(1),
+}
+
+func AddDropboxCommand(rootCmd *cobra.Command) {
+	// attach common flags
+	flag.SetCommonFlags(dropboxCreateCmd, true)
+
+	flag.SetTicketUpdateFlags(dropboxCreateCmd)
+
+	// not applicable to a new ticket
+	dropboxCreateCmd.Flags().MarkHidden("clear_ulimit")
+	dropboxCreateCmd.Flags().MarkHidden("clear_wflimit")
+	dropboxCreateCmd.Flags().MarkHidden("clear_wblimit")
+	dropboxCreateCmd.Flags().MarkHidden("clear_expiry")
+	dropboxCreateCmd.Flags().MarkHidden("rm_user")
+	dropboxCreateCmd.Flags().MarkHidden("rm_group")
+	dropboxCreateCmd.Flags().MarkHidden("rm_host")
+
+	dropboxCmd.AddCommand(dropboxCreateCmd)
+
+	rootCmd.AddCommand(dropboxCmd)
+}
+
+func processDropboxCreateCommand(command *cobra.Command, args []string) error {
+	dropbox, err := NewDropboxCommand(command, args)
+	if err != nil {
+		return err
+	}
+
+	return dropbox.ProcessCreate()
+}
+
+type DropboxCommand struct {
+	command *cobra.Command
+
+	commonFlagValues       *flag.CommonFlagValues
+	ticketUpdateFlagValues *flag.TicketUpdateFlagValues
+
+	account    *irodsclient_types.IRODSAccount
+	filesystem *irodsclient_fs.FileSystem
+
+	targetPath string
+}
+
+func NewDropboxCommand(command *cobra.Command, args []string) (*DropboxCommand, error) {
+	dropbox := &DropboxCommand{
+		command: command,
+
+		commonFlagValues:       flag.GetCommonFlagValues(command),
+		ticketUpdateFlagValues: flag.GetTicketUpdateFlagValues(command),
+	}
+
+	dropbox.targetPath = args[0]
+
+	return dropbox, nil
+}
+
+func (dropbox *DropboxCommand) ProcessCreate() error {
+	cont, err := flag.ProcessCommonFlags(dropbox.command)
+	if err != nil {
+		return errors.Wrapf(err, "failed to process common flags")
+	}
+
+	if !cont {
+		return nil
+	}
+
+	// handle local flags
+	_, err = config.InputMissingFields()
+	if err != nil {
+		return errors.Wrapf(err, "failed to input missing fields")
+	}
+
+	// Create a file system
+	dropbox.account = config.GetSessionConfig().ToIRODSAccount()
+
+	timeout := 0
+	if dropbox.commonFlagValues.TimeoutUpdated {
+		timeout = dropbox.commonFlagValues.Timeout
+	}
+
+	dropbox.filesystem, err = irods.GetIRODSFSClient(dropbox.account, true, timeout)
+	if err != nil {
+		return errors.Wrapf(err, "failed to get iRODS FS Client")
+	}
+	defer dropbox.filesystem.Release()
+
+	cwd := config.GetCWD()
+	home := config.GetHomeDir()
+	zone := dropbox.account.ClientZone
+	targetPath := path.MakeIRODSPath(cwd, home, zone, dropbox.targetPath)
+
+	ticket, err := dropbox.createDropbox(targetPath)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create a drop box for %q", targetPath)
+	}
+
+	dropbox.printDropbox(ticket)
+	return nil
+}
+
+func (dropbox *DropboxCommand) createDropbox(targetPath string) (*irodsclient_types.IRODSTicket, error) {
+	ticketName := xid.New().String()
+
+	logger := log.WithFields(log.Fields{
+		"ticket_name": ticketName,
+		"target_path": targetPath,
+	})
+
+	logger.Debug("create a drop box")
+
+	if !dropbox.filesystem.ExistsDir(targetPath) {
+		return nil, irodsclient_types.NewFileNotFoundError(targetPath)
+	}
+
+	err := dropbox.filesystem.CreateTicket(ticketName, irodsclient_types.TicketTypeWrite, targetPath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create ticket %q", ticketName)
+	}
+
+	err = dropbox.configureDropbox(ticketName)
+	if err != nil {
+		// do not leave a write ticket without limits
+		logger.Debugf("remove the ticket %q as it failed to configure", ticketName)
+		dropbox.filesystem.DeleteTicket(ticketName)
+		return nil, err
+	}
+
+	ticket, err := dropbox.filesystem.GetTicket(ticketName)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get ticket %q", ticketName)
+	}
+
+	return ticket, nil
+}
+
+func (dropbox *DropboxCommand) configureDropbox(ticketName string) error {
+	values := dropbox.ticketUpdateFlagValues
+
+	if values.UseLimitUpdated && values.UseLimit > 0 {
+		err := dropbox.filesystem.ModifyTicketUseLimit(ticketName, values.UseLimit)
+		if err != nil {
+			return errors.Wrapf(err, "failed to set uses limit of ticket %q", ticketName)
+		}
+	}
+
+	if values.WriteFileLimitUpdated && values.WriteFileLimit > 0 {
+		err := dropbox.filesystem.ModifyTicketWriteFileLimit(ticketName, values.WriteFileLimit)
+		if err != nil {
+			return errors.Wrapf(err, "failed to set write file limit of ticket %q", ticketName)
+		}
+	}
+
+	if values.WriteByteLimitUpdated && values.WriteByteLimit > 0 {
+		err := dropbox.filesystem.ModifyTicketWriteByteLimit(ticketName, values.WriteByteLimit)
+		if err != nil {
+			return errors.Wrapf(err, "failed to set write byte limit of ticket %q", ticketName)
+		}
+	}
+
+	if values.ExpirationTimeUpdated && !values.ExpirationTime.IsZero() {
+		err := dropbox.filesystem.ModifyTicketExpirationTime(ticketName, values.ExpirationTime)
+		if err != nil {
+			return errors.Wrapf(err, "failed to set expiration time of ticket %q", ticketName)
+		}
+	}
+
+	for _, user := range values.AddAllowedUsers {
+		err := dropbox.filesystem.AddTicketAllowedUser(ticketName, user)
+		if err != nil {
+			return errors.Wrapf(err, "failed to add allowed user %q to ticket %q", user, ticketName)
+		}
+	}
+
+	for _, group := range values.AddAllowedGroups {
+		err := dropbox.filesystem.AddTicketAllowedGroup(ticketName, group)
+		if err != nil {
+			return errors.Wrapf(err, "failed to add allowed group %q to ticket %q", group, ticketName)
+		}
+	}
+
+	for _, host := range values.AddAllowedHosts {
+		err := dropbox.filesystem.AddTicketAllowedHost(ticketName, host)
+		if err != nil {
+			return errors.Wrapf(err, "failed to add allowed host %q to ticket %q", host, ticketName)
+		}
+	}
+
+	return nil
+}
+
+func (dropbox *DropboxCommand) printDropbox(ticket *irodsclient_types.IRODSTicket) {
+	expiryTime := "none"
+	if !ticket.ExpirationTime.IsZero() {
+		expiryTime = types.MakeDateTimeString(ticket.ExpirationTime)
+	}
+
+	writeFileLimit := "none"
+	if ticket.WriteFileLimit > 0 {
+		writeFileLimit = fmt.Sprintf("%d", ticket.WriteFileLimit)
+	}
+
+	writeByteLimit := "none"
+	if ticket.WriteByteLimit > 0 {
+		writeByteLimit = types.SizeString(ticket.WriteByteLimit)
+	}
+
+	terminal.Printf("Created a drop box for %q\n", ticket.Path)
+	terminal.Printf("  Ticket: %s\n", ticket.Name)
+	terminal.Printf("  Expiry: %s\n", expiryTime)
+	terminal.Printf("  Write file limit: %s\n", writeFileLimit)
+	terminal.Printf("  Write byte limit: %s\n", writeByteLimit)
+
+	webdavBaseURL := config.GetSessionConfig().WebDAVBaseURL
+	if len(webdavBaseURL) > 0 {
+		terminal.Printf("  WebDAV URL: %s\n", webdav.GetTicketURL(webdavBaseURL, ticket.Path, ticket.Name))
+	}
+
+	terminal.Printf("  Command: gocmd put -T %s --anonymous <local-file-or-dir> %s\n", quoteShellArgument(ticket.Name), quoteShellArgument("i:"+ticket.Path))
+}
@@ -1,6 +1,7 @@
 package subcmd
 
 import (
+	"context"
 	"fmt"
 	"path"
 	"sort"
@@ -116,6 +117,14 @@ func (ls *LsCommand) Process() error {
 	}
 
 	// handle local flags
+	if ls.ticketAccessFlagValues.Anonymous {
+		if len(ls.ticketAccessFlagValues.Name) == 0 {
+			return errors.New("failed to access as anonymous user, ticket is not given")
+		}
+
+		config.SetAnonymousAccess(ls.ticketAccessFlagValues.Name)
+	}
+
 	_, err = config.InputMissingFields()
 	if err != nil {
 		return errors.Wrapf(err, "failed to input missing fields")
@@ -146,7 +155,7 @@ func (ls *LsCommand) Process() error {
 
 	// Expand wildcards
 	if ls.wildcardSearchFlagValues.WildcardSearch {
-		expanded_results, err := wildcard.ExpandWildcards(ls.filesystem, ls.account, ls.sourcePaths, true, true)
+		expanded_results, err := wildcard.ExpandWildcards(context.Background(), ls.filesystem, ls.account, ls.sourcePaths, true, true)
 		if err != nil {
 			return errors.Wrapf(err, "failed to expand wildcards")
 		}
@@ -469,6 +478,15 @@ func (ls *LsCommand) printDataObjectsAndCollections(outputFormatter *format.Outp
 					encryptManager := ls.getEncryptionManagerForDecryption(encryptionMode)
 
 					decryptedFilename, err := encryptManager.DecryptFilename(newName)
+					if err != nil && encryptionMode == encryption.EncryptionModeSSH {
+						// filename of multi-recipient file is encrypted with the first key in collection's key ring
+						encryptionConfig := encryption.GetEncryptionConfigFromMeta(ls.filesystem, path.Dir(entry.Path))
+						if len(encryptionConfig.PublicKeys) > 0 {
+							encryptManager.SetPublicKeyRing(encryptionConfig.PublicKeys)
+							decryptedFilename, err = encryptManager.DecryptFilename(path.Base(newName))
+						}
+					}
+
 					if err != nil {
 						logger.Debugf("%+v", err)
 						desc = "decryption_failed"
@@ -752,6 +770,15 @@ func (ls *LsCommand) printDataObjectsAndCollections(outputFormatter *format.Outp
 					encryptManager := ls.getEncryptionManagerForDecryption(encryptionMode)
 
 					decryptedFilename, err := encryptManager.DecryptFilename(newName)
+					if err != nil && encryptionMode == encryption.EncryptionModeSSH {
+						// filename of multi-recipient file is encrypted with the first key in collection's key ring
+						encryptionConfig := encryption.GetEncryptionConfigFromMeta(ls.filesystem, path.Dir(replica.DataObject.Path))
+						if len(encryptionConfig.PublicKeys) > 0 {
+							encryptManager.SetPublicKeyRing(encryptionConfig.PublicKeys)
+							decryptedFilename, err = encryptManager.DecryptFilename(path.Base(newName))
+						}
+					}
+
 					if err != nil {
 						logger.Debugf("%+v", err)
 						desc = "decryption_failed"
@@ -1184,10 +1211,13 @@ func (ls *LsCommand) getEncryptionManagerForDecryption(mode encryption.Encryptio
 	manager := encryption.NewEncryptionManager(mode)
 
 	switch mode {
-	case encryption.EncryptionModeWinSCP, encryption.EncryptionModePGP:
+	case encryption.EncryptionModeWinSCP, encryption.EncryptionModeAESGCM, encryption.EncryptionModePGP:
 		manager.SetKey([]byte(ls.decryptionFlagValues.Key))
 	case encryption.EncryptionModeSSH:
 		manager.SetPublicPrivateKey(ls.decryptionFlagValues.PrivateKeyPath)
+	case encryption.EncryptionModeAge:
+		manager.SetKey([]byte(ls.decryptionFlagValues.Key))
+		manager.SetPublicPrivateKey(ls.decryptionFlagValues.AgeIdentityPath)
 	}
 
 	return manager
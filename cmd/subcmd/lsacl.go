@@ -0,0 +1,360 @@
+package subcmd
+
+import (
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	irodsclient_fs "github.com/cyverse/go-irodsclient/fs"
+	irodsclient_types "github.com/cyverse/go-irodsclient/irods/types"
+	"github.com/cyverse/gocommands/cmd/flag"
+	"github.com/cyverse/gocommands/commons/config"
+	"github.com/cyverse/gocommands/commons/format"
+	"github.com/cyverse/gocommands/commons/irods"
+	commons_path "github.com/cyverse/gocommands/commons/path"
+	"github.com/cyverse/gocommands/commons/terminal"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var lsaclCmd = &cobra.Command{
+	Use:     "lsacl <collection|data-object>...",
+	Aliases: []string{"ls_acl", "list_acl"},
+	Short:   "Report access control lists of collections and data objects",
+	Long: `This command reports accesses of users and groups for the specified collections and data objects in iRODS.
+Accesses of users are effective accesses, the highest of their own accesses and accesses granted to their groups.
+Each access is compared with the parent collection, and deviations (added, changed, or removed accesses, and disabled inheritance) are flagged.
+Use -r to audit an entire collection tree, and --output_csv or --output_json to save the report.`,
+	RunE: processLsaclCommand,
+	Args: cobra.ArbitraryArgs,
+}
+
+func AddLsaclCommand(rootCmd *cobra.Command) {
+	// attach common flags
+	flag.SetCommonFlags(lsaclCmd, false)
+
+	flag.SetOutputFormatFlags(lsaclCmd, true)
+	flag.SetRecursiveFlags(lsaclCmd, false)
+	flag.SetACLAuditFlags(lsaclCmd)
+
+	rootCmd.AddCommand(lsaclCmd)
+}
+
+func processLsaclCommand(command *cobra.Command, args []string) error {
+	lsACL, err := NewLsACLCommand(command, args)
+	if err != nil {
+		return err
+	}
+
+	return lsACL.Process()
+}
+
+type LsACLCommand struct {
+	command *cobra.Command
+
+	commonFlagValues       *flag.CommonFlagValues
+	outputFormatFlagValues *flag.OutputFormatFlagValues
+	recursiveFlagValues    *flag.RecursiveFlagValues
+	aclAuditFlagValues     *flag.ACLAuditFlagValues
+
+	account    *irodsclient_types.IRODSAccount
+	filesystem *irodsclient_fs.FileSystem
+
+	sourcePaths []string
+
+	groupMembers map[string][]*irodsclient_types.IRODSUser // cache, key is user string of the group
+}
+
+func NewLsACLCommand(command *cobra.Command, args []string) (*LsACLCommand, error) {
+	lsACL := &LsACLCommand{
+		command: command,
+
+		commonFlagValues:       flag.GetCommonFlagValues(command),
+		outputFormatFlagValues: flag.GetOutputFormatFlagValues(),
+		recursiveFlagValues:    flag.GetRecursiveFlagValues(),
+		aclAuditFlagValues:     flag.GetACLAuditFlagValues(),
+
+		groupMembers: map[string][]*irodsclient_types.IRODSUser{},
+	}
+
+	// path
+	lsACL.sourcePaths = args
+
+	if len(lsACL.sourcePaths) == 0 {
+		lsACL.sourcePaths = []string{"."}
+	}
+
+	return lsACL, nil
+}
+
+func (lsACL *LsACLCommand) Process() error {
+	cont, err := flag.ProcessCommonFlags(lsACL.command)
+	if err != nil {
+		return errors.Wrapf(err, "failed to process common flags")
+	}
+
+	if !cont {
+		return nil
+	}
+
+	// handle local flags
+	_, err = config.InputMissingFields()
+	if err != nil {
+		return errors.Wrapf(err, "failed to input missing fields")
+	}
+
+	// Create a file system
+	lsACL.account = config.GetSessionConfig().ToIRODSAccount()
+
+	timeout := 0
+	if lsACL.commonFlagValues.TimeoutUpdated {
+		timeout = lsACL.commonFlagValues.Timeout
+	}
+
+	lsACL.filesystem, err = irods.GetIRODSFSClient(lsACL.account, false, timeout)
+	if err != nil {
+		return errors.Wrapf(err, "failed to get iRODS FS Client")
+	}
+	defer lsACL.filesystem.Release()
+
+	outputFormatter := format.NewOutputFormatter(terminal.GetTerminalWriter())
+	outputFormatterTable := outputFormatter.NewTable("iRODS Access Control Lists")
+
+	outputFormatterTable.SetHeader([]string{
+		"Type",
+		"Path",
+		"User",
+		"Access",
+		"Parent Access",
+		"Inheritance",
+		"Deviation",
+	})
+
+	// run
+	for _, sourcePath := range lsACL.sourcePaths {
+		err = lsACL.auditOne(outputFormatterTable, sourcePath)
+		if err != nil {
+			return errors.Wrapf(err, "failed to audit ACLs for %q", sourcePath)
+		}
+	}
+
+	if lsACL.outputFormatFlagValues.Format == format.OutputFormatLegacy {
+		lsACL.outputFormatFlagValues.Format = format.OutputFormatTable
+	}
+	outputFormatter.Render(lsACL.outputFormatFlagValues.Format)
+
+	return nil
+}
+
+func (lsACL *LsACLCommand) auditOne(outputFormatterTable *format.OutputFormatterTable, sourcePath string) error {
+	cwd := config.GetCWD()
+	home := config.GetHomeDir()
+	zone := lsACL.account.ClientZone
+	sourcePath = commons_path.MakeIRODSPath(cwd, home, zone, sourcePath)
+
+	sourceEntry, err := lsACL.filesystem.Stat(sourcePath)
+	if err != nil {
+		return errors.Wrapf(err, "failed to stat %q", sourcePath)
+	}
+
+	// parent of the given path may not be accessible
+	parentAccesses, parentInherit, parentKnown := lsACL.getParentCollectionACLs(path.Dir(sourcePath))
+
+	if sourceEntry.IsDir() {
+		return lsACL.auditCollection(outputFormatterTable, sourceEntry, parentAccesses, parentInherit, parentKnown, true)
+	}
+
+	accesses, err := lsACL.filesystem.ListFileACLs(sourceEntry.Path)
+	if err != nil {
+		return errors.Wrapf(err, "failed to list ACLs for data object %q", sourceEntry.Path)
+	}
+
+	return lsACL.appendRows(outputFormatterTable, sourceEntry, accesses, parentAccesses, parentKnown, nil, false)
+}
+
+func (lsACL *LsACLCommand) getParentCollectionACLs(parentPath string) ([]*irodsclient_types.IRODSAccess, bool, bool) {
+	logger := log.WithFields(log.Fields{
+		"parent_path": parentPath,
+	})
+
+	if parentPath == "/" {
+		return nil, false, false
+	}
+
+	accesses, err := lsACL.filesystem.ListDirACLs(parentPath)
+	if err != nil {
+		logger.WithError(err).Debugf("failed to list ACLs for parent collection %q, skip comparison", parentPath)
+		return nil, false, false
+	}
+
+	inherit, err := lsACL.filesystem.GetDirACLInheritance(parentPath)
+	if err != nil {
+		logger.WithError(err).Debugf("failed to get inheritance for parent collection %q, skip comparison", parentPath)
+		return nil, false, false
+	}
+
+	return accesses, inherit.Inheritance, true
+}
+
+func (lsACL *LsACLCommand) auditCollection(outputFormatterTable *format.OutputFormatterTable, collEntry *irodsclient_fs.Entry, parentAccesses []*irodsclient_types.IRODSAccess, parentInherit bool, parentKnown bool, listChildren bool) error {
+	accesses, err := lsACL.filesystem.ListDirACLs(collEntry.Path)
+	if err != nil {
+		return errors.Wrapf(err, "failed to list ACLs for collection %q", collEntry.Path)
+	}
+
+	inherit, err := lsACL.filesystem.GetDirACLInheritance(collEntry.Path)
+	if err != nil {
+		return errors.Wrapf(err, "failed to get inheritance for collection %q", collEntry.Path)
+	}
+
+	// broken inheritance, parent passes accesses down but this collection does not
+	inheritanceBroken := parentKnown && parentInherit && !inherit.Inheritance
+
+	err = lsACL.appendRows(outputFormatterTable, collEntry, accesses, parentAccesses, parentKnown, &inherit.Inheritance, inheritanceBroken)
+	if err != nil {
+		return err
+	}
+
+	if !listChildren {
+		return nil
+	}
+
+	entries, err := lsACL.filesystem.List(collEntry.Path)
+	if err != nil {
+		return errors.Wrapf(err, "failed to list collection %q", collEntry.Path)
+	}
+
+	sort.SliceStable(entries, func(i int, j int) bool {
+		return entries[i].Path < entries[j].Path
+	})
+
+	// accesses of data objects in the collection at once
+	childAccesses, err := lsACL.filesystem.ListACLsForEntries(collEntry.Path)
+	if err != nil {
+		return errors.Wrapf(err, "failed to list ACLs for entries in collection %q", collEntry.Path)
+	}
+
+	childAccessMap := map[string][]*irodsclient_types.IRODSAccess{}
+	for _, childAccess := range childAccesses {
+		childAccessMap[childAccess.Path] = append(childAccessMap[childAccess.Path], childAccess)
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			err = lsACL.auditCollection(outputFormatterTable, entry, accesses, inherit.Inheritance, true, lsACL.recursiveFlagValues.Recursive)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		err = lsACL.appendRows(outputFormatterTable, entry, childAccessMap[entry.Path], accesses, true, nil, false)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// getEffectiveACLs resolves members of groups in the accesses and returns effective accesses
+func (lsACL *LsACLCommand) getEffectiveACLs(accesses []*irodsclient_types.IRODSAccess) ([]*irodsclient_types.IRODSAccess, error) {
+	for _, access := range accesses {
+		if access.UserType != irodsclient_types.IRODSUserRodsGroup {
+			continue
+		}
+
+		key := irods.GetACLUserString(access.UserName, access.UserZone, access.UserType)
+		if _, ok := lsACL.groupMembers[key]; ok {
+			continue
+		}
+
+		members, err := lsACL.filesystem.ListGroupMembers(access.UserZone, access.UserName)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to list members of group %q", key)
+		}
+
+		lsACL.groupMembers[key] = members
+	}
+
+	return irods.GetEffectiveACLs(accesses, lsACL.groupMembers), nil
+}
+
+func (lsACL *LsACLCommand) appendRows(outputFormatterTable *format.OutputFormatterTable, entry *irodsclient_fs.Entry, accesses []*irodsclient_types.IRODSAccess, parentAccesses []*irodsclient_types.IRODSAccess, parentKnown bool, inherit *bool, inheritanceBroken bool) error {
+	entryType := "data-object"
+	if entry.IsDir() {
+		entryType = "collection"
+	}
+
+	inheritance := "-"
+	if inherit != nil {
+		inheritance = "disabled"
+		if *inherit {
+			inheritance = "enabled"
+		}
+	}
+
+	effectiveAccesses, err := lsACL.getEffectiveACLs(accesses)
+	if err != nil {
+		return err
+	}
+
+	effectiveParentAccesses := effectiveAccesses
+	if parentKnown {
+		effectiveParentAccesses, err = lsACL.getEffectiveACLs(parentAccesses)
+		if err != nil {
+			return err
+		}
+	}
+
+	diffs := irods.DiffACLs(effectiveParentAccesses, effectiveAccesses)
+
+	if len(diffs) == 0 {
+		// no access at all, still report the entry
+		diffs = append(diffs, irods.ACLDiff{})
+	}
+
+	for idx, diff := range diffs {
+		deviations := []string{}
+		if diff.Deviation != irods.ACLDeviationNone {
+			deviations = append(deviations, string(diff.Deviation))
+		}
+
+		// inheritance is a property of the collection, report it once
+		if inheritanceBroken && idx == 0 {
+			deviations = append(deviations, "inheritance_disabled")
+		}
+
+		if lsACL.aclAuditFlagValues.DeviationOnly && len(deviations) == 0 {
+			continue
+		}
+
+		user := ""
+		if len(diff.UserName) > 0 {
+			user = diff.GetUserString()
+		}
+
+		parentAccess := "-"
+		if parentKnown {
+			parentAccess = string(diff.ParentAccess)
+		}
+
+		deviation := "none"
+		if len(deviations) > 0 {
+			deviation = strings.Join(deviations, ",")
+		}
+
+		outputFormatterTable.AppendRow([]interface{}{
+			entryType,
+			entry.Path,
+			user,
+			string(diff.AccessLevel),
+			parentAccess,
+			inheritance,
+			deviation,
+		})
+	}
+
+	return nil
+}
@@ -0,0 +1,183 @@
+package subcmd
+
+import (
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	irodsclient_fs "github.com/cyverse/go-irodsclient/fs"
+	irodsclient_types "github.com/cyverse/go-irodsclient/irods/types"
+	"github.com/cyverse/gocommands/cmd/flag"
+	"github.com/cyverse/gocommands/commons/config"
+	"github.com/cyverse/gocommands/commons/format"
+	"github.com/cyverse/gocommands/commons/irods"
+	"github.com/cyverse/gocommands/commons/terminal"
+	"github.com/dustin/go-humanize"
+	"github.com/spf13/cobra"
+)
+
+var lsrescCmd = &cobra.Command{
+	Use:     "lsresc [resource-name]",
+	Aliases: []string{"ls_resc", "list_resource", "list_resources"},
+	Short:   "List iRODS resources in their hierarchy",
+	Long:    `This command lists iRODS resources as a tree, with their type, location, vault path, free space, and status. If a resource name is given, only the resource and its children are listed.`,
+	RunE:    processLsrescCommand,
+	Args:    cobra.MaximumNArgs(1),
+}
+
+func AddLsrescCommand(rootCmd *cobra.Command) {
+	// attach common flags
+	flag.SetCommonFlagsWithoutResource(lsrescCmd)
+	flag.SetOutputFormatFlags(lsrescCmd, true)
+
+	rootCmd.AddCommand(lsrescCmd)
+}
+
+func processLsrescCommand(command *cobra.Command, args []string) error {
+	lsResc, err := NewLsRescCommand(command, args)
+	if err != nil {
+		return err
+	}
+
+	return lsResc.Process()
+}
+
+type LsRescCommand struct {
+	command *cobra.Command
+
+	commonFlagValues       *flag.CommonFlagValues
+	outputFormatFlagValues *flag.OutputFormatFlagValues
+
+	account    *irodsclient_types.IRODSAccount
+	filesystem *irodsclient_fs.FileSystem
+
+	resource string
+}
+
+func NewLsRescCommand(command *cobra.Command, args []string) (*LsRescCommand, error) {
+	lsResc := &LsRescCommand{
+		command: command,
+
+		commonFlagValues:       flag.GetCommonFlagValues(command),
+		outputFormatFlagValues: flag.GetOutputFormatFlagValues(),
+	}
+
+	if len(args) > 0 {
+		lsResc.resource = args[0]
+	}
+
+	return lsResc, nil
+}
+
+func (lsResc *LsRescCommand) Process() error {
+	cont, err := flag.ProcessCommonFlags(lsResc.command)
+	if err != nil {
+		return errors.Wrapf(err, "failed to process common flags")
+	}
+
+	if !cont {
+		return nil
+	}
+
+	// handle local flags
+	_, err = config.InputMissingFields()
+	if err != nil {
+		return errors.Wrapf(err, "failed to input missing fields")
+	}
+
+	// Create a file system
+	lsResc.account = config.GetSessionConfig().ToIRODSAccount()
+
+	timeout := 0
+	if lsResc.commonFlagValues.TimeoutUpdated {
+		timeout = lsResc.commonFlagValues.Timeout
+	}
+
+	lsResc.filesystem, err = irods.GetIRODSFSClient(lsResc.account, true, timeout)
+	if err != nil {
+		return errors.Wrapf(err, "failed to get iRODS FS Client")
+	}
+	defer lsResc.filesystem.Release()
+
+	resources, err := irods.ListResourceInfos(lsResc.filesystem)
+	if err != nil {
+		return errors.Wrapf(err, "failed to list resources")
+	}
+
+	roots := irods.GetRootResourceInfos(resources)
+	if len(lsResc.resource) > 0 {
+		roots = []*irods.ResourceInfo{}
+		for _, resc := range resources {
+			if resc.Name == lsResc.resource {
+				roots = append(roots, resc)
+				break
+			}
+		}
+
+		if len(roots) == 0 {
+			return errors.Errorf("failed to find resource %q", lsResc.resource)
+		}
+	}
+
+	if lsResc.outputFormatFlagValues.Format == format.OutputFormatLegacy {
+		lsResc.outputFormatFlagValues.Format = format.OutputFormatTable
+	}
+
+	outputFormatter := format.NewOutputFormatter(terminal.GetTerminalWriter())
+	appendResourceTreeTable(outputFormatter, roots, lsResc.outputFormatFlagValues.Format == format.OutputFormatTable)
+	outputFormatter.Render(lsResc.outputFormatFlagValues.Format)
+
+	return nil
+}
+
+// appendResourceTreeTable adds a table listing resources in the trees, names are indented by depth if drawTree is set
+func appendResourceTreeTable(outputFormatter *format.OutputFormatter, roots []*irods.ResourceInfo, drawTree bool) {
+	outputFormatterTable := outputFormatter.NewTable("iRODS Resources")
+	outputFormatterTable.SetHeader([]string{
+		"Resource",
+		"Parent",
+		"Type",
+		"Location",
+		"Vault Path",
+		"Free Space",
+		"Status",
+	})
+
+	for _, root := range roots {
+		appendResourceTreeRows(outputFormatterTable, root, "", "", drawTree)
+	}
+}
+
+func appendResourceTreeRows(outputFormatterTable *format.OutputFormatterTable, resc *irods.ResourceInfo, prefix string, childPrefix string, drawTree bool) {
+	name := resc.Name
+	if drawTree {
+		name = prefix + resc.Name
+	}
+
+	parent := ""
+	if resc.Parent != nil {
+		parent = resc.Parent.Name
+	}
+
+	freeSpace := ""
+	if resc.FreeSpace >= 0 {
+		freeSpace = humanize.Bytes(uint64(resc.FreeSpace))
+	}
+
+	outputFormatterTable.AppendRow([]interface{}{
+		name,
+		parent,
+		resc.Type,
+		resc.Location,
+		resc.VaultPath,
+		freeSpace,
+		resc.Status,
+	})
+
+	for childIdx, child := range resc.Children {
+		if childIdx == len(resc.Children)-1 {
+			appendResourceTreeRows(outputFormatterTable, child, childPrefix+"└── ", childPrefix+strings.Repeat(" ", 4), drawTree)
+		} else {
+			appendResourceTreeRows(outputFormatterTable, child, childPrefix+"├── ", childPrefix+"│   ", drawTree)
+		}
+	}
+}
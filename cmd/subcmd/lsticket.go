@@ -3,6 +3,7 @@ package subcmd
 import (
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/cockroachdb/errors"
 	irodsclient_fs "github.com/cyverse/go-irodsclient/fs"
@@ -13,6 +14,7 @@ import (
 	"github.com/cyverse/gocommands/commons/irods"
 	"github.com/cyverse/gocommands/commons/terminal"
 	"github.com/cyverse/gocommands/commons/types"
+	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
 
@@ -30,6 +32,7 @@ func AddLsticketCommand(rootCmd *cobra.Command) {
 	flag.SetCommonFlags(lsticketCmd, true)
 	flag.SetOutputFormatFlags(lsticketCmd, true)
 	flag.SetListFlags(lsticketCmd, true, true)
+	flag.SetTicketFilterFlags(lsticketCmd)
 
 	rootCmd.AddCommand(lsticketCmd)
 }
@@ -49,6 +52,7 @@ type LsTicketCommand struct {
 	commonFlagValues       *flag.CommonFlagValues
 	outputFormatFlagValues *flag.OutputFormatFlagValues
 	listFlagValues         *flag.ListFlagValues
+	ticketFilterFlagValues *flag.TicketFilterFlagValues
 
 	account    *irodsclient_types.IRODSAccount
 	filesystem *irodsclient_fs.FileSystem
@@ -63,6 +67,7 @@ func NewLsTicketCommand(command *cobra.Command, args []string) (*LsTicketCommand
 		commonFlagValues:       flag.GetCommonFlagValues(command),
 		outputFormatFlagValues: flag.GetOutputFormatFlagValues(),
 		listFlagValues:         flag.GetListFlagValues(),
+		ticketFilterFlagValues: flag.GetTicketFilterFlagValues(),
 	}
 
 	// tickets
@@ -115,11 +120,15 @@ func (lsTicket *LsTicketCommand) Process() error {
 		"Path",
 		"Uses Limit",
 		"Uses Count",
+		"Uses %",
 		"Write File Limit",
 		"Write File Count",
+		"Write File %",
 		"Write Byte Limit",
 		"Write Byte Count",
+		"Write Byte %",
 		"Expiry Time",
+		"Status",
 	}
 
 	if lsTicket.listFlagValues.Format == format.ListFormatLong || lsTicket.listFlagValues.Format == format.ListFormatVeryLong {
@@ -150,8 +159,15 @@ func (lsTicket *LsTicketCommand) Process() error {
 		}
 	}
 
+	// checking dangling tickets requires a query per ticket, do it only when requested
+	tickets, statuses, statusErr := getTicketStatuses(lsTicket.filesystem, tickets, lsTicket.ticketFilterFlagValues.Dangling)
+
+	if lsTicket.ticketFilterFlagValues.HasFilter() {
+		tickets = filterTickets(tickets, statuses, lsTicket.ticketFilterFlagValues)
+	}
+
 	if len(tickets) > 0 {
-		err = lsTicket.printTickets(outputFormatterTable, tickets)
+		err = lsTicket.printTickets(outputFormatterTable, tickets, statuses)
 		if err != nil {
 			return errors.Wrapf(err, "failed to print tickets")
 		}
@@ -162,14 +178,14 @@ func (lsTicket *LsTicketCommand) Process() error {
 	}
 	outputFormatter.Render(lsTicket.outputFormatFlagValues.Format)
 
-	return nil
+	return statusErr
 }
 
-func (lsTicket *LsTicketCommand) printTickets(outputFormatterTable *format.OutputFormatterTable, tickets []*irodsclient_types.IRODSTicket) error {
+func (lsTicket *LsTicketCommand) printTickets(outputFormatterTable *format.OutputFormatterTable, tickets []*irodsclient_types.IRODSTicket, statuses map[int64]irods.TicketStatus) error {
 	sort.SliceStable(tickets, lsTicket.getTicketSortFunction(tickets, lsTicket.listFlagValues.SortOrder, lsTicket.listFlagValues.SortReverse))
 
 	for _, ticket := range tickets {
-		err := lsTicket.printTicketInternal(outputFormatterTable, ticket)
+		err := lsTicket.printTicketInternal(outputFormatterTable, ticket, statuses[ticket.ID])
 		if err != nil {
 			return errors.Wrapf(err, "failed to print ticket %q", ticket.Name)
 		}
@@ -178,7 +194,7 @@ func (lsTicket *LsTicketCommand) printTickets(outputFormatterTable *format.Outpu
 	return nil
 }
 
-func (lsTicket *LsTicketCommand) printTicketInternal(outputFormatterTable *format.OutputFormatterTable, ticket *irodsclient_types.IRODSTicket) error {
+func (lsTicket *LsTicketCommand) printTicketInternal(outputFormatterTable *format.OutputFormatterTable, ticket *irodsclient_types.IRODSTicket, status irods.TicketStatus) error {
 	expiryTime := "none"
 	if !ticket.ExpirationTime.IsZero() {
 		expiryTime = types.MakeDateTimeString(ticket.ExpirationTime)
@@ -194,11 +210,15 @@ func (lsTicket *LsTicketCommand) printTicketInternal(outputFormatterTable *forma
 		ticket.Path,
 		ticket.UsesLimit,
 		ticket.UsesCount,
+		irods.GetTicketUsagePercent(ticket.UsesCount, ticket.UsesLimit),
 		ticket.WriteFileLimit,
 		ticket.WriteFileCount,
+		irods.GetTicketUsagePercent(ticket.WriteFileCount, ticket.WriteFileLimit),
 		ticket.WriteByteLimit,
 		ticket.WriteByteCount,
+		irods.GetTicketUsagePercent(ticket.WriteByteCount, ticket.WriteByteLimit),
 		expiryTime,
+		status.String(),
 	}
 
 	if lsTicket.listFlagValues.Format == format.ListFormatLong || lsTicket.listFlagValues.Format == format.ListFormatVeryLong {
@@ -276,3 +296,44 @@ func (lsTicket *LsTicketCommand) getTicketSortFunction(tickets []*irodsclient_ty
 		}
 	}
 }
+
+// getTicketStatuses returns tickets whose status is checked, and their status by ticket ID
+// tickets whose status cannot be checked are skipped, and the first error is returned with the statuses
+func getTicketStatuses(filesystem *irodsclient_fs.FileSystem, tickets []*irodsclient_types.IRODSTicket, checkDangling bool) ([]*irodsclient_types.IRODSTicket, map[int64]irods.TicketStatus, error) {
+	now := time.Now()
+	checkedTickets := []*irodsclient_types.IRODSTicket{}
+	statuses := map[int64]irods.TicketStatus{}
+	var statusErr error
+
+	for _, ticket := range tickets {
+		status, err := irods.GetTicketStatus(filesystem, ticket, now, checkDangling)
+		if err != nil {
+			log.WithError(err).Errorf("skip ticket %q as its status cannot be checked", ticket.Name)
+			if statusErr == nil {
+				statusErr = errors.Wrapf(err, "failed to get status of ticket %q", ticket.Name)
+			}
+			continue
+		}
+
+		checkedTickets = append(checkedTickets, ticket)
+		statuses[ticket.ID] = status
+	}
+
+	return checkedTickets, statuses, statusErr
+}
+
+// filterTickets returns tickets matching any of the filters
+func filterTickets(tickets []*irodsclient_types.IRODSTicket, statuses map[int64]irods.TicketStatus, filterValues *flag.TicketFilterFlagValues) []*irodsclient_types.IRODSTicket {
+	filteredTickets := []*irodsclient_types.IRODSTicket{}
+
+	for _, ticket := range tickets {
+		status := statuses[ticket.ID]
+		if (filterValues.Expired && status.Expired) ||
+			(filterValues.Exhausted && status.Exhausted) ||
+			(filterValues.Dangling && status.Dangling) {
+			filteredTickets = append(filteredTickets, ticket)
+		}
+	}
+
+	return filteredTickets
+}
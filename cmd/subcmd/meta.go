@@ -0,0 +1,447 @@
+package subcmd
+
+import (
+	"context"
+	"sort"
+	"sync/atomic"
+
+	"github.com/cockroachdb/errors"
+	irodsclient_fs "github.com/cyverse/go-irodsclient/fs"
+	irodsclient_types "github.com/cyverse/go-irodsclient/irods/types"
+	"github.com/cyverse/gocommands/cmd/flag"
+	"github.com/cyverse/gocommands/commons/config"
+	"github.com/cyverse/gocommands/commons/format"
+	"github.com/cyverse/gocommands/commons/irods"
+	"github.com/cyverse/gocommands/commons/parallel"
+	"github.com/cyverse/gocommands/commons/path"
+	"github.com/cyverse/gocommands/commons/terminal"
+	"github.com/cyverse/gocommands/commons/wildcard"
+	"github.com/jedib0t/go-pretty/v6/progress"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var metaCmd = &cobra.Command{
+	Use:     "meta",
+	Aliases: []string{"metadata"},
+	Short:   "Import or export metadata in bulk",
+	Long:    `This command imports metadata of many iRODS objects from a file, or exports metadata of iRODS objects.`,
+}
+
+var metaImportCmd = &cobra.Command{
+	Use:   "import <csv-or-json-file>",
+	Short: "Import metadata from a CSV or JSON file",
+	Long: `This command applies metadata operations listed in a CSV or JSON file to iRODS objects in parallel.
+Each record has a target (path, user, or resource), attribute, value, unit, and operation (add, set, or rm; add by default).
+CSV columns are path, attribute, value, unit, and op in that order, unless the first row is a header naming the columns.
+JSON is an array of objects having the same keys, or output of 'meta export --output_json'.`,
+	Example: `  gocmd meta import metadata.csv
+
+  # metadata.csv
+  path,attribute,value,unit,op
+  /zone/home/user/data/sample1.fastq,sample_id,S-001,,set
+  /zone/home/user/data/sample1.fastq,read_length,150,bp,add
+  /zone/home/user/data/sample2.fastq,obsolete,,,rm`,
+	RunE: processMetaImportCommand,
+	Args: cobra.ExactArgs(1),
+}
+
+var metaExportCmd = &cobra.Command{
+	Use:   "export <irods-object>...",
+	Short: "Export metadata of iRODS objects",
+	Long: `This command lists metadata of iRODS objects in parallel. Use -r to export metadata of all data objects and collections in a collection tree.
+Output of --output_csv or --output_json can be imported with 'meta import'.`,
+	Example: `  gocmd meta export -r i:/zone/home/user/data --output_json > metadata.json`,
+	RunE:    processMetaExportCommand,
+	Args:    cobra.MinimumNArgs(1),
+}
+
+func AddMetadataCommand(rootCmd *cobra.Command) {
+	// attach common flags
+	flag.SetCommonFlagsWithoutResource(metaImportCmd)
+	flag.SetCommonFlagsWithoutResource(metaExportCmd)
+
+	flag.SetTargetObjectFlags(metaImportCmd)
+	flag.SetProgressFlags(metaImportCmd)
+	flag.SetMetadataBulkFlags(metaImportCmd)
+
+	flag.SetTargetObjectFlags(metaExportCmd)
+	flag.SetRecursiveFlags(metaExportCmd, false)
+	flag.SetOutputFormatFlags(metaExportCmd, true)
+	flag.SetProgressFlags(metaExportCmd)
+	flag.SetMetadataBulkFlags(metaExportCmd)
+
+	metaCmd.AddCommand(metaImportCmd)
+	metaCmd.AddCommand(metaExportCmd)
+
+	rootCmd.AddCommand(metaCmd)
+}
+
+func processMetaImportCommand(command *cobra.Command, args []string) error {
+	meta, err := NewMetadataCommand(command, args)
+	if err != nil {
+		return err
+	}
+
+	return meta.ProcessImport()
+}
+
+func processMetaExportCommand(command *cobra.Command, args []string) error {
+	meta, err := NewMetadataCommand(command, args)
+	if err != nil {
+		return err
+	}
+
+	return meta.ProcessExport()
+}
+
+type MetadataCommand struct {
+	command *cobra.Command
+
+	commonFlagValues       *flag.CommonFlagValues
+	targetObjectFlagValues *flag.TargetObjectFlagValues
+	recursiveFlagValues    *flag.RecursiveFlagValues
+	outputFormatFlagValues *flag.OutputFormatFlagValues
+	progressFlagValues     *flag.ProgressFlagValues
+	metadataBulkFlagValues *flag.MetadataBulkFlagValues
+
+	account    *irodsclient_types.IRODSAccount
+	filesystem *irodsclient_fs.FileSystem
+
+	args []string
+}
+
+func NewMetadataCommand(command *cobra.Command, args []string) (*MetadataCommand, error) {
+	meta := &MetadataCommand{
+		command: command,
+
+		commonFlagValues:       flag.GetCommonFlagValues(command),
+		targetObjectFlagValues: flag.GetTargetObjectFlagValues(command),
+		recursiveFlagValues:    flag.GetRecursiveFlagValues(),
+		outputFormatFlagValues: flag.GetOutputFormatFlagValues(),
+		progressFlagValues:     flag.GetProgressFlagValues(),
+		metadataBulkFlagValues: flag.GetMetadataBulkFlagValues(),
+	}
+
+	meta.args = args
+
+	return meta, nil
+}
+
+func (meta *MetadataCommand) connect() error {
+	cont, err := flag.ProcessCommonFlags(meta.command)
+	if err != nil {
+		return errors.Wrapf(err, "failed to process common flags")
+	}
+
+	if !cont {
+		return nil
+	}
+
+	// handle local flags
+	_, err = config.InputMissingFields()
+	if err != nil {
+		return errors.Wrapf(err, "failed to input missing fields")
+	}
+
+	// Create a file system
+	meta.account = config.GetSessionConfig().ToIRODSAccount()
+
+	timeout := 0
+	if meta.commonFlagValues.TimeoutUpdated {
+		timeout = meta.commonFlagValues.Timeout
+	}
+
+	meta.filesystem, err = irods.GetIRODSFSClient(meta.account, false, timeout)
+	if err != nil {
+		return errors.Wrapf(err, "failed to get iRODS FS Client")
+	}
+
+	return nil
+}
+
+func (meta *MetadataCommand) getTargetType() irods.MetadataTargetType {
+	if meta.targetObjectFlagValues.User {
+		return irods.MetadataTargetUser
+	} else if meta.targetObjectFlagValues.Resource {
+		return irods.MetadataTargetResource
+	}
+
+	return irods.MetadataTargetPath
+}
+
+func (meta *MetadataCommand) getTargetColumnName() string {
+	switch meta.getTargetType() {
+	case irods.MetadataTargetUser:
+		return "User"
+	case irods.MetadataTargetResource:
+		return "Resource"
+	default:
+		return "Path"
+	}
+}
+
+func (meta *MetadataCommand) makeTarget(target string) string {
+	if meta.getTargetType() != irods.MetadataTargetPath {
+		return target
+	}
+
+	cwd := config.GetCWD()
+	home := config.GetHomeDir()
+	zone := meta.account.ClientZone
+	return path.MakeIRODSPath(cwd, home, zone, target)
+}
+
+func (meta *MetadataCommand) ProcessImport() error {
+	records, err := irods.ReadMetadataRecordsFromFile(meta.args[0])
+	if err != nil {
+		return err
+	}
+
+	err = meta.connect()
+	if err != nil {
+		return err
+	}
+
+	if meta.filesystem == nil {
+		return nil
+	}
+	defer meta.filesystem.Release()
+
+	// group records by target, records of a target are applied in order
+	targets := []string{}
+	recordsByTarget := map[string][]irods.MetadataRecord{}
+	for _, record := range records {
+		record.Target = meta.makeTarget(record.Target)
+
+		if _, ok := recordsByTarget[record.Target]; !ok {
+			targets = append(targets, record.Target)
+		}
+		recordsByTarget[record.Target] = append(recordsByTarget[record.Target], record)
+	}
+
+	targetType := meta.getTargetType()
+	jobManager := parallel.NewParallelJobManager(meta.metadataBulkFlagValues.ThreadNumber, meta.progressFlagValues.ShowProgress, meta.progressFlagValues.ShowFullPath, meta.metadataBulkFlagValues.StopOnError)
+
+	appliedCount := int64(0)
+
+	for _, target := range targets {
+		targetRecords := recordsByTarget[target]
+
+		importTask := func(job *parallel.ParallelJob) error {
+			total := int64(len(targetRecords))
+
+			if job.IsCanceled() {
+				job.Progress("import", -1, total, true)
+				return nil
+			}
+
+			logger := log.WithFields(log.Fields{
+				"target": job.GetName(),
+			})
+
+			job.Progress("import", 0, total, false)
+
+			for recordIdx := range targetRecords {
+				record := targetRecords[recordIdx]
+
+				logger.Debugf("%s metadata %q", record.Operation, record.Attribute)
+
+				err := record.Apply(meta.filesystem, targetType, meta.account.ClientZone)
+				if err != nil {
+					job.Progress("import", -1, total, true)
+					return errors.Wrapf(err, "failed to %s metadata %q of %q", record.Operation, record.Attribute, record.Target)
+				}
+
+				atomic.AddInt64(&appliedCount, 1)
+				job.Progress("import", int64(recordIdx+1), total, false)
+			}
+
+			return nil
+		}
+
+		jobManager.Schedule(target, importTask, 1, progress.UnitsDefault)
+	}
+
+	err = jobManager.Start()
+
+	terminal.Printf("applied %d of %d metadata operations to %d targets\n", appliedCount, len(records), len(targets))
+
+	if err != nil {
+		return errors.Wrapf(err, "failed to import metadata from %q", meta.args[0])
+	}
+
+	return nil
+}
+
+func (meta *MetadataCommand) ProcessExport() error {
+	err := meta.connect()
+	if err != nil {
+		return err
+	}
+
+	if meta.filesystem == nil {
+		return nil
+	}
+	defer meta.filesystem.Release()
+
+	targets := []string{}
+	for _, arg := range meta.args {
+		target := meta.makeTarget(arg)
+
+		if meta.getTargetType() == irods.MetadataTargetPath && meta.recursiveFlagValues.Recursive {
+			collected, err := collectMetadataPaths(meta.filesystem, target)
+			if err != nil {
+				return err
+			}
+
+			targets = append(targets, collected...)
+			continue
+		}
+
+		targets = append(targets, target)
+	}
+
+	targetType := meta.getTargetType()
+	metasList := make([][]*irodsclient_types.IRODSMeta, len(targets))
+
+	jobManager := parallel.NewParallelJobManager(meta.metadataBulkFlagValues.ThreadNumber, meta.progressFlagValues.ShowProgress, meta.progressFlagValues.ShowFullPath, meta.metadataBulkFlagValues.StopOnError)
+
+	for targetIdx, target := range targets {
+		resultIdx := targetIdx
+		targetName := target
+
+		exportTask := func(job *parallel.ParallelJob) error {
+			if job.IsCanceled() {
+				job.Progress("export", -1, 1, true)
+				return nil
+			}
+
+			job.Progress("export", 0, 1, false)
+
+			metas, err := irods.ListMetadataForTarget(meta.filesystem, targetType, targetName, meta.account.ClientZone)
+			if err != nil {
+				job.Progress("export", -1, 1, true)
+				return errors.Wrapf(err, "failed to list metadata for %q", targetName)
+			}
+
+			metasList[resultIdx] = metas
+			job.Progress("export", 1, 1, false)
+			return nil
+		}
+
+		jobManager.Schedule(targetName, exportTask, 1, progress.UnitsDefault)
+	}
+
+	err = jobManager.Start()
+	if err != nil {
+		return errors.Wrapf(err, "failed to export metadata")
+	}
+
+	outputFormatter := format.NewOutputFormatter(terminal.GetTerminalWriter())
+	outputFormatterTable := outputFormatter.NewTable("iRODS Metadata")
+
+	outputFormatterTable.SetHeader([]string{
+		meta.getTargetColumnName(),
+		"ID",
+		"Attribute",
+		"Value",
+		"Unit",
+	})
+
+	for targetIdx, target := range targets {
+		metas := metasList[targetIdx]
+		sort.SliceStable(metas, func(i int, j int) bool {
+			return metas[i].AVUID < metas[j].AVUID
+		})
+
+		for _, avu := range metas {
+			outputFormatterTable.AppendRow([]interface{}{
+				target,
+				avu.AVUID,
+				avu.Name,
+				avu.Value,
+				avu.Units,
+			})
+		}
+	}
+
+	if meta.outputFormatFlagValues.Format == format.OutputFormatLegacy {
+		meta.outputFormatFlagValues.Format = format.OutputFormatTable
+	}
+	outputFormatter.Render(meta.outputFormatFlagValues.Format)
+
+	return nil
+}
+
+// collectMetadataPaths returns the path and paths of all entries under the path, sorted
+func collectMetadataPaths(filesystem *irodsclient_fs.FileSystem, targetPath string) ([]string, error) {
+	entry, err := filesystem.Stat(targetPath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to stat %q", targetPath)
+	}
+
+	paths := []string{entry.Path}
+	if !entry.IsDir() {
+		return paths, nil
+	}
+
+	entries, err := filesystem.List(entry.Path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list collection %q", entry.Path)
+	}
+
+	sort.SliceStable(entries, func(i int, j int) bool {
+		return entries[i].Path < entries[j].Path
+	})
+
+	for _, childEntry := range entries {
+		if childEntry.IsDir() {
+			childPaths, err := collectMetadataPaths(filesystem, childEntry.Path)
+			if err != nil {
+				return nil, err
+			}
+
+			paths = append(paths, childPaths...)
+			continue
+		}
+
+		paths = append(paths, childEntry.Path)
+	}
+
+	return paths, nil
+}
+
+// makeMetadataTargetPaths returns absolute paths of the targets, expanding wildcards and collections if enabled
+func makeMetadataTargetPaths(filesystem *irodsclient_fs.FileSystem, account *irodsclient_types.IRODSAccount, targets []string, wildcardSearch bool, recursive bool) ([]string, error) {
+	var err error
+	if wildcardSearch {
+		targets, err = wildcard.ExpandWildcards(context.Background(), filesystem, account, targets, true, true)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to expand wildcards")
+		}
+	}
+
+	cwd := config.GetCWD()
+	home := config.GetHomeDir()
+	zone := account.ClientZone
+
+	targetPaths := []string{}
+	for _, target := range targets {
+		targetPath := path.MakeIRODSPath(cwd, home, zone, target)
+
+		if recursive {
+			collected, err := collectMetadataPaths(filesystem, targetPath)
+			if err != nil {
+				return nil, err
+			}
+
+			targetPaths = append(targetPaths, collected...)
+			continue
+		}
+
+		targetPaths = append(targetPaths, targetPath)
+	}
+
+	return targetPaths, nil
+}
@@ -0,0 +1,226 @@
+package subcmd
+
+import (
+	"strconv"
+
+	"github.com/cockroachdb/errors"
+	irodsclient_fs "github.com/cyverse/go-irodsclient/fs"
+	irodsclient_types "github.com/cyverse/go-irodsclient/irods/types"
+	"github.com/cyverse/gocommands/cmd/flag"
+	"github.com/cyverse/gocommands/commons/config"
+	"github.com/cyverse/gocommands/commons/irods"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var modmetaCmd = &cobra.Command{
+	Use:     "modmeta <irods-object> <metadata-name> <new-metadata-value> [new-metadata-unit] OR <irods-object> <metadata-ID> <new-metadata-value> [new-metadata-unit]",
+	Aliases: []string{"mod_meta", "modify_meta", "mod_metadata", "modify_metadata"},
+	Short:   "Modify value and unit of existing metadata of a specified iRODS object",
+	Long:    `This command modifies value and unit of existing metadata of a specified iRODS object, such as a collection, data object, user, or resource, in a single request. The metadata is selected by its ID, or by its name if the object has only one metadata having the name. The unit is kept if a new unit is not given.`,
+	RunE:    processModmetaCommand,
+	Args:    cobra.RangeArgs(3, 4),
+}
+
+func AddModmetaCommand(rootCmd *cobra.Command) {
+	// attach common flags
+	flag.SetCommonFlagsWithoutResource(modmetaCmd)
+
+	flag.SetTargetObjectFlags(modmetaCmd)
+	flag.SetMetadataByIDFlags(modmetaCmd)
+	flag.SetRecursiveFlags(modmetaCmd, false)
+	flag.SetWildcardSearchFlags(modmetaCmd)
+
+	rootCmd.AddCommand(modmetaCmd)
+}
+
+func processModmetaCommand(command *cobra.Command, args []string) error {
+	modMeta, err := NewModMetaCommand(command, args)
+	if err != nil {
+		return err
+	}
+
+	return modMeta.Process()
+}
+
+type ModMetaCommand struct {
+	command *cobra.Command
+
+	commonFlagValues         *flag.CommonFlagValues
+	targetObjectFlagValues   *flag.TargetObjectFlagValues
+	metadataByIDFlagValues   *flag.MetadataByIDFlagValues
+	recursiveFlagValues      *flag.RecursiveFlagValues
+	wildcardSearchFlagValues *flag.WildcardSearchFlagValues
+
+	account    *irodsclient_types.IRODSAccount
+	filesystem *irodsclient_fs.FileSystem
+
+	targetObject string
+
+	avuID     int64
+	attribute string
+	value     string
+	unit      string
+}
+
+func NewModMetaCommand(command *cobra.Command, args []string) (*ModMetaCommand, error) {
+	modMeta := &ModMetaCommand{
+		command: command,
+
+		commonFlagValues:         flag.GetCommonFlagValues(command),
+		targetObjectFlagValues:   flag.GetTargetObjectFlagValues(command),
+		metadataByIDFlagValues:   flag.GetMetadataByIDFlagValues(command),
+		recursiveFlagValues:      flag.GetRecursiveFlagValues(),
+		wildcardSearchFlagValues: flag.GetWildcardSearchFlagValues(),
+	}
+
+	// path
+	modMeta.targetObject = args[0]
+
+	if modMeta.metadataByIDFlagValues.ByID {
+		avuID, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse AVUID %q", args[1])
+		}
+
+		modMeta.avuID = avuID
+	} else {
+		modMeta.attribute = args[1]
+	}
+
+	modMeta.value = args[2]
+
+	modMeta.unit = ""
+	if len(args) >= 4 {
+		modMeta.unit = args[3]
+	}
+
+	if len(modMeta.value) == 0 {
+		return nil, errors.Errorf("new metadata value is required")
+	}
+
+	if !modMeta.targetObjectFlagValues.Path && (modMeta.recursiveFlagValues.Recursive || modMeta.wildcardSearchFlagValues.WildcardSearch) {
+		return nil, errors.Errorf("recursive and wildcard are only supported for paths")
+	}
+
+	return modMeta, nil
+}
+
+func (modMeta *ModMetaCommand) Process() error {
+	cont, err := flag.ProcessCommonFlags(modMeta.command)
+	if err != nil {
+		return errors.Wrapf(err, "failed to process common flags")
+	}
+
+	if !cont {
+		return nil
+	}
+
+	// handle local flags
+	_, err = config.InputMissingFields()
+	if err != nil {
+		return errors.Wrapf(err, "failed to input missing fields")
+	}
+
+	// Create a file system
+	modMeta.account = config.GetSessionConfig().ToIRODSAccount()
+
+	timeout := 0
+	if modMeta.commonFlagValues.TimeoutUpdated {
+		timeout = modMeta.commonFlagValues.Timeout
+	}
+
+	modMeta.filesystem, err = irods.GetIRODSFSClient(modMeta.account, false, timeout)
+	if err != nil {
+		return errors.Wrapf(err, "failed to get iRODS FS Client")
+	}
+	defer modMeta.filesystem.Release()
+
+	// modify meta
+	targetType := irods.MetadataTargetPath
+	targets := []string{modMeta.targetObject}
+
+	if modMeta.targetObjectFlagValues.Path {
+		targets, err = makeMetadataTargetPaths(modMeta.filesystem, modMeta.account, targets, modMeta.wildcardSearchFlagValues.WildcardSearch, modMeta.recursiveFlagValues.Recursive)
+		if err != nil {
+			return err
+		}
+	} else if modMeta.targetObjectFlagValues.User {
+		targetType = irods.MetadataTargetUser
+	} else if modMeta.targetObjectFlagValues.Resource {
+		targetType = irods.MetadataTargetResource
+	} else {
+		// nothing updated
+		return errors.Errorf("path, user, or resource must be given")
+	}
+
+	modified := 0
+	for _, target := range targets {
+		ok, err := modMeta.modMeta(targetType, target)
+		if err != nil {
+			return err
+		}
+
+		if ok {
+			modified++
+		}
+	}
+
+	if modified == 0 {
+		if modMeta.metadataByIDFlagValues.ByID {
+			return errors.Errorf("failed to find metadata (id %d) of %s %q", modMeta.avuID, targetType, modMeta.targetObject)
+		}
+		return errors.Errorf("failed to find metadata (attr %q) of %s %q", modMeta.attribute, targetType, modMeta.targetObject)
+	}
+
+	return nil
+}
+
+// modMeta modifies the metadata of the target, returns false if the target does not have the metadata
+func (modMeta *ModMetaCommand) modMeta(targetType irods.MetadataTargetType, target string) (bool, error) {
+	logger := log.WithFields(log.Fields{
+		"target_type": targetType,
+		"target":      target,
+		"avu_id":      modMeta.avuID,
+		"attribute":   modMeta.attribute,
+		"value":       modMeta.value,
+		"unit":        modMeta.unit,
+	})
+
+	metas, err := irods.ListMetadataForTarget(modMeta.filesystem, targetType, target, modMeta.account.ClientZone)
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to list metadata of %s %q", targetType, target)
+	}
+
+	var oldMeta *irodsclient_types.IRODSMeta
+	for _, meta := range metas {
+		if modMeta.metadataByIDFlagValues.ByID {
+			if meta.AVUID == modMeta.avuID {
+				oldMeta = meta
+				break
+			}
+			continue
+		}
+
+		if meta.Name == modMeta.attribute {
+			if oldMeta != nil {
+				return false, errors.Errorf("%s %q has multiple metadata having attr %q, use metadata ID instead", targetType, target, modMeta.attribute)
+			}
+			oldMeta = meta
+		}
+	}
+
+	if oldMeta == nil {
+		logger.Debug("skip modifying metadata, metadata is not found")
+		return false, nil
+	}
+
+	logger.Debug("modify metadata")
+
+	err = irods.ModifyMetadataForTarget(modMeta.filesystem, targetType, target, modMeta.account.ClientZone, oldMeta, modMeta.value, modMeta.unit)
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to modify metadata of %s %q (attr %q, value %q, unit %q)", targetType, target, oldMeta.Name, modMeta.value, modMeta.unit)
+	}
+
+	return true, nil
+}
@@ -1,6 +1,7 @@
 package subcmd
 
 import (
+	"context"
 	"path"
 
 	"github.com/cockroachdb/errors"
@@ -110,7 +111,7 @@ func (mv *MvCommand) Process() error {
 
 	// Expand wildcards
 	if mv.wildcardSearchFlagValues.WildcardSearch {
-		mv.sourcePaths, err = wildcard.ExpandWildcards(mv.filesystem, mv.account, mv.sourcePaths, true, true)
+		mv.sourcePaths, err = wildcard.ExpandWildcards(context.Background(), mv.filesystem, mv.account, mv.sourcePaths, true, true)
 		if err != nil {
 			return errors.Wrapf(err, "failed to expand wildcards")
 		}
@@ -0,0 +1,745 @@
+package subcmd
+
+import (
+	"context"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/avast/retry-go"
+	"github.com/cockroachdb/errors"
+	irodsclient_fs "github.com/cyverse/go-irodsclient/fs"
+	irodsclient_types "github.com/cyverse/go-irodsclient/irods/types"
+	"github.com/cyverse/gocommands/cmd/flag"
+	"github.com/cyverse/gocommands/commons/config"
+	"github.com/cyverse/gocommands/commons/encryption"
+	"github.com/cyverse/gocommands/commons/interrupt"
+	"github.com/cyverse/gocommands/commons/irods"
+	"github.com/cyverse/gocommands/commons/parallel"
+	commons_path "github.com/cyverse/gocommands/commons/path"
+	"github.com/cyverse/gocommands/commons/terminal"
+	"github.com/cyverse/gocommands/commons/transfer"
+	"github.com/cyverse/gocommands/commons/types"
+	"github.com/cyverse/gocommands/commons/wildcard"
+	"github.com/cyverse/gocommands/pkg/gocommands"
+	"github.com/jedib0t/go-pretty/v6/progress"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var reencryptCmd = &cobra.Command{
+	Use:   "reencrypt <data-object-or-collection>...",
+	Short: "Re-encrypt encrypted iRODS data objects with a new key",
+	Long:  `This command re-encrypts encrypted iRODS data objects with a new key. Each data object is downloaded, decrypted with the current key, encrypted with the new key, and uploaded with a new encrypted filename. Key rings in collection metadata are replaced with the new keys.`,
+	RunE:  processReencryptCommand,
+	Args:  cobra.MinimumNArgs(1),
+}
+
+func AddReencryptCommand(rootCmd *cobra.Command) {
+	// attach common flags
+	flag.SetCommonFlags(reencryptCmd, false)
+
+	flag.SetReencryptFlags(reencryptCmd)
+	flag.SetParallelTransferFlags(reencryptCmd, true, true)
+	flag.SetRecursiveFlags(reencryptCmd, false)
+	flag.SetDryRunFlags(reencryptCmd)
+	flag.SetProgressFlags(reencryptCmd)
+	flag.SetRetryFlags(reencryptCmd)
+	flag.SetTransferReportFlags(reencryptCmd)
+	flag.SetWildcardSearchFlags(reencryptCmd)
+
+	rootCmd.AddCommand(reencryptCmd)
+}
+
+func processReencryptCommand(command *cobra.Command, args []string) error {
+	reencrypt, err := NewReencryptCommand(command, args)
+	if err != nil {
+		return err
+	}
+
+	return reencrypt.Process()
+}
+
+type ReencryptCommand struct {
+	command *cobra.Command
+
+	commonFlagValues           *flag.CommonFlagValues
+	reencryptFlagValues        *flag.ReencryptFlagValues
+	parallelTransferFlagValues *flag.ParallelTransferFlagValues
+	recursiveFlagValues        *flag.RecursiveFlagValues
+	dryRunFlagValues           *flag.DryRunFlagValues
+	progressFlagValues         *flag.ProgressFlagValues
+	retryFlagValues            *flag.RetryFlagValues
+	transferReportFlagValues   *flag.TransferReportFlagValues
+	wildcardSearchFlagValues   *flag.WildcardSearchFlagValues
+
+	maxConnectionNum int
+
+	account    *irodsclient_types.IRODSAccount
+	filesystem *irodsclient_fs.FileSystem
+
+	sourcePaths []string
+
+	parallelJobManager    *parallel.ParallelJobManager
+	transferReportManager *transfer.TransferReportManager
+	interruptHandler      *interrupt.Handler
+	inFlightTransfers     *transfer.InFlightTransfers
+
+	keyRings              map[string]*reencryptKeyRing // collection path -> key ring, nil if the collection has no key ring
+	newKeyRing            []string
+	totalReencryptedFiles int
+	mutex                 sync.Mutex
+}
+
+// reencryptKeyRing is a key ring in collection metadata for 'ssh' mode
+// it is replaced with the new keys once all data objects in the collection are re-encrypted
+type reencryptKeyRing struct {
+	publicKeys  []string
+	sshMode     bool // the collection is set to 'ssh' mode in metadata
+	scheduled   int
+	reencrypted int
+}
+
+func NewReencryptCommand(command *cobra.Command, args []string) (*ReencryptCommand, error) {
+	reencrypt := &ReencryptCommand{
+		command: command,
+
+		commonFlagValues:           flag.GetCommonFlagValues(command),
+		reencryptFlagValues:        flag.GetReencryptFlagValues(),
+		parallelTransferFlagValues: flag.GetParallelTransferFlagValues(),
+		recursiveFlagValues:        flag.GetRecursiveFlagValues(),
+		dryRunFlagValues:           flag.GetDryRunFlagValues(),
+		progressFlagValues:         flag.GetProgressFlagValues(),
+		retryFlagValues:            flag.GetRetryFlagValues(),
+		transferReportFlagValues:   flag.GetTransferReportFlagValues(command),
+		wildcardSearchFlagValues:   flag.GetWildcardSearchFlagValues(),
+
+		inFlightTransfers: transfer.NewInFlightTransfers(),
+		keyRings:          map[string]*reencryptKeyRing{},
+	}
+
+	reencrypt.maxConnectionNum = reencrypt.parallelTransferFlagValues.ThreadNumber
+
+	if len(reencrypt.reencryptFlagValues.ToKeys) == 0 || len(reencrypt.reencryptFlagValues.ToKeys[0]) == 0 {
+		return nil, errors.New("failed to re-encrypt without a new key, set 'to_key' option")
+	}
+
+	// path
+	reencrypt.sourcePaths = args
+
+	return reencrypt, nil
+}
+
+func (reencrypt *ReencryptCommand) Process() error {
+	logger := log.WithFields(log.Fields{})
+
+	cont, err := flag.ProcessCommonFlags(reencrypt.command)
+	if err != nil {
+		return errors.Wrapf(err, "failed to process common flags")
+	}
+
+	if !cont {
+		return nil
+	}
+
+	// handle local flags
+	_, err = config.InputMissingFields()
+	if err != nil {
+		return errors.Wrapf(err, "failed to input missing fields")
+	}
+
+	// Create a file system
+	reencrypt.account = config.GetSessionConfig().ToIRODSAccount()
+
+	// filenames in age mode are encrypted with the password by default, same as put
+	if len(reencrypt.reencryptFlagValues.FilenameKey) == 0 {
+		reencrypt.reencryptFlagValues.FilenameKey = reencrypt.account.Password
+	}
+
+	timeout := 0
+	if reencrypt.commonFlagValues.TimeoutUpdated {
+		timeout = reencrypt.commonFlagValues.Timeout
+	}
+
+	reencrypt.filesystem, err = irods.GetIRODSFSClientForLargeFileIO(reencrypt.account, reencrypt.maxConnectionNum, reencrypt.parallelTransferFlagValues.TCPBufferSize, false, timeout)
+	if err != nil {
+		return errors.Wrapf(err, "failed to get iRODS FS Client")
+	}
+	defer reencrypt.filesystem.Release()
+
+	// transfer report
+	reencrypt.transferReportManager, err = transfer.NewTransferReportManager(reencrypt.transferReportFlagValues.Report, reencrypt.transferReportFlagValues.ReportPath, reencrypt.transferReportFlagValues.ReportToStdout)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create transfer report manager")
+	}
+	defer reencrypt.transferReportManager.Release()
+
+	// parallel job manager
+	ioSession := reencrypt.filesystem.GetIOSession()
+	reencrypt.parallelJobManager = parallel.NewParallelJobManager(ioSession.GetMaxConnections(), reencrypt.progressFlagValues.ShowProgress, reencrypt.progressFlagValues.ShowFullPath, reencrypt.parallelTransferFlagValues.StopOnError)
+
+	// Expand wildcards
+	if reencrypt.wildcardSearchFlagValues.WildcardSearch {
+		reencrypt.sourcePaths, err = wildcard.ExpandWildcards(context.Background(), reencrypt.filesystem, reencrypt.account, reencrypt.sourcePaths, true, true)
+		if err != nil {
+			return errors.Wrapf(err, "failed to expand wildcards")
+		}
+	}
+
+	// run
+	for _, sourcePath := range reencrypt.sourcePaths {
+		err = reencrypt.reencryptOne(sourcePath)
+		if err != nil {
+			return errors.Wrapf(err, "failed to re-encrypt %q", sourcePath)
+		}
+	}
+
+	// refuse before re-encrypting if key rings can't be replaced
+	err = reencrypt.makeNewKeyRing()
+	if err != nil {
+		return err
+	}
+
+	if reencrypt.dryRunFlagValues.DryRun {
+		return reencrypt.updateKeyRings()
+	}
+
+	logger.Info("done scheduling jobs, starting jobs")
+
+	// handle Ctrl-C
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	reencrypt.interruptHandler = reencrypt.newInterruptHandler(cancel)
+	reencrypt.interruptHandler.Start()
+	defer reencrypt.interruptHandler.Stop()
+
+	err = reencrypt.parallelJobManager.StartWithContext(ctx)
+
+	// key rings of collections completed are replaced even if other collections failed
+	keyRingErr := reencrypt.updateKeyRings()
+
+	if reencrypt.interruptHandler.IsInterrupted() {
+		reencrypt.printPartialSummary("Interrupted", nil)
+		return types.NewInterruptedError(reencrypt.interruptHandler.GetSignal().String())
+	}
+
+	if err != nil {
+		return errors.Wrapf(err, "failed to perform re-encryption jobs")
+	}
+
+	return keyRingErr
+}
+
+// newInterruptHandler returns a handler canceling pending re-encryptions on the first Ctrl-C, and removing temp files and incomplete data objects on the second
+func (reencrypt *ReencryptCommand) newInterruptHandler(abortRunning context.CancelFunc) *interrupt.Handler {
+	logger := log.WithFields(log.Fields{})
+
+	handler := interrupt.NewHandler()
+
+	handler.AddCancelFunc(func() {
+		reencrypt.parallelJobManager.CancelJobs()
+	})
+
+	handler.AddAbortFunc(func() {
+		// stop running jobs, and wait for them to return before removing files they write
+		abortRunning()
+		if !parallel.WaitJobManagers(gocommands.AbortWaitTimeout, reencrypt.parallelJobManager) {
+			logger.Warnf("running jobs did not return in %s, removing incomplete files", gocommands.AbortWaitTimeout)
+		}
+
+		removedPaths, err := reencrypt.inFlightTransfers.CleanUp()
+		if err != nil {
+			logger.WithError(err).Warn("failed to remove incomplete files")
+		}
+
+		if reencrypt.transferReportManager != nil {
+			reencrypt.transferReportManager.Release()
+		}
+		reencrypt.printPartialSummary("Interrupted", removedPaths)
+	})
+
+	return handler
+}
+
+// printPartialSummary prints data objects re-encrypted and not re-encrypted when the re-encryption is stopped for the reason
+func (reencrypt *ReencryptCommand) printPartialSummary(reason string, removedPaths []string) {
+	reencrypt.mutex.Lock()
+	totalReencryptedFiles := reencrypt.totalReencryptedFiles
+	reencrypt.mutex.Unlock()
+
+	counts := reencrypt.parallelJobManager.GetJobCounts()
+	notReencrypted := counts.Total - int64(totalReencryptedFiles)
+
+	terminal.Printf("%s, re-encrypted %d files, %d files were not re-encrypted\n", reason, totalReencryptedFiles, notReencrypted)
+
+	for _, removedPath := range removedPaths {
+		terminal.Printf("Removed incomplete file %q\n", removedPath)
+	}
+
+	if reencrypt.transferReportFlagValues.Report && !reencrypt.transferReportFlagValues.ReportToStdout {
+		terminal.Printf("See transfer report %q for files not re-encrypted\n", reencrypt.transferReportFlagValues.ReportPath)
+	}
+}
+
+func (reencrypt *ReencryptCommand) reencryptOne(sourcePath string) error {
+	cwd := config.GetCWD()
+	home := config.GetHomeDir()
+	zone := reencrypt.account.ClientZone
+	sourcePath = commons_path.MakeIRODSPath(cwd, home, zone, sourcePath)
+
+	sourceEntry, err := reencrypt.filesystem.Stat(sourcePath)
+	if err != nil {
+		return errors.Wrapf(err, "failed to stat %q", sourcePath)
+	}
+
+	if sourceEntry.IsDir() {
+		// dir
+		if !reencrypt.recursiveFlagValues.Recursive {
+			return errors.New("cannot re-encrypt a collection, turn on 'recurse' option")
+		}
+
+		return reencrypt.reencryptDir(sourceEntry)
+	}
+
+	// file
+	return reencrypt.reencryptFile(sourceEntry)
+}
+
+func (reencrypt *ReencryptCommand) reencryptDir(sourceEntry *irodsclient_fs.Entry) error {
+	// key ring of the collection is replaced even if it has no data objects
+	reencrypt.getKeyRing(sourceEntry.Path)
+
+	entries, err := reencrypt.filesystem.List(sourceEntry.Path)
+	if err != nil {
+		return errors.Wrapf(err, "failed to list a directory %q", sourceEntry.Path)
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			// dir
+			err = reencrypt.reencryptDir(entry)
+			if err != nil {
+				return err
+			}
+		} else {
+			// file
+			err = reencrypt.reencryptFile(entry)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (reencrypt *ReencryptCommand) reencryptFile(sourceEntry *irodsclient_fs.Entry) error {
+	logger := log.WithFields(log.Fields{
+		"source_path": sourceEntry.Path,
+	})
+
+	encryptionMode := encryption.DetectEncryptionMode(sourceEntry.Name)
+	if encryptionMode == encryption.EncryptionModeNone {
+		logger.Debug("skip re-encrypting a data object. The data object is not encrypted!")
+		return nil
+	}
+
+	defaultNotes := []string{"reencrypt", string(encryptionMode)}
+
+	reportSimple := func(targetPath string, err error, additionalNotes ...string) {
+		now := time.Now()
+		newNotes := append(defaultNotes, additionalNotes...)
+
+		reportFile := &transfer.TransferReportFile{
+			Method:     transfer.TransferMethodReencrypt,
+			StartAt:    now,
+			EndAt:      now,
+			SourcePath: sourceEntry.Path,
+			SourceSize: sourceEntry.Size,
+			DestPath:   targetPath,
+			Error:      err,
+			Notes:      newNotes,
+		}
+
+		reencrypt.transferReportManager.AddFile(reportFile)
+	}
+
+	sourceDir := path.Dir(sourceEntry.Path)
+
+	var keyRing *reencryptKeyRing
+	if encryptionMode == encryption.EncryptionModeSSH {
+		keyRing = reencrypt.getKeyRing(sourceDir)
+	}
+
+	// new filename
+	decryptManager := reencrypt.getEncryptionManagerForDecryption(encryptionMode)
+	decryptedFilename, err := decryptManager.DecryptFilename(sourceEntry.Name)
+	if err != nil && keyRing != nil {
+		// filename of multi-recipient file is encrypted with the first key in collection's key ring
+		decryptManager.SetPublicKeyRing(keyRing.publicKeys)
+		decryptedFilename, err = decryptManager.DecryptFilename(sourceEntry.Name)
+	}
+
+	if err != nil {
+		reportSimple("", err)
+		return errors.Wrapf(err, "failed to decrypt filename %q", sourceEntry.Path)
+	}
+
+	encryptManager := reencrypt.getEncryptionManagerForEncryption(encryptionMode)
+	encryptedFilename, err := encryptManager.EncryptFilename(decryptedFilename)
+	if err != nil {
+		reportSimple("", err)
+		return errors.Wrapf(err, "failed to encrypt filename %q", decryptedFilename)
+	}
+
+	targetPath := path.Join(sourceDir, encryptedFilename)
+
+	if keyRing != nil {
+		reencrypt.mutex.Lock()
+		keyRing.scheduled++
+		reencrypt.mutex.Unlock()
+	}
+
+	if reencrypt.dryRunFlagValues.DryRun {
+		reportSimple(targetPath, nil, "dry_run")
+		terminal.Printf("re-encrypt %q (%s) to %q\n", sourceEntry.Path, decryptedFilename, targetPath)
+		return nil
+	}
+
+	reencryptTask := func(job *parallel.ParallelJob) error {
+		if job.IsCanceled() {
+			// job is canceled, do not run
+			job.Progress("download", -1, sourceEntry.Size, true)
+
+			reportSimple(targetPath, nil, "canceled")
+			logger.Debug("canceled a task for re-encrypting")
+			return nil
+		}
+
+		logger.Debug("re-encrypting a data object")
+
+		startTime := time.Now()
+
+		// only encrypted data is written to the temp directory
+		tempDir, err := os.MkdirTemp(reencrypt.reencryptFlagValues.TempPath, "gocmd-reencrypt-")
+		if err != nil {
+			job.Progress("download", -1, sourceEntry.Size, true)
+
+			reportSimple(targetPath, err)
+			return errors.Wrapf(err, "failed to create a temp directory in %q", reencrypt.reencryptFlagValues.TempPath)
+		}
+
+		reencrypt.inFlightTransfers.Start(tempDir, func() error {
+			return os.RemoveAll(tempDir)
+		})
+
+		defer func() {
+			os.RemoveAll(tempDir)
+			reencrypt.inFlightTransfers.Finish(tempDir)
+		}()
+
+		sourceTempPath := filepath.Join(tempDir, "source")
+		encryptedTempPath := filepath.Join(tempDir, "encrypted")
+
+		progressCallback := func(taskType string, processed int64, total int64) {
+			job.Progress(taskType, processed, total, false)
+		}
+
+		retryNum := reencrypt.retryFlagValues.GetRetryNumber()
+		retryInterval := reencrypt.retryFlagValues.GetRetryIntervalSeconds()
+
+		// download
+		job.Progress("download", 0, sourceEntry.Size, false)
+
+		retryErr := retry.Do(func() error {
+			_, downloadErr := reencrypt.filesystem.DownloadFile(sourceEntry.Path, "", sourceTempPath, false, progressCallback)
+			return downloadErr
+		}, retry.Attempts(uint(retryNum+1)), retry.Delay(retryInterval), retry.LastErrorOnly(true))
+		if retryErr != nil {
+			job.Progress("download", -1, sourceEntry.Size, true)
+
+			reportSimple(targetPath, retryErr)
+			return errors.Wrapf(retryErr, "failed to download %q after %d attempts", sourceEntry.Path, retryNum+1)
+		}
+
+		job.Progress("download", sourceEntry.Size, sourceEntry.Size, false)
+
+		// decrypt with the current key and encrypt with the new key, decrypted data is not written to disk
+		job.Progress("reencrypt", 0, sourceEntry.Size, false)
+
+		err = encryption.ReencryptFile(sourceTempPath, encryptedTempPath, decryptManager, encryptManager)
+		if err != nil {
+			job.Progress("reencrypt", -1, sourceEntry.Size, true)
+
+			reportSimple(targetPath, err)
+			return errors.Wrapf(err, "failed to re-encrypt %q", sourceEntry.Path)
+		}
+
+		job.Progress("reencrypt", sourceEntry.Size, sourceEntry.Size, false)
+
+		encryptedStat, err := os.Stat(encryptedTempPath)
+		if err != nil {
+			reportSimple(targetPath, err)
+			return errors.Wrapf(err, "failed to stat %q", encryptedTempPath)
+		}
+
+		// upload to a temp name to keep the original until the new data object is complete
+		uploadPath := path.Join(sourceDir, "."+encryptedFilename+".reencrypt")
+
+		job.Progress("upload", 0, encryptedStat.Size(), false)
+
+		reencrypt.inFlightTransfers.Start(uploadPath, func() error {
+			return reencrypt.filesystem.RemoveFile(uploadPath, true)
+		})
+		defer reencrypt.inFlightTransfers.Finish(uploadPath)
+
+		retryErr = retry.Do(func() error {
+			_, uploadErr := reencrypt.filesystem.UploadFile(encryptedTempPath, uploadPath, "", false, false, progressCallback)
+			return uploadErr
+		}, retry.Attempts(uint(retryNum+1)), retry.Delay(retryInterval), retry.LastErrorOnly(true))
+		if retryErr != nil {
+			job.Progress("upload", -1, encryptedStat.Size(), true)
+
+			reportSimple(targetPath, retryErr)
+			return errors.Wrapf(retryErr, "failed to upload %q after %d attempts", uploadPath, retryNum+1)
+		}
+
+		// replace the original with the new data object
+		err = reencrypt.replaceDataObject(sourceEntry, uploadPath, targetPath)
+		if err != nil {
+			job.Progress("upload", -1, encryptedStat.Size(), true)
+
+			reportSimple(targetPath, err)
+			return err
+		}
+
+		job.Progress("upload", encryptedStat.Size(), encryptedStat.Size(), false)
+
+		reencrypt.mutex.Lock()
+		reencrypt.totalReencryptedFiles++
+		if keyRing != nil {
+			keyRing.reencrypted++
+		}
+		reencrypt.mutex.Unlock()
+
+		reportFile := &transfer.TransferReportFile{
+			Method:     transfer.TransferMethodReencrypt,
+			StartAt:    startTime,
+			EndAt:      time.Now(),
+			SourcePath: sourceEntry.Path,
+			SourceSize: sourceEntry.Size,
+			DestPath:   targetPath,
+			DestSize:   encryptedStat.Size(),
+			Notes:      defaultNotes,
+		}
+
+		reencrypt.transferReportManager.AddFile(reportFile)
+
+		logger.Debugf("re-encrypted a data object to %q", targetPath)
+
+		return nil
+	}
+
+	reencrypt.parallelJobManager.Schedule(sourceEntry.Path, reencryptTask, 1, progress.UnitsBytes)
+	logger.Debugf("scheduled a data object re-encryption to %q", targetPath)
+
+	return nil
+}
+
+// replaceDataObject replaces the source data object with the uploaded data object
+// AVUs and ACLs of the source are copied, and the source is kept under a backup name until the uploaded data object is renamed
+func (reencrypt *ReencryptCommand) replaceDataObject(sourceEntry *irodsclient_fs.Entry, uploadPath string, targetPath string) error {
+	logger := log.WithFields(log.Fields{
+		"source_path": sourceEntry.Path,
+		"upload_path": uploadPath,
+		"target_path": targetPath,
+	})
+
+	removeUploaded := func() {
+		removeErr := reencrypt.filesystem.RemoveFile(uploadPath, true)
+		if removeErr != nil {
+			logger.WithError(removeErr).Warnf("failed to remove %q", uploadPath)
+		}
+	}
+
+	err := irods.CopyMetadata(reencrypt.filesystem, sourceEntry.Path, uploadPath)
+	if err != nil {
+		removeUploaded()
+		return errors.Wrapf(err, "failed to copy metadata of %q", sourceEntry.Path)
+	}
+
+	protectedUser := reencrypt.account.ClientUser + "#" + reencrypt.account.ClientZone
+	err = irods.CopyACLs(reencrypt.filesystem, sourceEntry.Path, uploadPath, false, protectedUser)
+	if err != nil {
+		removeUploaded()
+		return errors.Wrapf(err, "failed to copy ACLs of %q", sourceEntry.Path)
+	}
+
+	backupPath := path.Join(path.Dir(sourceEntry.Path), "."+sourceEntry.Name+".reencrypt-orig")
+
+	err = reencrypt.filesystem.RenameFileToFile(sourceEntry.Path, backupPath)
+	if err != nil {
+		removeUploaded()
+		return errors.Wrapf(err, "failed to rename %q to %q", sourceEntry.Path, backupPath)
+	}
+
+	err = reencrypt.filesystem.RenameFileToFile(uploadPath, targetPath)
+	if err != nil {
+		// roll back
+		rollbackErr := reencrypt.filesystem.RenameFileToFile(backupPath, sourceEntry.Path)
+		if rollbackErr != nil {
+			return errors.Wrapf(err, "failed to rename %q to %q, the original is kept at %q and the new one at %q", uploadPath, targetPath, backupPath, uploadPath)
+		}
+
+		removeUploaded()
+		return errors.Wrapf(err, "failed to rename %q to %q", uploadPath, targetPath)
+	}
+
+	err = reencrypt.filesystem.RemoveFile(backupPath, true)
+	if err != nil {
+		return errors.Wrapf(err, "failed to remove the original data object kept at %q", backupPath)
+	}
+
+	return nil
+}
+
+func (reencrypt *ReencryptCommand) getEncryptionManagerForDecryption(mode encryption.EncryptionMode) *encryption.EncryptionManager {
+	manager := encryption.NewEncryptionManager(mode)
+	fromKey := reencrypt.reencryptFlagValues.FromKey
+
+	switch mode {
+	case encryption.EncryptionModeWinSCP, encryption.EncryptionModeAESGCM, encryption.EncryptionModePGP:
+		if len(fromKey) == 0 {
+			fromKey = reencrypt.account.Password
+		}
+
+		manager.SetKey([]byte(fromKey))
+	case encryption.EncryptionModeSSH:
+		if len(fromKey) == 0 {
+			fromKey = encryption.GetDefaultPrivateKeyPath()
+		}
+
+		manager.SetPublicPrivateKey(fromKey)
+	case encryption.EncryptionModeAge:
+		if len(fromKey) == 0 {
+			fromKey = encryption.GetDefaultAgeIdentityPath()
+		}
+
+		manager.SetKey([]byte(reencrypt.reencryptFlagValues.FilenameKey))
+		manager.SetPublicPrivateKey(fromKey)
+	}
+
+	return manager
+}
+
+func (reencrypt *ReencryptCommand) getEncryptionManagerForEncryption(mode encryption.EncryptionMode) *encryption.EncryptionManager {
+	manager := encryption.NewEncryptionManager(mode)
+	toKeys := reencrypt.reencryptFlagValues.ToKeys
+
+	switch mode {
+	case encryption.EncryptionModeWinSCP, encryption.EncryptionModeAESGCM, encryption.EncryptionModePGP:
+		manager.SetKey([]byte(toKeys[0]))
+	case encryption.EncryptionModeSSH:
+		manager.SetPublicKeyRing(toKeys)
+	case encryption.EncryptionModeAge:
+		manager.SetKey([]byte(reencrypt.reencryptFlagValues.FilenameKey))
+		manager.SetAgeRecipients(toKeys)
+	}
+
+	return manager
+}
+
+// getKeyRing returns the key ring in metadata of the collection, nil if the collection has no key ring
+func (reencrypt *ReencryptCommand) getKeyRing(collectionPath string) *reencryptKeyRing {
+	reencrypt.mutex.Lock()
+	defer reencrypt.mutex.Unlock()
+
+	keyRing, ok := reencrypt.keyRings[collectionPath]
+	if ok {
+		return keyRing
+	}
+
+	encryptionConfig := encryption.GetEncryptionConfigFromMeta(reencrypt.filesystem, collectionPath)
+	if len(encryptionConfig.PublicKeys) > 0 {
+		keyRing = &reencryptKeyRing{
+			publicKeys: encryptionConfig.PublicKeys,
+			sshMode:    encryptionConfig.Mode == encryption.EncryptionModeSSH,
+		}
+	}
+
+	reencrypt.keyRings[collectionPath] = keyRing
+	return keyRing
+}
+
+// getKeyRingsToUpdate returns paths of collections whose key rings are replaced, in 'ssh' mode or having data objects in 'ssh' mode
+func (reencrypt *ReencryptCommand) getKeyRingsToUpdate() []string {
+	reencrypt.mutex.Lock()
+	defer reencrypt.mutex.Unlock()
+
+	collectionPaths := []string{}
+	for collectionPath, keyRing := range reencrypt.keyRings {
+		if keyRing != nil && (keyRing.sshMode || keyRing.scheduled > 0) {
+			collectionPaths = append(collectionPaths, collectionPath)
+		}
+	}
+
+	sort.Strings(collectionPaths)
+	return collectionPaths
+}
+
+// makeNewKeyRing makes authorized keys of the new keys to replace key rings in metadata
+// fails if collections have key rings but the new keys are not ssh public keys
+func (reencrypt *ReencryptCommand) makeNewKeyRing() error {
+	collectionPaths := reencrypt.getKeyRingsToUpdate()
+	if len(collectionPaths) == 0 {
+		return nil
+	}
+
+	publicKeys, err := encryption.DecodePublicKeyRing(reencrypt.reencryptFlagValues.ToKeys)
+	if err != nil {
+		return errors.Wrapf(err, "failed to replace key ring of %q, 'to_key' must be ssh public keys or key rings", collectionPaths[0])
+	}
+
+	reencrypt.newKeyRing, err = encryption.EncodeAuthorizedKeys(publicKeys)
+	if err != nil {
+		return errors.Wrapf(err, "failed to replace key ring of %q", collectionPaths[0])
+	}
+
+	return nil
+}
+
+// updateKeyRings replaces key rings in metadata of collections with the new keys
+// collections having data objects not re-encrypted keep the current key ring, so the current key can still decrypt their filenames
+func (reencrypt *ReencryptCommand) updateKeyRings() error {
+	logger := log.WithFields(log.Fields{})
+
+	for _, collectionPath := range reencrypt.getKeyRingsToUpdate() {
+		reencrypt.mutex.Lock()
+		keyRing := reencrypt.keyRings[collectionPath]
+		notReencrypted := keyRing.scheduled - keyRing.reencrypted
+		reencrypt.mutex.Unlock()
+
+		if reencrypt.dryRunFlagValues.DryRun {
+			terminal.Printf("replace key ring of %q (%d keys) with %d keys\n", collectionPath, len(keyRing.publicKeys), len(reencrypt.newKeyRing))
+			continue
+		}
+
+		if notReencrypted > 0 {
+			terminal.Printf("skip replacing key ring of %q, %d data objects were not re-encrypted\n", collectionPath, notReencrypted)
+			continue
+		}
+
+		err := encryption.SetPublicKeysToMeta(reencrypt.filesystem, collectionPath, reencrypt.newKeyRing)
+		if err != nil {
+			return errors.Wrapf(err, "failed to replace key ring of %q", collectionPath)
+		}
+
+		logger.Debugf("replaced key ring of %q with %d keys", collectionPath, len(reencrypt.newKeyRing))
+	}
+
+	return nil
+}
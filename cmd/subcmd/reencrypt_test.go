@@ -0,0 +1,158 @@
+package subcmd
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"filippo.io/age"
+	irodsclient_types "github.com/cyverse/go-irodsclient/irods/types"
+	"github.com/cyverse/gocommands/cmd/flag"
+	"github.com/cyverse/gocommands/commons/encryption"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestReencrypt(t *testing.T) {
+	t.Run("test GetEncryptionManager", testGetEncryptionManager)
+}
+
+func testGetEncryptionManager(t *testing.T) {
+	tempDir := t.TempDir()
+
+	password := "password"
+	filenameKey := "filename key"
+
+	// one current and two new ssh keys
+	sshPrivateKeyPaths := []string{}
+	sshAuthorizedKeys := []string{}
+	for i := 0; i < 3; i++ {
+		privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+		assert.NoError(t, err)
+
+		publicKey, err := ssh.NewPublicKey(&privateKey.PublicKey)
+		assert.NoError(t, err)
+
+		privateKeyPath := filepath.Join(tempDir, fmt.Sprintf("ssh%d", i))
+		privateKeyPem := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(privateKey)})
+		err = os.WriteFile(privateKeyPath, privateKeyPem, 0600)
+		assert.NoError(t, err)
+
+		sshPrivateKeyPaths = append(sshPrivateKeyPaths, privateKeyPath)
+		sshAuthorizedKeys = append(sshAuthorizedKeys, string(ssh.MarshalAuthorizedKey(publicKey)))
+	}
+
+	// one current and two new age identities
+	ageIdentityPaths := []string{}
+	ageRecipients := []string{}
+	for i := 0; i < 3; i++ {
+		identity, err := age.GenerateX25519Identity()
+		assert.NoError(t, err)
+
+		identityPath := filepath.Join(tempDir, fmt.Sprintf("age%d", i))
+		err = os.WriteFile(identityPath, []byte(identity.String()+"\n"), 0600)
+		assert.NoError(t, err)
+
+		ageIdentityPaths = append(ageIdentityPaths, identityPath)
+		ageRecipients = append(ageRecipients, identity.Recipient().String())
+	}
+
+	newKeyManager := func(mode encryption.EncryptionMode, key string) *encryption.EncryptionManager {
+		manager := encryption.NewEncryptionManager(mode)
+		manager.SetKey([]byte(key))
+		return manager
+	}
+
+	newSSHManager := func(privateKeyIdx int, keyRing []string) *encryption.EncryptionManager {
+		manager := encryption.NewEncryptionManager(encryption.EncryptionModeSSH)
+		manager.SetPublicPrivateKey(sshPrivateKeyPaths[privateKeyIdx])
+		manager.SetPublicKeyRing(keyRing)
+		return manager
+	}
+
+	newAgeManager := func(identityIdx int, recipients []string) *encryption.EncryptionManager {
+		manager := encryption.NewEncryptionManager(encryption.EncryptionModeAge)
+		manager.SetKey([]byte(filenameKey))
+		manager.SetPublicPrivateKey(ageIdentityPaths[identityIdx])
+		manager.SetAgeRecipients(recipients)
+		return manager
+	}
+
+	tests := []struct {
+		name           string
+		mode           encryption.EncryptionMode
+		fromKey        string
+		toKeys         []string
+		currentManager *encryption.EncryptionManager // encrypts with the current key
+		newManager     *encryption.EncryptionManager // decrypts with the new key
+	}{
+		{"winscp", encryption.EncryptionModeWinSCP, "current key", []string{"new key"}, newKeyManager(encryption.EncryptionModeWinSCP, "current key"), newKeyManager(encryption.EncryptionModeWinSCP, "new key")},
+		{"winscp with password", encryption.EncryptionModeWinSCP, "", []string{"new key"}, newKeyManager(encryption.EncryptionModeWinSCP, password), newKeyManager(encryption.EncryptionModeWinSCP, "new key")},
+		{"aesgcm", encryption.EncryptionModeAESGCM, "current key", []string{"new key"}, newKeyManager(encryption.EncryptionModeAESGCM, "current key"), newKeyManager(encryption.EncryptionModeAESGCM, "new key")},
+		{"pgp with password", encryption.EncryptionModePGP, "", []string{"new key"}, newKeyManager(encryption.EncryptionModePGP, password), newKeyManager(encryption.EncryptionModePGP, "new key")},
+		{"ssh", encryption.EncryptionModeSSH, sshPrivateKeyPaths[0], []string{sshAuthorizedKeys[1]}, newSSHManager(0, []string{sshAuthorizedKeys[0]}), newSSHManager(1, nil)},
+		{"ssh multi-recipient", encryption.EncryptionModeSSH, sshPrivateKeyPaths[0], []string{sshAuthorizedKeys[1], sshAuthorizedKeys[2]}, newSSHManager(0, []string{sshAuthorizedKeys[0]}), newSSHManager(2, []string{sshAuthorizedKeys[1], sshAuthorizedKeys[2]})},
+		{"age", encryption.EncryptionModeAge, ageIdentityPaths[0], []string{ageRecipients[1], ageRecipients[2]}, newAgeManager(0, []string{ageRecipients[0]}), newAgeManager(2, nil)},
+	}
+
+	filename := "data.bin"
+	data := []byte("data to re-encrypt with a new key")
+
+	for _, test := range tests {
+		reencrypt := &ReencryptCommand{
+			reencryptFlagValues: &flag.ReencryptFlagValues{
+				FromKey:     test.fromKey,
+				ToKeys:      test.toKeys,
+				FilenameKey: filenameKey,
+			},
+			account: &irodsclient_types.IRODSAccount{
+				Password: password,
+			},
+		}
+
+		decryptManager := reencrypt.getEncryptionManagerForDecryption(test.mode)
+		encryptManager := reencrypt.getEncryptionManagerForEncryption(test.mode)
+
+		// filename
+		currentFilename, err := test.currentManager.EncryptFilename(filename)
+		assert.NoError(t, err, test.name)
+
+		decryptedFilename, err := decryptManager.DecryptFilename(currentFilename)
+		assert.NoError(t, err, test.name)
+		assert.Equal(t, filename, decryptedFilename, test.name)
+
+		newFilename, err := encryptManager.EncryptFilename(decryptedFilename)
+		assert.NoError(t, err, test.name)
+
+		decryptedFilename, err = test.newManager.DecryptFilename(newFilename)
+		assert.NoError(t, err, test.name)
+		assert.Equal(t, filename, decryptedFilename, test.name)
+
+		// data
+		sourcePath := filepath.Join(tempDir, test.name+".src")
+		currentPath := filepath.Join(tempDir, test.name+".current")
+		newPath := filepath.Join(tempDir, test.name+".new")
+		decryptedPath := filepath.Join(tempDir, test.name+".dec")
+
+		err = os.WriteFile(sourcePath, data, 0600)
+		assert.NoError(t, err, test.name)
+
+		err = test.currentManager.EncryptFile(sourcePath, currentPath)
+		assert.NoError(t, err, test.name)
+
+		err = encryption.ReencryptFile(currentPath, newPath, decryptManager, encryptManager)
+		assert.NoError(t, err, test.name)
+
+		err = test.newManager.DecryptFile(newPath, decryptedPath)
+		assert.NoError(t, err, test.name)
+
+		decryptedData, err := os.ReadFile(decryptedPath)
+		assert.NoError(t, err, test.name)
+		assert.Equal(t, data, decryptedData, test.name)
+	}
+}
@@ -1,6 +1,8 @@
 package subcmd
 
 import (
+	"context"
+
 	"github.com/cockroachdb/errors"
 	irodsclient_fs "github.com/cyverse/go-irodsclient/fs"
 	irodsclient_types "github.com/cyverse/go-irodsclient/irods/types"
@@ -104,7 +106,7 @@ func (rm *RmCommand) Process() error {
 
 	// Expand wildcards
 	if rm.wildcardSearchFlagValues.WildcardSearch {
-		rm.targetPaths, err = wildcard.ExpandWildcards(rm.filesystem, rm.account, rm.targetPaths, true, true)
+		rm.targetPaths, err = wildcard.ExpandWildcards(context.Background(), rm.filesystem, rm.account, rm.targetPaths, true, true)
 		if err != nil {
 			return errors.Wrapf(err, "failed to expand wildcards")
 		}
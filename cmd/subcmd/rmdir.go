@@ -1,6 +1,8 @@
 package subcmd
 
 import (
+	"context"
+
 	"github.com/cockroachdb/errors"
 	irodsclient_fs "github.com/cyverse/go-irodsclient/fs"
 	irodsclient_types "github.com/cyverse/go-irodsclient/irods/types"
@@ -105,7 +107,7 @@ func (rmDir *RmDirCommand) Process() error {
 
 	// Expand wildcards
 	if rmDir.wildcardSearchFlagValues.WildcardSearch {
-		rmDir.targetPaths, err = wildcard.ExpandWildcards(rmDir.filesystem, rmDir.account, rmDir.targetPaths, true, false)
+		rmDir.targetPaths, err = wildcard.ExpandWildcards(context.Background(), rmDir.filesystem, rmDir.account, rmDir.targetPaths, true, false)
 		if err != nil {
 			return errors.Wrapf(err, "failed to expand wildcards")
 		}
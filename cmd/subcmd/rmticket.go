@@ -6,7 +6,9 @@ import (
 	irodsclient_types "github.com/cyverse/go-irodsclient/irods/types"
 	"github.com/cyverse/gocommands/cmd/flag"
 	"github.com/cyverse/gocommands/commons/config"
+	"github.com/cyverse/gocommands/commons/format"
 	"github.com/cyverse/gocommands/commons/irods"
+	"github.com/cyverse/gocommands/commons/terminal"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
@@ -15,14 +17,18 @@ var rmticketCmd = &cobra.Command{
 	Use:     "rmticket <ticket-name-or-id>...",
 	Aliases: []string{"rm_ticket", "remove_ticket"},
 	Short:   "Remove tickets for a user",
-	Long:    `This command removes one or more tickets for the specified user.`,
-	RunE:    processRmticketCommand,
-	Args:    cobra.MinimumNArgs(1),
+	Long: `This command removes one or more tickets for the specified user.
+With --expired, --exhausted, or --dangling, it removes all tickets of the user matching any of the filters.`,
+	RunE: processRmticketCommand,
+	Args: cobra.ArbitraryArgs,
 }
 
 func AddRmticketCommand(rootCmd *cobra.Command) {
 	// attach common flags
 	flag.SetCommonFlags(rmticketCmd, true)
+	flag.SetOutputFormatFlags(rmticketCmd, true)
+	flag.SetTicketFilterFlags(rmticketCmd)
+	flag.SetDryRunFlags(rmticketCmd)
 
 	rootCmd.AddCommand(rmticketCmd)
 }
@@ -39,7 +45,10 @@ func processRmticketCommand(command *cobra.Command, args []string) error {
 type RmTicketCommand struct {
 	command *cobra.Command
 
-	commonFlagValues *flag.CommonFlagValues
+	commonFlagValues       *flag.CommonFlagValues
+	outputFormatFlagValues *flag.OutputFormatFlagValues
+	ticketFilterFlagValues *flag.TicketFilterFlagValues
+	dryRunFlagValues       *flag.DryRunFlagValues
 
 	account    *irodsclient_types.IRODSAccount
 	filesystem *irodsclient_fs.FileSystem
@@ -51,7 +60,10 @@ func NewRmTicketCommand(command *cobra.Command, args []string) (*RmTicketCommand
 	rmTicket := &RmTicketCommand{
 		command: command,
 
-		commonFlagValues: flag.GetCommonFlagValues(command),
+		commonFlagValues:       flag.GetCommonFlagValues(command),
+		outputFormatFlagValues: flag.GetOutputFormatFlagValues(),
+		ticketFilterFlagValues: flag.GetTicketFilterFlagValues(),
+		dryRunFlagValues:       flag.GetDryRunFlagValues(),
 	}
 
 	// tickets
@@ -70,6 +82,10 @@ func (rmTicket *RmTicketCommand) Process() error {
 		return nil
 	}
 
+	if len(rmTicket.tickets) == 0 && !rmTicket.ticketFilterFlagValues.HasFilter() {
+		return errors.New("either tickets or filters (--expired, --exhausted, --dangling) must be given")
+	}
+
 	// handle local flags
 	_, err = config.InputMissingFields()
 	if err != nil {
@@ -90,7 +106,16 @@ func (rmTicket *RmTicketCommand) Process() error {
 	}
 	defer rmTicket.filesystem.Release()
 
+	if rmTicket.ticketFilterFlagValues.HasFilter() {
+		return rmTicket.removeTicketsWithFilter()
+	}
+
 	for _, ticketName := range rmTicket.tickets {
+		if rmTicket.dryRunFlagValues.DryRun {
+			terminal.Printf("remove ticket %q (dry run)\n", ticketName)
+			continue
+		}
+
 		err = rmTicket.removeTicket(ticketName)
 		if err != nil {
 			return errors.Wrapf(err, "failed to remove ticket %q", ticketName)
@@ -99,6 +124,73 @@ func (rmTicket *RmTicketCommand) Process() error {
 	return nil
 }
 
+func (rmTicket *RmTicketCommand) removeTicketsWithFilter() error {
+	tickets := []*irodsclient_types.IRODSTicket{}
+	if len(rmTicket.tickets) == 0 {
+		allTickets, err := rmTicket.filesystem.ListTickets()
+		if err != nil {
+			return errors.Wrapf(err, "failed to list tickets")
+		}
+
+		tickets = allTickets
+	} else {
+		for _, ticketName := range rmTicket.tickets {
+			ticket, err := rmTicket.filesystem.GetTicket(ticketName)
+			if err != nil {
+				return errors.Wrapf(err, "failed to get ticket %q", ticketName)
+			}
+
+			tickets = append(tickets, ticket)
+		}
+	}
+
+	// tickets of unknown status are not removed
+	tickets, statuses, statusErr := getTicketStatuses(rmTicket.filesystem, tickets, rmTicket.ticketFilterFlagValues.Dangling)
+	tickets = filterTickets(tickets, statuses, rmTicket.ticketFilterFlagValues)
+
+	outputFormatter := format.NewOutputFormatter(terminal.GetTerminalWriter())
+	outputFormatterTable := outputFormatter.NewTable("Removed iRODS Tickets")
+
+	outputFormatterTable.SetHeader([]string{
+		"Name",
+		"Type",
+		"Path",
+		"Status",
+		"Result",
+	})
+
+	removeErr := statusErr
+	for _, ticket := range tickets {
+		result := "removed"
+		if rmTicket.dryRunFlagValues.DryRun {
+			result = "dry run"
+		} else {
+			err := rmTicket.removeTicket(ticket.Name)
+			if err != nil {
+				result = "failed"
+				if removeErr == nil {
+					removeErr = errors.Wrapf(err, "failed to remove ticket %q", ticket.Name)
+				}
+			}
+		}
+
+		outputFormatterTable.AppendRow([]interface{}{
+			ticket.Name,
+			ticket.Type,
+			ticket.Path,
+			statuses[ticket.ID].String(),
+			result,
+		})
+	}
+
+	if rmTicket.outputFormatFlagValues.Format == format.OutputFormatLegacy {
+		rmTicket.outputFormatFlagValues.Format = format.OutputFormatTable
+	}
+	outputFormatter.Render(rmTicket.outputFormatFlagValues.Format)
+
+	return removeErr
+}
+
 func (rmTicket *RmTicketCommand) removeTicket(ticketName string) error {
 	logger := log.WithFields(log.Fields{
 		"ticket_name": ticketName,
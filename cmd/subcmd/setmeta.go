@@ -0,0 +1,174 @@
+package subcmd
+
+import (
+	"github.com/cockroachdb/errors"
+	irodsclient_fs "github.com/cyverse/go-irodsclient/fs"
+	irodsclient_types "github.com/cyverse/go-irodsclient/irods/types"
+	"github.com/cyverse/gocommands/cmd/flag"
+	"github.com/cyverse/gocommands/commons/config"
+	"github.com/cyverse/gocommands/commons/irods"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var setmetaCmd = &cobra.Command{
+	Use:     "setmeta <irods-object> <metadata-name> <metadata-value> [metadata-unit]",
+	Aliases: []string{"set_meta", "set_metadata"},
+	Short:   "Set metadata of a specified iRODS object, replacing existing values",
+	Long:    `This command sets metadata of a specified iRODS object, such as a collection, data object, user, or resource. All existing metadata having the name are replaced with the given value and unit in a single request, or the metadata is added if the name does not exist.`,
+	RunE:    processSetmetaCommand,
+	Args:    cobra.RangeArgs(3, 4),
+}
+
+func AddSetmetaCommand(rootCmd *cobra.Command) {
+	// attach common flags
+	flag.SetCommonFlagsWithoutResource(setmetaCmd)
+
+	flag.SetTargetObjectFlags(setmetaCmd)
+	flag.SetRecursiveFlags(setmetaCmd, false)
+	flag.SetWildcardSearchFlags(setmetaCmd)
+
+	rootCmd.AddCommand(setmetaCmd)
+}
+
+func processSetmetaCommand(command *cobra.Command, args []string) error {
+	setMeta, err := NewSetMetaCommand(command, args)
+	if err != nil {
+		return err
+	}
+
+	return setMeta.Process()
+}
+
+type SetMetaCommand struct {
+	command *cobra.Command
+
+	commonFlagValues         *flag.CommonFlagValues
+	targetObjectFlagValues   *flag.TargetObjectFlagValues
+	recursiveFlagValues      *flag.RecursiveFlagValues
+	wildcardSearchFlagValues *flag.WildcardSearchFlagValues
+
+	account    *irodsclient_types.IRODSAccount
+	filesystem *irodsclient_fs.FileSystem
+
+	targetObject string
+
+	attribute string
+	value     string
+	unit      string
+}
+
+func NewSetMetaCommand(command *cobra.Command, args []string) (*SetMetaCommand, error) {
+	setMeta := &SetMetaCommand{
+		command: command,
+
+		commonFlagValues:         flag.GetCommonFlagValues(command),
+		targetObjectFlagValues:   flag.GetTargetObjectFlagValues(command),
+		recursiveFlagValues:      flag.GetRecursiveFlagValues(),
+		wildcardSearchFlagValues: flag.GetWildcardSearchFlagValues(),
+	}
+
+	// get avu
+	setMeta.targetObject = args[0]
+	setMeta.attribute = args[1]
+	setMeta.value = args[2]
+
+	setMeta.unit = ""
+	if len(args) >= 4 {
+		setMeta.unit = args[3]
+	}
+
+	if len(setMeta.attribute) == 0 {
+		return nil, errors.Errorf("metadata attribute is required")
+	}
+
+	if len(setMeta.value) == 0 {
+		return nil, errors.Errorf("metadata value is required")
+	}
+
+	if !setMeta.targetObjectFlagValues.Path && (setMeta.recursiveFlagValues.Recursive || setMeta.wildcardSearchFlagValues.WildcardSearch) {
+		return nil, errors.Errorf("recursive and wildcard are only supported for paths")
+	}
+
+	return setMeta, nil
+}
+
+func (setMeta *SetMetaCommand) Process() error {
+	cont, err := flag.ProcessCommonFlags(setMeta.command)
+	if err != nil {
+		return errors.Wrapf(err, "failed to process common flags")
+	}
+
+	if !cont {
+		return nil
+	}
+
+	// handle local flags
+	_, err = config.InputMissingFields()
+	if err != nil {
+		return errors.Wrapf(err, "failed to input missing fields")
+	}
+
+	// Create a file system
+	setMeta.account = config.GetSessionConfig().ToIRODSAccount()
+
+	timeout := 0
+	if setMeta.commonFlagValues.TimeoutUpdated {
+		timeout = setMeta.commonFlagValues.Timeout
+	}
+
+	setMeta.filesystem, err = irods.GetIRODSFSClient(setMeta.account, false, timeout)
+	if err != nil {
+		return errors.Wrapf(err, "failed to get iRODS FS Client")
+	}
+	defer setMeta.filesystem.Release()
+
+	// set meta
+	if setMeta.targetObjectFlagValues.Path {
+		targetPaths, err := makeMetadataTargetPaths(setMeta.filesystem, setMeta.account, []string{setMeta.targetObject}, setMeta.wildcardSearchFlagValues.WildcardSearch, setMeta.recursiveFlagValues.Recursive)
+		if err != nil {
+			return err
+		}
+
+		for _, targetPath := range targetPaths {
+			err = setMeta.setMeta(irods.MetadataTargetPath, targetPath)
+			if err != nil {
+				return err
+			}
+		}
+	} else if setMeta.targetObjectFlagValues.User {
+		err = setMeta.setMeta(irods.MetadataTargetUser, setMeta.targetObject)
+		if err != nil {
+			return err
+		}
+	} else if setMeta.targetObjectFlagValues.Resource {
+		err = setMeta.setMeta(irods.MetadataTargetResource, setMeta.targetObject)
+		if err != nil {
+			return err
+		}
+	} else {
+		// nothing updated
+		return errors.Errorf("path, user, or resource must be given")
+	}
+
+	return nil
+}
+
+func (setMeta *SetMetaCommand) setMeta(targetType irods.MetadataTargetType, target string) error {
+	logger := log.WithFields(log.Fields{
+		"target_type": targetType,
+		"target":      target,
+		"attribute":   setMeta.attribute,
+		"value":       setMeta.value,
+		"unit":        setMeta.unit,
+	})
+
+	logger.Debug("set metadata")
+
+	err := irods.SetMetadataForTarget(setMeta.filesystem, targetType, target, setMeta.account.ClientZone, setMeta.attribute, setMeta.value, setMeta.unit)
+	if err != nil {
+		return errors.Wrapf(err, "failed to set metadata to %s %q (attr %q, value %q, unit %q)", targetType, target, setMeta.attribute, setMeta.value, setMeta.unit)
+	}
+
+	return nil
+}
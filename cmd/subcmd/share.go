@@ -0,0 +1,486 @@
+package subcmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	irodsclient_fs "github.com/cyverse/go-irodsclient/fs"
+	irodsclient_types "github.com/cyverse/go-irodsclient/irods/types"
+	"github.com/cyverse/gocommands/cmd/flag"
+	"github.com/cyverse/gocommands/commons/config"
+	"github.com/cyverse/gocommands/commons/format"
+	"github.com/cyverse/gocommands/commons/irods"
+	"github.com/cyverse/gocommands/commons/path"
+	"github.com/cyverse/gocommands/commons/terminal"
+	"github.com/cyverse/gocommands/commons/types"
+	"github.com/cyverse/gocommands/commons/webdav"
+	"github.com/rs/xid"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var shareCmd = &cobra.Command{
+	Use:   "share <collection|data-object>",
+	Short: "Share a collection or data object",
+	Long: `This command shares the specified collection or data object in iRODS by creating a ticket.
+It sets the expiration, uses limit, and allowed users of the ticket in one step, and prints the WebDAV URL and the gocmd command to access the shared path.`,
+	Example: `  gocmd share i:/zone/home/user/dataset --expires 7d --uses 10 --users alice
+  gocmd share list
+  gocmd share revoke <ticket-name>`,
+	RunE: processShareCommand,
+	Args: cobra.ExactArgs(1),
+}
+
+var shareListCmd = &cobra.Command{
+	Use:     "list [collection|data-object]...",
+	Aliases: []string{"ls"},
+	Short:   "List shares",
+	Long:    `This command lists shares (tickets) of the user. If paths are given, only shares for the paths are listed.`,
+	RunE:    processShareListCommand,
+	Args:    cobra.ArbitraryArgs,
+}
+
+var shareRevokeCmd = &cobra.Command{
+	Use:     "revoke <ticket-name|collection|data-object>...",
+	Aliases: []string{"rm"},
+	Short:   "Revoke shares",
+	Long:    `This command revokes shares (tickets). If a path (starting with 'i:' or '/') is given, all shares for the path are revoked.`,
+	RunE:    processShareRevokeCommand,
+	Args:    cobra.MinimumNArgs(1),
+}
+
+func AddShareCommand(rootCmd *cobra.Command) {
+	// attach common flags
+	flag.SetCommonFlags(shareCmd, true)
+	flag.SetShareFlags(shareCmd)
+
+	flag.SetCommonFlags(shareListCmd, true)
+	flag.SetOutputFormatFlags(shareListCmd, true)
+
+	flag.SetCommonFlags(shareRevokeCmd, true)
+	flag.SetDryRunFlags(shareRevokeCmd)
+
+	shareCmd.AddCommand(shareListCmd)
+	shareCmd.AddCommand(shareRevokeCmd)
+
+	rootCmd.AddCommand(shareCmd)
+}
+
+func processShareCommand(command *cobra.Command, args []string) error {
+	share, err := NewShareCommand(command, args)
+	if err != nil {
+		return err
+	}
+
+	return share.Process()
+}
+
+func processShareListCommand(command *cobra.Command, args []string) error {
+	share, err := NewShareCommand(command, args)
+	if err != nil {
+		return err
+	}
+
+	return share.ProcessList()
+}
+
+func processShareRevokeCommand(command *cobra.Command, args []string) error {
+	share, err := NewShareCommand(command, args)
+	if err != nil {
+		return err
+	}
+
+	return share.ProcessRevoke()
+}
+
+type ShareCommand struct {
+	command *cobra.Command
+
+	commonFlagValues       *flag.CommonFlagValues
+	shareFlagValues        *flag.ShareFlagValues
+	outputFormatFlagValues *flag.OutputFormatFlagValues
+	dryRunFlagValues       *flag.DryRunFlagValues
+
+	account    *irodsclient_types.IRODSAccount
+	filesystem *irodsclient_fs.FileSystem
+
+	args []string
+}
+
+func NewShareCommand(command *cobra.Command, args []string) (*ShareCommand, error) {
+	share := &ShareCommand{
+		command: command,
+
+		commonFlagValues:       flag.GetCommonFlagValues(command),
+		shareFlagValues:        flag.GetShareFlagValues(command),
+		outputFormatFlagValues: flag.GetOutputFormatFlagValues(),
+		dryRunFlagValues:       flag.GetDryRunFlagValues(),
+	}
+
+	share.args = args
+
+	return share, nil
+}
+
+func (share *ShareCommand) connect() (bool, error) {
+	cont, err := flag.ProcessCommonFlags(share.command)
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to process common flags")
+	}
+
+	if !cont {
+		return false, nil
+	}
+
+	// handle local flags
+	_, err = config.InputMissingFields()
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to input missing fields")
+	}
+
+	// Create a file system
+	share.account = config.GetSessionConfig().ToIRODSAccount()
+
+	timeout := 0
+	if share.commonFlagValues.TimeoutUpdated {
+		timeout = share.commonFlagValues.Timeout
+	}
+
+	share.filesystem, err = irods.GetIRODSFSClient(share.account, true, timeout)
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to get iRODS FS Client")
+	}
+
+	return true, nil
+}
+
+func (share *ShareCommand) makeIRODSPath(irodsPath string) string {
+	cwd := config.GetCWD()
+	home := config.GetHomeDir()
+	zone := share.account.ClientZone
+	return path.MakeIRODSPath(cwd, home, zone, irodsPath)
+}
+
+func (share *ShareCommand) Process() error {
+	if share.command.Flags().Changed("expires") && !share.shareFlagValues.ExpiresUpdated {
+		return errors.Errorf("failed to parse expiration %q", share.command.Flags().Lookup("expires").Value.String())
+	}
+
+	cont, err := share.connect()
+	if err != nil {
+		return err
+	}
+
+	if !cont {
+		return nil
+	}
+	defer share.filesystem.Release()
+
+	targetPath := share.makeIRODSPath(share.args[0])
+
+	ticket, err := share.createShare(targetPath)
+	if err != nil {
+		return errors.Wrapf(err, "failed to share %q", targetPath)
+	}
+
+	share.printShare(ticket)
+	return nil
+}
+
+func (share *ShareCommand) createShare(targetPath string) (*irodsclient_types.IRODSTicket, error) {
+	ticketName := xid.New().String()
+	ticketType := irodsclient_types.TicketTypeRead
+	if share.shareFlagValues.Write {
+		ticketType = irodsclient_types.TicketTypeWrite
+	}
+
+	logger := log.WithFields(log.Fields{
+		"ticket_name": ticketName,
+		"ticket_type": ticketType,
+		"target_path": targetPath,
+	})
+
+	logger.Debug("create a share")
+
+	if !share.filesystem.Exists(targetPath) {
+		return nil, irodsclient_types.NewFileNotFoundError(targetPath)
+	}
+
+	err := share.filesystem.CreateTicket(ticketName, ticketType, targetPath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create ticket %q", ticketName)
+	}
+
+	err = share.configureShare(ticketName)
+	if err != nil {
+		// do not leave a half-configured share, it may be less restrictive than requested
+		logger.Debugf("remove the ticket %q as it failed to configure", ticketName)
+		share.filesystem.DeleteTicket(ticketName)
+		return nil, err
+	}
+
+	ticket, err := share.filesystem.GetTicket(ticketName)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get ticket %q", ticketName)
+	}
+
+	return ticket, nil
+}
+
+func (share *ShareCommand) configureShare(ticketName string) error {
+	if share.shareFlagValues.UseLimit > 0 {
+		err := share.filesystem.ModifyTicketUseLimit(ticketName, share.shareFlagValues.UseLimit)
+		if err != nil {
+			return errors.Wrapf(err, "failed to set uses limit of ticket %q", ticketName)
+		}
+	}
+
+	if share.shareFlagValues.ExpiresUpdated {
+		err := share.filesystem.ModifyTicketExpirationTime(ticketName, share.shareFlagValues.ExpirationTime)
+		if err != nil {
+			return errors.Wrapf(err, "failed to set expiration time of ticket %q", ticketName)
+		}
+	}
+
+	for _, user := range share.shareFlagValues.Users {
+		err := share.filesystem.AddTicketAllowedUser(ticketName, user)
+		if err != nil {
+			return errors.Wrapf(err, "failed to add allowed user %q to ticket %q", user, ticketName)
+		}
+	}
+
+	for _, group := range share.shareFlagValues.Groups {
+		err := share.filesystem.AddTicketAllowedGroup(ticketName, group)
+		if err != nil {
+			return errors.Wrapf(err, "failed to add allowed group %q to ticket %q", group, ticketName)
+		}
+	}
+
+	for _, host := range share.shareFlagValues.Hosts {
+		err := share.filesystem.AddTicketAllowedHost(ticketName, host)
+		if err != nil {
+			return errors.Wrapf(err, "failed to add allowed host %q to ticket %q", host, ticketName)
+		}
+	}
+
+	return nil
+}
+
+func (share *ShareCommand) getWebDAVURL(ticket *irodsclient_types.IRODSTicket) string {
+	webdavBaseURL := config.GetSessionConfig().WebDAVBaseURL
+	if len(webdavBaseURL) == 0 {
+		return ""
+	}
+
+	return webdav.GetTicketURL(webdavBaseURL, ticket.Path, ticket.Name)
+}
+
+func (share *ShareCommand) getGetCommand(ticket *irodsclient_types.IRODSTicket) string {
+	return fmt.Sprintf("gocmd get -T %s %s .", quoteShellArgument(ticket.Name), quoteShellArgument("i:"+ticket.Path))
+}
+
+// quoteShellArgument quotes the argument with single quotes if it contains characters special to shells
+func quoteShellArgument(arg string) string {
+	if len(arg) > 0 && strings.IndexFunc(arg, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_./:@%+=,", r))
+	}) < 0 {
+		return arg
+	}
+
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
+
+func (share *ShareCommand) printShare(ticket *irodsclient_types.IRODSTicket) {
+	expiryTime := "none"
+	if !ticket.ExpirationTime.IsZero() {
+		expiryTime = types.MakeDateTimeString(ticket.ExpirationTime)
+	}
+
+	usesLimit := "none"
+	if ticket.UsesLimit > 0 {
+		usesLimit = fmt.Sprintf("%d", ticket.UsesLimit)
+	}
+
+	terminal.Printf("Shared %q\n", ticket.Path)
+	terminal.Printf("  Ticket: %s (%s)\n", ticket.Name, ticket.Type)
+	terminal.Printf("  Expiry: %s\n", expiryTime)
+	terminal.Printf("  Uses limit: %s\n", usesLimit)
+
+	if len(share.shareFlagValues.Users) > 0 {
+		terminal.Printf("  Allowed users: %s\n", strings.Join(share.shareFlagValues.Users, ", "))
+	}
+
+	if len(share.shareFlagValues.Groups) > 0 {
+		terminal.Printf("  Allowed groups: %s\n", strings.Join(share.shareFlagValues.Groups, ", "))
+	}
+
+	if len(share.shareFlagValues.Hosts) > 0 {
+		terminal.Printf("  Allowed hosts: %s\n", strings.Join(share.shareFlagValues.Hosts, ", "))
+	}
+
+	webdavURL := share.getWebDAVURL(ticket)
+	if len(webdavURL) > 0 {
+		terminal.Printf("  WebDAV URL: %s\n", webdavURL)
+	} else {
+		terminal.Printf("  WebDAV URL: not available, 'irods_webdav_base_url' is not configured\n")
+	}
+
+	terminal.Printf("  Command: %s\n", share.getGetCommand(ticket))
+}
+
+func (share *ShareCommand) listShares(paths []string) ([]*irodsclient_types.IRODSTicket, error) {
+	tickets, err := share.filesystem.ListTickets()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list tickets")
+	}
+
+	if len(paths) == 0 {
+		return tickets, nil
+	}
+
+	pathMap := map[string]bool{}
+	for _, p := range paths {
+		pathMap[share.makeIRODSPath(p)] = true
+	}
+
+	filteredTickets := []*irodsclient_types.IRODSTicket{}
+	for _, ticket := range tickets {
+		if _, ok := pathMap[ticket.Path]; ok {
+			filteredTickets = append(filteredTickets, ticket)
+		}
+	}
+
+	return filteredTickets, nil
+}
+
+func (share *ShareCommand) ProcessList() error {
+	cont, err := share.connect()
+	if err != nil {
+		return err
+	}
+
+	if !cont {
+		return nil
+	}
+	defer share.filesystem.Release()
+
+	tickets, err := share.listShares(share.args)
+	if err != nil {
+		return err
+	}
+
+	sort.SliceStable(tickets, func(i int, j int) bool {
+		return tickets[i].Path < tickets[j].Path || (tickets[i].Path == tickets[j].Path && tickets[i].Name < tickets[j].Name)
+	})
+
+	outputFormatter := format.NewOutputFormatter(terminal.GetTerminalWriter())
+	outputFormatterTable := outputFormatter.NewTable("Shares")
+
+	outputFormatterTable.SetHeader([]string{
+		"Name",
+		"Type",
+		"Path",
+		"Uses",
+		"Expiry Time",
+		"Expired",
+		"WebDAV URL",
+	})
+
+	now := time.Now()
+	for _, ticket := range tickets {
+		expiryTime := "none"
+		expired := false
+		if !ticket.ExpirationTime.IsZero() {
+			expiryTime = types.MakeDateTimeString(ticket.ExpirationTime)
+			expired = ticket.ExpirationTime.Before(now)
+		}
+
+		uses := fmt.Sprintf("%d", ticket.UsesCount)
+		if ticket.UsesLimit > 0 {
+			uses = fmt.Sprintf("%d/%d", ticket.UsesCount, ticket.UsesLimit)
+		}
+
+		outputFormatterTable.AppendRow([]interface{}{
+			ticket.Name,
+			ticket.Type,
+			ticket.Path,
+			uses,
+			expiryTime,
+			expired,
+			share.getWebDAVURL(ticket),
+		})
+	}
+
+	if share.outputFormatFlagValues.Format == format.OutputFormatLegacy {
+		share.outputFormatFlagValues.Format = format.OutputFormatTable
+	}
+	outputFormatter.Render(share.outputFormatFlagValues.Format)
+
+	return nil
+}
+
+func (share *ShareCommand) ProcessRevoke() error {
+	cont, err := share.connect()
+	if err != nil {
+		return err
+	}
+
+	if !cont {
+		return nil
+	}
+	defer share.filesystem.Release()
+
+	ticketNames := []string{}
+	paths := []string{}
+	for _, arg := range share.args {
+		if strings.HasPrefix(arg, "i:") || strings.HasPrefix(arg, "/") {
+			paths = append(paths, arg)
+		} else {
+			ticketNames = append(ticketNames, arg)
+		}
+	}
+
+	if len(paths) > 0 {
+		tickets, err := share.listShares(paths)
+		if err != nil {
+			return err
+		}
+
+		for _, ticket := range tickets {
+			ticketNames = append(ticketNames, ticket.Name)
+		}
+	}
+
+	for _, ticketName := range ticketNames {
+		err = share.revokeShare(ticketName)
+		if err != nil {
+			return errors.Wrapf(err, "failed to revoke share %q", ticketName)
+		}
+	}
+
+	return nil
+}
+
+func (share *ShareCommand) revokeShare(ticketName string) error {
+	logger := log.WithFields(log.Fields{
+		"ticket_name": ticketName,
+	})
+
+	if share.dryRunFlagValues.DryRun {
+		terminal.Printf("revoke share %q (dry run)\n", ticketName)
+		return nil
+	}
+
+	logger.Debug("revoke a share")
+
+	err := share.filesystem.DeleteTicket(ticketName)
+	if err != nil {
+		return errors.Wrapf(err, "failed to delete ticket %q", ticketName)
+	}
+
+	terminal.Printf("revoked share %q\n", ticketName)
+	return nil
+}
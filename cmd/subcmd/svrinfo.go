@@ -1,6 +1,8 @@
 package subcmd
 
 import (
+	"fmt"
+
 	"github.com/cockroachdb/errors"
 	irodsclient_fs "github.com/cyverse/go-irodsclient/fs"
 	irodsclient_types "github.com/cyverse/go-irodsclient/irods/types"
@@ -16,7 +18,7 @@ var svrinfoCmd = &cobra.Command{
 	Use:     "svrinfo",
 	Aliases: []string{"server_info"},
 	Short:   "Display information about the iRODS server",
-	Long:    `This command displays information about the iRODS server, such as its version and configuration.`,
+	Long:    `This command displays information about the iRODS server, such as its version and configuration. With --full, it also displays connection parameters, round-trip latency, server capabilities, and the resource tree for troubleshooting.`,
 	RunE:    processSvrinfoCommand,
 	Args:    cobra.NoArgs,
 }
@@ -25,6 +27,7 @@ func AddSvrinfoCommand(rootCmd *cobra.Command) {
 	// attach common flags
 	flag.SetCommonFlags(svrinfoCmd, true)
 	flag.SetOutputFormatFlags(svrinfoCmd, true)
+	flag.SetServerInfoFlags(svrinfoCmd)
 
 	rootCmd.AddCommand(svrinfoCmd)
 }
@@ -43,6 +46,7 @@ type SvrInfoCommand struct {
 
 	commonFlagValues       *flag.CommonFlagValues
 	outputFormatFlagValues *flag.OutputFormatFlagValues
+	serverInfoFlagValues   *flag.ServerInfoFlagValues
 
 	account    *irodsclient_types.IRODSAccount
 	filesystem *irodsclient_fs.FileSystem
@@ -54,6 +58,7 @@ func NewSvrInfoCommand(command *cobra.Command, args []string) (*SvrInfoCommand,
 
 		commonFlagValues:       flag.GetCommonFlagValues(command),
 		outputFormatFlagValues: flag.GetOutputFormatFlagValues(),
+		serverInfoFlagValues:   flag.GetServerInfoFlagValues(),
 	}
 
 	return svrInfo, nil
@@ -117,6 +122,13 @@ func (svrInfo *SvrInfoCommand) displayInfo() error {
 		svrInfo.account.ClientZone,
 	})
 
+	if svrInfo.serverInfoFlagValues.Full {
+		err = svrInfo.appendFullInfo(outputFormatter)
+		if err != nil {
+			return err
+		}
+	}
+
 	if svrInfo.outputFormatFlagValues.Format == format.OutputFormatLegacy {
 		svrInfo.outputFormatFlagValues.Format = format.OutputFormatTable
 	}
@@ -124,3 +136,95 @@ func (svrInfo *SvrInfoCommand) displayInfo() error {
 
 	return nil
 }
+
+func (svrInfo *SvrInfoCommand) appendFullInfo(outputFormatter *format.OutputFormatter) error {
+	connInfo, err := irods.GetServerConnectionInfo(svrInfo.filesystem)
+	if err != nil {
+		return errors.Wrapf(err, "failed to get connection info")
+	}
+
+	minLatency, avgLatency, err := irods.MeasureServerLatency(svrInfo.filesystem, 5)
+	if err != nil {
+		return errors.Wrapf(err, "failed to measure latency")
+	}
+
+	// connection
+	ssl := "no"
+	encryptionAlgorithm := ""
+	encryptionKeySize := ""
+	encryptionSaltSize := ""
+	encryptionHashRounds := ""
+	if connInfo.SSL {
+		ssl = "yes"
+
+		if svrInfo.account.SSLConfiguration != nil {
+			encryptionAlgorithm = svrInfo.account.SSLConfiguration.EncryptionAlgorithm
+			encryptionKeySize = fmt.Sprintf("%d", svrInfo.account.SSLConfiguration.EncryptionKeySize)
+			encryptionSaltSize = fmt.Sprintf("%d", svrInfo.account.SSLConfiguration.EncryptionSaltSize)
+			encryptionHashRounds = fmt.Sprintf("%d", svrInfo.account.SSLConfiguration.EncryptionNumHashRounds)
+		}
+	}
+
+	connTable := outputFormatter.NewTable("Connection")
+	connTable.SetHeader([]string{
+		"Host",
+		"Port",
+		"User",
+		"Auth Scheme",
+		"CS Negotiation",
+		"SSL",
+		"Encryption Algorithm",
+		"Encryption Key Size",
+		"Encryption Salt Size",
+		"Encryption Hash Rounds",
+		"Latency (min)",
+		"Latency (avg)",
+	})
+
+	connTable.AppendRow([]interface{}{
+		svrInfo.account.Host,
+		svrInfo.account.Port,
+		svrInfo.account.ClientUser,
+		string(svrInfo.account.AuthenticationScheme),
+		string(svrInfo.account.CSNegotiationPolicy),
+		ssl,
+		encryptionAlgorithm,
+		encryptionKeySize,
+		encryptionSaltSize,
+		encryptionHashRounds,
+		minLatency.String(),
+		avgLatency.String(),
+	})
+
+	// capabilities
+	capabilityTable := outputFormatter.NewTable("Server Capabilities")
+	capabilityTable.SetHeader([]string{
+		"Feature",
+		"Available",
+		"Detail",
+	})
+
+	for _, capability := range irods.GetServerCapabilities(svrInfo.filesystem, connInfo) {
+		available := "no"
+		if capability.Available {
+			available = "yes"
+		}
+
+		capabilityTable.AppendRow([]interface{}{
+			capability.Name,
+			available,
+			capability.Reason,
+		})
+	}
+
+	// resources
+	resources, err := irods.ListResourceInfos(svrInfo.filesystem)
+	if err != nil {
+		return errors.Wrapf(err, "failed to list resources")
+	}
+
+	drawTree := svrInfo.outputFormatFlagValues.Format == format.OutputFormatTable || svrInfo.outputFormatFlagValues.Format == format.OutputFormatLegacy
+	appendResourceTreeTable(outputFormatter, irods.GetRootResourceInfos(resources), drawTree)
+
+	return nil
+}
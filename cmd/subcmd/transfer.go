@@ -0,0 +1,363 @@
+package subcmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	irodsclient_types "github.com/cyverse/go-irodsclient/irods/types"
+	"github.com/cyverse/gocommands/cmd/flag"
+	"github.com/cyverse/gocommands/commons/config"
+	"github.com/cyverse/gocommands/commons/interrupt"
+	"github.com/cyverse/gocommands/commons/parallel"
+	"github.com/cyverse/gocommands/commons/terminal"
+	"github.com/cyverse/gocommands/commons/tracing"
+	"github.com/cyverse/gocommands/commons/types"
+	"github.com/cyverse/gocommands/pkg/gocommands"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// getTransferAccount returns the account of the session, accessing with the ticket if given
+func getTransferAccount(ticketAccessFlagValues *flag.TicketAccessFlagValues) (*irodsclient_types.IRODSAccount, error) {
+	logger := log.WithFields(log.Fields{})
+
+	if ticketAccessFlagValues != nil && ticketAccessFlagValues.Anonymous {
+		if len(ticketAccessFlagValues.Name) == 0 {
+			return nil, errors.New("failed to access as anonymous user, ticket is not given")
+		}
+
+		config.SetAnonymousAccess(ticketAccessFlagValues.Name)
+	}
+
+	_, err := config.InputMissingFields()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to input missing fields")
+	}
+
+	account := config.GetSessionConfig().ToIRODSAccount()
+	if ticketAccessFlagValues != nil && len(ticketAccessFlagValues.Name) > 0 {
+		logger.Debugf("use ticket: %q", ticketAccessFlagValues.Name)
+		account.Ticket = ticketAccessFlagValues.Name
+	}
+
+	return account, nil
+}
+
+// getTransferOptions returns options common to transfers, set from command-line flags
+func getTransferOptions(command *cobra.Command, account *irodsclient_types.IRODSAccount) (gocommands.TransferOptions, error) {
+	commonFlagValues := flag.GetCommonFlagValues(command)
+	parallelTransferFlagValues := flag.GetParallelTransferFlagValues()
+	scheduleFlagValues := flag.GetScheduleFlagValues()
+	retryFlagValues := flag.GetRetryFlagValues()
+	syncFlagValues := flag.GetSyncFlagValues()
+	transferReportFlagValues := flag.GetTransferReportFlagValues(command)
+	progressFlagValues := flag.GetProgressFlagValues()
+
+	schedulePolicy, err := scheduleFlagValues.GetSchedulePolicy()
+	if err != nil {
+		return gocommands.TransferOptions{}, errors.Wrapf(err, "failed to get schedule policy")
+	}
+
+	progressMode, err := progressFlagValues.GetProgressMode()
+	if err != nil {
+		return gocommands.TransferOptions{}, errors.Wrapf(err, "failed to get progress mode")
+	}
+
+	options := gocommands.TransferOptions{
+		Account:    account,
+		WorkingDir: config.GetCWD(),
+		HomeDir:    config.GetHomeDir(),
+
+		Force: flag.GetForceFlagValues().Force,
+		Confirm: func(message string) bool {
+			if commonFlagValues.YesAll {
+				return true
+			} else if commonFlagValues.NoAll {
+				return false
+			}
+
+			return terminal.InputYN(message)
+		},
+		Recursive:       flag.GetRecursiveFlagValues().Recursive,
+		NoRoot:          flag.GetNoRootFlagValues().NoRoot,
+		Differential:    flag.GetDifferentialTransferFlagValues().DifferentialTransfer,
+		NoHash:          flag.GetDifferentialTransferFlagValues().NoHash,
+		VerifyChecksum:  flag.GetChecksumFlagValues().VerifyChecksum,
+		ExcludeHidden:   flag.GetHiddenFileFlagValues().Exclude,
+		DeleteOnSuccess: flag.GetPostTransferFlagValues().DeleteOnSuccess,
+		MetadataSidecar: flag.GetMetadataSidecarFlagValues().MetadataSidecar,
+
+		Sync:   syncFlagValues.Sync,
+		Delete: syncFlagValues.Delete,
+		Age:    time.Duration(syncFlagValues.Age) * time.Minute,
+
+		ThreadNum:        parallelTransferFlagValues.ThreadNumber,
+		ThreadNumPerFile: parallelTransferFlagValues.ThreadNumberPerFile,
+		TCPBufferSize:    parallelTransferFlagValues.TCPBufferSize,
+		SingleThread:     parallelTransferFlagValues.SingleThread,
+		ICAT:             parallelTransferFlagValues.Icat,
+		WebDAV:           parallelTransferFlagValues.WebDAV,
+		WebDAVBaseURL:    config.GetSessionConfig().WebDAVBaseURL,
+		StopOnError:      parallelTransferFlagValues.StopOnError,
+		RetryNum:         retryFlagValues.GetRetryNumber(),
+		RetryInterval:    retryFlagValues.GetRetryIntervalSeconds(),
+
+		SchedulePolicy:   gocommands.SchedulePolicy(schedulePolicy),
+		PriorityPatterns: scheduleFlagValues.PriorityPatterns,
+
+		ShowProgress:        progressFlagValues.ShowProgress,
+		ShowFullPath:        progressFlagValues.ShowFullPath,
+		ProgressMode:        gocommands.ProgressMode(progressMode),
+		ProgressSlowFileNum: progressFlagValues.SlowFileNum,
+	}
+
+	if commonFlagValues.TimeoutUpdated {
+		options.Timeout = time.Duration(commonFlagValues.Timeout) * time.Second
+	}
+
+	if transferReportFlagValues.Report {
+		options.ReportPath = transferReportFlagValues.ReportPath
+		if transferReportFlagValues.ReportToStdout {
+			options.ReportPath = "-"
+		}
+	}
+
+	return options, nil
+}
+
+// getEncryptionOptions returns options of encryption, set from command-line flags
+func getEncryptionOptions(command *cobra.Command) gocommands.EncryptionOptions {
+	encryptionFlagValues := flag.GetEncryptionFlagValues(command)
+
+	options := gocommands.EncryptionOptions{
+		Encryption:           encryptionFlagValues.Encryption,
+		NoEncryption:         encryptionFlagValues.NoEncryption,
+		EncryptionIgnoreMeta: encryptionFlagValues.IgnoreMeta,
+		EncryptionMode:       encryptionFlagValues.Mode,
+		EncryptionKey:        encryptionFlagValues.Key,
+		EncryptionRecipients: encryptionFlagValues.AgeRecipients,
+		EncryptionTempPath:   encryptionFlagValues.TempPath,
+	}
+
+	// keys in metadata of target collections are used if keys are not given
+	if encryptionFlagValues.PublicPrivateKeyPathsUpdated {
+		options.EncryptionPublicKeyPaths = encryptionFlagValues.PublicPrivateKeyPaths
+	}
+
+	return options
+}
+
+// getDecryptionOptions returns options of decryption, set from command-line flags
+func getDecryptionOptions(command *cobra.Command) gocommands.DecryptionOptions {
+	decryptionFlagValues := flag.GetDecryptionFlagValues(command)
+
+	return gocommands.DecryptionOptions{
+		NoDecryption:             decryptionFlagValues.NoDecryption || !decryptionFlagValues.Decryption,
+		DecryptionKey:            decryptionFlagValues.Key,
+		DecryptionPrivateKeyPath: decryptionFlagValues.PrivateKeyPath,
+		DecryptionIdentityPath:   decryptionFlagValues.AgeIdentityPath,
+		DecryptionTempPath:       decryptionFlagValues.TempPath,
+	}
+}
+
+// getBundleOptions returns options of bundles, set from command-line flags
+func getBundleOptions() gocommands.BundleOptions {
+	bundleTransferFlagValues := flag.GetBundleTransferFlagValues()
+
+	return gocommands.BundleOptions{
+		LocalTempPath:      bundleTransferFlagValues.LocalTempPath,
+		IRODSTempPath:      bundleTransferFlagValues.IRODSTempPath,
+		ClearOld:           bundleTransferFlagValues.ClearOld,
+		MinFileNumInBundle: bundleTransferFlagValues.MinFileNumInBundle,
+		MaxFileNumInBundle: bundleTransferFlagValues.MaxFileNumInBundle,
+		MaxBundleFileSize:  bundleTransferFlagValues.MaxBundleFileSize,
+		NoBulkRegistration: bundleTransferFlagValues.NoBulkRegistration,
+		AdaptiveBundles:    bundleTransferFlagValues.AdaptiveBundles,
+	}
+}
+
+// runTransfer runs the transfer newTransfer returns, with progress events, metrics, and tracing set from command-line flags
+// The first Ctrl-C cancels pending transfers, the second aborts running transfers and removes incomplete files
+func runTransfer(command *cobra.Command, options *gocommands.TransferOptions, newTransfer func() (gocommands.Transfer, error)) error {
+	commonFlagValues := flag.GetCommonFlagValues(command)
+	progressFlagValues := flag.GetProgressFlagValues()
+	metricsFlagValues := flag.GetMetricsFlagValues()
+	tracingFlagValues := flag.GetTracingFlagValues()
+
+	progressEventWriter, err := progressFlagValues.OpenProgressEventWriter()
+	if err != nil {
+		return errors.Wrapf(err, "failed to open progress event output %q", progressFlagValues.EventTarget)
+	}
+
+	if progressEventWriter != nil {
+		defer progressEventWriter.Close()
+	}
+
+	options.OnEvent = func(event gocommands.ProgressEvent) {
+		switch event.Event {
+		case gocommands.ProgressEventSkipped, gocommands.ProgressEventResumed:
+			terminal.Printf("%s\n", event.Message)
+		default:
+			if progressEventWriter != nil {
+				progressEventWriter.Write(newParallelProgressEvent(event))
+			}
+		}
+	}
+
+	transferMetrics, metricsExporter, err := metricsFlagValues.StartMetricsExporter()
+	if err != nil {
+		return errors.Wrap(err, "failed to start metrics exporter")
+	}
+
+	if metricsExporter != nil {
+		defer metricsExporter.Close()
+	}
+
+	if transferMetrics != nil {
+		options.Metrics = transferMetrics
+	}
+
+	tracer, err := tracingFlagValues.StartTracer()
+	if err != nil {
+		return errors.Wrapf(err, "failed to start tracer for %q", tracingFlagValues.Target)
+	}
+	defer tracer.Close()
+
+	trx, err := newTransfer()
+	if err != nil {
+		return err
+	}
+
+	transferred := getTransferredWord(trx)
+
+	// handle Ctrl-C and the deadline
+	ctx, cancel := commonFlagValues.GetContext()
+	defer cancel()
+
+	ctx = tracing.ContextWithTracer(ctx, tracer)
+
+	interruptHandler := interrupt.NewHandler()
+	interruptHandler.AddCancelFunc(trx.CancelPending)
+	interruptHandler.AddAbortFunc(func() {
+		result := trx.Abort()
+		printPartialTransferSummary("Interrupted", result, transferred, options.ReportPath)
+	})
+
+	interruptHandler.Start()
+	defer interruptHandler.Stop()
+
+	err = trx.Run(ctx)
+
+	if interruptHandler.IsInterrupted() {
+		printPartialTransferSummary("Interrupted", trx.GetResult(), transferred, options.ReportPath)
+		return types.NewInterruptedError(interruptHandler.GetSignal().String())
+	}
+
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		printPartialTransferSummary("Deadline exceeded", trx.GetResult(), transferred, options.ReportPath)
+		return types.NewDeadlineExceededError(commonFlagValues.Deadline)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	// print final summary
+	if options.ShowProgress {
+		result := trx.GetResult()
+
+		timeTaken := result.Duration.Seconds()
+		bps := float64(result.Bytes) / timeTaken
+		bpsString := fmt.Sprintf("%s/s", types.SizeString(int64(bps)))
+		terminal.Printf("%s %d files, %s in total, time taken: %.2f seconds, average speed: %s\n", capitalize(transferred), result.Files, types.SizeString(result.Bytes), timeTaken, bpsString)
+
+		printAdaptiveBundleSummary(trx)
+	}
+
+	return nil
+}
+
+// newParallelProgressEvent returns the event of the transfer in the form written to the progress event output
+func newParallelProgressEvent(event gocommands.ProgressEvent) *parallel.ProgressEvent {
+	return &parallel.ProgressEvent{
+		Time:  event.Time,
+		Event: parallel.ProgressEventType(event.Event),
+
+		JobID:     event.JobID,
+		JobName:   event.JobName,
+		Task:      event.Task,
+		Processed: event.Processed,
+		Total:     event.Total,
+		Size:      event.Size,
+		Files:     event.Files,
+		Error:     event.Error,
+
+		TotalJobs:    event.TotalJobs,
+		DoneJobs:     event.DoneJobs,
+		FailedJobs:   event.FailedJobs,
+		CanceledJobs: event.CanceledJobs,
+		TotalFiles:   event.TotalFiles,
+		DoneFiles:    event.DoneFiles,
+		TotalBytes:   event.TotalBytes,
+		DoneBytes:    event.DoneBytes,
+		ElapsedSec:   event.ElapsedSec,
+	}
+}
+
+// printPartialTransferSummary prints files transferred and not transferred when the transfer is stopped for the reason
+func printPartialTransferSummary(reason string, result *gocommands.TransferResult, transferred string, reportPath string) {
+	terminal.Printf("%s, %s %d files, %s in total, %d files were not %s\n", reason, transferred, result.Files, types.SizeString(result.Bytes), result.NotTransferred, transferred)
+
+	for _, removedPath := range result.RemovedPaths {
+		terminal.Printf("Removed incomplete file %q\n", removedPath)
+	}
+
+	if len(reportPath) > 0 && reportPath != "-" {
+		terminal.Printf("See transfer report %q for files not %s\n", reportPath, transferred)
+	}
+}
+
+// printAdaptiveBundleSummary prints the last decision of adaptive bundles if the transfer is a bput with adaptive bundles
+func printAdaptiveBundleSummary(trx gocommands.Transfer) {
+	if sync, ok := trx.(*gocommands.SyncTransfer); ok {
+		trx = sync.GetTransfer()
+	}
+
+	bput, ok := trx.(*gocommands.BputTransfer)
+	if !ok {
+		return
+	}
+
+	decisions := bput.GetAdaptiveBundleDecisions()
+	if len(decisions) > 0 {
+		lastDecision := decisions[len(decisions)-1]
+		terminal.Printf("Adaptive bundles: %d rounds, last throughput %.2f files/s, max_file_num %d, max_bundle_size %s, concurrency %d\n", len(decisions), lastDecision.FilesPerSecond, lastDecision.MaxFileNumInBundle, types.SizeString(lastDecision.MaxBundleFileSize), lastDecision.Concurrency)
+	}
+}
+
+// getTransferredWord returns the word describing files the transfer transferred
+func getTransferredWord(trx gocommands.Transfer) string {
+	switch t := trx.(type) {
+	case *gocommands.PutTransfer, *gocommands.BputTransfer:
+		return "uploaded"
+	case *gocommands.GetTransfer:
+		return "downloaded"
+	case *gocommands.CopyTransfer:
+		return "copied"
+	case *gocommands.SyncTransfer:
+		return getTransferredWord(t.GetTransfer())
+	default:
+		return "transferred"
+	}
+}
+
+func capitalize(word string) string {
+	if len(word) == 0 {
+		return word
+	}
+
+	return strings.ToUpper(word[:1]) + word[1:]
+}
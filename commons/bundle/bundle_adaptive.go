@@ -0,0 +1,402 @@
+package bundle
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/cyverse/gocommands/commons/types"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	adaptiveBundleFileNumMin     int     = 2
+	adaptiveBundleFileNumMax     int     = 10000
+	adaptiveBundleSizeMin        int64   = 1 * 1024 * 1024         // 1MB
+	adaptiveBundleSizeMax        int64   = 16 * 1024 * 1024 * 1024 // 16GB
+	adaptiveBundlesPerConcurrent int     = 2                       // bundles per round for each concurrent bundle
+	adaptiveThroughputTolerance  float64 = 0.05                    // 5% change is considered noise
+)
+
+// BundleStats stores time taken by each stage of a bundle transfer
+type BundleStats struct {
+	BundleID        int64
+	FileNum         int
+	Size            int64
+	TarDuration     time.Duration
+	UploadDuration  time.Duration
+	ExtractDuration time.Duration
+}
+
+// AdaptiveBundleDecision describes a tuning decision made after a round of bundle transfers
+type AdaptiveBundleDecision struct {
+	Round              int
+	StartTime          time.Time
+	EndTime            time.Time
+	FileNum            int
+	Size               int64
+	FilesPerSecond     float64
+	TarRatio           float64
+	UploadRatio        float64
+	ExtractRatio       float64
+	MaxFileNumInBundle int // for next round
+	MaxBundleFileSize  int64
+	Concurrency        int
+	Reason             string
+}
+
+// ToNotes returns the decision as transfer report notes
+func (decision *AdaptiveBundleDecision) ToNotes() []string {
+	return []string{
+		"adaptive",
+		fmt.Sprintf("round %d", decision.Round),
+		fmt.Sprintf("%d files", decision.FileNum),
+		fmt.Sprintf("%.2f files/s", decision.FilesPerSecond),
+		fmt.Sprintf("tar %.0f%%, upload %.0f%%, extract %.0f%%", decision.TarRatio*100, decision.UploadRatio*100, decision.ExtractRatio*100),
+		fmt.Sprintf("max_file_num %d", decision.MaxFileNumInBundle),
+		fmt.Sprintf("max_bundle_size %s", types.SizeString(decision.MaxBundleFileSize)),
+		fmt.Sprintf("concurrency %d", decision.Concurrency),
+		decision.Reason,
+	}
+}
+
+// adaptiveBundleKnob is a parameter tuned by AdaptiveBundleController
+type adaptiveBundleKnob int
+
+const (
+	adaptiveBundleKnobNone adaptiveBundleKnob = iota
+	adaptiveBundleKnobSize
+	adaptiveBundleKnobConcurrency
+)
+
+// adaptiveBundleParams is a set of parameters used in a round
+type adaptiveBundleParams struct {
+	maxFileNumInBundle int
+	maxBundleFileSize  int64
+	concurrency        int
+}
+
+// AdaptiveBundleController tunes bundle size and concurrency to maximize files/second
+// It performs hill climbing, changing a parameter at a time, alternating between bundle size and concurrency
+// A change making throughput worse is reverted in the next round before any other change
+type AdaptiveBundleController struct {
+	maxFileNumInBundle int
+	maxBundleFileSize  int64
+	concurrency        int
+	maxConcurrency     int
+
+	round              int
+	roundStartTime     time.Time
+	roundStats         []BundleStats
+	lastFilesPerSecond float64              // throughput of the parameters accepted, the baseline
+	lastParams         adaptiveBundleParams // parameters accepted, restored if the change is reverted
+	lastKnob           adaptiveBundleKnob   // parameter changed for current round
+	nextKnob           adaptiveBundleKnob   // parameter to change next
+	sizeDirection      int                  // 1 to grow, -1 to shrink
+	concurrencyDir     int                  // 1 to grow, -1 to shrink
+	decisions          []AdaptiveBundleDecision
+
+	now func() time.Time // clock, replaced in tests
+
+	mutex sync.Mutex
+}
+
+// NewAdaptiveBundleController creates a new AdaptiveBundleController
+func NewAdaptiveBundleController(maxFileNumInBundle int, maxBundleFileSize int64, maxConcurrency int) *AdaptiveBundleController {
+	if maxConcurrency < 1 {
+		maxConcurrency = 1
+	}
+
+	// start from the half to be able to move to both directions
+	concurrency := maxConcurrency / 2
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	controller := &AdaptiveBundleController{
+		maxFileNumInBundle: maxFileNumInBundle,
+		maxBundleFileSize:  maxBundleFileSize,
+		concurrency:        concurrency,
+		maxConcurrency:     maxConcurrency,
+
+		round:              0,
+		roundStats:         []BundleStats{},
+		lastFilesPerSecond: 0,
+		lastKnob:           adaptiveBundleKnobNone,
+		nextKnob:           adaptiveBundleKnobSize,
+		sizeDirection:      1,
+		concurrencyDir:     1,
+		decisions:          []AdaptiveBundleDecision{},
+
+		now: time.Now,
+	}
+
+	controller.clamp()
+
+	return controller
+}
+
+func (controller *AdaptiveBundleController) getParams() adaptiveBundleParams {
+	return adaptiveBundleParams{
+		maxFileNumInBundle: controller.maxFileNumInBundle,
+		maxBundleFileSize:  controller.maxBundleFileSize,
+		concurrency:        controller.concurrency,
+	}
+}
+
+func (controller *AdaptiveBundleController) setParams(params adaptiveBundleParams) {
+	controller.maxFileNumInBundle = params.maxFileNumInBundle
+	controller.maxBundleFileSize = params.maxBundleFileSize
+	controller.concurrency = params.concurrency
+}
+
+// changeKnob moves the knob a step to its direction, returns false if the knob is at the limit
+// the direction is reversed at the limit, to move to the other way next time
+func (controller *AdaptiveBundleController) changeKnob(knob adaptiveBundleKnob) bool {
+	params := controller.getParams()
+
+	switch knob {
+	case adaptiveBundleKnobSize:
+		if controller.sizeDirection > 0 {
+			controller.maxFileNumInBundle *= 2
+			controller.maxBundleFileSize *= 2
+		} else {
+			controller.maxFileNumInBundle /= 2
+			controller.maxBundleFileSize /= 2
+		}
+	case adaptiveBundleKnobConcurrency:
+		controller.concurrency += controller.concurrencyDir
+	default:
+		return false
+	}
+
+	controller.clamp()
+
+	if controller.getParams() == params {
+		controller.reverseKnob(knob)
+		return false
+	}
+
+	return true
+}
+
+func (controller *AdaptiveBundleController) reverseKnob(knob adaptiveBundleKnob) {
+	switch knob {
+	case adaptiveBundleKnobSize:
+		controller.sizeDirection = -controller.sizeDirection
+	case adaptiveBundleKnobConcurrency:
+		controller.concurrencyDir = -controller.concurrencyDir
+	}
+}
+
+func getOtherAdaptiveBundleKnob(knob adaptiveBundleKnob) adaptiveBundleKnob {
+	if knob == adaptiveBundleKnobSize {
+		return adaptiveBundleKnobConcurrency
+	}
+	return adaptiveBundleKnobSize
+}
+
+func (controller *AdaptiveBundleController) clamp() {
+	if controller.maxFileNumInBundle < adaptiveBundleFileNumMin {
+		controller.maxFileNumInBundle = adaptiveBundleFileNumMin
+	}
+
+	if controller.maxFileNumInBundle > adaptiveBundleFileNumMax {
+		controller.maxFileNumInBundle = adaptiveBundleFileNumMax
+	}
+
+	if controller.maxBundleFileSize < adaptiveBundleSizeMin {
+		controller.maxBundleFileSize = adaptiveBundleSizeMin
+	}
+
+	if controller.maxBundleFileSize > adaptiveBundleSizeMax {
+		controller.maxBundleFileSize = adaptiveBundleSizeMax
+	}
+
+	if controller.concurrency < 1 {
+		controller.concurrency = 1
+	}
+
+	if controller.concurrency > controller.maxConcurrency {
+		controller.concurrency = controller.maxConcurrency
+	}
+}
+
+// GetMaxFileNumInBundle returns max number of files in a bundle for current round
+func (controller *AdaptiveBundleController) GetMaxFileNumInBundle() int {
+	controller.mutex.Lock()
+	defer controller.mutex.Unlock()
+
+	return controller.maxFileNumInBundle
+}
+
+// GetMaxBundleFileSize returns max bundle file size for current round
+func (controller *AdaptiveBundleController) GetMaxBundleFileSize() int64 {
+	controller.mutex.Lock()
+	defer controller.mutex.Unlock()
+
+	return controller.maxBundleFileSize
+}
+
+// GetConcurrency returns number of bundles to be transferred concurrently for current round
+func (controller *AdaptiveBundleController) GetConcurrency() int {
+	controller.mutex.Lock()
+	defer controller.mutex.Unlock()
+
+	return controller.concurrency
+}
+
+// GetBundlesPerRound returns number of bundles to be transferred in current round
+func (controller *AdaptiveBundleController) GetBundlesPerRound() int {
+	controller.mutex.Lock()
+	defer controller.mutex.Unlock()
+
+	return controller.concurrency * adaptiveBundlesPerConcurrent
+}
+
+// GetThreadsPerBundle returns number of transfer threads for a bundle, so that concurrency is kept under maxConnections
+func (controller *AdaptiveBundleController) GetThreadsPerBundle(maxConnections int) int {
+	controller.mutex.Lock()
+	defer controller.mutex.Unlock()
+
+	threads := maxConnections / controller.concurrency
+	if threads < 1 {
+		return 1
+	}
+	return threads
+}
+
+// GetDecisions returns all decisions made
+func (controller *AdaptiveBundleController) GetDecisions() []AdaptiveBundleDecision {
+	controller.mutex.Lock()
+	defer controller.mutex.Unlock()
+
+	return controller.decisions
+}
+
+// StartRound marks the beginning of a round
+func (controller *AdaptiveBundleController) StartRound() {
+	controller.mutex.Lock()
+	defer controller.mutex.Unlock()
+
+	controller.round++
+	controller.roundStartTime = controller.now()
+	controller.roundStats = []BundleStats{}
+}
+
+// RecordBundle records stage timings of a bundle transferred in current round
+func (controller *AdaptiveBundleController) RecordBundle(stats BundleStats) {
+	controller.mutex.Lock()
+	defer controller.mutex.Unlock()
+
+	controller.roundStats = append(controller.roundStats, stats)
+}
+
+// EndRound marks the end of a round, and adjusts parameters for next round
+func (controller *AdaptiveBundleController) EndRound(fileNum int, size int64) AdaptiveBundleDecision {
+	controller.mutex.Lock()
+	defer controller.mutex.Unlock()
+
+	logger := log.WithFields(log.Fields{
+		"round": controller.round,
+	})
+
+	now := controller.now()
+	elapsed := now.Sub(controller.roundStartTime).Seconds()
+
+	filesPerSecond := 0.0
+	if elapsed > 0 {
+		filesPerSecond = float64(fileNum) / elapsed
+	}
+
+	// stage ratio
+	tarDuration := time.Duration(0)
+	uploadDuration := time.Duration(0)
+	extractDuration := time.Duration(0)
+	for _, stats := range controller.roundStats {
+		tarDuration += stats.TarDuration
+		uploadDuration += stats.UploadDuration
+		extractDuration += stats.ExtractDuration
+	}
+
+	totalDuration := (tarDuration + uploadDuration + extractDuration).Seconds()
+	tarRatio := 0.0
+	uploadRatio := 0.0
+	extractRatio := 0.0
+	if totalDuration > 0 {
+		tarRatio = tarDuration.Seconds() / totalDuration
+		uploadRatio = uploadDuration.Seconds() / totalDuration
+		extractRatio = extractDuration.Seconds() / totalDuration
+	}
+
+	// change of throughput made by the change in last round
+	changed := controller.lastKnob != adaptiveBundleKnobNone && controller.lastFilesPerSecond > 0
+	change := 0.0
+	if changed {
+		change = (filesPerSecond - controller.lastFilesPerSecond) / controller.lastFilesPerSecond
+	}
+
+	reason := ""
+	if changed && change < -adaptiveThroughputTolerance {
+		// the change made it worse, revert it and make no other change in this round
+		controller.setParams(controller.lastParams)
+		controller.reverseKnob(controller.lastKnob)
+		controller.nextKnob = getOtherAdaptiveBundleKnob(controller.lastKnob)
+		controller.lastKnob = adaptiveBundleKnobNone
+		reason = fmt.Sprintf("throughput dropped by %.0f%%, reverting", -change*100)
+	} else {
+		// accept current parameters as the baseline
+		if !changed {
+			reason = "probe"
+		} else if change > adaptiveThroughputTolerance {
+			reason = fmt.Sprintf("throughput improved by %.0f%%, continuing", change*100)
+		} else {
+			reason = "throughput unchanged"
+		}
+
+		controller.lastFilesPerSecond = filesPerSecond
+		controller.lastParams = controller.getParams()
+
+		knob := controller.nextKnob
+		// when extraction dominates, bigger bundles starve the server, prefer more concurrent bundles
+		if extractRatio > 0.5 && controller.concurrency < controller.maxConcurrency {
+			knob = adaptiveBundleKnobConcurrency
+			controller.concurrencyDir = 1
+			reason += ", extract-bound"
+		}
+
+		// make a change, the other knob is changed if the knob is at the limit
+		controller.lastKnob = adaptiveBundleKnobNone
+		if controller.changeKnob(knob) {
+			controller.lastKnob = knob
+		} else if controller.changeKnob(getOtherAdaptiveBundleKnob(knob)) {
+			controller.lastKnob = getOtherAdaptiveBundleKnob(knob)
+		}
+
+		controller.nextKnob = getOtherAdaptiveBundleKnob(controller.lastKnob)
+	}
+
+	controller.clamp()
+
+	decision := AdaptiveBundleDecision{
+		Round:              controller.round,
+		StartTime:          controller.roundStartTime,
+		EndTime:            now,
+		FileNum:            fileNum,
+		Size:               size,
+		FilesPerSecond:     filesPerSecond,
+		TarRatio:           tarRatio,
+		UploadRatio:        uploadRatio,
+		ExtractRatio:       extractRatio,
+		MaxFileNumInBundle: controller.maxFileNumInBundle,
+		MaxBundleFileSize:  controller.maxBundleFileSize,
+		Concurrency:        controller.concurrency,
+		Reason:             reason,
+	}
+
+	controller.decisions = append(controller.decisions, decision)
+
+	logger.Infof("adaptive bundle round %d: %d files in %.2f seconds (%.2f files/s), tar %.0f%%, upload %.0f%%, extract %.0f%%, next max_file_num %d, max_bundle_size %s, concurrency %d (%s)", decision.Round, fileNum, elapsed, filesPerSecond, tarRatio*100, uploadRatio*100, extractRatio*100, decision.MaxFileNumInBundle, types.SizeString(decision.MaxBundleFileSize), decision.Concurrency, reason)
+
+	return decision
+}
@@ -0,0 +1,107 @@
+package bundle
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdaptiveBundleController(t *testing.T) {
+	t.Run("test Clamp", testAdaptiveBundleControllerClamp)
+	t.Run("test EndRound", testAdaptiveBundleControllerEndRound)
+}
+
+func testAdaptiveBundleControllerClamp(t *testing.T) {
+	tests := []struct {
+		name           string
+		fileNum        int
+		size           int64
+		maxConcurrency int
+		expected       adaptiveBundleParams
+	}{
+		{"within limits", 50, 32 * 1024 * 1024, 8, adaptiveBundleParams{50, 32 * 1024 * 1024, 4}},
+		{"below minimums", 0, 1, 0, adaptiveBundleParams{adaptiveBundleFileNumMin, adaptiveBundleSizeMin, 1}},
+		{"above maximums", 100000, 64 * 1024 * 1024 * 1024, 1, adaptiveBundleParams{adaptiveBundleFileNumMax, adaptiveBundleSizeMax, 1}},
+	}
+
+	for _, test := range tests {
+		controller := NewAdaptiveBundleController(test.fileNum, test.size, test.maxConcurrency)
+		assert.Equal(t, test.expected, controller.getParams(), test.name)
+	}
+}
+
+type adaptiveBundleRound struct {
+	fileNum        int
+	seconds        int
+	extractRatio   float64
+	expected       adaptiveBundleParams
+	expectedReason string
+}
+
+func testAdaptiveBundleControllerEndRound(t *testing.T) {
+	const mb int64 = 1024 * 1024
+
+	tests := []struct {
+		name           string
+		maxConcurrency int
+		rounds         []adaptiveBundleRound
+	}{
+		{
+			name:           "hill climbing reverts a change making throughput worse",
+			maxConcurrency: 8,
+			rounds: []adaptiveBundleRound{
+				{100, 10, 0, adaptiveBundleParams{100, 64 * mb, 4}, "probe"},                                  // grow bundles
+				{120, 10, 0, adaptiveBundleParams{100, 64 * mb, 5}, "throughput improved by 20%, continuing"}, // keep bundles, add concurrency
+				{90, 10, 0, adaptiveBundleParams{100, 64 * mb, 4}, "throughput dropped by 25%, reverting"},    // revert concurrency only
+				{120, 10, 0, adaptiveBundleParams{200, 128 * mb, 4}, "probe"},                                 // re-measure, grow bundles
+				{122, 10, 0, adaptiveBundleParams{200, 128 * mb, 3}, "throughput unchanged"},                  // concurrency reversed
+			},
+		},
+		{
+			name:           "extract-bound round changes concurrency only",
+			maxConcurrency: 8,
+			rounds: []adaptiveBundleRound{
+				{100, 10, 0.8, adaptiveBundleParams{50, 32 * mb, 5}, "probe, extract-bound"},
+				{100, 10, 0, adaptiveBundleParams{100, 64 * mb, 5}, "throughput unchanged"},
+			},
+		},
+		{
+			name:           "the other knob is changed when a knob is at the limit",
+			maxConcurrency: 1,
+			rounds: []adaptiveBundleRound{
+				{100, 10, 0, adaptiveBundleParams{100, 64 * mb, 1}, "probe"},
+				{150, 10, 0, adaptiveBundleParams{200, 128 * mb, 1}, "throughput improved by 50%, continuing"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		controller := NewAdaptiveBundleController(50, 32*mb, test.maxConcurrency)
+
+		clock := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+		controller.now = func() time.Time {
+			return clock
+		}
+
+		for i, round := range test.rounds {
+			controller.StartRound()
+
+			extractDuration := time.Duration(round.extractRatio * float64(time.Second))
+			controller.RecordBundle(BundleStats{
+				FileNum:         round.fileNum,
+				UploadDuration:  time.Second - extractDuration,
+				ExtractDuration: extractDuration,
+			})
+
+			clock = clock.Add(time.Duration(round.seconds) * time.Second)
+			decision := controller.EndRound(round.fileNum, 0)
+
+			assert.Equal(t, round.expected, controller.getParams(), "%s, round %d", test.name, i+1)
+			assert.Equal(t, round.expectedReason, decision.Reason, "%s, round %d", test.name, i+1)
+			assert.Equal(t, round.expected.concurrency, decision.Concurrency)
+		}
+
+		assert.Len(t, controller.GetDecisions(), len(test.rounds))
+	}
+}
@@ -0,0 +1,27 @@
+package config
+
+import (
+	"sync"
+
+	"github.com/rs/xid"
+)
+
+var (
+	correlationID     string
+	correlationIDOnce sync.Once
+)
+
+// GetCorrelationID returns the ID of the run, recorded in logs, transfer reports, and the client program name
+// to tell apart runs on the same host
+func GetCorrelationID() string {
+	correlationIDOnce.Do(func() {
+		correlationID = xid.New().String()
+	})
+
+	return correlationID
+}
+
+// GetClientProgramName returns the client program name sent to the iRODS server, tagged with the correlation ID
+func GetClientProgramName() string {
+	return ClientProgramName + "-" + GetCorrelationID()
+}
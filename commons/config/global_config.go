@@ -9,11 +9,16 @@ import (
 
 	"github.com/cockroachdb/errors"
 	irodsclient_config "github.com/cyverse/go-irodsclient/config"
+	irodsclient_types "github.com/cyverse/go-irodsclient/irods/types"
 	"github.com/cyverse/gocommands/commons/catalog"
 	terminal "github.com/cyverse/gocommands/commons/terminal"
 	log "github.com/sirupsen/logrus"
 )
 
+const (
+	AnonymousUsername string = "anonymous"
+)
+
 var (
 	environmentManager *irodsclient_config.ICommandsEnvironmentManager
 )
@@ -112,6 +117,17 @@ func SetCWD(cwd string) error {
 	return nil
 }
 
+// SetAnonymousAccess sets the environment to access iRODS as anonymous user with the ticket
+func SetAnonymousAccess(ticket string) {
+	env := environmentManager.Environment
+	env.Username = AnonymousUsername
+	env.ClientUsername = AnonymousUsername
+	env.Password = ""
+	env.PAMToken = ""
+	env.AuthenticationScheme = string(irodsclient_types.AuthSchemeNative)
+	env.Ticket = ticket
+}
+
 // InputMissingFields inputs missing fields
 func InputMissingFields() (bool, error) {
 	updated := false
@@ -182,7 +198,7 @@ func InputMissingFields() (bool, error) {
 
 	password := environmentManager.Environment.Password
 	pamToken := environmentManager.Environment.PAMToken
-	if len(password) == 0 && len(pamToken) == 0 && environmentManager.Environment.Username != "anonymous" {
+	if len(password) == 0 && len(pamToken) == 0 && environmentManager.Environment.Username != AnonymousUsername {
 		environmentManager.Environment.Password = terminal.InputPassword("iRODS Password")
 		updated = true
 	}
@@ -27,6 +27,7 @@ type AdditionalSystemConfig struct {
 type SystemConfig struct {
 	IRODSConfig      map[string]interface{}  `json:"irods_config,omitempty" yaml:"irods_config,omitempty"`
 	AdditionalConfig *AdditionalSystemConfig `json:"additional_config,omitempty" yaml:"additional_config,omitempty"`
+	CommandFlags     CommandFlags            `json:"command_flags,omitempty" yaml:"command_flags,omitempty"` // overridden by user config
 }
 
 func InitSystemConfig() error {
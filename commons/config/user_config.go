@@ -0,0 +1,95 @@
+package config
+
+import (
+	"os"
+
+	"github.com/cockroachdb/errors"
+	"github.com/cyverse/gocommands/commons/path"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	userConfigFilename string = "gocmd.yaml"
+)
+
+var (
+	userConfig      *UserConfig
+	userConfigError error
+)
+
+// CommandFlags stores default flag values of commands, keyed by command name and flag name
+// e.g., put: {progress: true, retry: 5}
+type CommandFlags map[string]map[string]interface{}
+
+// UserConfig stores user-specific configuration
+type UserConfig struct {
+	Path         string
+	CommandFlags CommandFlags
+}
+
+// InitUserConfig loads the user config, the error is kept for GetUserConfigError
+func InitUserConfig() error {
+	usrConfig, err := NewUserConfig()
+	if err != nil {
+		userConfigError = err
+		return err
+	}
+
+	userConfig = usrConfig
+	userConfigError = nil
+	return nil
+}
+
+// GetUserConfigError returns the error of loading the user config in InitUserConfig, nil if it is loaded or does not exist
+func GetUserConfigError() error {
+	return userConfigError
+}
+
+// GetUserConfigPath returns the user-specific configuration file path for gocmd
+func GetUserConfigPath() (string, error) {
+	return path.ExpandLocalHomeDirPath(defaultIRODSConfigDirPath + "/" + userConfigFilename)
+}
+
+func GetUserConfig() *UserConfig {
+	return userConfig
+}
+
+// NewUserConfig reads UserConfig from a YAML file located at default path
+func NewUserConfig() (*UserConfig, error) {
+	configPath, err := GetUserConfigPath()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get user config path")
+	}
+
+	st, err := os.Stat(configPath)
+	if err != nil {
+		// file does not exist or is not accessible
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrapf(err, "failed to stat %q", configPath)
+	}
+
+	if st.IsDir() {
+		// path is directory
+		return nil, errors.Errorf("%q is a directory", configPath)
+	}
+
+	yamlBytes, err := os.ReadFile(configPath)
+	if err != nil {
+		// file is not accessible
+		return nil, errors.Wrapf(err, "failed to read %q", configPath)
+	}
+
+	commandFlags := CommandFlags{}
+
+	err = yaml.Unmarshal(yamlBytes, &commandFlags)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to unmarshal %q", configPath)
+	}
+
+	return &UserConfig{
+		Path:         configPath,
+		CommandFlags: commandFlags,
+	}, nil
+}
@@ -1,12 +1,20 @@
 package encryption
 
 import (
+	"bufio"
 	"crypto/rsa"
+	"io"
+	"os"
 	"strings"
 
+	"filippo.io/age"
 	"github.com/cockroachdb/errors"
 )
 
+const (
+	reencryptBufferSize int = 1024 * 1024 // 1MB
+)
+
 // EncryptionMode determines encryption mode
 type EncryptionMode string
 
@@ -17,6 +25,10 @@ const (
 	EncryptionModePGP EncryptionMode = "PGP"
 	// EncryptionModeSSH is for SSH key encryption
 	EncryptionModeSSH EncryptionMode = "SSH"
+	// EncryptionModeAge is for age encryption with X25519 or SSH recipients
+	EncryptionModeAge EncryptionMode = "AGE"
+	// EncryptionModeAESGCM is for authenticated AES GCM encryption in chunks
+	EncryptionModeAESGCM EncryptionMode = "AESGCM"
 	// EncryptionModeNone is for none encryption
 	EncryptionModeNone EncryptionMode = "NONE"
 )
@@ -30,6 +42,10 @@ func GetEncryptionMode(mode string) EncryptionMode {
 		return EncryptionModePGP
 	case string(EncryptionModeSSH):
 		return EncryptionModeSSH
+	case string(EncryptionModeAge):
+		return EncryptionModeAge
+	case string(EncryptionModeAESGCM), "GCM", "AES-GCM":
+		return EncryptionModeAESGCM
 	case string(EncryptionModeNone):
 		return EncryptionModeNone
 	default:
@@ -48,6 +64,12 @@ func DetectEncryptionMode(p string) EncryptionMode {
 	} else if strings.HasSuffix(p, SshEncryptedFileExtension) {
 		// ssh
 		return EncryptionModeSSH
+	} else if strings.HasSuffix(p, AesGcmEncryptedFileExtension) {
+		// aes gcm
+		return EncryptionModeAESGCM
+	} else if strings.HasSuffix(p, AgeEncryptedFileExtension) {
+		// age
+		return EncryptionModeAge
 	} else {
 		return EncryptionModeNone
 	}
@@ -67,6 +89,8 @@ type EncryptionManager struct {
 	mode                 EncryptionMode
 	key                  []byte
 	publicprivateKeyPath string
+	publicKeyRing        []string
+	ageRecipients        []string
 }
 
 // NewEncryptionManager creates a new EncryptionManager
@@ -88,6 +112,28 @@ func (manager *EncryptionManager) SetPublicPrivateKey(keyPath string) {
 	manager.publicprivateKeyPath = keyPath
 }
 
+// SetPublicKeyRing sets public keys of recipients for 'ssh' mode, each can be a key file, a key ring file, or an authorized key string
+func (manager *EncryptionManager) SetPublicKeyRing(keys []string) {
+	manager.publicKeyRing = keys
+}
+
+// SetAgeRecipients sets age recipients, each can be a recipient string or a path to a recipient file
+func (manager *EncryptionManager) SetAgeRecipients(recipients []string) {
+	manager.ageRecipients = recipients
+}
+
+func (manager *EncryptionManager) getAgeRecipients() ([]age.Recipient, error) {
+	return ParseAgeRecipients(manager.ageRecipients)
+}
+
+func (manager *EncryptionManager) getAgeIdentities() ([]age.Identity, error) {
+	if len(manager.publicprivateKeyPath) > 0 {
+		return ParseAgeIdentities(manager.publicprivateKeyPath)
+	}
+
+	return nil, errors.Errorf("failed to load age identities, identity path is not given")
+}
+
 func (manager *EncryptionManager) getPublicKey() (*rsa.PublicKey, error) {
 	if len(manager.publicprivateKeyPath) > 0 {
 		pub, err := DecodePublicKey(manager.publicprivateKeyPath)
@@ -101,6 +147,19 @@ func (manager *EncryptionManager) getPublicKey() (*rsa.PublicKey, error) {
 	return nil, errors.Errorf("failed to load public key, public or private key path is not given")
 }
 
+func (manager *EncryptionManager) getPublicKeys() ([]*rsa.PublicKey, error) {
+	if len(manager.publicKeyRing) > 0 {
+		return DecodePublicKeyRing(manager.publicKeyRing)
+	}
+
+	pub, err := manager.getPublicKey()
+	if err != nil {
+		return nil, err
+	}
+
+	return []*rsa.PublicKey{pub}, nil
+}
+
 func (manager *EncryptionManager) getPrivateKey() (*rsa.PrivateKey, error) {
 	if len(manager.publicprivateKeyPath) > 0 {
 		priv, err := DecodePrivateKey(manager.publicprivateKeyPath)
@@ -122,13 +181,17 @@ func (manager *EncryptionManager) EncryptFilename(filename string) (string, erro
 	case EncryptionModePGP:
 		return EncryptFilenamePGP(filename), nil
 	case EncryptionModeSSH:
-		// load publickey
-		publicKey, err := manager.getPublicKey()
+		// load publickeys, filename is encrypted with the first key
+		publicKeys, err := manager.getPublicKeys()
 		if err != nil {
 			return "", err
 		}
 
-		return EncryptFilenameSSH(filename, publicKey)
+		return EncryptFilenameSSH(filename, publicKeys[0])
+	case EncryptionModeAge:
+		return EncryptFilenameAge(filename, manager.key)
+	case EncryptionModeAESGCM:
+		return EncryptFilenameAESGCM(filename, manager.key)
 	default:
 		return "", errors.Errorf("unknown encryption mode")
 	}
@@ -148,7 +211,29 @@ func (manager *EncryptionManager) DecryptFilename(filename string) (string, erro
 			return "", err
 		}
 
-		return DecryptFilenameSSH(filename, privateKey)
+		decryptedFilename, err := DecryptFilenameSSH(filename, privateKey)
+		if err == nil || len(manager.publicKeyRing) == 0 {
+			return decryptedFilename, err
+		}
+
+		// filename of multi-recipient file is encrypted with the first key in key ring
+		publicKeys, ringErr := DecodePublicKeyRing(manager.publicKeyRing)
+		if ringErr != nil {
+			return "", err
+		}
+
+		for _, publicKey := range publicKeys {
+			decryptedFilename, ringErr = DecryptFilenameSSHWithPublicKey(filename, publicKey)
+			if ringErr == nil {
+				return decryptedFilename, nil
+			}
+		}
+
+		return "", err
+	case EncryptionModeAge:
+		return DecryptFilenameAge(filename, manager.key)
+	case EncryptionModeAESGCM:
+		return DecryptFilenameAESGCM(filename, manager.key)
 	default:
 		return "", errors.Errorf("unknown encryption mode")
 	}
@@ -162,13 +247,23 @@ func (manager *EncryptionManager) EncryptFile(source string, target string) erro
 	case EncryptionModePGP:
 		return EncryptFilePGP(source, target, manager.key)
 	case EncryptionModeSSH:
-		// load publickey
-		publicKey, err := manager.getPublicKey()
+		// load publickeys
+		publicKeys, err := manager.getPublicKeys()
+		if err != nil {
+			return err
+		}
+
+		return EncryptFileSSHMultiRecipient(source, target, publicKeys)
+	case EncryptionModeAge:
+		// load recipients
+		recipients, err := manager.getAgeRecipients()
 		if err != nil {
 			return err
 		}
 
-		return EncryptFileSSH(source, target, publicKey)
+		return EncryptFileAge(source, target, recipients)
+	case EncryptionModeAESGCM:
+		return EncryptFileAESGCM(source, target, manager.key)
 	default:
 		return errors.Errorf("unknown encryption mode")
 	}
@@ -189,7 +284,132 @@ func (manager *EncryptionManager) DecryptFile(source string, target string) erro
 		}
 
 		return DecryptFileSSH(source, target, privateKey)
+	case EncryptionModeAge:
+		// load identities
+		identities, err := manager.getAgeIdentities()
+		if err != nil {
+			return err
+		}
+
+		return DecryptFileAge(source, target, identities)
+	case EncryptionModeAESGCM:
+		return DecryptFileAESGCM(source, target, manager.key)
 	default:
 		return errors.Errorf("unknown encryption mode")
 	}
 }
+
+// EncryptReaderWriter encrypts data from reader to writer
+func (manager *EncryptionManager) EncryptReaderWriter(reader io.Reader, writer io.Writer) error {
+	switch manager.mode {
+	case EncryptionModeWinSCP:
+		return EncryptWinSCPReaderWriter(reader, writer, manager.key)
+	case EncryptionModePGP:
+		return EncryptPGPReaderWriter(reader, writer, manager.key)
+	case EncryptionModeSSH:
+		// load publickeys
+		publicKeys, err := manager.getPublicKeys()
+		if err != nil {
+			return err
+		}
+
+		return EncryptSSHReaderWriter(reader, writer, publicKeys)
+	case EncryptionModeAge:
+		// load recipients
+		recipients, err := manager.getAgeRecipients()
+		if err != nil {
+			return err
+		}
+
+		return EncryptAgeReaderWriter(reader, writer, recipients)
+	case EncryptionModeAESGCM:
+		return EncryptAESGCMReaderWriter(reader, writer, manager.key)
+	default:
+		return errors.Errorf("unknown encryption mode")
+	}
+}
+
+// DecryptReaderWriter decrypts data from reader to writer
+func (manager *EncryptionManager) DecryptReaderWriter(reader io.Reader, writer io.Writer) error {
+	switch manager.mode {
+	case EncryptionModeWinSCP:
+		return DecryptWinSCPReaderWriter(reader, writer, manager.key)
+	case EncryptionModePGP:
+		return DecryptPGPReaderWriter(reader, writer, manager.key)
+	case EncryptionModeSSH:
+		// load privatekey
+		privateKey, err := manager.getPrivateKey()
+		if err != nil {
+			return err
+		}
+
+		return DecryptSSHReaderWriter(reader, writer, privateKey)
+	case EncryptionModeAge:
+		// load identities
+		identities, err := manager.getAgeIdentities()
+		if err != nil {
+			return err
+		}
+
+		return DecryptAgeReaderWriter(reader, writer, identities)
+	case EncryptionModeAESGCM:
+		return DecryptAESGCMReaderWriter(reader, writer, manager.key)
+	default:
+		return errors.Errorf("unknown encryption mode")
+	}
+}
+
+// ReencryptFile decrypts local source file with decryptManager and encrypts it to target with encryptManager
+// decrypted data is streamed between the two, and never written to disk
+func ReencryptFile(source string, target string, decryptManager *EncryptionManager, encryptManager *EncryptionManager) error {
+	sourceFileHandle, err := os.Open(source)
+	if err != nil {
+		return errors.Wrapf(err, "failed to open file %q", source)
+	}
+
+	defer sourceFileHandle.Close()
+
+	targetFileHandle, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create file %q", target)
+	}
+
+	defer targetFileHandle.Close()
+
+	pipeReader, pipeWriter := io.Pipe()
+
+	decryptErrChan := make(chan error, 1)
+	go func() {
+		// buffer writes, ciphers write a block at a time
+		bufWriter := bufio.NewWriterSize(pipeWriter, reencryptBufferSize)
+		decryptErr := decryptManager.DecryptReaderWriter(sourceFileHandle, bufWriter)
+		if decryptErr == nil {
+			decryptErr = bufWriter.Flush()
+		}
+
+		// the reader gets EOF if decryptErr is nil
+		pipeWriter.CloseWithError(decryptErr)
+		decryptErrChan <- decryptErr
+	}()
+
+	encryptErr := encryptManager.EncryptReaderWriter(bufio.NewReaderSize(pipeReader, reencryptBufferSize), targetFileHandle)
+
+	// unblock the decryption if the encryption stopped early
+	pipeReader.Close()
+	decryptErr := <-decryptErrChan
+
+	if decryptErr != nil {
+		return errors.Wrapf(decryptErr, "failed to decrypt file %q", source)
+	}
+
+	if encryptErr != nil {
+		return errors.Wrapf(encryptErr, "failed to encrypt file %q", source)
+	}
+
+	err = targetFileHandle.Close()
+	if err != nil {
+		return errors.Wrapf(err, "failed to close file %q", target)
+	}
+
+	return nil
+}
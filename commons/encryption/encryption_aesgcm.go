@@ -0,0 +1,319 @@
+package encryption
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/cyverse/gocommands/commons/types"
+	"golang.org/x/crypto/hkdf"
+)
+
+const (
+	AesGcmEncryptedFileExtension string = ".aesgcm.enc"
+	AesGcmHeader                 string = "aesgcmstream...."
+
+	// AesGcmChunkSize is the size of plaintext in a chunk
+	AesGcmChunkSize int = 64 * 1024
+
+	aesGcmNoncePrefixLen int    = 7
+	aesGcmFileKeyInfo    string = "gocommands aesgcm stream"
+	aesGcmNameKeyInfo    string = "gocommands aesgcm filename"
+)
+
+// deriveAESGCMKey derives a 256-bit key from user key, salt, and purpose
+func deriveAESGCMKey(key []byte, salt []byte, info string) ([]byte, error) {
+	derivedKey := make([]byte, 32)
+	_, err := io.ReadFull(hkdf.New(sha256.New, key, salt, []byte(info)), derivedKey)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to derive key")
+	}
+
+	return derivedKey, nil
+}
+
+func newAESGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create AES cipher")
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create GCM")
+	}
+
+	return aead, nil
+}
+
+// makeAESGCMStreamNonce makes a nonce for STREAM construction, prefix || counter || last flag
+func makeAESGCMStreamNonce(prefix []byte, counter uint32, last bool) []byte {
+	nonce := make([]byte, aesGcmNoncePrefixLen+5)
+	copy(nonce, prefix)
+	binary.BigEndian.PutUint32(nonce[aesGcmNoncePrefixLen:], counter)
+	if last {
+		nonce[aesGcmNoncePrefixLen+4] = 1
+	}
+	return nonce
+}
+
+func EncryptFilenameAESGCM(filename string, key []byte) (string, error) {
+	nameKey, err := deriveAESGCMKey(key, nil, aesGcmNameKeyInfo)
+	if err != nil {
+		return "", err
+	}
+
+	aead, err := newAESGCM(nameKey)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to generate nonce")
+	}
+
+	// convert to utf8
+	utf8Filename := strings.ToValidUTF8(filename, "_")
+
+	// nonce in front
+	encryptedFilename := aead.Seal(nonce, nonce, []byte(utf8Filename), nil)
+
+	// base64 encode
+	b64EncodedFilename := base64.RawStdEncoding.EncodeToString(encryptedFilename)
+	// replace / to _
+	b64EncodedFilename = strings.ReplaceAll(b64EncodedFilename, "/", "_")
+
+	return fmt.Sprintf("%s%s", b64EncodedFilename, AesGcmEncryptedFileExtension), nil
+}
+
+func DecryptFilenameAESGCM(filename string, key []byte) (string, error) {
+	// trim file ext
+	filename = strings.TrimSuffix(filename, AesGcmEncryptedFileExtension)
+
+	// replace _ to /
+	filename = strings.ReplaceAll(filename, "_", "/")
+
+	// base64 decode
+	encryptedFilename, err := base64.RawStdEncoding.DecodeString(filename)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to base64 decode filename")
+	}
+
+	nameKey, err := deriveAESGCMKey(key, nil, aesGcmNameKeyInfo)
+	if err != nil {
+		return "", err
+	}
+
+	aead, err := newAESGCM(nameKey)
+	if err != nil {
+		return "", err
+	}
+
+	if len(encryptedFilename) < aead.NonceSize()+aead.Overhead() {
+		return "", errors.New("failed to extract nonce from filename")
+	}
+
+	nonce := encryptedFilename[:aead.NonceSize()]
+	decryptedFilename, err := aead.Open(nil, nonce, encryptedFilename[aead.NonceSize():], nil)
+	if err != nil {
+		return "", errors.New("failed to decrypt filename with wrong key")
+	}
+
+	return string(decryptedFilename), nil
+}
+
+// EncryptAESGCMReaderWriter encrypts data in chunks, each chunk is authenticated and the last chunk is marked to detect truncation
+func EncryptAESGCMReaderWriter(reader io.Reader, writer io.Writer, key []byte) error {
+	// header: magic || salt || nonce prefix
+	header := make([]byte, len(AesGcmHeader)+AesSaltLen+aesGcmNoncePrefixLen)
+	copy(header, AesGcmHeader)
+	_, err := rand.Read(header[len(AesGcmHeader):])
+	if err != nil {
+		return errors.Wrapf(err, "failed to generate salt")
+	}
+
+	salt := header[len(AesGcmHeader) : len(AesGcmHeader)+AesSaltLen]
+	noncePrefix := header[len(AesGcmHeader)+AesSaltLen:]
+
+	fileKey, err := deriveAESGCMKey(key, salt, aesGcmFileKeyInfo)
+	if err != nil {
+		return err
+	}
+
+	aead, err := newAESGCM(fileKey)
+	if err != nil {
+		return err
+	}
+
+	_, err = writer.Write(header)
+	if err != nil {
+		return errors.Wrapf(err, "failed to write header")
+	}
+
+	bufReader := bufio.NewReaderSize(reader, AesGcmChunkSize)
+	plaintext := make([]byte, AesGcmChunkSize)
+	ciphertext := make([]byte, 0, AesGcmChunkSize+aead.Overhead())
+
+	for counter := uint32(0); ; counter++ {
+		readLen, readErr := io.ReadFull(bufReader, plaintext)
+		if readErr != nil && readErr != io.EOF && readErr != io.ErrUnexpectedEOF {
+			return errors.Wrapf(readErr, "failed to read data")
+		}
+
+		last := readErr != nil
+		if !last {
+			// check if there is more data
+			_, peekErr := bufReader.Peek(1)
+			if peekErr == io.EOF {
+				last = true
+			} else if peekErr != nil {
+				return errors.Wrapf(peekErr, "failed to read data")
+			}
+		}
+
+		if counter == ^uint32(0) && !last {
+			return errors.New("failed to encrypt data, data is too large")
+		}
+
+		nonce := makeAESGCMStreamNonce(noncePrefix, counter, last)
+		ciphertext = aead.Seal(ciphertext[:0], nonce, plaintext[:readLen], header)
+
+		_, err = writer.Write(ciphertext)
+		if err != nil {
+			return errors.Wrapf(err, "failed to write data")
+		}
+
+		if last {
+			return nil
+		}
+	}
+}
+
+// DecryptAESGCMReaderWriter decrypts data in chunks, returns IntegrityError if any chunk is tampered or data is truncated
+func DecryptAESGCMReaderWriter(reader io.Reader, writer io.Writer, key []byte) error {
+	header := make([]byte, len(AesGcmHeader)+AesSaltLen+aesGcmNoncePrefixLen)
+	_, err := io.ReadFull(reader, header)
+	if err != nil {
+		return types.NewIntegrityError("", "failed to read AES GCM header")
+	}
+
+	if !bytes.Equal(header[:len(AesGcmHeader)], []byte(AesGcmHeader)) {
+		return errors.New("failed to read AES GCM header")
+	}
+
+	salt := header[len(AesGcmHeader) : len(AesGcmHeader)+AesSaltLen]
+	noncePrefix := header[len(AesGcmHeader)+AesSaltLen:]
+
+	fileKey, err := deriveAESGCMKey(key, salt, aesGcmFileKeyInfo)
+	if err != nil {
+		return err
+	}
+
+	aead, err := newAESGCM(fileKey)
+	if err != nil {
+		return err
+	}
+
+	bufReader := bufio.NewReaderSize(reader, AesGcmChunkSize+aead.Overhead())
+	ciphertext := make([]byte, AesGcmChunkSize+aead.Overhead())
+	plaintext := make([]byte, 0, AesGcmChunkSize)
+
+	for counter := uint32(0); ; counter++ {
+		readLen, readErr := io.ReadFull(bufReader, ciphertext)
+		if readErr != nil && readErr != io.EOF && readErr != io.ErrUnexpectedEOF {
+			return errors.Wrapf(readErr, "failed to read data")
+		}
+
+		last := readErr != nil
+		if !last {
+			// check if there is more data
+			_, peekErr := bufReader.Peek(1)
+			if peekErr == io.EOF {
+				last = true
+			} else if peekErr != nil {
+				return errors.Wrapf(peekErr, "failed to read data")
+			}
+		}
+
+		nonce := makeAESGCMStreamNonce(noncePrefix, counter, last)
+		plaintext, err = aead.Open(plaintext[:0], nonce, ciphertext[:readLen], header)
+		if err != nil {
+			return types.NewIntegrityError("", fmt.Sprintf("failed to authenticate chunk %d, data is tampered or truncated", counter))
+		}
+
+		_, err = writer.Write(plaintext)
+		if err != nil {
+			return errors.Wrapf(err, "failed to write data")
+		}
+
+		if last {
+			return nil
+		}
+	}
+}
+
+func EncryptFileAESGCM(source string, target string, key []byte) error {
+	sourceFileHandle, err := os.Open(source)
+	if err != nil {
+		return errors.Wrapf(err, "failed to open file %q", source)
+	}
+
+	defer sourceFileHandle.Close()
+
+	targetFileHandle, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create file %q", target)
+	}
+
+	defer targetFileHandle.Close()
+
+	err = EncryptAESGCMReaderWriter(sourceFileHandle, targetFileHandle, key)
+	if err != nil {
+		return errors.Wrapf(err, "failed to encrypt file %q", source)
+	}
+
+	return nil
+}
+
+func DecryptFileAESGCM(source string, target string, key []byte) error {
+	sourceFileHandle, err := os.Open(source)
+	if err != nil {
+		return errors.Wrapf(err, "failed to open file %q", source)
+	}
+
+	defer sourceFileHandle.Close()
+
+	targetFileHandle, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create file %q", target)
+	}
+
+	err = DecryptAESGCMReaderWriter(sourceFileHandle, targetFileHandle, key)
+	targetFileHandle.Close()
+
+	if err != nil {
+		// do not leave unauthenticated data
+		os.Remove(target)
+
+		var integrityErr *types.IntegrityError
+		if errors.As(err, &integrityErr) {
+			return types.NewIntegrityError(source, integrityErr.Reason)
+		}
+
+		return errors.Wrapf(err, "failed to decrypt file %q", source)
+	}
+
+	return nil
+}
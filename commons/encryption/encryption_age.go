@@ -0,0 +1,317 @@
+package encryption
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"filippo.io/age"
+	"filippo.io/age/agessh"
+	"github.com/cockroachdb/errors"
+	"github.com/cyverse/gocommands/commons/path"
+)
+
+const (
+	AgeEncryptedFileExtension string = ".age"
+
+	defaultAgeRecipientPath = "~/.ssh/id_ed25519.pub"
+	defaultAgeIdentityPath  = "~/.ssh/id_ed25519"
+)
+
+// GetDefaultAgeRecipientPath returns default age recipient path, if it does not exist, return empty string
+func GetDefaultAgeRecipientPath() string {
+	recipientPath, err := path.ExpandLocalHomeDirPath(defaultAgeRecipientPath)
+	if err != nil {
+		return ""
+	}
+
+	st, err := os.Stat(recipientPath)
+	if err == nil && !st.IsDir() {
+		return recipientPath
+	}
+
+	return ""
+}
+
+// GetDefaultAgeIdentityPath returns default age identity path
+func GetDefaultAgeIdentityPath() string {
+	identityPath, err := path.ExpandLocalHomeDirPath(defaultAgeIdentityPath)
+	if err != nil {
+		return ""
+	}
+
+	return identityPath
+}
+
+// ParseAgeRecipient parses an age recipient, 'age1...' for X25519 or 'ssh-ed25519 ...'/'ssh-rsa ...' for SSH keys
+func ParseAgeRecipient(recipient string) (age.Recipient, error) {
+	recipient = strings.TrimSpace(recipient)
+
+	switch {
+	case strings.HasPrefix(recipient, "age1"):
+		r, err := age.ParseX25519Recipient(recipient)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse X25519 recipient %q", recipient)
+		}
+		return r, nil
+	case strings.HasPrefix(recipient, "ssh-"):
+		r, err := agessh.ParseRecipient(recipient)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse SSH recipient %q", recipient)
+		}
+		return r, nil
+	default:
+		return nil, errors.Errorf("unknown recipient type %q", recipient)
+	}
+}
+
+// ParseAgeRecipients parses age recipients, each can be a recipient string or a path to a file containing recipients, one per line
+func ParseAgeRecipients(recipients []string) ([]age.Recipient, error) {
+	parsedRecipients := []age.Recipient{}
+
+	for _, recipient := range recipients {
+		recipient = strings.TrimSpace(recipient)
+		if len(recipient) == 0 {
+			continue
+		}
+
+		if strings.HasPrefix(recipient, "age1") || strings.HasPrefix(recipient, "ssh-") {
+			r, err := ParseAgeRecipient(recipient)
+			if err != nil {
+				return nil, err
+			}
+
+			parsedRecipients = append(parsedRecipients, r)
+			continue
+		}
+
+		// recipient file
+		recipientPath, err := path.ExpandLocalHomeDirPath(recipient)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to expand path %q", recipient)
+		}
+
+		recipientFile, err := os.Open(recipientPath)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to open recipient file %q", recipientPath)
+		}
+
+		scanner := bufio.NewScanner(recipientFile)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if len(line) == 0 || strings.HasPrefix(line, "#") {
+				continue
+			}
+
+			r, err := ParseAgeRecipient(line)
+			if err != nil {
+				recipientFile.Close()
+				return nil, errors.Wrapf(err, "failed to parse recipient file %q", recipientPath)
+			}
+
+			parsedRecipients = append(parsedRecipients, r)
+		}
+
+		err = scanner.Err()
+		recipientFile.Close()
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read recipient file %q", recipientPath)
+		}
+	}
+
+	if len(parsedRecipients) == 0 {
+		return nil, errors.New("no age recipients are given")
+	}
+
+	return parsedRecipients, nil
+}
+
+// ParseAgeIdentities parses age identities from a file, the file can be an age identity file or an SSH private key
+func ParseAgeIdentities(identityPath string) ([]age.Identity, error) {
+	identityBytes, err := os.ReadFile(identityPath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read identity file %q", identityPath)
+	}
+
+	if bytes.Contains(identityBytes, []byte("PRIVATE KEY")) {
+		// ssh private key
+		identity, err := agessh.ParseIdentity(identityBytes)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse SSH identity file %q", identityPath)
+		}
+
+		return []age.Identity{identity}, nil
+	}
+
+	identities, err := age.ParseIdentities(bytes.NewReader(identityBytes))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse identity file %q", identityPath)
+	}
+
+	return identities, nil
+}
+
+// EncryptFilenameAge encrypts filename, age recipients cannot derive a shared secret, so the symmetric key is used for filename
+func EncryptFilenameAge(filename string, key []byte) (string, error) {
+	if len(key) == 0 {
+		// no filename encryption
+		return fmt.Sprintf("%s%s", filename, AgeEncryptedFileExtension), nil
+	}
+
+	// generate salt
+	salt := make([]byte, AesSaltLen)
+	_, err := rand.Read(salt)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to generate salt")
+	}
+
+	// convert to utf8
+	utf8Filename := strings.ToValidUTF8(filename, "_")
+
+	// encrypt with aes 256 ctr
+	encryptedFilename, err := EncryptAESCTR([]byte(utf8Filename), salt, key)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to encrypt filename")
+	}
+
+	// add salt in front
+	concatenatedFilename := make([]byte, len(salt)+len(encryptedFilename))
+	copy(concatenatedFilename, salt)
+	copy(concatenatedFilename[len(salt):], encryptedFilename)
+
+	// base64 encode
+	b64EncodedFilename := base64.RawStdEncoding.EncodeToString(concatenatedFilename)
+	// replace / to _
+	b64EncodedFilename = strings.ReplaceAll(b64EncodedFilename, "/", "_")
+
+	newFilename := fmt.Sprintf("%s%s", b64EncodedFilename, AgeEncryptedFileExtension)
+
+	return newFilename, nil
+}
+
+// DecryptFilenameAge decrypts filename
+func DecryptFilenameAge(filename string, key []byte) (string, error) {
+	// trim file ext
+	filename = strings.TrimSuffix(filename, AgeEncryptedFileExtension)
+
+	if len(key) == 0 {
+		// no filename encryption
+		return filename, nil
+	}
+
+	// replace _ to /
+	filename = strings.ReplaceAll(filename, "_", "/")
+
+	// base64 decode
+	concatenatedFilename, err := base64.RawStdEncoding.DecodeString(filename)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to base64 decode filename")
+	}
+
+	if len(concatenatedFilename) < AesSaltLen {
+		return "", errors.New("failed to extract salt from filename")
+	}
+
+	salt := concatenatedFilename[:AesSaltLen]
+	encryptedFilename := concatenatedFilename[AesSaltLen:]
+
+	// decrypt with aes 256 ctr
+	decryptedFilename, err := DecryptAESCTR(encryptedFilename, salt, key)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to decrypt filename")
+	}
+
+	if !IsCorrectFilename(decryptedFilename) {
+		return "", errors.New("failed to decrypt filename with wrong key")
+	}
+
+	return string(decryptedFilename), nil
+}
+
+// EncryptAgeReaderWriter encrypts data from reader to writer for all recipients
+func EncryptAgeReaderWriter(reader io.Reader, writer io.Writer, recipients []age.Recipient) error {
+	encryptWriter, err := age.Encrypt(writer, recipients...)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create a encrypt writer")
+	}
+
+	_, err = io.Copy(encryptWriter, reader)
+	if err != nil {
+		encryptWriter.Close()
+		return errors.Wrapf(err, "failed to encrypt data")
+	}
+
+	// flush the last chunk
+	err = encryptWriter.Close()
+	if err != nil {
+		return errors.Wrapf(err, "failed to encrypt data")
+	}
+
+	return nil
+}
+
+// DecryptAgeReaderWriter decrypts data from reader to writer with one of identities
+func DecryptAgeReaderWriter(reader io.Reader, writer io.Writer, identities []age.Identity) error {
+	decryptReader, err := age.Decrypt(reader, identities...)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create a decrypt reader")
+	}
+
+	_, err = io.Copy(writer, decryptReader)
+	if err != nil {
+		return errors.Wrapf(err, "failed to decrypt data")
+	}
+
+	return nil
+}
+
+func EncryptFileAge(source string, target string, recipients []age.Recipient) error {
+	sourceFileHandle, err := os.Open(source)
+	if err != nil {
+		return errors.Wrapf(err, "failed to open file %q", source)
+	}
+
+	defer sourceFileHandle.Close()
+
+	targetFileHandle, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create file %q", target)
+	}
+
+	defer targetFileHandle.Close()
+
+	err = EncryptAgeReaderWriter(sourceFileHandle, targetFileHandle, recipients)
+	if err != nil {
+		return errors.Wrapf(err, "failed to encrypt file %q", source)
+	}
+
+	return nil
+}
+
+func DecryptFileAge(source string, target string, identities []age.Identity) error {
+	sourceFileHandle, err := os.Open(source)
+	if err != nil {
+		return errors.Wrapf(err, "failed to open file %q", source)
+	}
+
+	defer sourceFileHandle.Close()
+
+	targetFileHandle, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create file %q", target)
+	}
+
+	defer targetFileHandle.Close()
+
+	err = DecryptAgeReaderWriter(sourceFileHandle, targetFileHandle, identities)
+	if err != nil {
+		return errors.Wrapf(err, "failed to decrypt file %q", source)
+	}
+
+	return nil
+}
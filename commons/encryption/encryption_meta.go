@@ -3,17 +3,25 @@ package encryption
 import (
 	"strings"
 
+	"github.com/cockroachdb/errors"
 	irodsclient_fs "github.com/cyverse/go-irodsclient/fs"
 )
 
+const (
+	// PublicKeyMetaName is the attribute of public keys of recipients in collection metadata
+	PublicKeyMetaName string = "encryption.pub_key"
+)
+
 type EncryptionConfig struct {
-	Mode EncryptionMode
+	Mode       EncryptionMode
+	PublicKeys []string // authorized keys of recipients for SSH mode
 }
 
 // GetEncryptionConfigFromMeta returns encryption config from meta
 func GetEncryptionConfigFromMeta(filesystem *irodsclient_fs.FileSystem, targetPath string) *EncryptionConfig {
 	config := EncryptionConfig{
-		Mode: EncryptionModeNone,
+		Mode:       EncryptionModeNone,
+		PublicKeys: []string{},
 	}
 
 	metas, err := filesystem.ListMetadata(targetPath)
@@ -25,8 +33,66 @@ func GetEncryptionConfigFromMeta(filesystem *irodsclient_fs.FileSystem, targetPa
 		switch strings.ToLower(meta.Name) {
 		case "encryption.mode", "gocommands.encryption.mode", "encryption::mode", "gocommands::encryption::mode":
 			config.Mode = GetEncryptionMode(meta.Value)
+		case PublicKeyMetaName, "gocommands.encryption.pub_key", "encryption::pub_key", "gocommands::encryption::pub_key":
+			config.PublicKeys = append(config.PublicKeys, meta.Value)
 		}
 	}
 
 	return &config
 }
+
+func isPublicKeyMeta(name string) bool {
+	switch strings.ToLower(name) {
+	case PublicKeyMetaName, "gocommands.encryption.pub_key", "encryption::pub_key", "gocommands::encryption::pub_key":
+		return true
+	default:
+		return false
+	}
+}
+
+// SetPublicKeysToMeta replaces public keys of recipients in metadata of the target with the given authorized keys
+// new keys are added before old keys are removed, so the target always has a key ring
+func SetPublicKeysToMeta(filesystem *irodsclient_fs.FileSystem, targetPath string, publicKeys []string) error {
+	metas, err := filesystem.ListMetadata(targetPath)
+	if err != nil {
+		return errors.Wrapf(err, "failed to list metadata of %q", targetPath)
+	}
+
+	newKeys := map[string]bool{}
+	for _, publicKey := range publicKeys {
+		newKeys[publicKey] = true
+	}
+
+	existingKeys := map[string]bool{}
+	for _, meta := range metas {
+		if isPublicKeyMeta(meta.Name) {
+			existingKeys[meta.Value] = true
+		}
+	}
+
+	for _, publicKey := range publicKeys {
+		if existingKeys[publicKey] {
+			continue
+		}
+
+		err = filesystem.AddMetadata(targetPath, PublicKeyMetaName, publicKey, "")
+		if err != nil {
+			return errors.Wrapf(err, "failed to add public key to metadata of %q", targetPath)
+		}
+
+		existingKeys[publicKey] = true
+	}
+
+	for _, meta := range metas {
+		if !isPublicKeyMeta(meta.Name) || newKeys[meta.Value] {
+			continue
+		}
+
+		err = filesystem.DeleteMetadata(targetPath, meta.AVUID)
+		if err != nil {
+			return errors.Wrapf(err, "failed to remove public key from metadata of %q", targetPath)
+		}
+	}
+
+	return nil
+}
@@ -44,18 +44,33 @@ func EncryptFilePGP(source string, target string, key []byte) error {
 
 	defer targetFileHandle.Close()
 
+	err = EncryptPGPReaderWriter(sourceFileHandle, targetFileHandle, key)
+	if err != nil {
+		return errors.Wrapf(err, "failed to encrypt file %q", source)
+	}
+
+	return nil
+}
+
+// EncryptPGPReaderWriter encrypts data from reader to writer
+func EncryptPGPReaderWriter(reader io.Reader, writer io.Writer, key []byte) error {
 	encryptionConfig := &packet.Config{
 		DefaultCipher: packet.CipherAES256,
 	}
 
-	writeHandle, err := openpgp.SymmetricallyEncrypt(targetFileHandle, key, nil, encryptionConfig)
+	writeHandle, err := openpgp.SymmetricallyEncrypt(writer, key, nil, encryptionConfig)
 	if err != nil {
-		return errors.Wrapf(err, "failed to create a encrypt writer for %q", target)
+		return errors.Wrapf(err, "failed to create a encrypt writer")
 	}
 
-	defer writeHandle.Close()
+	_, err = io.Copy(writeHandle, reader)
+	if err != nil {
+		writeHandle.Close()
+		return errors.Wrapf(err, "failed to encrypt data")
+	}
 
-	_, err = io.Copy(writeHandle, sourceFileHandle)
+	// flush the last packet
+	err = writeHandle.Close()
 	if err != nil {
 		return errors.Wrapf(err, "failed to encrypt data")
 	}
@@ -78,6 +93,16 @@ func DecryptFilePGP(source string, target string, key []byte) error {
 
 	defer targetFileHandle.Close()
 
+	err = DecryptPGPReaderWriter(sourceFileHandle, targetFileHandle, key)
+	if err != nil {
+		return errors.Wrapf(err, "failed to decrypt file %q", source)
+	}
+
+	return nil
+}
+
+// DecryptPGPReaderWriter decrypts data from reader to writer
+func DecryptPGPReaderWriter(reader io.Reader, writer io.Writer, key []byte) error {
 	encryptionConfig := &packet.Config{
 		DefaultCipher: packet.CipherAES256,
 	}
@@ -91,14 +116,14 @@ func DecryptFilePGP(source string, target string, key []byte) error {
 		return key, nil
 	}
 
-	messageDetail, err := openpgp.ReadMessage(sourceFileHandle, nil, prompt, encryptionConfig)
+	messageDetail, err := openpgp.ReadMessage(reader, nil, prompt, encryptionConfig)
 	if err != nil {
-		return errors.Wrapf(err, "failed to decrypt for %q", source)
+		return errors.Wrapf(err, "failed to decrypt")
 	}
 
-	_, err = io.Copy(targetFileHandle, messageDetail.UnverifiedBody)
+	_, err = io.Copy(writer, messageDetail.UnverifiedBody)
 	if err != nil {
-		return errors.Wrapf(err, "failed to decrypt data for %q", source)
+		return errors.Wrapf(err, "failed to decrypt data")
 	}
 
 	return nil
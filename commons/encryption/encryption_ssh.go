@@ -126,3 +126,105 @@ func DecodePublicKey(publickeyPath string) (*rsa.PublicKey, error) {
 
 	return nil, errors.New("failed to get public key")
 }
+
+// DecodePublicKeyRing decodes public keys, each can be a public (or private) key file, a key ring file containing authorized keys, or an authorized key string
+func DecodePublicKeyRing(keys []string) ([]*rsa.PublicKey, error) {
+	publicKeys := []*rsa.PublicKey{}
+
+	for _, key := range keys {
+		key = strings.TrimSpace(key)
+		if len(key) == 0 {
+			continue
+		}
+
+		if strings.HasPrefix(key, "ssh-") {
+			// authorized key string
+			pubKeys, err := decodeAuthorizedKeys([]byte(key))
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to parse public key %q", key)
+			}
+
+			publicKeys = append(publicKeys, pubKeys...)
+			continue
+		}
+
+		keyPath, err := path.ExpandLocalHomeDirPath(key)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to expand path %q", key)
+		}
+
+		keyBytes, err := os.ReadFile(keyPath)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read public key file %q", keyPath)
+		}
+
+		block, _ := pem.Decode(keyBytes)
+		if block != nil {
+			// single key in pem
+			pubKey, err := DecodePublicKey(keyPath)
+			if err != nil {
+				return nil, err
+			}
+
+			publicKeys = append(publicKeys, pubKey)
+			continue
+		}
+
+		// key ring
+		pubKeys, err := decodeAuthorizedKeys(keyBytes)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse public key file %q", keyPath)
+		}
+
+		publicKeys = append(publicKeys, pubKeys...)
+	}
+
+	if len(publicKeys) == 0 {
+		return nil, errors.New("no public keys are given")
+	}
+
+	return publicKeys, nil
+}
+
+func decodeAuthorizedKeys(keyBytes []byte) ([]*rsa.PublicKey, error) {
+	publicKeys := []*rsa.PublicKey{}
+
+	rest := keyBytes
+	for len(strings.TrimSpace(string(rest))) > 0 {
+		publicKey, _, _, nextRest, err := ssh.ParseAuthorizedKey(rest)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse authorized key")
+		}
+
+		parsedCryptoKey, ok := publicKey.(ssh.CryptoPublicKey)
+		if !ok {
+			return nil, errors.New("failed to get crypto public key")
+		}
+
+		pubKey, ok := parsedCryptoKey.CryptoPublicKey().(*rsa.PublicKey)
+		if !ok {
+			return nil, errors.New("failed to get RSA public key")
+		}
+
+		publicKeys = append(publicKeys, pubKey)
+		rest = nextRest
+	}
+
+	return publicKeys, nil
+}
+
+// EncodeAuthorizedKeys encodes public keys to authorized key strings, the format of key rings in metadata
+func EncodeAuthorizedKeys(publicKeys []*rsa.PublicKey) ([]string, error) {
+	authorizedKeys := []string{}
+
+	for _, publicKey := range publicKeys {
+		sshPublicKey, err := ssh.NewPublicKey(publicKey)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to encode public key")
+		}
+
+		authorizedKeys = append(authorizedKeys, strings.TrimSpace(string(ssh.MarshalAuthorizedKey(sshPublicKey))))
+	}
+
+	return authorizedKeys, nil
+}
@@ -1,6 +1,7 @@
 package encryption
 
 import (
+	"bufio"
 	"bytes"
 	"encoding/base64"
 	"encoding/binary"
@@ -15,12 +16,17 @@ import (
 	_ "crypto/sha256"
 
 	"github.com/cockroachdb/errors"
+	"github.com/cyverse/gocommands/commons/types"
 	_ "golang.org/x/crypto/ripemd160"
 )
 
 const (
-	SshEncryptedFileExtension string = ".rsaaesctr.enc"
-	SshRsaAesCtrHeader        string = "rsaaesctr......."
+	SshEncryptedFileExtension        string = ".rsaaesctr.enc"
+	SshRsaAesCtrHeader               string = "rsaaesctr......."
+	SshRsaAesCtrMultiRecipientHeader string = "rsaaesctrmulti.."
+
+	sshMaxRecipients          int = 1024 // max number of recipients of a file
+	sshMaxEncryptedHeaderSize int = 2048 // RSA modulus size of 16384-bit keys
 )
 
 func EncryptFilenameSSH(filename string, publickey *rsa.PublicKey) (string, error) {
@@ -59,6 +65,11 @@ func EncryptFilenameSSH(filename string, publickey *rsa.PublicKey) (string, erro
 }
 
 func DecryptFilenameSSH(filename string, privatekey *rsa.PrivateKey) (string, error) {
+	return DecryptFilenameSSHWithPublicKey(filename, &privatekey.PublicKey)
+}
+
+// DecryptFilenameSSHWithPublicKey decrypts filename with public key, filenames are encrypted with the first key in key ring for multi-recipient files
+func DecryptFilenameSSHWithPublicKey(filename string, publickey *rsa.PublicKey) (string, error) {
 	// trim file ext
 	filename = strings.TrimSuffix(filename, SshEncryptedFileExtension)
 
@@ -79,7 +90,7 @@ func DecryptFilenameSSH(filename string, privatekey *rsa.PrivateKey) (string, er
 	encryptedFilename := concatenatedFilename[AesSaltLen:]
 
 	// decrypt with aes 256 ctr
-	decryptedFilename, err := DecryptAESCTR(encryptedFilename, salt, privatekey.PublicKey.N.Bytes()[:32])
+	decryptedFilename, err := DecryptAESCTR(encryptedFilename, salt, publickey.N.Bytes()[:32])
 	if err != nil {
 		return "", errors.Wrapf(err, "failed to decrypt filename")
 	}
@@ -106,18 +117,102 @@ func EncryptFileSSH(source string, target string, publickey *rsa.PublicKey) erro
 
 	defer targetFileHandle.Close()
 
-	stat, err := sourceFileHandle.Stat()
+	return EncryptSSHReaderWriter(sourceFileHandle, targetFileHandle, []*rsa.PublicKey{publickey})
+}
+
+func DecryptFileSSH(source string, target string, privatekey *rsa.PrivateKey) error {
+	sourceFileHandle, err := os.Open(source)
+	if err != nil {
+		return errors.Wrapf(err, "failed to open file %q", source)
+	}
+
+	defer sourceFileHandle.Close()
+
+	targetFileHandle, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create file %q", target)
+	}
+
+	defer targetFileHandle.Close()
+
+	err = DecryptSSHReaderWriter(sourceFileHandle, targetFileHandle, privatekey)
+	if err != nil {
+		var integrityErr *types.IntegrityError
+		if errors.As(err, &integrityErr) {
+			return types.NewIntegrityError(source, integrityErr.Reason)
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// EncryptFileSSHMultiRecipient encrypts file for multiple public keys, any of matching private keys can decrypt the file
+func EncryptFileSSHMultiRecipient(source string, target string, publickeys []*rsa.PublicKey) error {
+	err := checkSSHRecipients(publickeys)
+	if err != nil {
+		return err
+	}
+
+	sourceFileHandle, err := os.Open(source)
+	if err != nil {
+		return errors.Wrapf(err, "failed to open file %q", source)
+	}
+
+	defer sourceFileHandle.Close()
+
+	targetFileHandle, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create file %q", target)
+	}
+
+	defer targetFileHandle.Close()
+
+	return EncryptSSHReaderWriter(sourceFileHandle, targetFileHandle, publickeys)
+}
+
+func checkSSHRecipients(publickeys []*rsa.PublicKey) error {
+	if len(publickeys) == 0 {
+		return errors.New("no public keys are given")
+	}
+
+	if len(publickeys) > sshMaxRecipients {
+		return errors.Errorf("too many public keys are given, max %d", sshMaxRecipients)
+	}
+
+	return nil
+}
+
+// EncryptSSHReaderWriter encrypts data from reader to writer for public keys, nothing is written for empty data
+// the single recipient format is used for a key for compatibility
+func EncryptSSHReaderWriter(reader io.Reader, writer io.Writer, publickeys []*rsa.PublicKey) error {
+	err := checkSSHRecipients(publickeys)
 	if err != nil {
-		return errors.Wrapf(err, "failed to stat file %q", source)
+		return err
 	}
 
-	if stat.Size() == 0 {
+	bufReader := bufio.NewReader(reader)
+	_, err = bufReader.Peek(1)
+	if err == io.EOF {
 		// empty file
 		return nil
 	}
 
+	if err != nil {
+		return errors.Wrapf(err, "failed to read data")
+	}
+
+	if len(publickeys) == 1 {
+		return encryptSSHSingleRecipient(bufReader, writer, publickeys[0])
+	}
+
+	return encryptSSHMultiRecipient(bufReader, writer, publickeys)
+}
+
+func encryptSSHSingleRecipient(reader io.Reader, writer io.Writer, publickey *rsa.PublicKey) error {
 	// write header
-	_, err = targetFileHandle.Write([]byte(SshRsaAesCtrHeader))
+	_, err := writer.Write([]byte(SshRsaAesCtrHeader))
 	if err != nil {
 		return errors.Wrapf(err, "failed to write header")
 	}
@@ -150,18 +245,18 @@ func EncryptFileSSH(source string, target string, publickey *rsa.PublicKey) erro
 	// write header len
 	lenBuffer := make([]byte, 32)
 	binary.LittleEndian.PutUint32(lenBuffer, uint32(len(encryptedHeader)))
-	_, err = targetFileHandle.Write(lenBuffer)
+	_, err = writer.Write(lenBuffer)
 	if err != nil {
 		return errors.Wrapf(err, "failed to write encrypted header length")
 	}
 
 	// write salt and shared key
-	_, err = targetFileHandle.Write(encryptedHeader)
+	_, err = writer.Write(encryptedHeader)
 	if err != nil {
 		return errors.Wrapf(err, "failed to write encrypted header")
 	}
 
-	err = EncryptAESCTRReaderWriter(sourceFileHandle, targetFileHandle, salt, sharedKey)
+	err = EncryptAESCTRReaderWriter(reader, writer, salt, sharedKey)
 	if err != nil {
 		return errors.Wrapf(err, "failed to encrypt file content")
 	}
@@ -169,24 +264,73 @@ func EncryptFileSSH(source string, target string, publickey *rsa.PublicKey) erro
 	return nil
 }
 
-func DecryptFileSSH(source string, target string, privatekey *rsa.PrivateKey) error {
-	sourceFileHandle, err := os.Open(source)
+func encryptSSHMultiRecipient(reader io.Reader, writer io.Writer, publickeys []*rsa.PublicKey) error {
+	// write header
+	_, err := writer.Write([]byte(SshRsaAesCtrMultiRecipientHeader))
 	if err != nil {
-		return errors.Wrapf(err, "failed to open file %q", source)
+		return errors.Wrapf(err, "failed to write header")
 	}
 
-	defer sourceFileHandle.Close()
+	// generate salt
+	salt := make([]byte, AesSaltLen)
+	_, err = rand.Read(salt)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read random data")
+	}
 
-	targetFileHandle, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
+	// generate shared key
+	sharedKey := make([]byte, 32)
+	_, err = rand.Read(sharedKey)
 	if err != nil {
-		return errors.Wrapf(err, "failed to create file %q", target)
+		return errors.Wrapf(err, "failed to generate random shared key")
 	}
 
-	defer targetFileHandle.Close()
+	headerBuffer := make([]byte, AesSaltLen+32)
+	copy(headerBuffer[:AesSaltLen], salt)
+	copy(headerBuffer[AesSaltLen:], sharedKey)
+
+	// write recipient count
+	lenBuffer := make([]byte, 32)
+	binary.LittleEndian.PutUint32(lenBuffer, uint32(len(publickeys)))
+	_, err = writer.Write(lenBuffer)
+	if err != nil {
+		return errors.Wrapf(err, "failed to write recipient count")
+	}
+
+	// RSA encrypt salt and shared key for each recipient
+	oaepLabel := []byte("")
+	for _, publickey := range publickeys {
+		encryptedHeader, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, publickey, headerBuffer, oaepLabel)
+		if err != nil {
+			return errors.Wrapf(err, "failed to encrypt header")
+		}
+
+		binary.LittleEndian.PutUint32(lenBuffer, uint32(len(encryptedHeader)))
+		_, err = writer.Write(lenBuffer)
+		if err != nil {
+			return errors.Wrapf(err, "failed to write encrypted header length")
+		}
+
+		_, err = writer.Write(encryptedHeader)
+		if err != nil {
+			return errors.Wrapf(err, "failed to write encrypted header")
+		}
+	}
+
+	err = EncryptAESCTRReaderWriter(reader, writer, salt, sharedKey)
+	if err != nil {
+		return errors.Wrapf(err, "failed to encrypt file content")
+	}
 
+	return nil
+}
+
+// DecryptSSHReaderWriter decrypts data from reader to writer with the private key, nothing is written for empty data
+func DecryptSSHReaderWriter(reader io.Reader, writer io.Writer, privatekey *rsa.PrivateKey) error {
 	header := make([]byte, 16)
-	readLen, err := sourceFileHandle.Read(header)
-	if err == io.EOF && readLen == 0 {
+	_, err := io.ReadFull(reader, header)
+	if err == io.EOF {
+		// empty file
 		return nil
 	}
 
@@ -194,31 +338,27 @@ func DecryptFileSSH(source string, target string, privatekey *rsa.PrivateKey) er
 		return errors.Wrapf(err, "failed to read RSA AES CTR header")
 	}
 
+	if bytes.Equal(header, []byte(SshRsaAesCtrMultiRecipientHeader)) {
+		return decryptFileSSHMultiRecipient(reader, writer, privatekey)
+	}
+
 	if !bytes.Equal(header, []byte(SshRsaAesCtrHeader)) {
 		return errors.New("failed to read RSA AES CTR header")
 	}
 
 	lenBuffer := make([]byte, 32)
-	readLen, err = sourceFileHandle.Read(lenBuffer)
+	_, err = io.ReadFull(reader, lenBuffer)
 	if err != nil {
 		return errors.Wrapf(err, "failed to read encrypted header length")
 	}
 
-	if readLen != 32 {
-		return errors.New("failed to read encrypted header length")
-	}
-
 	encryptedHeaderLength := binary.LittleEndian.Uint32(lenBuffer)
 	encryptedHeaderBuffer := make([]byte, encryptedHeaderLength)
-	readLen, err = sourceFileHandle.Read(encryptedHeaderBuffer)
+	_, err = io.ReadFull(reader, encryptedHeaderBuffer)
 	if err != nil {
 		return errors.Wrapf(err, "failed to read encrypted header")
 	}
 
-	if readLen != int(encryptedHeaderLength) {
-		return errors.New("failed to read encrypted header")
-	}
-
 	// RSA decrypt
 	oaepLabel := []byte("")
 	decryptedHeader, err := rsa.DecryptOAEP(sha256.New(), rand.Reader, privatekey, encryptedHeaderBuffer, oaepLabel)
@@ -233,7 +373,70 @@ func DecryptFileSSH(source string, target string, privatekey *rsa.PrivateKey) er
 	salt := decryptedHeader[:AesSaltLen]
 	sharedKey := decryptedHeader[AesSaltLen:]
 
-	err = DecryptAESCTRReaderWriter(sourceFileHandle, targetFileHandle, salt, sharedKey)
+	err = DecryptAESCTRReaderWriter(reader, writer, salt, sharedKey)
+	if err != nil {
+		return errors.Wrapf(err, "failed to decrypt file content")
+	}
+
+	return nil
+}
+
+func decryptFileSSHMultiRecipient(reader io.Reader, writer io.Writer, privatekey *rsa.PrivateKey) error {
+	lenBuffer := make([]byte, 32)
+	_, err := io.ReadFull(reader, lenBuffer)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read recipient count")
+	}
+
+	// values in the header are not trusted, they are checked before allocating buffers
+	recipientCount := binary.LittleEndian.Uint32(lenBuffer)
+	if recipientCount == 0 || recipientCount > uint32(sshMaxRecipients) {
+		return types.NewIntegrityError("", fmt.Sprintf("invalid recipient count %d, max %d", recipientCount, sshMaxRecipients))
+	}
+
+	var decryptedHeader []byte
+	oaepLabel := []byte("")
+	for i := uint32(0); i < recipientCount; i++ {
+		_, err = io.ReadFull(reader, lenBuffer)
+		if err != nil {
+			return errors.Wrapf(err, "failed to read encrypted header length")
+		}
+
+		encryptedHeaderLength := binary.LittleEndian.Uint32(lenBuffer)
+		if encryptedHeaderLength == 0 || encryptedHeaderLength > uint32(sshMaxEncryptedHeaderSize) {
+			return types.NewIntegrityError("", fmt.Sprintf("invalid encrypted header length %d, max %d", encryptedHeaderLength, sshMaxEncryptedHeaderSize))
+		}
+
+		if decryptedHeader != nil || int(encryptedHeaderLength) != privatekey.Size() {
+			// already found, or the header is for a key of different size, skip
+			_, err = io.CopyN(io.Discard, reader, int64(encryptedHeaderLength))
+			if err != nil {
+				return errors.Wrapf(err, "failed to read encrypted header")
+			}
+			continue
+		}
+
+		encryptedHeaderBuffer := make([]byte, encryptedHeaderLength)
+		_, err = io.ReadFull(reader, encryptedHeaderBuffer)
+		if err != nil {
+			return errors.Wrapf(err, "failed to read encrypted header")
+		}
+
+		// RSA decrypt, fails if the header is not for this key
+		header, err := rsa.DecryptOAEP(sha256.New(), rand.Reader, privatekey, encryptedHeaderBuffer, oaepLabel)
+		if err == nil && len(header) == AesSaltLen+32 {
+			decryptedHeader = header
+		}
+	}
+
+	if decryptedHeader == nil {
+		return errors.New("failed to decrypt header, the private key is not one of recipients")
+	}
+
+	salt := decryptedHeader[:AesSaltLen]
+	sharedKey := decryptedHeader[AesSaltLen:]
+
+	err = DecryptAESCTRReaderWriter(reader, writer, salt, sharedKey)
 	if err != nil {
 		return errors.Wrapf(err, "failed to decrypt file content")
 	}
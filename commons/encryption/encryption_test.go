@@ -1,12 +1,21 @@
 package encryption
 
 import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/binary"
 	"encoding/hex"
+	"encoding/pem"
+	"fmt"
 	"os"
 	"testing"
 
+	"filippo.io/age"
 	irodsclient_util "github.com/cyverse/go-irodsclient/irods/util"
 	"github.com/cyverse/gocommands/commons/path"
+	"github.com/cyverse/gocommands/commons/types"
+	"golang.org/x/crypto/ssh"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -19,6 +28,13 @@ func TestEncrypt(t *testing.T) {
 	t.Run("test EncryptFilePGP", testEncryptFilePGP)
 	t.Run("test EncryptFileWinSCP", testEncryptFileWinSCP)
 	t.Run("test EncryptFileSSH", testEncryptFileSSH)
+	t.Run("test EncryptFileSSHMultiRecipient", testEncryptFileSSHMultiRecipient)
+	t.Run("test DecryptFileSSHMultiRecipientInvalidHeader", testDecryptFileSSHMultiRecipientInvalidHeader)
+	t.Run("test EncryptFilenameAESGCM", testEncryptFilenameAESGCM)
+	t.Run("test EncryptFileAESGCM", testEncryptFileAESGCM)
+	t.Run("test EncryptFilenameAge", testEncryptFilenameAge)
+	t.Run("test EncryptFileAge", testEncryptFileAge)
+	t.Run("test ReencryptFile", testReencryptFile)
 }
 
 func makeFixedContentTestDataBuf(size int64) []byte {
@@ -265,3 +281,393 @@ func testEncryptFileSSH(t *testing.T) {
 	err = os.Remove(decFilePath)
 	assert.NoError(t, err)
 }
+
+func testEncryptFilenameAge(t *testing.T) {
+	filename := "LICENSE"
+
+	password := "4444444444444444444444444444444444444444444444444444444444444444"
+	passwordBytes, err := hex.DecodeString(password)
+	assert.NoError(t, err)
+
+	encryptManager := NewEncryptionManager(EncryptionModeAge)
+	encryptManager.SetKey(passwordBytes)
+
+	encFilename, err := encryptManager.EncryptFilename(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, EncryptionModeAge, DetectEncryptionMode(encFilename))
+
+	decFilename, err := encryptManager.DecryptFilename(encFilename)
+	assert.NoError(t, err)
+
+	// compare
+	assert.Equal(t, filename, decFilename)
+}
+
+func testEncryptFileAge(t *testing.T) {
+	fileSize := 10 * 1024 * 1024 // 10MB
+
+	filename := "test_large_file.bin"
+	filepath, err := createLocalTestFile(filename, int64(fileSize))
+	assert.NoError(t, err)
+
+	encFilePath := filepath + ".enc"
+	decFilePath := filepath + ".dec"
+
+	// two recipients
+	identity1, err := age.GenerateX25519Identity()
+	assert.NoError(t, err)
+
+	identity2, err := age.GenerateX25519Identity()
+	assert.NoError(t, err)
+
+	identityPath := filepath + ".key"
+	err = os.WriteFile(identityPath, []byte(identity2.String()+"\n"), 0600)
+	assert.NoError(t, err)
+
+	encryptManager := NewEncryptionManager(EncryptionModeAge)
+	encryptManager.SetAgeRecipients([]string{identity1.Recipient().String(), identity2.Recipient().String()})
+
+	err = encryptManager.EncryptFile(filepath, encFilePath)
+	assert.NoError(t, err)
+
+	// decrypt with the second recipient
+	decryptManager := NewEncryptionManager(EncryptionModeAge)
+	decryptManager.SetPublicPrivateKey(identityPath)
+
+	err = decryptManager.DecryptFile(encFilePath, decFilePath)
+	assert.NoError(t, err)
+
+	// compare
+	sourceHash, err := irodsclient_util.HashLocalFile(filepath, "SHA-256", nil)
+	assert.NoError(t, err)
+
+	decHash, err := irodsclient_util.HashLocalFile(decFilePath, "SHA-256", nil)
+	assert.NoError(t, err)
+
+	assert.Equal(t, sourceHash, decHash)
+
+	err = os.Remove(filepath)
+	assert.NoError(t, err)
+
+	err = os.Remove(encFilePath)
+	assert.NoError(t, err)
+
+	err = os.Remove(decFilePath)
+	assert.NoError(t, err)
+
+	err = os.Remove(identityPath)
+	assert.NoError(t, err)
+}
+
+func testEncryptFileSSHMultiRecipient(t *testing.T) {
+	fileSize := 10 * 1024 * 1024 // 10MB
+
+	filename := "test_large_file.bin"
+	filepath, err := createLocalTestFile(filename, int64(fileSize))
+	assert.NoError(t, err)
+
+	encFilePath := filepath + ".enc"
+	decFilePath := filepath + ".dec"
+
+	// key ring with two recipients
+	ring := []byte{}
+	privateKeys := []*rsa.PrivateKey{}
+	for i := 0; i < 2; i++ {
+		privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+		assert.NoError(t, err)
+
+		publicKey, err := ssh.NewPublicKey(&privateKey.PublicKey)
+		assert.NoError(t, err)
+
+		ring = append(ring, ssh.MarshalAuthorizedKey(publicKey)...)
+		privateKeys = append(privateKeys, privateKey)
+	}
+
+	ringPath := filepath + ".ring"
+	err = os.WriteFile(ringPath, ring, 0600)
+	assert.NoError(t, err)
+
+	privateKeyPath := filepath + ".key"
+	privateKeyPem := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(privateKeys[1])})
+	err = os.WriteFile(privateKeyPath, privateKeyPem, 0600)
+	assert.NoError(t, err)
+
+	encryptManager := NewEncryptionManager(EncryptionModeSSH)
+	encryptManager.SetPublicKeyRing([]string{ringPath})
+
+	encFilename, err := encryptManager.EncryptFilename(filename)
+	assert.NoError(t, err)
+
+	err = encryptManager.EncryptFile(filepath, encFilePath)
+	assert.NoError(t, err)
+
+	// decrypt with the second recipient
+	decryptManager := NewEncryptionManager(EncryptionModeSSH)
+	decryptManager.SetPublicPrivateKey(privateKeyPath)
+	decryptManager.SetPublicKeyRing([]string{ringPath})
+
+	decFilename, err := decryptManager.DecryptFilename(encFilename)
+	assert.NoError(t, err)
+	assert.Equal(t, filename, decFilename)
+
+	err = decryptManager.DecryptFile(encFilePath, decFilePath)
+	assert.NoError(t, err)
+
+	// compare
+	sourceHash, err := irodsclient_util.HashLocalFile(filepath, "SHA-256", nil)
+	assert.NoError(t, err)
+
+	decHash, err := irodsclient_util.HashLocalFile(decFilePath, "SHA-256", nil)
+	assert.NoError(t, err)
+
+	assert.Equal(t, sourceHash, decHash)
+
+	for _, p := range []string{filepath, encFilePath, decFilePath, ringPath, privateKeyPath} {
+		err = os.Remove(p)
+		assert.NoError(t, err)
+	}
+}
+
+func testDecryptFileSSHMultiRecipientInvalidHeader(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	lenField := func(value uint32) []byte {
+		field := make([]byte, 32)
+		binary.LittleEndian.PutUint32(field, value)
+		return field
+	}
+
+	tests := []struct {
+		name   string
+		header [][]byte
+	}{
+		{"too many recipients", [][]byte{lenField(0xFFFFFFFF)}},
+		{"no recipients", [][]byte{lenField(0)}},
+		{"too long header", [][]byte{lenField(2), lenField(0xFFFFFFFF)}},
+	}
+
+	for _, test := range tests {
+		content := []byte(SshRsaAesCtrMultiRecipientHeader)
+		for _, field := range test.header {
+			content = append(content, field...)
+		}
+
+		encFilePath := "test_invalid_header.enc"
+		decFilePath := "test_invalid_header.dec"
+
+		err = os.WriteFile(encFilePath, content, 0600)
+		assert.NoError(t, err)
+
+		err = DecryptFileSSH(encFilePath, decFilePath, privateKey)
+		assert.Error(t, err, test.name)
+		assert.True(t, types.IsIntegrityError(err), test.name)
+
+		for _, p := range []string{encFilePath, decFilePath} {
+			err = os.Remove(p)
+			assert.NoError(t, err)
+		}
+	}
+}
+
+func testEncryptFilenameAESGCM(t *testing.T) {
+	filename := "LICENSE"
+
+	password := "4444444444444444444444444444444444444444444444444444444444444444"
+	passwordBytes, err := hex.DecodeString(password)
+	assert.NoError(t, err)
+
+	encryptManager := NewEncryptionManager(EncryptionModeAESGCM)
+	encryptManager.SetKey(passwordBytes)
+
+	encFilename, err := encryptManager.EncryptFilename(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, EncryptionModeAESGCM, DetectEncryptionMode(encFilename))
+
+	decFilename, err := encryptManager.DecryptFilename(encFilename)
+	assert.NoError(t, err)
+
+	// compare
+	assert.Equal(t, filename, decFilename)
+}
+
+func testEncryptFileAESGCM(t *testing.T) {
+	fileSize := 10*1024*1024 + 100 // 10MB, not aligned to chunk
+
+	filename := "test_large_file.bin"
+	filepath, err := createLocalTestFile(filename, int64(fileSize))
+	assert.NoError(t, err)
+
+	password := "4444444444444444444444444444444444444444444444444444444444444444"
+	passwordBytes, err := hex.DecodeString(password)
+	assert.NoError(t, err)
+
+	encFilePath := filepath + ".enc"
+	decFilePath := filepath + ".dec"
+
+	encryptManager := NewEncryptionManager(EncryptionModeAESGCM)
+	encryptManager.SetKey(passwordBytes)
+
+	err = encryptManager.EncryptFile(filepath, encFilePath)
+	assert.NoError(t, err)
+
+	err = encryptManager.DecryptFile(encFilePath, decFilePath)
+	assert.NoError(t, err)
+
+	// compare
+	sourceHash, err := irodsclient_util.HashLocalFile(filepath, "SHA-256", nil)
+	assert.NoError(t, err)
+
+	decHash, err := irodsclient_util.HashLocalFile(decFilePath, "SHA-256", nil)
+	assert.NoError(t, err)
+
+	assert.Equal(t, sourceHash, decHash)
+
+	encData, err := os.ReadFile(encFilePath)
+	assert.NoError(t, err)
+
+	// tampered
+	tamperedData := make([]byte, len(encData))
+	copy(tamperedData, encData)
+	tamperedData[len(tamperedData)/2] ^= 0x01
+
+	err = os.WriteFile(encFilePath, tamperedData, 0600)
+	assert.NoError(t, err)
+
+	err = encryptManager.DecryptFile(encFilePath, decFilePath)
+	assert.True(t, types.IsIntegrityError(err))
+
+	// truncated at chunk boundary
+	headerLen := len(AesGcmHeader) + AesSaltLen + aesGcmNoncePrefixLen
+	err = os.WriteFile(encFilePath, encData[:headerLen+(AesGcmChunkSize+16)*3], 0600)
+	assert.NoError(t, err)
+
+	err = encryptManager.DecryptFile(encFilePath, decFilePath)
+	assert.True(t, types.IsIntegrityError(err))
+
+	err = os.Remove(filepath)
+	assert.NoError(t, err)
+
+	err = os.Remove(encFilePath)
+	assert.NoError(t, err)
+}
+
+func testReencryptFile(t *testing.T) {
+	tempDir := t.TempDir()
+
+	// old and new keys of each mode
+	oldPassword := []byte("old password")
+	newPassword := []byte("new password")
+
+	sshPrivateKeyPaths := []string{}
+	sshAuthorizedKeys := []string{}
+	for _, name := range []string{"old", "new"} {
+		privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+		assert.NoError(t, err)
+
+		publicKey, err := ssh.NewPublicKey(&privateKey.PublicKey)
+		assert.NoError(t, err)
+
+		privateKeyPath := tempDir + "/" + name + ".key"
+		privateKeyPem := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(privateKey)})
+		err = os.WriteFile(privateKeyPath, privateKeyPem, 0600)
+		assert.NoError(t, err)
+
+		sshPrivateKeyPaths = append(sshPrivateKeyPaths, privateKeyPath)
+		sshAuthorizedKeys = append(sshAuthorizedKeys, string(ssh.MarshalAuthorizedKey(publicKey)))
+	}
+
+	ageIdentityPaths := []string{}
+	ageRecipients := []string{}
+	for _, name := range []string{"old", "new"} {
+		identity, err := age.GenerateX25519Identity()
+		assert.NoError(t, err)
+
+		identityPath := tempDir + "/" + name + ".age"
+		err = os.WriteFile(identityPath, []byte(identity.String()+"\n"), 0600)
+		assert.NoError(t, err)
+
+		ageIdentityPaths = append(ageIdentityPaths, identityPath)
+		ageRecipients = append(ageRecipients, identity.Recipient().String())
+	}
+
+	makeManager := func(mode EncryptionMode, idx int) *EncryptionManager {
+		manager := NewEncryptionManager(mode)
+
+		switch mode {
+		case EncryptionModeWinSCP, EncryptionModePGP, EncryptionModeAESGCM:
+			if idx == 0 {
+				manager.SetKey(oldPassword)
+			} else {
+				manager.SetKey(newPassword)
+			}
+		case EncryptionModeSSH:
+			manager.SetPublicKeyRing([]string{sshAuthorizedKeys[idx]})
+			manager.SetPublicPrivateKey(sshPrivateKeyPaths[idx])
+		case EncryptionModeAge:
+			manager.SetAgeRecipients([]string{ageRecipients[idx]})
+			manager.SetPublicPrivateKey(ageIdentityPaths[idx])
+		}
+
+		return manager
+	}
+
+	tests := []struct {
+		mode EncryptionMode
+		size int64
+	}{
+		{EncryptionModeWinSCP, 3*1024*1024 + 7},
+		{EncryptionModeWinSCP, 0},
+		{EncryptionModePGP, 3*1024*1024 + 7},
+		{EncryptionModeSSH, 3*1024*1024 + 7},
+		{EncryptionModeSSH, 0},
+		{EncryptionModeAge, 3*1024*1024 + 7},
+		{EncryptionModeAESGCM, 3*1024*1024 + 7},
+	}
+
+	for _, test := range tests {
+		name := fmt.Sprintf("%s %d", test.mode, test.size)
+
+		filepath, err := createLocalTestFile("test_reencrypt_file.bin", test.size)
+		assert.NoError(t, err, name)
+
+		oldEncFilePath := filepath + ".old.enc"
+		newEncFilePath := filepath + ".new.enc"
+		decFilePath := filepath + ".dec"
+
+		oldManager := makeManager(test.mode, 0)
+		newManager := makeManager(test.mode, 1)
+
+		err = oldManager.EncryptFile(filepath, oldEncFilePath)
+		assert.NoError(t, err, name)
+
+		err = ReencryptFile(oldEncFilePath, newEncFilePath, oldManager, newManager)
+		assert.NoError(t, err, name)
+
+		err = newManager.DecryptFile(newEncFilePath, decFilePath)
+		assert.NoError(t, err, name)
+
+		// compare
+		sourceHash, err := irodsclient_util.HashLocalFile(filepath, "SHA-256", nil)
+		assert.NoError(t, err, name)
+
+		decHash, err := irodsclient_util.HashLocalFile(decFilePath, "SHA-256", nil)
+		assert.NoError(t, err, name)
+
+		assert.Equal(t, sourceHash, decHash, name)
+
+		if test.size > 0 && test.mode != EncryptionModeWinSCP {
+			// the old key does not decrypt, winscp mode is not authenticated
+			err = oldManager.DecryptFile(newEncFilePath, decFilePath)
+			assert.Error(t, err, name)
+
+			// re-encryption with a wrong current key fails
+			err = ReencryptFile(newEncFilePath, oldEncFilePath+".2", oldManager, newManager)
+			assert.Error(t, err, name)
+		}
+
+		for _, p := range []string{filepath, oldEncFilePath, newEncFilePath, decFilePath, oldEncFilePath + ".2"} {
+			os.Remove(p)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package encryption
 
 import (
+	"bufio"
 	"bytes"
 	"crypto/rand"
 	"encoding/base64"
@@ -101,18 +102,24 @@ func EncryptFileWinSCP(source string, target string, key []byte) error {
 
 	defer targetFileHandle.Close()
 
-	stat, err := sourceFileHandle.Stat()
-	if err != nil {
-		return errors.Wrapf(err, "failed to stat file %q", source)
-	}
+	return EncryptWinSCPReaderWriter(sourceFileHandle, targetFileHandle, key)
+}
 
-	if stat.Size() == 0 {
+// EncryptWinSCPReaderWriter encrypts data from reader to writer, nothing is written for empty data
+func EncryptWinSCPReaderWriter(reader io.Reader, writer io.Writer, key []byte) error {
+	bufReader := bufio.NewReader(reader)
+	_, err := bufReader.Peek(1)
+	if err == io.EOF {
 		// empty file
 		return nil
 	}
 
+	if err != nil {
+		return errors.Wrapf(err, "failed to read data")
+	}
+
 	// write header
-	_, err = targetFileHandle.Write([]byte(WinSCPAesCtrHeader))
+	_, err = writer.Write([]byte(WinSCPAesCtrHeader))
 	if err != nil {
 		return errors.Wrapf(err, "failed to write header")
 	}
@@ -127,12 +134,12 @@ func EncryptFileWinSCP(source string, target string, key []byte) error {
 	}
 
 	// write salt
-	_, err = targetFileHandle.Write(salt)
+	_, err = writer.Write(salt)
 	if err != nil {
 		return errors.Wrapf(err, "failed to write salt")
 	}
 
-	err = EncryptAESCTRReaderWriter(sourceFileHandle, targetFileHandle, salt, key)
+	err = EncryptAESCTRReaderWriter(bufReader, writer, salt, key)
 	if err != nil {
 		return errors.Wrapf(err, "failed to encrypt file content")
 	}
@@ -155,10 +162,16 @@ func DecryptFileWinSCP(source string, target string, key []byte) error {
 
 	defer targetFileHandle.Close()
 
+	return DecryptWinSCPReaderWriter(sourceFileHandle, targetFileHandle, key)
+}
+
+// DecryptWinSCPReaderWriter decrypts data from reader to writer, nothing is written for empty data
+func DecryptWinSCPReaderWriter(reader io.Reader, writer io.Writer, key []byte) error {
 	header := make([]byte, 16)
 
-	readLen, err := sourceFileHandle.Read(header)
-	if err == io.EOF && readLen == 0 {
+	_, err := io.ReadFull(reader, header)
+	if err == io.EOF {
+		// empty file
 		return nil
 	}
 
@@ -171,16 +184,12 @@ func DecryptFileWinSCP(source string, target string, key []byte) error {
 	}
 
 	salt := make([]byte, AesSaltLen)
-	readLen, err = sourceFileHandle.Read(salt)
+	_, err = io.ReadFull(reader, salt)
 	if err != nil {
 		return errors.Wrapf(err, "failed to read salt")
 	}
 
-	if readLen != AesSaltLen {
-		return errors.Wrapf(err, "failed to read salt, read len %d", readLen)
-	}
-
-	err = DecryptAESCTRReaderWriter(sourceFileHandle, targetFileHandle, salt, key)
+	err = DecryptAESCTRReaderWriter(reader, writer, salt, key)
 	if err != nil {
 		return errors.Wrapf(err, "failed to decrypt file content")
 	}
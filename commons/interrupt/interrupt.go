@@ -0,0 +1,125 @@
+package interrupt
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"github.com/cyverse/gocommands/commons/terminal"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// AbortExitCode is the exit code when the process is aborted by a signal
+	AbortExitCode int = 130
+)
+
+// Handler handles interrupt signals (Ctrl-C) during transfers
+// On the first signal, cancel functions are called to stop scheduling new work while in-flight work completes
+// On the second signal, abort functions are called to clean up in-flight work, then the process exits
+type Handler struct {
+	cancelFuncs []func()
+	abortFuncs  []func()
+	signal      os.Signal
+	signalChan  chan os.Signal
+	stopChan    chan bool
+	stopOnce    sync.Once
+	mutex       sync.Mutex
+}
+
+// NewHandler creates a new Handler
+func NewHandler() *Handler {
+	return &Handler{
+		cancelFuncs: []func(){},
+		abortFuncs:  []func(){},
+		signal:      nil,
+		signalChan:  make(chan os.Signal, 2),
+		stopChan:    make(chan bool),
+		mutex:       sync.Mutex{},
+	}
+}
+
+// AddCancelFunc adds a function called on the first signal
+func (handler *Handler) AddCancelFunc(cancelFunc func()) {
+	handler.mutex.Lock()
+	defer handler.mutex.Unlock()
+
+	handler.cancelFuncs = append(handler.cancelFuncs, cancelFunc)
+}
+
+// AddAbortFunc adds a function called on the second signal, before the process exits
+func (handler *Handler) AddAbortFunc(abortFunc func()) {
+	handler.mutex.Lock()
+	defer handler.mutex.Unlock()
+
+	handler.abortFuncs = append(handler.abortFuncs, abortFunc)
+}
+
+// Start starts handling signals
+func (handler *Handler) Start() {
+	signal.Notify(handler.signalChan, os.Interrupt, syscall.SIGTERM)
+
+	go handler.run()
+}
+
+// Stop stops handling signals, signals are handled by default handler again
+func (handler *Handler) Stop() {
+	handler.stopOnce.Do(func() {
+		signal.Stop(handler.signalChan)
+		close(handler.stopChan)
+	})
+}
+
+// IsInterrupted returns true if a signal is received
+func (handler *Handler) IsInterrupted() bool {
+	handler.mutex.Lock()
+	defer handler.mutex.Unlock()
+
+	return handler.signal != nil
+}
+
+// GetSignal returns the signal received, nil if not interrupted
+func (handler *Handler) GetSignal() os.Signal {
+	handler.mutex.Lock()
+	defer handler.mutex.Unlock()
+
+	return handler.signal
+}
+
+func (handler *Handler) run() {
+	logger := log.WithFields(log.Fields{})
+
+	for {
+		select {
+		case <-handler.stopChan:
+			return
+		case sig := <-handler.signalChan:
+			handler.mutex.Lock()
+			firstSignal := handler.signal == nil
+			handler.signal = sig
+			cancelFuncs := handler.cancelFuncs
+			abortFuncs := handler.abortFuncs
+			handler.mutex.Unlock()
+
+			if firstSignal {
+				logger.Infof("received signal %q, canceling pending jobs", sig.String())
+				terminal.Printf("\nInterrupted, waiting for in-flight files to complete. Press Ctrl-C again to abort.\n")
+
+				for _, cancelFunc := range cancelFuncs {
+					cancelFunc()
+				}
+				continue
+			}
+
+			logger.Infof("received signal %q again, aborting in-flight jobs", sig.String())
+			terminal.Printf("\nAborting, removing incomplete files.\n")
+
+			for _, abortFunc := range abortFuncs {
+				abortFunc()
+			}
+
+			os.Exit(AbortExitCode)
+		}
+	}
+}
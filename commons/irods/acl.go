@@ -0,0 +1,198 @@
+package irods
+
+import (
+	"fmt"
+	"sort"
+
+	irodsclient_types "github.com/cyverse/go-irodsclient/irods/types"
+)
+
+type ACLDeviation string
+
+const (
+	// ACLDeviationNone means the access is the same as the parent collection
+	ACLDeviationNone ACLDeviation = ""
+	// ACLDeviationAdded means the user has access that the parent collection does not grant
+	ACLDeviationAdded ACLDeviation = "added"
+	// ACLDeviationChanged means the user has different access level from the parent collection
+	ACLDeviationChanged ACLDeviation = "changed"
+	// ACLDeviationRemoved means the user has access to the parent collection, but not to the entry
+	ACLDeviationRemoved ACLDeviation = "removed"
+)
+
+// ACLDiff is an access of a user compared to the parent collection
+type ACLDiff struct {
+	UserName     string
+	UserZone     string
+	UserType     irodsclient_types.IRODSUserType
+	AccessLevel  irodsclient_types.IRODSAccessLevelType
+	ParentAccess irodsclient_types.IRODSAccessLevelType
+	Deviation    ACLDeviation
+}
+
+// GetACLUserString returns user string of the access, groups are prefixed with 'g:'
+func GetACLUserString(userName string, userZone string, userType irodsclient_types.IRODSUserType) string {
+	if userType == irodsclient_types.IRODSUserRodsGroup {
+		return fmt.Sprintf("g:%s#%s", userName, userZone)
+	}
+
+	return fmt.Sprintf("%s#%s", userName, userZone)
+}
+
+// GetUserString returns user string of the diff
+func (diff *ACLDiff) GetUserString() string {
+	return GetACLUserString(diff.UserName, diff.UserZone, diff.UserType)
+}
+
+// accessLevelOrder lists access levels from the lowest to the highest
+var accessLevelOrder = []irodsclient_types.IRODSAccessLevelType{
+	irodsclient_types.IRODSAccessLevelNull,
+	irodsclient_types.IRODSAccessLevelExecute,
+	irodsclient_types.IRODSAccessLevelReadAnnotation,
+	irodsclient_types.IRODSAccessLevelReadSystemMetadata,
+	irodsclient_types.IRODSAccessLevelReadMetadata,
+	irodsclient_types.IRODSAccessLevelReadObject,
+	irodsclient_types.IRODSAccessLevelWriteAnnotation,
+	irodsclient_types.IRODSAccessLevelCreateMetadata,
+	irodsclient_types.IRODSAccessLevelModifyMetadata,
+	irodsclient_types.IRODSAccessLevelDeleteMetadata,
+	irodsclient_types.IRODSAccessLevelAdministerObject,
+	irodsclient_types.IRODSAccessLevelCreateObject,
+	irodsclient_types.IRODSAccessLevelModifyObject,
+	irodsclient_types.IRODSAccessLevelDeleteObject,
+	irodsclient_types.IRODSAccessLevelCreateToken,
+	irodsclient_types.IRODSAccessLevelDeleteToken,
+	irodsclient_types.IRODSAccessLevelCurate,
+	irodsclient_types.IRODSAccessLevelOwner,
+}
+
+func getAccessLevelRank(accessLevel irodsclient_types.IRODSAccessLevelType) int {
+	for rank, level := range accessLevelOrder {
+		if level == accessLevel {
+			return rank
+		}
+	}
+
+	return 0
+}
+
+// IsHigherAccessLevel returns true if the access level grants more than the other
+func IsHigherAccessLevel(accessLevel irodsclient_types.IRODSAccessLevelType, other irodsclient_types.IRODSAccessLevelType) bool {
+	return getAccessLevelRank(accessLevel) > getAccessLevelRank(other)
+}
+
+// GetEffectiveACLs returns effective accesses, accesses of groups are kept and each user gets the highest of its own access and accesses of its groups
+// groupMembers maps user strings of groups (see GetACLUserString) to their members, users having access only through groups are added
+func GetEffectiveACLs(accesses []*irodsclient_types.IRODSAccess, groupMembers map[string][]*irodsclient_types.IRODSUser) []*irodsclient_types.IRODSAccess {
+	effectiveAccesses := []*irodsclient_types.IRODSAccess{}
+	userAccessMap := map[string]*irodsclient_types.IRODSAccess{}
+
+	grant := func(path string, userName string, userZone string, userType irodsclient_types.IRODSUserType, accessLevel irodsclient_types.IRODSAccessLevelType) {
+		key := GetACLUserString(userName, userZone, userType)
+		if userAccess, ok := userAccessMap[key]; ok {
+			if IsHigherAccessLevel(accessLevel, userAccess.AccessLevel) {
+				userAccess.AccessLevel = accessLevel
+			}
+			return
+		}
+
+		userAccess := &irodsclient_types.IRODSAccess{
+			Path:        path,
+			UserName:    userName,
+			UserZone:    userZone,
+			UserType:    userType,
+			AccessLevel: accessLevel,
+		}
+		userAccessMap[key] = userAccess
+		effectiveAccesses = append(effectiveAccesses, userAccess)
+	}
+
+	for _, access := range accesses {
+		if access.UserType == irodsclient_types.IRODSUserRodsGroup {
+			groupAccess := *access
+			effectiveAccesses = append(effectiveAccesses, &groupAccess)
+			continue
+		}
+
+		grant(access.Path, access.UserName, access.UserZone, access.UserType, access.AccessLevel)
+	}
+
+	for _, access := range accesses {
+		if access.UserType != irodsclient_types.IRODSUserRodsGroup {
+			continue
+		}
+
+		for _, member := range groupMembers[GetACLUserString(access.UserName, access.UserZone, access.UserType)] {
+			if member.IsGroup() {
+				continue
+			}
+
+			grant(access.Path, member.Name, member.Zone, member.Type, access.AccessLevel)
+		}
+	}
+
+	return effectiveAccesses
+}
+
+// DiffACLs compares accesses of an entry with accesses of its parent collection, returns diffs sorted by user
+func DiffACLs(parentAccesses []*irodsclient_types.IRODSAccess, accesses []*irodsclient_types.IRODSAccess) []ACLDiff {
+	diffMap := map[string]*ACLDiff{}
+
+	for _, access := range accesses {
+		key := GetACLUserString(access.UserName, access.UserZone, access.UserType)
+		diffMap[key] = &ACLDiff{
+			UserName:     access.UserName,
+			UserZone:     access.UserZone,
+			UserType:     access.UserType,
+			AccessLevel:  access.AccessLevel,
+			ParentAccess: irodsclient_types.IRODSAccessLevelNull,
+			Deviation:    ACLDeviationAdded,
+		}
+	}
+
+	for _, parentAccess := range parentAccesses {
+		key := GetACLUserString(parentAccess.UserName, parentAccess.UserZone, parentAccess.UserType)
+		if diff, ok := diffMap[key]; ok {
+			diff.ParentAccess = parentAccess.AccessLevel
+			if diff.AccessLevel == parentAccess.AccessLevel {
+				diff.Deviation = ACLDeviationNone
+			} else {
+				diff.Deviation = ACLDeviationChanged
+			}
+			continue
+		}
+
+		diffMap[key] = &ACLDiff{
+			UserName:     parentAccess.UserName,
+			UserZone:     parentAccess.UserZone,
+			UserType:     parentAccess.UserType,
+			AccessLevel:  irodsclient_types.IRODSAccessLevelNull,
+			ParentAccess: parentAccess.AccessLevel,
+			Deviation:    ACLDeviationRemoved,
+		}
+	}
+
+	keys := make([]string, 0, len(diffMap))
+	for key := range diffMap {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	diffs := make([]ACLDiff, 0, len(keys))
+	for _, key := range keys {
+		diffs = append(diffs, *diffMap[key])
+	}
+
+	return diffs
+}
+
+// HasACLDeviation returns true if any of diffs deviates from the parent collection
+func HasACLDeviation(diffs []ACLDiff) bool {
+	for _, diff := range diffs {
+		if diff.Deviation != ACLDeviationNone {
+			return true
+		}
+	}
+
+	return false
+}
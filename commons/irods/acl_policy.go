@@ -0,0 +1,251 @@
+package irods
+
+import (
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	irodsclient_fs "github.com/cyverse/go-irodsclient/fs"
+	irodsclient_types "github.com/cyverse/go-irodsclient/irods/types"
+	"gopkg.in/yaml.v3"
+)
+
+// ACLPolicy is a declarative ACL policy, read from a YAML file
+type ACLPolicy struct {
+	Rules []ACLPolicyRule `yaml:"rules"`
+}
+
+// ACLPolicyRule describes desired accesses for a path
+type ACLPolicyRule struct {
+	Path      string            `yaml:"path"`
+	Recursive bool              `yaml:"recursive,omitempty"`
+	Inherit   *bool             `yaml:"inherit,omitempty"`   // nil to keep current inheritance
+	Exclusive bool              `yaml:"exclusive,omitempty"` // remove accesses not listed
+	Access    []ACLPolicyAccess `yaml:"access,omitempty"`
+}
+
+// ACLPolicyAccess describes access level of a principal, principal is 'user', 'user#zone', 'g:group', or 'g:group#zone'
+type ACLPolicyAccess struct {
+	Principal string `yaml:"principal"`
+	Level     string `yaml:"level"`
+}
+
+// ACLAction is a change to be made to match the policy
+type ACLAction struct {
+	Path          string
+	UserName      string // empty for inheritance change
+	UserZone      string
+	CurrentAccess irodsclient_types.IRODSAccessLevelType
+	DesiredAccess irodsclient_types.IRODSAccessLevelType
+	Inherit       *bool
+}
+
+// IsInheritanceChange returns true if the action changes inheritance
+func (action *ACLAction) IsInheritanceChange() bool {
+	return action.Inherit != nil
+}
+
+// ACLDesiredState is the desired ACL state of an entry, merged from policy rules
+type ACLDesiredState struct {
+	Accesses  map[string]irodsclient_types.IRODSAccessLevelType // key is user#zone
+	Inherit   *bool
+	Exclusive bool
+}
+
+// NewACLPolicyFromFile reads ACL policy from a YAML file
+func NewACLPolicyFromFile(policyPath string) (*ACLPolicy, error) {
+	policyBytes, err := os.ReadFile(policyPath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read ACL policy file %q", policyPath)
+	}
+
+	policy, err := NewACLPolicyFromYAML(policyBytes)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse ACL policy file %q", policyPath)
+	}
+
+	return policy, nil
+}
+
+// NewACLPolicyFromYAML parses ACL policy from YAML
+func NewACLPolicyFromYAML(yamlBytes []byte) (*ACLPolicy, error) {
+	policy := &ACLPolicy{}
+	err := yaml.Unmarshal(yamlBytes, policy)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to unmarshal YAML")
+	}
+
+	err = policy.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return policy, nil
+}
+
+// Validate validates the policy
+func (policy *ACLPolicy) Validate() error {
+	if len(policy.Rules) == 0 {
+		return errors.New("no rules are given")
+	}
+
+	for ruleIdx, rule := range policy.Rules {
+		if len(rule.Path) == 0 {
+			return errors.Errorf("path is not given for rule %d", ruleIdx)
+		}
+
+		for _, access := range rule.Access {
+			if len(strings.TrimSpace(access.Principal)) == 0 {
+				return errors.Errorf("principal is not given for rule %d (%q)", ruleIdx, rule.Path)
+			}
+
+			if strings.ToLower(strings.TrimSpace(access.Level)) != string(irodsclient_types.IRODSAccessLevelNull) &&
+				irodsclient_types.GetIRODSAccessLevelType(access.Level) == irodsclient_types.IRODSAccessLevelNull {
+				return errors.Errorf("unknown access level %q for principal %q in rule %d (%q)", access.Level, access.Principal, ruleIdx, rule.Path)
+			}
+		}
+	}
+
+	return nil
+}
+
+// ParseACLPrincipal parses principal string, returns user name and zone, and whether it is a group
+func ParseACLPrincipal(principal string, defaultZone string) (string, string, bool) {
+	principal = strings.TrimSpace(principal)
+
+	isGroup := false
+	if strings.HasPrefix(principal, "g:") {
+		isGroup = true
+		principal = principal[2:]
+	}
+
+	userName := principal
+	zone := defaultZone
+	if idx := strings.LastIndex(principal, "#"); idx >= 0 {
+		userName = principal[:idx]
+		zone = principal[idx+1:]
+	}
+
+	return userName, zone, isGroup
+}
+
+// MergeACLDesiredState merges the rule into the desired state, later rules override earlier rules
+func MergeACLDesiredState(state *ACLDesiredState, rule *ACLPolicyRule, defaultZone string) *ACLDesiredState {
+	if state == nil {
+		state = &ACLDesiredState{
+			Accesses: map[string]irodsclient_types.IRODSAccessLevelType{},
+		}
+	}
+
+	if rule.Exclusive {
+		// exclusive rule replaces accesses of earlier rules
+		state.Accesses = map[string]irodsclient_types.IRODSAccessLevelType{}
+		state.Exclusive = true
+	}
+
+	for _, access := range rule.Access {
+		userName, zone, _ := ParseACLPrincipal(access.Principal, defaultZone)
+		state.Accesses[userName+"#"+zone] = irodsclient_types.GetIRODSAccessLevelType(access.Level)
+	}
+
+	if rule.Inherit != nil {
+		inherit := *rule.Inherit
+		state.Inherit = &inherit
+	}
+
+	return state
+}
+
+// ComputeACLActions computes actions to make current accesses match the desired state
+// Own access of protectedUser is never removed by exclusive rules, to avoid locking the user out
+func ComputeACLActions(entryPath string, isDir bool, currentAccesses []*irodsclient_types.IRODSAccess, currentInherit bool, state *ACLDesiredState, protectedUser string) []ACLAction {
+	actions := []ACLAction{}
+
+	currentMap := map[string]*irodsclient_types.IRODSAccess{}
+	for _, access := range currentAccesses {
+		currentMap[access.UserName+"#"+access.UserZone] = access
+	}
+
+	keys := make([]string, 0, len(state.Accesses))
+	for key := range state.Accesses {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		desiredAccess := state.Accesses[key]
+		currentAccess := irodsclient_types.IRODSAccessLevelNull
+		if access, ok := currentMap[key]; ok {
+			currentAccess = access.AccessLevel
+		}
+
+		if currentAccess == desiredAccess {
+			continue
+		}
+
+		userName, zone, _ := ParseACLPrincipal(key, "")
+		actions = append(actions, ACLAction{
+			Path:          entryPath,
+			UserName:      userName,
+			UserZone:      zone,
+			CurrentAccess: currentAccess,
+			DesiredAccess: desiredAccess,
+		})
+	}
+
+	if state.Exclusive {
+		currentKeys := make([]string, 0, len(currentMap))
+		for key := range currentMap {
+			currentKeys = append(currentKeys, key)
+		}
+		sort.Strings(currentKeys)
+
+		for _, key := range currentKeys {
+			if _, ok := state.Accesses[key]; ok {
+				continue
+			}
+
+			access := currentMap[key]
+			if key == protectedUser && access.AccessLevel == irodsclient_types.IRODSAccessLevelOwner {
+				continue
+			}
+
+			actions = append(actions, ACLAction{
+				Path:          entryPath,
+				UserName:      access.UserName,
+				UserZone:      access.UserZone,
+				CurrentAccess: access.AccessLevel,
+				DesiredAccess: irodsclient_types.IRODSAccessLevelNull,
+			})
+		}
+	}
+
+	if isDir && state.Inherit != nil && *state.Inherit != currentInherit {
+		inherit := *state.Inherit
+		actions = append(actions, ACLAction{
+			Path:    entryPath,
+			Inherit: &inherit,
+		})
+	}
+
+	return actions
+}
+
+// ApplyACLAction makes the change of the action
+func ApplyACLAction(filesystem *irodsclient_fs.FileSystem, action *ACLAction) error {
+	if action.IsInheritanceChange() {
+		err := filesystem.ChangeDirACLInheritance(action.Path, *action.Inherit, false, false)
+		if err != nil {
+			return errors.Wrapf(err, "failed to change inheritance of %q", action.Path)
+		}
+		return nil
+	}
+
+	err := filesystem.ChangeACLs(action.Path, action.DesiredAccess, action.UserName, action.UserZone, false, false)
+	if err != nil {
+		return errors.Wrapf(err, "failed to change access of %q for %s#%s", action.Path, action.UserName, action.UserZone)
+	}
+
+	return nil
+}
@@ -0,0 +1,200 @@
+package irods
+
+import (
+	"testing"
+
+	irodsclient_types "github.com/cyverse/go-irodsclient/irods/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestACLPolicy(t *testing.T) {
+	t.Run("test MergeACLDesiredState", testMergeACLDesiredState)
+	t.Run("test ComputeACLActions", testComputeACLActions)
+}
+
+func testMergeACLDesiredState(t *testing.T) {
+	inherit := true
+
+	tests := []struct {
+		name              string
+		rules             []ACLPolicyRule
+		expectedAccesses  map[string]irodsclient_types.IRODSAccessLevelType
+		expectedExclusive bool
+		expectedInherit   *bool
+	}{
+		{
+			name: "later rules override earlier rules",
+			rules: []ACLPolicyRule{
+				{Path: "/zone/home/project", Access: []ACLPolicyAccess{{"alice", "read"}, {"g:lab", "read"}}},
+				{Path: "/zone/home/project/data", Access: []ACLPolicyAccess{{"alice#zone", "write"}}},
+			},
+			expectedAccesses: map[string]irodsclient_types.IRODSAccessLevelType{
+				"alice#zone": irodsclient_types.IRODSAccessLevelModifyObject,
+				"lab#zone":   irodsclient_types.IRODSAccessLevelReadObject,
+			},
+		},
+		{
+			name: "exclusive rule replaces accesses of earlier rules",
+			rules: []ACLPolicyRule{
+				{Path: "/zone/home/project", Access: []ACLPolicyAccess{{"alice", "read"}, {"g:lab", "read"}}},
+				{Path: "/zone/home/project/private", Exclusive: true, Access: []ACLPolicyAccess{{"bob#other", "own"}}},
+			},
+			expectedAccesses: map[string]irodsclient_types.IRODSAccessLevelType{
+				"bob#other": irodsclient_types.IRODSAccessLevelOwner,
+			},
+			expectedExclusive: true,
+		},
+		{
+			name: "exclusive stays after non-exclusive rules",
+			rules: []ACLPolicyRule{
+				{Path: "/zone/home/project", Exclusive: true, Access: []ACLPolicyAccess{{"alice", "own"}}},
+				{Path: "/zone/home/project/data", Inherit: &inherit, Access: []ACLPolicyAccess{{"bob", "read"}}},
+			},
+			expectedAccesses: map[string]irodsclient_types.IRODSAccessLevelType{
+				"alice#zone": irodsclient_types.IRODSAccessLevelOwner,
+				"bob#zone":   irodsclient_types.IRODSAccessLevelReadObject,
+			},
+			expectedExclusive: true,
+			expectedInherit:   &inherit,
+		},
+	}
+
+	for _, test := range tests {
+		var state *ACLDesiredState
+		for idx := range test.rules {
+			state = MergeACLDesiredState(state, &test.rules[idx], "zone")
+		}
+
+		assert.Equal(t, test.expectedAccesses, state.Accesses, test.name)
+		assert.Equal(t, test.expectedExclusive, state.Exclusive, test.name)
+		assert.Equal(t, test.expectedInherit, state.Inherit, test.name)
+	}
+}
+
+func testComputeACLActions(t *testing.T) {
+	enable := true
+
+	currentAccesses := []*irodsclient_types.IRODSAccess{
+		newTestAccess("alice", irodsclient_types.IRODSUserRodsUser, irodsclient_types.IRODSAccessLevelOwner),
+		newTestAccess("bob", irodsclient_types.IRODSUserRodsUser, irodsclient_types.IRODSAccessLevelReadObject),
+		newTestAccess("lab", irodsclient_types.IRODSUserRodsGroup, irodsclient_types.IRODSAccessLevelReadObject),
+	}
+
+	tests := []struct {
+		name          string
+		isDir         bool
+		state         *ACLDesiredState
+		protectedUser string
+		expected      []string // user#zone:current->desired, or inherit:value
+	}{
+		{
+			name:  "no change",
+			isDir: true,
+			state: &ACLDesiredState{
+				Accesses: map[string]irodsclient_types.IRODSAccessLevelType{
+					"bob#zone": irodsclient_types.IRODSAccessLevelReadObject,
+				},
+			},
+			expected: []string{},
+		},
+		{
+			name:  "non-exclusive keeps unlisted accesses",
+			isDir: true,
+			state: &ACLDesiredState{
+				Accesses: map[string]irodsclient_types.IRODSAccessLevelType{
+					"bob#zone":   irodsclient_types.IRODSAccessLevelModifyObject,
+					"carol#zone": irodsclient_types.IRODSAccessLevelReadObject,
+				},
+			},
+			expected: []string{
+				"bob#zone:read_object->modify_object",
+				"carol#zone:null->read_object",
+			},
+		},
+		{
+			name:  "exclusive removes unlisted accesses",
+			isDir: true,
+			state: &ACLDesiredState{
+				Accesses: map[string]irodsclient_types.IRODSAccessLevelType{
+					"carol#zone": irodsclient_types.IRODSAccessLevelReadObject,
+				},
+				Exclusive: true,
+			},
+			expected: []string{
+				"carol#zone:null->read_object",
+				"alice#zone:own->null",
+				"bob#zone:read_object->null",
+				"lab#zone:read_object->null",
+			},
+		},
+		{
+			name:  "exclusive does not remove own access of protected user",
+			isDir: true,
+			state: &ACLDesiredState{
+				Accesses:  map[string]irodsclient_types.IRODSAccessLevelType{},
+				Exclusive: true,
+			},
+			protectedUser: "alice#zone",
+			expected: []string{
+				"bob#zone:read_object->null",
+				"lab#zone:read_object->null",
+			},
+		},
+		{
+			name:  "explicit access of protected user is applied",
+			isDir: true,
+			state: &ACLDesiredState{
+				Accesses: map[string]irodsclient_types.IRODSAccessLevelType{
+					"alice#zone": irodsclient_types.IRODSAccessLevelReadObject,
+					"bob#zone":   irodsclient_types.IRODSAccessLevelReadObject,
+					"lab#zone":   irodsclient_types.IRODSAccessLevelReadObject,
+				},
+				Exclusive: true,
+			},
+			protectedUser: "alice#zone",
+			expected: []string{
+				"alice#zone:own->read_object",
+			},
+		},
+		{
+			name:  "inheritance is changed for collections only",
+			isDir: true,
+			state: &ACLDesiredState{
+				Accesses: map[string]irodsclient_types.IRODSAccessLevelType{},
+				Inherit:  &enable,
+			},
+			expected: []string{"inherit:true"},
+		},
+		{
+			name:  "inheritance is not changed for data objects",
+			isDir: false,
+			state: &ACLDesiredState{
+				Accesses: map[string]irodsclient_types.IRODSAccessLevelType{},
+				Inherit:  &enable,
+			},
+			expected: []string{},
+		},
+	}
+
+	for _, test := range tests {
+		actions := ComputeACLActions("/zone/home/project", test.isDir, currentAccesses, false, test.state, test.protectedUser)
+
+		actual := []string{}
+		for _, action := range actions {
+			assert.Equal(t, "/zone/home/project", action.Path, test.name)
+
+			if action.IsInheritanceChange() {
+				if *action.Inherit {
+					actual = append(actual, "inherit:true")
+				} else {
+					actual = append(actual, "inherit:false")
+				}
+				continue
+			}
+
+			actual = append(actual, action.UserName+"#"+action.UserZone+":"+string(action.CurrentAccess)+"->"+string(action.DesiredAccess))
+		}
+
+		assert.Equal(t, test.expected, actual, test.name)
+	}
+}
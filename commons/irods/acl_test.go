@@ -0,0 +1,139 @@
+package irods
+
+import (
+	"testing"
+
+	irodsclient_types "github.com/cyverse/go-irodsclient/irods/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestACL(t *testing.T) {
+	t.Run("test GetEffectiveACLs", testGetEffectiveACLs)
+	t.Run("test DiffACLs", testDiffACLs)
+}
+
+func newTestAccess(userName string, userType irodsclient_types.IRODSUserType, accessLevel irodsclient_types.IRODSAccessLevelType) *irodsclient_types.IRODSAccess {
+	return &irodsclient_types.IRODSAccess{
+		Path:        "/zone/home/project",
+		UserName:    userName,
+		UserZone:    "zone",
+		UserType:    userType,
+		AccessLevel: accessLevel,
+	}
+}
+
+func newTestUser(name string, userType irodsclient_types.IRODSUserType) *irodsclient_types.IRODSUser {
+	return &irodsclient_types.IRODSUser{
+		Name: name,
+		Zone: "zone",
+		Type: userType,
+	}
+}
+
+func testGetEffectiveACLs(t *testing.T) {
+	groupMembers := map[string][]*irodsclient_types.IRODSUser{
+		"g:lab#zone": {
+			newTestUser("lab", irodsclient_types.IRODSUserRodsGroup),
+			newTestUser("alice", irodsclient_types.IRODSUserRodsUser),
+			newTestUser("bob", irodsclient_types.IRODSUserRodsUser),
+		},
+		"g:readers#zone": {
+			newTestUser("alice", irodsclient_types.IRODSUserRodsUser),
+			newTestUser("carol", irodsclient_types.IRODSUserRodsUser),
+		},
+	}
+
+	tests := []struct {
+		name     string
+		accesses []*irodsclient_types.IRODSAccess
+		expected map[string]irodsclient_types.IRODSAccessLevelType
+	}{
+		{
+			name: "users only",
+			accesses: []*irodsclient_types.IRODSAccess{
+				newTestAccess("alice", irodsclient_types.IRODSUserRodsUser, irodsclient_types.IRODSAccessLevelOwner),
+			},
+			expected: map[string]irodsclient_types.IRODSAccessLevelType{
+				"alice#zone": irodsclient_types.IRODSAccessLevelOwner,
+			},
+		},
+		{
+			name: "members get access of group",
+			accesses: []*irodsclient_types.IRODSAccess{
+				newTestAccess("lab", irodsclient_types.IRODSUserRodsGroup, irodsclient_types.IRODSAccessLevelModifyObject),
+			},
+			expected: map[string]irodsclient_types.IRODSAccessLevelType{
+				"g:lab#zone": irodsclient_types.IRODSAccessLevelModifyObject,
+				"alice#zone": irodsclient_types.IRODSAccessLevelModifyObject,
+				"bob#zone":   irodsclient_types.IRODSAccessLevelModifyObject,
+			},
+		},
+		{
+			name: "highest access wins",
+			accesses: []*irodsclient_types.IRODSAccess{
+				newTestAccess("alice", irodsclient_types.IRODSUserRodsUser, irodsclient_types.IRODSAccessLevelReadObject),
+				newTestAccess("bob", irodsclient_types.IRODSUserRodsUser, irodsclient_types.IRODSAccessLevelOwner),
+				newTestAccess("readers", irodsclient_types.IRODSUserRodsGroup, irodsclient_types.IRODSAccessLevelReadObject),
+				newTestAccess("lab", irodsclient_types.IRODSUserRodsGroup, irodsclient_types.IRODSAccessLevelModifyObject),
+			},
+			expected: map[string]irodsclient_types.IRODSAccessLevelType{
+				"g:lab#zone":     irodsclient_types.IRODSAccessLevelModifyObject,
+				"g:readers#zone": irodsclient_types.IRODSAccessLevelReadObject,
+				"alice#zone":     irodsclient_types.IRODSAccessLevelModifyObject,
+				"bob#zone":       irodsclient_types.IRODSAccessLevelOwner,
+				"carol#zone":     irodsclient_types.IRODSAccessLevelReadObject,
+			},
+		},
+		{
+			name: "unknown group has no members",
+			accesses: []*irodsclient_types.IRODSAccess{
+				newTestAccess("others", irodsclient_types.IRODSUserRodsGroup, irodsclient_types.IRODSAccessLevelReadObject),
+			},
+			expected: map[string]irodsclient_types.IRODSAccessLevelType{
+				"g:others#zone": irodsclient_types.IRODSAccessLevelReadObject,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		effectiveAccesses := GetEffectiveACLs(test.accesses, groupMembers)
+
+		actual := map[string]irodsclient_types.IRODSAccessLevelType{}
+		for _, access := range effectiveAccesses {
+			actual[GetACLUserString(access.UserName, access.UserZone, access.UserType)] = access.AccessLevel
+		}
+
+		assert.Equal(t, test.expected, actual, test.name)
+		assert.Len(t, effectiveAccesses, len(test.expected), test.name)
+	}
+}
+
+func testDiffACLs(t *testing.T) {
+	parentAccesses := []*irodsclient_types.IRODSAccess{
+		newTestAccess("alice", irodsclient_types.IRODSUserRodsUser, irodsclient_types.IRODSAccessLevelOwner),
+		newTestAccess("bob", irodsclient_types.IRODSUserRodsUser, irodsclient_types.IRODSAccessLevelReadObject),
+		newTestAccess("lab", irodsclient_types.IRODSUserRodsGroup, irodsclient_types.IRODSAccessLevelReadObject),
+	}
+
+	accesses := []*irodsclient_types.IRODSAccess{
+		newTestAccess("alice", irodsclient_types.IRODSUserRodsUser, irodsclient_types.IRODSAccessLevelOwner),
+		newTestAccess("bob", irodsclient_types.IRODSUserRodsUser, irodsclient_types.IRODSAccessLevelModifyObject),
+		newTestAccess("carol", irodsclient_types.IRODSUserRodsUser, irodsclient_types.IRODSAccessLevelReadObject),
+	}
+
+	diffs := DiffACLs(parentAccesses, accesses)
+
+	actual := map[string]ACLDeviation{}
+	for _, diff := range diffs {
+		actual[diff.GetUserString()] = diff.Deviation
+	}
+
+	assert.Equal(t, map[string]ACLDeviation{
+		"alice#zone": ACLDeviationNone,
+		"bob#zone":   ACLDeviationChanged,
+		"carol#zone": ACLDeviationAdded,
+		"g:lab#zone": ACLDeviationRemoved,
+	}, actual)
+	assert.True(t, HasACLDeviation(diffs))
+	assert.False(t, HasACLDeviation(DiffACLs(accesses, accesses)))
+}
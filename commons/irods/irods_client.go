@@ -12,7 +12,7 @@ import (
 
 // GetIRODSFSClient returns a file system client
 func GetIRODSFSClient(account *irodsclient_types.IRODSAccount, infiniteCache bool, timeout int) (*irodsclient_fs.FileSystem, error) {
-	fsConfig := irodsclient_fs.NewFileSystemConfig(config.ClientProgramName)
+	fsConfig := irodsclient_fs.NewFileSystemConfig(config.GetClientProgramName())
 
 	// set operation time out
 	fsConfig.MetadataConnection.OperationTimeout = config.FilesystemTimeout
@@ -51,7 +51,7 @@ func GetIRODSFSClient(account *irodsclient_types.IRODSAccount, infiniteCache boo
 
 // GetIRODSFSClientForLargeFileIO returns a file system client
 func GetIRODSFSClientForLargeFileIO(account *irodsclient_types.IRODSAccount, maxIOConnection int, tcpBufferSize int, infiniteCache bool, timeout int) (*irodsclient_fs.FileSystem, error) {
-	fsConfig := irodsclient_fs.NewFileSystemConfig(config.ClientProgramName)
+	fsConfig := irodsclient_fs.NewFileSystemConfig(config.GetClientProgramName())
 
 	if infiniteCache {
 		// set infinite cache timeout
@@ -97,7 +97,7 @@ func GetIRODSFSClientForLargeFileIO(account *irodsclient_types.IRODSAccount, max
 // used for init subcommand
 func GetIRODSConnection(account *irodsclient_types.IRODSAccount) (*irodsclient_conn.IRODSConnection, error) {
 	config := irodsclient_conn.IRODSConnectionConfig{
-		ApplicationName: config.ClientProgramName,
+		ApplicationName: config.GetClientProgramName(),
 	}
 
 	conn, err := irodsclient_conn.NewIRODSConnection(account, &config)
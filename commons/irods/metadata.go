@@ -0,0 +1,390 @@
+package irods
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	irodsclient_fs "github.com/cyverse/go-irodsclient/fs"
+	"github.com/cyverse/go-irodsclient/irods/message"
+	irodsclient_types "github.com/cyverse/go-irodsclient/irods/types"
+)
+
+const (
+	// MetadataSidecarSuffix is the suffix of sidecar files that hold AVUs of a data object or collection
+	MetadataSidecarSuffix = ".meta.json"
+)
+
+// MetadataTargetType is a type of iRODS objects having metadata
+type MetadataTargetType string
+
+const (
+	// MetadataTargetPath is for data objects and collections
+	MetadataTargetPath MetadataTargetType = "path"
+	// MetadataTargetUser is for users and groups
+	MetadataTargetUser MetadataTargetType = "user"
+	// MetadataTargetResource is for resources
+	MetadataTargetResource MetadataTargetType = "resource"
+)
+
+// MetadataSidecar is the content of a sidecar file
+type MetadataSidecar struct {
+	Path     string               `json:"path"`
+	Metadata []MetadataSidecarAVU `json:"metadata"`
+}
+
+// MetadataSidecarAVU is an AVU stored in a sidecar file
+type MetadataSidecarAVU struct {
+	Attribute string `json:"attribute"`
+	Value     string `json:"value"`
+	Units     string `json:"units,omitempty"`
+}
+
+// GetMetadataSidecarPath returns sidecar file path for the local path
+func GetMetadataSidecarPath(localPath string) string {
+	return strings.TrimRight(localPath, "/") + MetadataSidecarSuffix
+}
+
+// IsMetadataSidecarPath returns true if the path is a sidecar file
+func IsMetadataSidecarPath(localPath string) bool {
+	return strings.HasSuffix(localPath, MetadataSidecarSuffix)
+}
+
+// ExportMetadataToSidecar writes AVUs of the iRODS path to a sidecar file of the local path
+// No sidecar file is written if the iRODS path has no AVUs
+func ExportMetadataToSidecar(filesystem *irodsclient_fs.FileSystem, irodsPath string, localPath string) error {
+	metas, err := ListMetadataForTarget(filesystem, MetadataTargetPath, irodsPath, "")
+	if err != nil {
+		return errors.Wrapf(err, "failed to list metadata for %q", irodsPath)
+	}
+
+	if len(metas) == 0 {
+		return nil
+	}
+
+	sidecar := MetadataSidecar{
+		Path:     irodsPath,
+		Metadata: make([]MetadataSidecarAVU, 0, len(metas)),
+	}
+
+	for _, meta := range metas {
+		sidecar.Metadata = append(sidecar.Metadata, MetadataSidecarAVU{
+			Attribute: meta.Name,
+			Value:     meta.Value,
+			Units:     meta.Units,
+		})
+	}
+
+	sidecarBytes, err := json.MarshalIndent(sidecar, "", "  ")
+	if err != nil {
+		return errors.Wrapf(err, "failed to marshal metadata for %q", irodsPath)
+	}
+
+	sidecarPath := GetMetadataSidecarPath(localPath)
+	err = os.WriteFile(sidecarPath, sidecarBytes, 0644)
+	if err != nil {
+		return errors.Wrapf(err, "failed to write metadata sidecar file %q", sidecarPath)
+	}
+
+	return nil
+}
+
+// ImportMetadataFromSidecar adds AVUs in the sidecar file of the local path to the iRODS path
+// It does nothing if the sidecar file does not exist, AVUs already present are skipped
+func ImportMetadataFromSidecar(filesystem *irodsclient_fs.FileSystem, localPath string, irodsPath string) error {
+	sidecar, err := readMetadataSidecar(localPath)
+	if err != nil {
+		return err
+	}
+
+	if sidecar == nil {
+		return nil
+	}
+
+	metas := make([]*irodsclient_types.IRODSMeta, 0, len(sidecar.Metadata))
+	for _, avu := range sidecar.Metadata {
+		metas = append(metas, &irodsclient_types.IRODSMeta{
+			Name:  avu.Attribute,
+			Value: avu.Value,
+			Units: avu.Units,
+		})
+	}
+
+	return addMissingMetadata(filesystem, irodsPath, metas)
+}
+
+// readMetadataSidecar reads the sidecar file of the local path, returns nil if the file does not exist
+func readMetadataSidecar(localPath string) (*MetadataSidecar, error) {
+	sidecarPath := GetMetadataSidecarPath(localPath)
+
+	sidecarBytes, err := os.ReadFile(sidecarPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+
+		return nil, errors.Wrapf(err, "failed to read metadata sidecar file %q", sidecarPath)
+	}
+
+	sidecar := &MetadataSidecar{}
+	err = json.Unmarshal(sidecarBytes, sidecar)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse metadata sidecar file %q", sidecarPath)
+	}
+
+	return sidecar, nil
+}
+
+// CopyMetadata adds AVUs of the source iRODS path to the target iRODS path, AVUs already present are skipped
+func CopyMetadata(filesystem *irodsclient_fs.FileSystem, sourcePath string, targetPath string) error {
+	metas, err := ListMetadataForTarget(filesystem, MetadataTargetPath, sourcePath, "")
+	if err != nil {
+		return errors.Wrapf(err, "failed to list metadata for %q", sourcePath)
+	}
+
+	return addMissingMetadata(filesystem, targetPath, metas)
+}
+
+func addMissingMetadata(filesystem *irodsclient_fs.FileSystem, irodsPath string, metas []*irodsclient_types.IRODSMeta) error {
+	if len(metas) == 0 {
+		return nil
+	}
+
+	existingMetas, err := ListMetadataForTarget(filesystem, MetadataTargetPath, irodsPath, "")
+	if err != nil {
+		return errors.Wrapf(err, "failed to list metadata for %q", irodsPath)
+	}
+
+	existing := map[string]bool{}
+	for _, meta := range existingMetas {
+		existing[meta.Name+"\x00"+meta.Value+"\x00"+meta.Units] = true
+	}
+
+	for _, meta := range metas {
+		key := meta.Name + "\x00" + meta.Value + "\x00" + meta.Units
+		if existing[key] {
+			continue
+		}
+
+		err = AddMetadataForTarget(filesystem, MetadataTargetPath, irodsPath, "", meta.Name, meta.Value, meta.Units)
+		if err != nil {
+			return errors.Wrapf(err, "failed to add metadata %q to %q", meta.Name, irodsPath)
+		}
+
+		existing[key] = true
+	}
+
+	return nil
+}
+
+// CopyACLs makes accesses and inheritance of the target iRODS path match the source iRODS path
+// Accesses of the target not given to the source are removed, except own access of protectedUser to avoid locking the user out
+func CopyACLs(filesystem *irodsclient_fs.FileSystem, sourcePath string, targetPath string, isDir bool, protectedUser string) error {
+	sourceAccesses, sourceInherit, err := listACLs(filesystem, sourcePath, isDir)
+	if err != nil {
+		return err
+	}
+
+	targetAccesses, targetInherit, err := listACLs(filesystem, targetPath, isDir)
+	if err != nil {
+		return err
+	}
+
+	state := &ACLDesiredState{
+		Accesses:  map[string]irodsclient_types.IRODSAccessLevelType{},
+		Exclusive: true,
+	}
+
+	for _, access := range sourceAccesses {
+		state.Accesses[access.UserName+"#"+access.UserZone] = access.AccessLevel
+	}
+
+	if isDir {
+		state.Inherit = &sourceInherit
+	}
+
+	actions := ComputeACLActions(targetPath, isDir, targetAccesses, targetInherit, state, protectedUser)
+	for actionIdx := range actions {
+		err = ApplyACLAction(filesystem, &actions[actionIdx])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// listACLs returns accesses of the iRODS path, and inheritance if it is a collection
+func listACLs(filesystem *irodsclient_fs.FileSystem, irodsPath string, isDir bool) ([]*irodsclient_types.IRODSAccess, bool, error) {
+	if !isDir {
+		accesses, err := filesystem.ListFileACLs(irodsPath)
+		if err != nil {
+			return nil, false, errors.Wrapf(err, "failed to list ACLs for %q", irodsPath)
+		}
+
+		return accesses, false, nil
+	}
+
+	accesses, err := filesystem.ListDirACLs(irodsPath)
+	if err != nil {
+		return nil, false, errors.Wrapf(err, "failed to list ACLs for %q", irodsPath)
+	}
+
+	inherit, err := filesystem.GetDirACLInheritance(irodsPath)
+	if err != nil {
+		return nil, false, errors.Wrapf(err, "failed to get inheritance for collection %q", irodsPath)
+	}
+
+	return accesses, inherit.Inheritance, nil
+}
+
+// CopyModifyTime sets modification time of the target data object to the source data object's
+func CopyModifyTime(filesystem *irodsclient_fs.FileSystem, sourcePath string, targetPath string) error {
+	err := filesystem.Touch(targetPath, "", true, nil, sourcePath, nil)
+	if err != nil {
+		return errors.Wrapf(err, "failed to set modification time of %q", targetPath)
+	}
+
+	return nil
+}
+
+// ParseMetadataUser parses user string 'user' or 'user#zone', returns user name and zone
+func ParseMetadataUser(user string, defaultZone string) (string, string) {
+	if idx := strings.LastIndex(user, "#"); idx >= 0 {
+		return user[:idx], user[idx+1:]
+	}
+
+	return user, defaultZone
+}
+
+// ListMetadataForTarget lists AVUs of the target
+func ListMetadataForTarget(filesystem *irodsclient_fs.FileSystem, targetType MetadataTargetType, target string, defaultZone string) ([]*irodsclient_types.IRODSMeta, error) {
+	switch targetType {
+	case MetadataTargetUser:
+		username, zone := ParseMetadataUser(target, defaultZone)
+		return filesystem.ListUserMetadata(username, zone)
+	case MetadataTargetResource:
+		return filesystem.ListResourceMetadata(target)
+	default:
+		return filesystem.ListMetadata(target)
+	}
+}
+
+// AddMetadataForTarget adds an AVU to the target
+func AddMetadataForTarget(filesystem *irodsclient_fs.FileSystem, targetType MetadataTargetType, target string, defaultZone string, attName string, attValue string, attUnits string) error {
+	switch targetType {
+	case MetadataTargetUser:
+		username, zone := ParseMetadataUser(target, defaultZone)
+		return filesystem.AddUserMetadata(username, zone, attName, attValue, attUnits)
+	case MetadataTargetResource:
+		return filesystem.AddResourceMetadata(target, attName, attValue, attUnits)
+	default:
+		return filesystem.AddMetadata(target, attName, attValue, attUnits)
+	}
+}
+
+// RemoveMetadataForTarget removes AVUs from the target, all AVUs having the attribute are removed if value and units are empty
+func RemoveMetadataForTarget(filesystem *irodsclient_fs.FileSystem, targetType MetadataTargetType, target string, defaultZone string, attName string, attValue string, attUnits string) error {
+	byName := len(attValue) == 0 && len(attUnits) == 0
+
+	switch targetType {
+	case MetadataTargetUser:
+		username, zone := ParseMetadataUser(target, defaultZone)
+		if byName {
+			return filesystem.DeleteUserMetadataByName(username, zone, attName)
+		}
+		return filesystem.DeleteUserMetadataByAVU(username, zone, attName, attValue, attUnits)
+	case MetadataTargetResource:
+		if byName {
+			return filesystem.DeleteResourceMetadataByName(target, attName)
+		}
+		return filesystem.DeleteResourceMetadataByAVU(target, attName, attValue, attUnits)
+	default:
+		if byName {
+			return filesystem.DeleteMetadataByName(target, attName)
+		}
+		return filesystem.DeleteMetadataByAVU(target, attName, attValue, attUnits)
+	}
+}
+
+// SetMetadataForTarget sets value and units of the attribute of the target in a single request (imeta set semantics)
+// Existing AVUs having the attribute are replaced, the AVU is added if the attribute does not exist
+func SetMetadataForTarget(filesystem *irodsclient_fs.FileSystem, targetType MetadataTargetType, target string, defaultZone string, attName string, attValue string, attUnits string) error {
+	itemType, itemName := getMetadataItem(filesystem, targetType, target, defaultZone)
+
+	meta := &irodsclient_types.IRODSMeta{
+		Name:  attName,
+		Value: attValue,
+		Units: attUnits,
+	}
+
+	request := message.NewIRODSMessageSetMetadataRequest(itemType, itemName, meta)
+	err := requestModifyMetadata(filesystem, request)
+	if err != nil {
+		return errors.Wrapf(err, "failed to set metadata %q of %q", attName, target)
+	}
+
+	if targetType == MetadataTargetPath {
+		filesystem.InvalidateCacheForPath(target)
+	}
+
+	return nil
+}
+
+// ModifyMetadataForTarget changes value and units of an existing AVU of the target in a single request (imeta mod semantics)
+// Units are kept if attUnits is empty
+func ModifyMetadataForTarget(filesystem *irodsclient_fs.FileSystem, targetType MetadataTargetType, target string, defaultZone string, oldMeta *irodsclient_types.IRODSMeta, attValue string, attUnits string) error {
+	itemType, itemName := getMetadataItem(filesystem, targetType, target, defaultZone)
+
+	// iRODS takes new name, value, and units with prefixes 'n:', 'v:', and 'u:', empty ones are kept
+	newMeta := &irodsclient_types.IRODSMeta{
+		Value: fmt.Sprintf("v:%s", attValue),
+	}
+
+	if len(attUnits) > 0 {
+		newMeta.Units = fmt.Sprintf("u:%s", attUnits)
+	}
+
+	request := message.NewIRODSMessageReplaceMetadataRequest(itemType, itemName, oldMeta, newMeta)
+	err := requestModifyMetadata(filesystem, request)
+	if err != nil {
+		return errors.Wrapf(err, "failed to modify metadata %q (id %d) of %q", oldMeta.Name, oldMeta.AVUID, target)
+	}
+
+	if targetType == MetadataTargetPath {
+		filesystem.InvalidateCacheForPath(target)
+	}
+
+	return nil
+}
+
+func getMetadataItem(filesystem *irodsclient_fs.FileSystem, targetType MetadataTargetType, target string, defaultZone string) (irodsclient_types.IRODSMetaItemType, string) {
+	switch targetType {
+	case MetadataTargetUser:
+		username, zone := ParseMetadataUser(target, defaultZone)
+		return irodsclient_types.IRODSUserMetaItemType, fmt.Sprintf("%s#%s", username, zone)
+	case MetadataTargetResource:
+		return irodsclient_types.IRODSResourceMetaItemType, target
+	default:
+		if filesystem.ExistsDir(target) {
+			return irodsclient_types.IRODSCollectionMetaItemType, target
+		}
+		return irodsclient_types.IRODSDataObjectMetaItemType, target
+	}
+}
+
+func requestModifyMetadata(filesystem *irodsclient_fs.FileSystem, request *message.IRODSMessageModifyMetadataRequest) error {
+	conn, err := filesystem.GetMetadataConnection(true)
+	if err != nil {
+		return errors.Wrapf(err, "failed to get connection")
+	}
+	defer filesystem.ReturnMetadataConnection(conn) //nolint
+
+	conn.Lock()
+	defer conn.Unlock()
+
+	response := message.IRODSMessageModifyMetadataResponse{}
+	return conn.RequestAndCheck(request, &response, nil, conn.GetOperationTimeout())
+}
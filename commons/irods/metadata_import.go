@@ -0,0 +1,235 @@
+package irods
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	irodsclient_fs "github.com/cyverse/go-irodsclient/fs"
+)
+
+// MetadataOperation is an operation on an AVU
+type MetadataOperation string
+
+const (
+	// MetadataOperationAdd adds an AVU
+	MetadataOperationAdd MetadataOperation = "add"
+	// MetadataOperationSet replaces AVUs having the attribute
+	MetadataOperationSet MetadataOperation = "set"
+	// MetadataOperationRemove removes an AVU, or all AVUs having the attribute if value and units are empty
+	MetadataOperationRemove MetadataOperation = "rm"
+)
+
+// GetMetadataOperation returns MetadataOperation from string, empty string is add
+func GetMetadataOperation(op string) (MetadataOperation, error) {
+	switch strings.ToLower(strings.TrimSpace(op)) {
+	case "", string(MetadataOperationAdd):
+		return MetadataOperationAdd, nil
+	case string(MetadataOperationSet):
+		return MetadataOperationSet, nil
+	case string(MetadataOperationRemove), "remove", "del", "delete":
+		return MetadataOperationRemove, nil
+	default:
+		return "", errors.Errorf("unknown metadata operation %q", op)
+	}
+}
+
+// MetadataRecord is an operation on an AVU of a target, read from an import file
+type MetadataRecord struct {
+	Target    string
+	Attribute string
+	Value     string
+	Units     string
+	Operation MetadataOperation
+}
+
+// Apply applies the record to the target
+func (record *MetadataRecord) Apply(filesystem *irodsclient_fs.FileSystem, targetType MetadataTargetType, defaultZone string) error {
+	switch record.Operation {
+	case MetadataOperationSet:
+		return SetMetadataForTarget(filesystem, targetType, record.Target, defaultZone, record.Attribute, record.Value, record.Units)
+	case MetadataOperationRemove:
+		return RemoveMetadataForTarget(filesystem, targetType, record.Target, defaultZone, record.Attribute, record.Value, record.Units)
+	default:
+		return AddMetadataForTarget(filesystem, targetType, record.Target, defaultZone, record.Attribute, record.Value, record.Units)
+	}
+}
+
+type metadataRecordField int
+
+const (
+	metadataRecordFieldUnknown metadataRecordField = iota
+	metadataRecordFieldTarget
+	metadataRecordFieldAttribute
+	metadataRecordFieldValue
+	metadataRecordFieldUnits
+	metadataRecordFieldOperation
+)
+
+func getMetadataRecordField(name string) metadataRecordField {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "path", "target", "user", "resource":
+		return metadataRecordFieldTarget
+	case "attribute", "attr", "name":
+		return metadataRecordFieldAttribute
+	case "value":
+		return metadataRecordFieldValue
+	case "unit", "units":
+		return metadataRecordFieldUnits
+	case "op", "operation":
+		return metadataRecordFieldOperation
+	default:
+		return metadataRecordFieldUnknown
+	}
+}
+
+// ReadMetadataRecordsFromFile reads metadata records from a CSV or JSON file, decided by the file extension
+func ReadMetadataRecordsFromFile(filePath string) ([]MetadataRecord, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open file %q", filePath)
+	}
+	defer f.Close()
+
+	var records []MetadataRecord
+	if strings.ToLower(filepath.Ext(filePath)) == ".json" {
+		records, err = ReadMetadataRecordsFromJSON(f)
+	} else {
+		records, err = ReadMetadataRecordsFromCSV(f)
+	}
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read metadata records from %q", filePath)
+	}
+
+	return records, nil
+}
+
+// ReadMetadataRecordsFromCSV reads metadata records from CSV
+// Columns are path, attribute, value, unit, and op, unless the first row is a header naming the columns
+func ReadMetadataRecordsFromCSV(reader io.Reader) ([]MetadataRecord, error) {
+	csvReader := csv.NewReader(reader)
+	csvReader.FieldsPerRecord = -1
+	csvReader.TrimLeadingSpace = true
+	csvReader.Comment = '#'
+
+	rows, err := csvReader.ReadAll()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse CSV")
+	}
+
+	fields := []metadataRecordField{
+		metadataRecordFieldTarget,
+		metadataRecordFieldAttribute,
+		metadataRecordFieldValue,
+		metadataRecordFieldUnits,
+		metadataRecordFieldOperation,
+	}
+
+	if len(rows) > 0 && getMetadataRecordField(rows[0][0]) == metadataRecordFieldTarget {
+		// header
+		fields = make([]metadataRecordField, len(rows[0]))
+		for idx, name := range rows[0] {
+			fields[idx] = getMetadataRecordField(name)
+		}
+		rows = rows[1:]
+	}
+
+	records := make([]MetadataRecord, 0, len(rows))
+	for rowIdx, row := range rows {
+		values := map[metadataRecordField]string{}
+		for idx, value := range row {
+			if idx < len(fields) {
+				values[fields[idx]] = value
+			}
+		}
+
+		record, err := newMetadataRecord(values)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse row %d", rowIdx+1)
+		}
+
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+// ReadMetadataRecordsFromJSON reads metadata records from JSON
+// It accepts an array of objects having path, attribute, value, unit, and op, or output of 'meta export --output_json'
+func ReadMetadataRecordsFromJSON(reader io.Reader) ([]MetadataRecord, error) {
+	objects := []map[string]interface{}{}
+	err := json.NewDecoder(reader).Decode(&objects)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse JSON")
+	}
+
+	rows := []map[string]interface{}{}
+	for _, object := range objects {
+		if data, ok := object["data"].([]interface{}); ok {
+			// exported tables
+			for _, row := range data {
+				if rowMap, ok := row.(map[string]interface{}); ok {
+					rows = append(rows, rowMap)
+				}
+			}
+			continue
+		}
+
+		rows = append(rows, object)
+	}
+
+	records := make([]MetadataRecord, 0, len(rows))
+	for rowIdx, row := range rows {
+		values := map[metadataRecordField]string{}
+		for name, value := range row {
+			if value == nil {
+				continue
+			}
+			values[getMetadataRecordField(name)] = fmt.Sprintf("%v", value)
+		}
+
+		record, err := newMetadataRecord(values)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse record %d", rowIdx+1)
+		}
+
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+func newMetadataRecord(values map[metadataRecordField]string) (MetadataRecord, error) {
+	op, err := GetMetadataOperation(values[metadataRecordFieldOperation])
+	if err != nil {
+		return MetadataRecord{}, err
+	}
+
+	record := MetadataRecord{
+		Target:    strings.TrimSpace(values[metadataRecordFieldTarget]),
+		Attribute: values[metadataRecordFieldAttribute],
+		Value:     values[metadataRecordFieldValue],
+		Units:     values[metadataRecordFieldUnits],
+		Operation: op,
+	}
+
+	if len(record.Target) == 0 {
+		return MetadataRecord{}, errors.New("target is not given")
+	}
+
+	if len(record.Attribute) == 0 {
+		return MetadataRecord{}, errors.Errorf("attribute is not given for %q", record.Target)
+	}
+
+	if len(record.Value) == 0 && record.Operation != MetadataOperationRemove {
+		return MetadataRecord{}, errors.Errorf("value is not given for attribute %q of %q", record.Attribute, record.Target)
+	}
+
+	return record, nil
+}
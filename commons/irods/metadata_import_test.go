@@ -0,0 +1,137 @@
+package irods
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetadataImport(t *testing.T) {
+	t.Run("test ReadMetadataRecordsFromCSV", testReadMetadataRecordsFromCSV)
+	t.Run("test ReadMetadataRecordsFromJSON", testReadMetadataRecordsFromJSON)
+}
+
+func testReadMetadataRecordsFromCSV(t *testing.T) {
+	tests := []struct {
+		name     string
+		csv      string
+		expected []MetadataRecord
+		hasError bool
+	}{
+		{
+			name: "no header",
+			csv:  "/zone/home/user/a.txt,sample,S1,,\n/zone/home/user/b.txt,sample,S2,mg,set\n",
+			expected: []MetadataRecord{
+				{"/zone/home/user/a.txt", "sample", "S1", "", MetadataOperationAdd},
+				{"/zone/home/user/b.txt", "sample", "S2", "mg", MetadataOperationSet},
+			},
+		},
+		{
+			name: "header with reordered columns",
+			csv:  "Path,Op,Value,Attribute\n/zone/home/user/a.txt,add,S1,sample\n",
+			expected: []MetadataRecord{
+				{"/zone/home/user/a.txt", "sample", "S1", "", MetadataOperationAdd},
+			},
+		},
+		{
+			name: "header naming user target with unknown columns",
+			csv:  "user,attr,value,comment\nalice#zone,team,lab,ignored\n",
+			expected: []MetadataRecord{
+				{"alice#zone", "team", "lab", "", MetadataOperationAdd},
+			},
+		},
+		{
+			name: "comments and short rows",
+			csv:  "# path,attribute,value\n/zone/home/user/a.txt,sample,S1\n",
+			expected: []MetadataRecord{
+				{"/zone/home/user/a.txt", "sample", "S1", "", MetadataOperationAdd},
+			},
+		},
+		{
+			name: "rm without a value",
+			csv:  "path,attribute,value,unit,op\n/zone/home/user/a.txt,sample,,,rm\n/zone/home/user/b.txt,sample,,,delete\n",
+			expected: []MetadataRecord{
+				{"/zone/home/user/a.txt", "sample", "", "", MetadataOperationRemove},
+				{"/zone/home/user/b.txt", "sample", "", "", MetadataOperationRemove},
+			},
+		},
+		{
+			name:     "add without a value",
+			csv:      "/zone/home/user/a.txt,sample,,,add\n",
+			hasError: true,
+		},
+		{
+			name:     "missing attribute",
+			csv:      "/zone/home/user/a.txt,,S1\n",
+			hasError: true,
+		},
+		{
+			name:     "unknown operation",
+			csv:      "/zone/home/user/a.txt,sample,S1,,copy\n",
+			hasError: true,
+		},
+		{
+			name:     "empty",
+			csv:      "",
+			expected: []MetadataRecord{},
+		},
+	}
+
+	for _, test := range tests {
+		records, err := ReadMetadataRecordsFromCSV(strings.NewReader(test.csv))
+		if test.hasError {
+			assert.Error(t, err, test.name)
+			continue
+		}
+
+		assert.NoError(t, err, test.name)
+		assert.Equal(t, test.expected, records, test.name)
+	}
+}
+
+func testReadMetadataRecordsFromJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		json     string
+		expected []MetadataRecord
+		hasError bool
+	}{
+		{
+			name: "array of records",
+			json: `[{"path": "/zone/home/user/a.txt", "attribute": "size", "value": 10, "unit": "mb"}, {"path": "/zone/home/user/a.txt", "attribute": "sample", "op": "rm"}]`,
+			expected: []MetadataRecord{
+				{"/zone/home/user/a.txt", "size", "10", "mb", MetadataOperationAdd},
+				{"/zone/home/user/a.txt", "sample", "", "", MetadataOperationRemove},
+			},
+		},
+		{
+			name: "exported tables",
+			json: `[{"title": "Metadata", "data": [{"path": "/zone/home/user/a.txt", "name": "sample", "value": "S1", "units": null}]}]`,
+			expected: []MetadataRecord{
+				{"/zone/home/user/a.txt", "sample", "S1", "", MetadataOperationAdd},
+			},
+		},
+		{
+			name:     "missing target",
+			json:     `[{"attribute": "sample", "value": "S1"}]`,
+			hasError: true,
+		},
+		{
+			name:     "not an array",
+			json:     `{"path": "/zone/home/user/a.txt"}`,
+			hasError: true,
+		},
+	}
+
+	for _, test := range tests {
+		records, err := ReadMetadataRecordsFromJSON(strings.NewReader(test.json))
+		if test.hasError {
+			assert.Error(t, err, test.name)
+			continue
+		}
+
+		assert.NoError(t, err, test.name)
+		assert.Equal(t, test.expected, records, test.name)
+	}
+}
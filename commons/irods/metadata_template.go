@@ -0,0 +1,235 @@
+package irods
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	irodsclient_types "github.com/cyverse/go-irodsclient/irods/types"
+	irodsclient_util "github.com/cyverse/go-irodsclient/irods/util"
+)
+
+const (
+	// MetadataTemplateVariableFileName is the name of the local file or directory
+	MetadataTemplateVariableFileName string = "filename"
+	// MetadataTemplateVariableSize is the size of the local file in bytes
+	MetadataTemplateVariableSize string = "size"
+	// MetadataTemplateVariableChecksum is the SHA-256 checksum of the local file in hex
+	MetadataTemplateVariableChecksum string = "checksum"
+	// MetadataTemplateVariableUploadTime is the time the upload started, in RFC3339
+	MetadataTemplateVariableUploadTime string = "upload_time"
+	// MetadataTemplateVariableUser is the iRODS user uploading the data
+	MetadataTemplateVariableUser string = "user"
+	// MetadataTemplateVariableType is 'data-object' or 'collection'
+	MetadataTemplateVariableType string = "type"
+)
+
+const (
+	// MetadataTemplateAppliesToAll applies the attribute to data objects and collections
+	MetadataTemplateAppliesToAll string = ""
+	// MetadataTemplateAppliesToDataObject applies the attribute to data objects only
+	MetadataTemplateAppliesToDataObject string = "data-object"
+	// MetadataTemplateAppliesToCollection applies the attribute to collections only
+	MetadataTemplateAppliesToCollection string = "collection"
+)
+
+var (
+	metadataTemplateVariableRegexp = regexp.MustCompile(`\{\{\s*([a-z_]+)\s*\}\}`)
+)
+
+// MetadataTemplate describes AVUs required or applied to uploaded data objects and collections, read from a JSON file
+type MetadataTemplate struct {
+	Attributes []MetadataTemplateAttribute `json:"attributes"`
+}
+
+// MetadataTemplateAttribute describes an attribute in the template
+type MetadataTemplateAttribute struct {
+	Attribute string `json:"attribute"`
+	Value     string `json:"value,omitempty"` // default value, may contain variables like {{filename}}
+	Units     string `json:"units,omitempty"`
+	Required  bool   `json:"required,omitempty"`
+	Pattern   string `json:"pattern,omitempty"`    // regular expression the value must match
+	AppliesTo string `json:"applies_to,omitempty"` // 'data-object', 'collection', or empty for both
+
+	patternRegexp *regexp.Regexp
+}
+
+// NewMetadataTemplateFromFile reads metadata template from a JSON file
+func NewMetadataTemplateFromFile(templatePath string) (*MetadataTemplate, error) {
+	templateBytes, err := os.ReadFile(templatePath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read metadata template file %q", templatePath)
+	}
+
+	template := &MetadataTemplate{}
+	err = json.Unmarshal(templateBytes, template)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse metadata template file %q", templatePath)
+	}
+
+	err = template.Validate()
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid metadata template file %q", templatePath)
+	}
+
+	return template, nil
+}
+
+// Validate validates the template and compiles patterns
+func (template *MetadataTemplate) Validate() error {
+	if len(template.Attributes) == 0 {
+		return errors.New("no attributes are given")
+	}
+
+	for attrIdx := range template.Attributes {
+		attr := &template.Attributes[attrIdx]
+
+		if len(attr.Attribute) == 0 {
+			return errors.Errorf("attribute name is not given for attribute %d", attrIdx)
+		}
+
+		switch attr.AppliesTo {
+		case MetadataTemplateAppliesToAll, MetadataTemplateAppliesToDataObject, MetadataTemplateAppliesToCollection:
+		default:
+			return errors.Errorf("unknown applies_to %q for attribute %q", attr.AppliesTo, attr.Attribute)
+		}
+
+		for _, match := range metadataTemplateVariableRegexp.FindAllStringSubmatch(attr.Value, -1) {
+			if !isMetadataTemplateVariable(match[1]) {
+				return errors.Errorf("unknown variable %q for attribute %q", match[1], attr.Attribute)
+			}
+		}
+
+		if len(attr.Pattern) > 0 {
+			patternRegexp, err := regexp.Compile(attr.Pattern)
+			if err != nil {
+				return errors.Wrapf(err, "failed to compile pattern %q for attribute %q", attr.Pattern, attr.Attribute)
+			}
+			attr.patternRegexp = patternRegexp
+		}
+	}
+
+	return nil
+}
+
+func isMetadataTemplateVariable(name string) bool {
+	switch name {
+	case MetadataTemplateVariableFileName, MetadataTemplateVariableSize, MetadataTemplateVariableChecksum,
+		MetadataTemplateVariableUploadTime, MetadataTemplateVariableUser, MetadataTemplateVariableType:
+		return true
+	default:
+		return false
+	}
+}
+
+// UsesVariable returns true if any of attribute values uses the variable
+func (template *MetadataTemplate) UsesVariable(name string) bool {
+	for _, attr := range template.Attributes {
+		for _, match := range metadataTemplateVariableRegexp.FindAllStringSubmatch(attr.Value, -1) {
+			if match[1] == name {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// MakeVariables returns variables of the local file or directory
+// Checksum is calculated only if the template uses it, as it reads the whole file
+func (template *MetadataTemplate) MakeVariables(localPath string, stat os.FileInfo, user string, uploadTime time.Time) (map[string]string, error) {
+	variables := map[string]string{
+		MetadataTemplateVariableFileName:   stat.Name(),
+		MetadataTemplateVariableUploadTime: uploadTime.Format(time.RFC3339),
+		MetadataTemplateVariableUser:       user,
+		MetadataTemplateVariableType:       MetadataTemplateAppliesToDataObject,
+	}
+
+	if stat.IsDir() {
+		variables[MetadataTemplateVariableType] = MetadataTemplateAppliesToCollection
+		return variables, nil
+	}
+
+	variables[MetadataTemplateVariableSize] = strconv.FormatInt(stat.Size(), 10)
+
+	if template.UsesVariable(MetadataTemplateVariableChecksum) {
+		checksum, err := irodsclient_util.HashLocalFile(localPath, string(irodsclient_types.ChecksumAlgorithmSHA256), nil)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get checksum of %q", localPath)
+		}
+
+		variables[MetadataTemplateVariableChecksum] = hex.EncodeToString(checksum)
+	}
+
+	return variables, nil
+}
+
+// Resolve returns AVUs of the template for a data object or collection
+// Values given override default values of the template. It returns an error if a required attribute has no value or a value does not match the pattern
+func (template *MetadataTemplate) Resolve(variables map[string]string, values map[string]string, isDir bool) ([]*irodsclient_types.IRODSMeta, error) {
+	metas := []*irodsclient_types.IRODSMeta{}
+
+	for _, attr := range template.Attributes {
+		if attr.AppliesTo == MetadataTemplateAppliesToDataObject && isDir {
+			continue
+		}
+
+		if attr.AppliesTo == MetadataTemplateAppliesToCollection && !isDir {
+			continue
+		}
+
+		value, ok := values[attr.Attribute]
+		if !ok {
+			value = metadataTemplateVariableRegexp.ReplaceAllStringFunc(attr.Value, func(match string) string {
+				name := metadataTemplateVariableRegexp.FindStringSubmatch(match)[1]
+				return variables[name]
+			})
+		}
+
+		value = strings.TrimSpace(value)
+
+		if len(value) == 0 {
+			if attr.Required {
+				return nil, errors.Errorf("required attribute %q is not given", attr.Attribute)
+			}
+			continue
+		}
+
+		if attr.patternRegexp != nil && !attr.patternRegexp.MatchString(value) {
+			return nil, errors.Errorf("value %q of attribute %q does not match pattern %q", value, attr.Attribute, attr.Pattern)
+		}
+
+		metas = append(metas, &irodsclient_types.IRODSMeta{
+			Name:  attr.Attribute,
+			Value: value,
+			Units: attr.Units,
+		})
+	}
+
+	return metas, nil
+}
+
+// ReadMetadataSidecarValues returns attribute values in the sidecar file of the local path, empty if the file does not exist
+func ReadMetadataSidecarValues(localPath string) (map[string]string, error) {
+	values := map[string]string{}
+
+	sidecar, err := readMetadataSidecar(localPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if sidecar == nil {
+		return values, nil
+	}
+
+	for _, avu := range sidecar.Metadata {
+		values[avu.Attribute] = avu.Value
+	}
+
+	return values, nil
+}
@@ -0,0 +1,226 @@
+package irods
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	irodsclient_types "github.com/cyverse/go-irodsclient/irods/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetadataTemplate(t *testing.T) {
+	t.Run("test ValidateMetadataTemplate", testValidateMetadataTemplate)
+	t.Run("test ResolveMetadataTemplate", testResolveMetadataTemplate)
+	t.Run("test MakeMetadataTemplateVariables", testMakeMetadataTemplateVariables)
+}
+
+func testValidateMetadataTemplate(t *testing.T) {
+	tests := []struct {
+		name       string
+		attributes []MetadataTemplateAttribute
+		hasError   bool
+	}{
+		{"valid", []MetadataTemplateAttribute{{Attribute: "project", Required: true, Pattern: "^[A-Z]+$"}, {Attribute: "file", Value: "{{filename}} {{ size }}", AppliesTo: "data-object"}}, false},
+		{"no attributes", []MetadataTemplateAttribute{}, true},
+		{"no attribute name", []MetadataTemplateAttribute{{Value: "value"}}, true},
+		{"unknown applies_to", []MetadataTemplateAttribute{{Attribute: "project", AppliesTo: "file"}}, true},
+		{"unknown variable", []MetadataTemplateAttribute{{Attribute: "project", Value: "{{owner}}"}}, true},
+		{"invalid pattern", []MetadataTemplateAttribute{{Attribute: "project", Pattern: "[A-Z"}}, true},
+	}
+
+	for _, test := range tests {
+		template := &MetadataTemplate{
+			Attributes: test.attributes,
+		}
+
+		err := template.Validate()
+		if test.hasError {
+			assert.Error(t, err, test.name)
+		} else {
+			assert.NoError(t, err, test.name)
+		}
+	}
+}
+
+func testResolveMetadataTemplate(t *testing.T) {
+	template := &MetadataTemplate{
+		Attributes: []MetadataTemplateAttribute{
+			{Attribute: "project", Required: true, Pattern: "^[A-Z]+[0-9]*$"},
+			{Attribute: "sample_id", Value: "unknown", Units: "id"},
+			{Attribute: "source", Value: "{{filename}} ({{ size }} bytes) by {{user}}", AppliesTo: MetadataTemplateAppliesToDataObject},
+			{Attribute: "kind", Value: "{{type}}", AppliesTo: MetadataTemplateAppliesToCollection},
+			{Attribute: "checksum", Value: "{{checksum}}"},
+		},
+	}
+
+	err := template.Validate()
+	assert.NoError(t, err)
+
+	fileVariables := map[string]string{
+		MetadataTemplateVariableFileName: "a.txt",
+		MetadataTemplateVariableSize:     "10",
+		MetadataTemplateVariableUser:     "alice",
+		MetadataTemplateVariableType:     MetadataTemplateAppliesToDataObject,
+	}
+
+	dirVariables := map[string]string{
+		MetadataTemplateVariableFileName: "dir",
+		MetadataTemplateVariableUser:     "alice",
+		MetadataTemplateVariableType:     MetadataTemplateAppliesToCollection,
+	}
+
+	tests := []struct {
+		name      string
+		variables map[string]string
+		values    map[string]string
+		isDir     bool
+		expected  []*irodsclient_types.IRODSMeta
+		hasError  bool
+	}{
+		{
+			name:      "data object with variables and defaults",
+			variables: fileVariables,
+			values:    map[string]string{"project": "LAB1"},
+			expected: []*irodsclient_types.IRODSMeta{
+				{Name: "project", Value: "LAB1"},
+				{Name: "sample_id", Value: "unknown", Units: "id"},
+				{Name: "source", Value: "a.txt (10 bytes) by alice"},
+			},
+		},
+		{
+			name:      "collection applies collection attributes only",
+			variables: dirVariables,
+			values:    map[string]string{"project": "LAB"},
+			isDir:     true,
+			expected: []*irodsclient_types.IRODSMeta{
+				{Name: "project", Value: "LAB"},
+				{Name: "sample_id", Value: "unknown", Units: "id"},
+				{Name: "kind", Value: "collection"},
+			},
+		},
+		{
+			name:      "given values override defaults and are trimmed",
+			variables: fileVariables,
+			values:    map[string]string{"project": " LAB ", "sample_id": "s-1", "checksum": "abc"},
+			expected: []*irodsclient_types.IRODSMeta{
+				{Name: "project", Value: "LAB"},
+				{Name: "sample_id", Value: "s-1", Units: "id"},
+				{Name: "source", Value: "a.txt (10 bytes) by alice"},
+				{Name: "checksum", Value: "abc"},
+			},
+		},
+		{
+			name:      "empty value drops an optional attribute",
+			variables: fileVariables,
+			values:    map[string]string{"project": "LAB", "sample_id": ""},
+			expected: []*irodsclient_types.IRODSMeta{
+				{Name: "project", Value: "LAB"},
+				{Name: "source", Value: "a.txt (10 bytes) by alice"},
+			},
+		},
+		{
+			name:      "required attribute missing",
+			variables: fileVariables,
+			values:    map[string]string{},
+			hasError:  true,
+		},
+		{
+			name:      "required attribute empty",
+			variables: fileVariables,
+			values:    map[string]string{"project": " "},
+			hasError:  true,
+		},
+		{
+			name:      "value not matching pattern",
+			variables: fileVariables,
+			values:    map[string]string{"project": "lab"},
+			hasError:  true,
+		},
+	}
+
+	for _, test := range tests {
+		metas, err := template.Resolve(test.variables, test.values, test.isDir)
+		if test.hasError {
+			assert.Error(t, err, test.name)
+			continue
+		}
+
+		assert.NoError(t, err, test.name)
+		assert.Equal(t, test.expected, metas, test.name)
+	}
+}
+
+func testMakeMetadataTemplateVariables(t *testing.T) {
+	tempDir := t.TempDir()
+
+	filePath := filepath.Join(tempDir, "a.txt")
+	err := os.WriteFile(filePath, []byte("hello"), 0600)
+	assert.NoError(t, err)
+
+	fileStat, err := os.Stat(filePath)
+	assert.NoError(t, err)
+
+	dirStat, err := os.Stat(tempDir)
+	assert.NoError(t, err)
+
+	uploadTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	withChecksum := &MetadataTemplate{Attributes: []MetadataTemplateAttribute{{Attribute: "checksum", Value: "{{checksum}}"}}}
+	withoutChecksum := &MetadataTemplate{Attributes: []MetadataTemplateAttribute{{Attribute: "file", Value: "{{filename}}"}}}
+
+	tests := []struct {
+		name     string
+		template *MetadataTemplate
+		path     string
+		stat     os.FileInfo
+		expected map[string]string
+	}{
+		{
+			name:     "file with checksum",
+			template: withChecksum,
+			path:     filePath,
+			stat:     fileStat,
+			expected: map[string]string{
+				MetadataTemplateVariableFileName:   "a.txt",
+				MetadataTemplateVariableSize:       "5",
+				MetadataTemplateVariableChecksum:   "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824",
+				MetadataTemplateVariableUploadTime: "2024-01-02T03:04:05Z",
+				MetadataTemplateVariableUser:       "alice",
+				MetadataTemplateVariableType:       MetadataTemplateAppliesToDataObject,
+			},
+		},
+		{
+			name:     "file without checksum",
+			template: withoutChecksum,
+			path:     filePath,
+			stat:     fileStat,
+			expected: map[string]string{
+				MetadataTemplateVariableFileName:   "a.txt",
+				MetadataTemplateVariableSize:       "5",
+				MetadataTemplateVariableUploadTime: "2024-01-02T03:04:05Z",
+				MetadataTemplateVariableUser:       "alice",
+				MetadataTemplateVariableType:       MetadataTemplateAppliesToDataObject,
+			},
+		},
+		{
+			name:     "directory",
+			template: withChecksum,
+			path:     tempDir,
+			stat:     dirStat,
+			expected: map[string]string{
+				MetadataTemplateVariableFileName:   dirStat.Name(),
+				MetadataTemplateVariableUploadTime: "2024-01-02T03:04:05Z",
+				MetadataTemplateVariableUser:       "alice",
+				MetadataTemplateVariableType:       MetadataTemplateAppliesToCollection,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		variables, err := test.template.MakeVariables(test.path, test.stat, "alice", uploadTime)
+		assert.NoError(t, err, test.name)
+		assert.Equal(t, test.expected, variables, test.name)
+	}
+}
@@ -0,0 +1,306 @@
+package irods
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	irodsclient_fs "github.com/cyverse/go-irodsclient/fs"
+	"github.com/cyverse/go-irodsclient/irods/common"
+	"github.com/cyverse/go-irodsclient/irods/message"
+	irodsclient_types "github.com/cyverse/go-irodsclient/irods/types"
+)
+
+// ResourceInfo is a resource with its place in the resource hierarchy
+type ResourceInfo struct {
+	ID        int64
+	Name      string
+	Zone      string
+	Type      string
+	Class     string
+	Location  string
+	VaultPath string
+	Context   string
+	Status    string
+	// FreeSpace is -1 if the resource does not report free space
+	FreeSpace int64
+
+	// parent has ID (iRODS 4.2+) or name (iRODS 4.1) of the parent resource, empty for root resources
+	parent   string
+	Parent   *ResourceInfo
+	Children []*ResourceInfo
+}
+
+// IsRoot returns true if the resource has no parent
+func (resc *ResourceInfo) IsRoot() bool {
+	return resc.Parent == nil
+}
+
+// ServerConnectionInfo has parameters negotiated with the server
+type ServerConnectionInfo struct {
+	Version *irodsclient_types.IRODSVersion
+	SSL     bool
+}
+
+// ServerCapability describes if a feature is available on the server
+type ServerCapability struct {
+	Name      string
+	Available bool
+	Reason    string
+}
+
+// ListResourceInfos returns all resources, with parents and children linked
+func ListResourceInfos(filesystem *irodsclient_fs.FileSystem) ([]*ResourceInfo, error) {
+	columns := []common.ICATColumnNumber{
+		common.ICAT_COLUMN_R_RESC_ID,
+		common.ICAT_COLUMN_R_RESC_NAME,
+		common.ICAT_COLUMN_R_ZONE_NAME,
+		common.ICAT_COLUMN_R_TYPE_NAME,
+		common.ICAT_COLUMN_R_CLASS_NAME,
+		common.ICAT_COLUMN_R_LOC,
+		common.ICAT_COLUMN_R_VAULT_PATH,
+		common.ICAT_COLUMN_R_FREE_SPACE,
+		common.ICAT_COLUMN_R_RESC_STATUS,
+		common.ICAT_COLUMN_R_RESC_CONTEXT,
+		common.ICAT_COLUMN_R_RESC_PARENT,
+	}
+
+	rows, err := queryRows(filesystem, columns, 0)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to query resources")
+	}
+
+	resources := make([]*ResourceInfo, 0, len(rows))
+	for _, row := range rows {
+		rescID, err := strconv.ParseInt(row[common.ICAT_COLUMN_R_RESC_ID], 10, 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse resource id %q", row[common.ICAT_COLUMN_R_RESC_ID])
+		}
+
+		freeSpace := int64(-1)
+		if freeSpaceString := strings.TrimSpace(row[common.ICAT_COLUMN_R_FREE_SPACE]); len(freeSpaceString) > 0 {
+			freeSpace, err = strconv.ParseInt(freeSpaceString, 10, 64)
+			if err != nil {
+				freeSpace = -1
+			}
+		}
+
+		resources = append(resources, &ResourceInfo{
+			ID:        rescID,
+			Name:      row[common.ICAT_COLUMN_R_RESC_NAME],
+			Zone:      row[common.ICAT_COLUMN_R_ZONE_NAME],
+			Type:      row[common.ICAT_COLUMN_R_TYPE_NAME],
+			Class:     row[common.ICAT_COLUMN_R_CLASS_NAME],
+			Location:  row[common.ICAT_COLUMN_R_LOC],
+			VaultPath: row[common.ICAT_COLUMN_R_VAULT_PATH],
+			Context:   row[common.ICAT_COLUMN_R_RESC_CONTEXT],
+			Status:    row[common.ICAT_COLUMN_R_RESC_STATUS],
+			FreeSpace: freeSpace,
+			parent:    strings.TrimSpace(row[common.ICAT_COLUMN_R_RESC_PARENT]),
+			Children:  []*ResourceInfo{},
+		})
+	}
+
+	linkResourceInfos(resources)
+
+	return resources, nil
+}
+
+func linkResourceInfos(resources []*ResourceInfo) {
+	sort.SliceStable(resources, func(i int, j int) bool {
+		return resources[i].Name < resources[j].Name
+	})
+
+	resourcesByID := map[string]*ResourceInfo{}
+	resourcesByName := map[string]*ResourceInfo{}
+	for _, resc := range resources {
+		resourcesByID[strconv.FormatInt(resc.ID, 10)] = resc
+		resourcesByName[resc.Name] = resc
+	}
+
+	for _, resc := range resources {
+		if len(resc.parent) == 0 {
+			continue
+		}
+
+		parent, ok := resourcesByID[resc.parent]
+		if !ok {
+			parent, ok = resourcesByName[resc.parent]
+		}
+
+		if ok && parent != resc {
+			resc.Parent = parent
+			parent.Children = append(parent.Children, resc)
+		}
+	}
+}
+
+// GetRootResourceInfos returns resources having no parent
+func GetRootResourceInfos(resources []*ResourceInfo) []*ResourceInfo {
+	roots := []*ResourceInfo{}
+	for _, resc := range resources {
+		if resc.IsRoot() {
+			roots = append(roots, resc)
+		}
+	}
+
+	return roots
+}
+
+// GetServerConnectionInfo returns parameters negotiated with the server
+func GetServerConnectionInfo(filesystem *irodsclient_fs.FileSystem) (*ServerConnectionInfo, error) {
+	conn, err := filesystem.GetMetadataConnection(true)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get connection")
+	}
+	defer filesystem.ReturnMetadataConnection(conn) //nolint
+
+	return &ServerConnectionInfo{
+		Version: conn.GetVersion(),
+		SSL:     conn.IsSSL(),
+	}, nil
+}
+
+// MeasureServerLatency sends minimal queries to the server and returns the minimum and average round-trip time
+func MeasureServerLatency(filesystem *irodsclient_fs.FileSystem, count int) (time.Duration, time.Duration, error) {
+	if count < 1 {
+		count = 1
+	}
+
+	// establish a connection before measuring
+	_, err := queryRows(filesystem, []common.ICATColumnNumber{common.ICAT_COLUMN_R_RESC_NAME}, 1)
+	if err != nil {
+		return 0, 0, errors.Wrapf(err, "failed to query resource")
+	}
+
+	minLatency := time.Duration(0)
+	totalLatency := time.Duration(0)
+	for i := 0; i < count; i++ {
+		startTime := time.Now()
+
+		_, err := queryRows(filesystem, []common.ICATColumnNumber{common.ICAT_COLUMN_R_RESC_NAME}, 1)
+		if err != nil {
+			return 0, 0, errors.Wrapf(err, "failed to query resource")
+		}
+
+		latency := time.Since(startTime)
+		if i == 0 || latency < minLatency {
+			minLatency = latency
+		}
+		totalLatency += latency
+	}
+
+	return minLatency, totalLatency / time.Duration(count), nil
+}
+
+// GetServerCapabilities returns availability of features gocommands uses
+func GetServerCapabilities(filesystem *irodsclient_fs.FileSystem, connInfo *ServerConnectionInfo) []ServerCapability {
+	capabilities := []ServerCapability{}
+
+	capabilities = append(capabilities, ServerCapability{
+		Name:      "Parallel Transfer",
+		Available: filesystem.SupportParallelUpload(),
+		Reason:    "requires iRODS 4.2.9 or higher",
+	})
+
+	_, err := filesystem.ListTicketsBasic()
+	ticketReason := "ticket catalog is accessible"
+	if err != nil {
+		ticketReason = err.Error()
+	}
+
+	capabilities = append(capabilities, ServerCapability{
+		Name:      "Tickets",
+		Available: err == nil,
+		Reason:    ticketReason,
+	})
+
+	capabilities = append(capabilities, ServerCapability{
+		Name:      "Atomic Metadata Operations",
+		Available: connInfo.Version.HasHigherVersionThan(4, 2, 8),
+		Reason:    "requires iRODS 4.2.8 or higher",
+	})
+
+	return capabilities
+}
+
+// queryRows runs a general query selecting the columns, returns up to maxRows rows, or all rows if maxRows is 0
+func queryRows(filesystem *irodsclient_fs.FileSystem, columns []common.ICATColumnNumber, maxRows int) ([]map[common.ICATColumnNumber]string, error) {
+	conn, err := filesystem.GetMetadataConnection(true)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get connection")
+	}
+	defer filesystem.ReturnMetadataConnection(conn) //nolint
+
+	conn.Lock()
+	defer conn.Unlock()
+
+	pageRows := common.MaxQueryRows
+	if maxRows > 0 && maxRows < pageRows {
+		pageRows = maxRows
+	}
+
+	rows := []map[common.ICATColumnNumber]string{}
+
+	continueIndex := 0
+	for {
+		query := message.NewIRODSMessageQueryRequest(pageRows, continueIndex, 0, 0)
+		for _, column := range columns {
+			query.AddSelect(column)
+		}
+
+		queryResult := message.IRODSMessageQueryResponse{}
+		err := conn.Request(query, &queryResult, nil, conn.GetOperationTimeout())
+		if err == nil {
+			err = queryResult.CheckError()
+		}
+
+		if err != nil {
+			if irodsclient_types.GetIRODSErrorCode(err) == common.CAT_NO_ROWS_FOUND {
+				break
+			}
+
+			return nil, errors.Wrapf(err, "failed to receive a query result message")
+		}
+
+		if queryResult.RowCount == 0 {
+			break
+		}
+
+		pageStart := len(rows)
+		for row := 0; row < queryResult.RowCount; row++ {
+			rows = append(rows, map[common.ICATColumnNumber]string{})
+		}
+
+		for _, sqlResult := range queryResult.SQLResult {
+			if len(sqlResult.Values) != queryResult.RowCount {
+				return nil, errors.Errorf("failed to receive query rows - requires %d, but received %d values", queryResult.RowCount, len(sqlResult.Values))
+			}
+
+			for row, value := range sqlResult.Values {
+				rows[pageStart+row][common.ICATColumnNumber(sqlResult.AttributeIndex)] = value
+			}
+		}
+
+		continueIndex = queryResult.ContinueIndex
+		if continueIndex == 0 {
+			break
+		}
+
+		if maxRows > 0 && len(rows) >= maxRows {
+			// close the query
+			closeQuery := message.NewIRODSMessageQueryRequest(0, continueIndex, 0, 0)
+			for _, column := range columns {
+				closeQuery.AddSelect(column)
+			}
+
+			closeQueryResult := message.IRODSMessageQueryResponse{}
+			conn.Request(closeQuery, &closeQueryResult, nil, conn.GetOperationTimeout()) //nolint
+			break
+		}
+	}
+
+	return rows, nil
+}
@@ -0,0 +1,107 @@
+package irods
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	irodsclient_fs "github.com/cyverse/go-irodsclient/fs"
+	irodsclient_types "github.com/cyverse/go-irodsclient/irods/types"
+)
+
+// IsTicketExpired checks if the ticket is expired at the given time
+func IsTicketExpired(ticket *irodsclient_types.IRODSTicket, now time.Time) bool {
+	if ticket.ExpirationTime.IsZero() {
+		return false
+	}
+
+	return ticket.ExpirationTime.Before(now)
+}
+
+// IsTicketExhausted checks if the ticket has reached any of its limits
+func IsTicketExhausted(ticket *irodsclient_types.IRODSTicket) bool {
+	if ticket.UsesLimit > 0 && ticket.UsesCount >= ticket.UsesLimit {
+		return true
+	}
+
+	if ticket.Type == irodsclient_types.TicketTypeWrite {
+		if ticket.WriteFileLimit > 0 && ticket.WriteFileCount >= ticket.WriteFileLimit {
+			return true
+		}
+
+		if ticket.WriteByteLimit > 0 && ticket.WriteByteCount >= ticket.WriteByteLimit {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsTicketDangling checks if the path of the ticket does not exist anymore
+// errors other than file not found are returned, as the path may still exist
+func IsTicketDangling(filesystem *irodsclient_fs.FileSystem, ticket *irodsclient_types.IRODSTicket) (bool, error) {
+	_, err := filesystem.Stat(ticket.Path)
+	if err != nil {
+		if irodsclient_types.IsFileNotFoundError(err) {
+			return true, nil
+		}
+
+		return false, errors.Wrapf(err, "failed to stat %q of ticket %q", ticket.Path, ticket.Name)
+	}
+
+	return false, nil
+}
+
+// GetTicketUsagePercent returns usage of a limit in percent, returns "none" if there is no limit
+func GetTicketUsagePercent(count int64, limit int64) string {
+	if limit <= 0 {
+		return "none"
+	}
+
+	return fmt.Sprintf("%.0f%%", float64(count)*100/float64(limit))
+}
+
+// TicketStatus describes the state of a ticket
+type TicketStatus struct {
+	Expired   bool
+	Exhausted bool
+	Dangling  bool
+}
+
+// GetTicketStatus returns status of the ticket, dangling is checked only when checkDangling is true as it requires a query per ticket
+func GetTicketStatus(filesystem *irodsclient_fs.FileSystem, ticket *irodsclient_types.IRODSTicket, now time.Time, checkDangling bool) (TicketStatus, error) {
+	status := TicketStatus{
+		Expired:   IsTicketExpired(ticket, now),
+		Exhausted: IsTicketExhausted(ticket),
+	}
+
+	if checkDangling {
+		dangling, err := IsTicketDangling(filesystem, ticket)
+		if err != nil {
+			return status, err
+		}
+
+		status.Dangling = dangling
+	}
+
+	return status, nil
+}
+
+// String returns status as a comma separated string
+func (status TicketStatus) String() string {
+	states := []string{}
+	if status.Expired {
+		states = append(states, "expired")
+	}
+
+	if status.Exhausted {
+		states = append(states, "exhausted")
+	}
+
+	if status.Dangling {
+		states = append(states, "dangling")
+	}
+
+	return strings.Join(states, ", ")
+}
@@ -0,0 +1,162 @@
+package metrics
+
+import (
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	textfileUpdateInterval time.Duration = 15 * time.Second
+)
+
+// Exporter exposes metrics via an HTTP endpoint for Prometheus, or a text file for the node_exporter textfile collector
+type Exporter struct {
+	metrics      *Metrics
+	server       *http.Server
+	textfilePath string
+
+	stopChan  chan bool
+	waitGroup sync.WaitGroup
+	closeOnce sync.Once
+}
+
+// NewExporter creates a new Exporter and starts exporting the metrics
+// metrics are served at /metrics of the listen address if it is not empty, and written to the textfile path periodically if it is not empty
+func NewExporter(metrics *Metrics, listen string, textfilePath string) (*Exporter, error) {
+	exporter := &Exporter{
+		metrics:      metrics,
+		server:       nil,
+		textfilePath: textfilePath,
+
+		stopChan: make(chan bool),
+	}
+
+	if len(listen) > 0 {
+		listener, err := net.Listen("tcp", listen)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to listen on %q", listen)
+		}
+
+		mux := http.NewServeMux()
+		mux.HandleFunc("/metrics", exporter.serveMetrics)
+
+		exporter.server = &http.Server{
+			Handler:           mux,
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+
+		exporter.waitGroup.Add(1)
+		go func() {
+			defer exporter.waitGroup.Done()
+
+			serveErr := exporter.server.Serve(listener)
+			if serveErr != nil && serveErr != http.ErrServerClosed {
+				log.WithError(serveErr).Warnf("failed to serve metrics on %q", listen)
+			}
+		}()
+	}
+
+	if len(textfilePath) > 0 {
+		err := exporter.writeTextfile()
+		if err != nil {
+			exporter.Close()
+			return nil, err
+		}
+
+		exporter.waitGroup.Add(1)
+		go func() {
+			defer exporter.waitGroup.Done()
+
+			ticker := time.NewTicker(textfileUpdateInterval)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-exporter.stopChan:
+					return
+				case <-ticker.C:
+					writeErr := exporter.writeTextfile()
+					if writeErr != nil {
+						log.WithError(writeErr).Warn("failed to write metrics")
+					}
+				}
+			}
+		}()
+	}
+
+	return exporter, nil
+}
+
+func (exporter *Exporter) serveMetrics(writer http.ResponseWriter, request *http.Request) {
+	writer.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+
+	err := exporter.metrics.WriteText(writer)
+	if err != nil {
+		log.WithError(err).Debug("failed to write metrics to response")
+	}
+}
+
+// writeTextfile writes metrics to a temporary file and renames it, so the collector never reads a partial file
+func (exporter *Exporter) writeTextfile() error {
+	tempFile, err := os.CreateTemp(filepath.Dir(exporter.textfilePath), "."+filepath.Base(exporter.textfilePath)+".*")
+	if err != nil {
+		return errors.Wrapf(err, "failed to create a temporary file for %q", exporter.textfilePath)
+	}
+
+	tempPath := tempFile.Name()
+
+	err = exporter.metrics.WriteText(tempFile)
+	if err != nil {
+		tempFile.Close()
+		os.Remove(tempPath)
+		return errors.Wrapf(err, "failed to write metrics to %q", tempPath)
+	}
+
+	err = tempFile.Close()
+	if err != nil {
+		os.Remove(tempPath)
+		return errors.Wrapf(err, "failed to close %q", tempPath)
+	}
+
+	err = os.Chmod(tempPath, 0o644)
+	if err != nil {
+		os.Remove(tempPath)
+		return errors.Wrapf(err, "failed to change mode of %q", tempPath)
+	}
+
+	err = os.Rename(tempPath, exporter.textfilePath)
+	if err != nil {
+		os.Remove(tempPath)
+		return errors.Wrapf(err, "failed to rename %q to %q", tempPath, exporter.textfilePath)
+	}
+
+	return nil
+}
+
+// Close stops the HTTP endpoint and writes the final metrics to the text file
+func (exporter *Exporter) Close() error {
+	var err error
+
+	exporter.closeOnce.Do(func() {
+		close(exporter.stopChan)
+
+		if exporter.server != nil {
+			exporter.server.Close()
+		}
+
+		exporter.waitGroup.Wait()
+
+		if len(exporter.textfilePath) > 0 {
+			err = exporter.writeTextfile()
+		}
+	})
+
+	return err
+}
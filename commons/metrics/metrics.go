@@ -0,0 +1,173 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// metric names, exposed in the Prometheus text format
+const (
+	MetricJobsTotal             string = "gocmd_jobs_total"
+	MetricTransferredFilesTotal string = "gocmd_transferred_files_total"
+	MetricTransferredBytesTotal string = "gocmd_transferred_bytes_total"
+	MetricRetriesTotal          string = "gocmd_retries_total"
+	MetricBundleStageSeconds    string = "gocmd_bundle_stage_seconds_total"
+	MetricBundleStageCount      string = "gocmd_bundle_stages_total"
+	MetricStartTimeSeconds      string = "gocmd_start_time_seconds"
+)
+
+type metricDesc struct {
+	help      string
+	gauge     bool
+	labelKeys []string
+}
+
+var metricDescs = map[string]metricDesc{
+	MetricJobsTotal:             {help: "Number of transfer jobs finished, by status (done, failed, or canceled).", labelKeys: []string{"status"}},
+	MetricTransferredFilesTotal: {help: "Number of files transferred, by method and transfer mode.", labelKeys: []string{"method", "mode"}},
+	MetricTransferredBytesTotal: {help: "Number of bytes transferred, by method and transfer mode.", labelKeys: []string{"method", "mode"}},
+	MetricRetriesTotal:          {help: "Number of transfer retries, by method.", labelKeys: []string{"method"}},
+	MetricBundleStageSeconds:    {help: "Total time spent in bundle stages (tar, upload, or extract) in seconds.", labelKeys: []string{"stage"}},
+	MetricBundleStageCount:      {help: "Number of bundles completing bundle stages (tar, upload, or extract).", labelKeys: []string{"stage"}},
+	MetricStartTimeSeconds:      {help: "Start time of the gocmd process since unix epoch in seconds.", gauge: true},
+}
+
+// Metrics collects counters of transfers
+// all methods are no-op on nil, so callers do not need to check if metrics are enabled
+type Metrics struct {
+	values map[string]map[string]float64 // metric name -> label values joined -> value
+	mutex  sync.Mutex
+}
+
+// NewMetrics creates a new Metrics
+func NewMetrics() *Metrics {
+	metrics := &Metrics{
+		values: map[string]map[string]float64{},
+	}
+
+	metrics.set(MetricStartTimeSeconds, float64(time.Now().UnixNano())/float64(time.Second))
+
+	return metrics
+}
+
+func (metrics *Metrics) add(name string, value float64, labelValues ...string) {
+	if metrics == nil {
+		return
+	}
+
+	metrics.mutex.Lock()
+	defer metrics.mutex.Unlock()
+
+	series, ok := metrics.values[name]
+	if !ok {
+		series = map[string]float64{}
+		metrics.values[name] = series
+	}
+
+	series[strings.Join(labelValues, "\x00")] += value
+}
+
+func (metrics *Metrics) set(name string, value float64, labelValues ...string) {
+	if metrics == nil {
+		return
+	}
+
+	metrics.mutex.Lock()
+	defer metrics.mutex.Unlock()
+
+	metrics.values[name] = map[string]float64{
+		strings.Join(labelValues, "\x00"): value,
+	}
+}
+
+// AddJob counts a job finished with the status, done, failed, or canceled
+func (metrics *Metrics) AddJob(status string) {
+	metrics.add(MetricJobsTotal, 1, status)
+}
+
+// AddTransfer counts files transferred with the method (e.g., PUT) and transfer mode (e.g., redirect)
+func (metrics *Metrics) AddTransfer(method string, mode string, files int, size int64) {
+	metrics.add(MetricTransferredFilesTotal, float64(files), method, mode)
+	metrics.add(MetricTransferredBytesTotal, float64(size), method, mode)
+}
+
+// AddRetry counts a retry of a transfer with the method
+func (metrics *Metrics) AddRetry(method string) {
+	metrics.add(MetricRetriesTotal, 1, method)
+}
+
+// AddBundleStage adds time taken for a bundle stage, tar, upload, or extract
+func (metrics *Metrics) AddBundleStage(stage string, duration time.Duration) {
+	metrics.add(MetricBundleStageSeconds, duration.Seconds(), stage)
+	metrics.add(MetricBundleStageCount, 1, stage)
+}
+
+// WriteText writes metrics in the Prometheus text exposition format
+func (metrics *Metrics) WriteText(writer io.Writer) error {
+	if metrics == nil {
+		return nil
+	}
+
+	metrics.mutex.Lock()
+	defer metrics.mutex.Unlock()
+
+	names := make([]string, 0, len(metrics.values))
+	for name := range metrics.values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	sb := strings.Builder{}
+	for _, name := range names {
+		desc := metricDescs[name]
+		metricType := "counter"
+		if desc.gauge {
+			metricType = "gauge"
+		}
+
+		fmt.Fprintf(&sb, "# HELP %s %s\n", name, desc.help)
+		fmt.Fprintf(&sb, "# TYPE %s %s\n", name, metricType)
+
+		series := metrics.values[name]
+		keys := make([]string, 0, len(series))
+		for key := range series {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			sb.WriteString(name)
+
+			if len(desc.labelKeys) > 0 {
+				labelValues := strings.Split(key, "\x00")
+				labels := make([]string, 0, len(desc.labelKeys))
+				for idx, labelKey := range desc.labelKeys {
+					labelValue := ""
+					if idx < len(labelValues) {
+						labelValue = labelValues[idx]
+					}
+
+					labels = append(labels, fmt.Sprintf("%s=\"%s\"", labelKey, escapeLabelValue(labelValue)))
+				}
+
+				sb.WriteString("{" + strings.Join(labels, ",") + "}")
+			}
+
+			sb.WriteString(" " + strconv.FormatFloat(series[key], 'g', -1, 64) + "\n")
+		}
+	}
+
+	_, err := io.WriteString(writer, sb.String())
+	return err
+}
+
+func escapeLabelValue(value string) string {
+	value = strings.ReplaceAll(value, "\\", "\\\\")
+	value = strings.ReplaceAll(value, "\"", "\\\"")
+	return strings.ReplaceAll(value, "\n", "\\n")
+}
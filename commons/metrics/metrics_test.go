@@ -0,0 +1,120 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetrics(t *testing.T) {
+	t.Run("test WriteText", testWriteText)
+	t.Run("test WriteTextNil", testWriteTextNil)
+}
+
+func testWriteText(t *testing.T) {
+	jobsHeader := "# HELP gocmd_jobs_total Number of transfer jobs finished, by status (done, failed, or canceled).\n# TYPE gocmd_jobs_total counter\n"
+	filesHeader := "# HELP gocmd_transferred_files_total Number of files transferred, by method and transfer mode.\n# TYPE gocmd_transferred_files_total counter\n"
+	bytesHeader := "# HELP gocmd_transferred_bytes_total Number of bytes transferred, by method and transfer mode.\n# TYPE gocmd_transferred_bytes_total counter\n"
+	stageSecondsHeader := "# HELP gocmd_bundle_stage_seconds_total Total time spent in bundle stages (tar, upload, or extract) in seconds.\n# TYPE gocmd_bundle_stage_seconds_total counter\n"
+	stageCountHeader := "# HELP gocmd_bundle_stages_total Number of bundles completing bundle stages (tar, upload, or extract).\n# TYPE gocmd_bundle_stages_total counter\n"
+
+	tests := []struct {
+		name     string
+		record   func(metrics *Metrics)
+		expected string
+	}{
+		{
+			"empty",
+			func(metrics *Metrics) {},
+			"",
+		},
+		{
+			"counters are summed and sorted by labels",
+			func(metrics *Metrics) {
+				metrics.AddJob("failed")
+				metrics.AddJob("done")
+				metrics.AddJob("done")
+			},
+			jobsHeader +
+				"gocmd_jobs_total{status=\"done\"} 2\n" +
+				"gocmd_jobs_total{status=\"failed\"} 1\n",
+		},
+		{
+			"multiple labels and metrics sorted by name",
+			func(metrics *Metrics) {
+				metrics.AddTransfer("PUT", "redirect", 2, 2048)
+				metrics.AddTransfer("GET", "parallel", 1, 100)
+			},
+			bytesHeader +
+				"gocmd_transferred_bytes_total{method=\"GET\",mode=\"parallel\"} 100\n" +
+				"gocmd_transferred_bytes_total{method=\"PUT\",mode=\"redirect\"} 2048\n" +
+				filesHeader +
+				"gocmd_transferred_files_total{method=\"GET\",mode=\"parallel\"} 1\n" +
+				"gocmd_transferred_files_total{method=\"PUT\",mode=\"redirect\"} 2\n",
+		},
+		{
+			"fractional values",
+			func(metrics *Metrics) {
+				metrics.AddBundleStage("tar", 1500*time.Millisecond)
+			},
+			stageSecondsHeader +
+				"gocmd_bundle_stage_seconds_total{stage=\"tar\"} 1.5\n" +
+				stageCountHeader +
+				"gocmd_bundle_stages_total{stage=\"tar\"} 1\n",
+		},
+		{
+			"backslash in label",
+			func(metrics *Metrics) {
+				metrics.AddJob("a\\b")
+			},
+			jobsHeader + "gocmd_jobs_total{status=\"a\\\\b\"} 1\n",
+		},
+		{
+			"quote in label",
+			func(metrics *Metrics) {
+				metrics.AddJob("say \"hi\"")
+			},
+			jobsHeader + "gocmd_jobs_total{status=\"say \\\"hi\\\"\"} 1\n",
+		},
+		{
+			"newline in label",
+			func(metrics *Metrics) {
+				metrics.AddJob("line1\nline2")
+			},
+			jobsHeader + "gocmd_jobs_total{status=\"line1\\nline2\"} 1\n",
+		},
+		{
+			"backslash followed by n in label",
+			func(metrics *Metrics) {
+				metrics.AddJob("\\n\n")
+			},
+			jobsHeader + "gocmd_jobs_total{status=\"\\\\n\\n\"} 1\n",
+		},
+	}
+
+	for _, test := range tests {
+		// start time is not recorded to make the output deterministic
+		metrics := &Metrics{
+			values: map[string]map[string]float64{},
+		}
+
+		test.record(metrics)
+
+		sb := strings.Builder{}
+		err := metrics.WriteText(&sb)
+		assert.NoError(t, err, test.name)
+		assert.Equal(t, test.expected, sb.String(), test.name)
+	}
+}
+
+func testWriteTextNil(t *testing.T) {
+	var metrics *Metrics
+	metrics.AddJob("done")
+
+	sb := strings.Builder{}
+	err := metrics.WriteText(&sb)
+	assert.NoError(t, err)
+	assert.Empty(t, sb.String())
+}
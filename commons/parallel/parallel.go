@@ -2,11 +2,14 @@ package parallel
 
 import (
 	"container/list"
+	"context"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/cockroachdb/errors"
 	"github.com/cyverse/gocommands/commons/terminal"
+	"github.com/cyverse/gocommands/commons/tracing"
 	"github.com/jedib0t/go-pretty/v6/progress"
 	log "github.com/sirupsen/logrus"
 )
@@ -14,24 +17,42 @@ import (
 type ParallelJobTask func(job *ParallelJob) error
 
 type ParallelJob struct {
+	// moved to top to avoid 64bit alignment issue
+	processedBytes int64
+
 	manager *ParallelJobManager
 
 	index        int64
 	name         string
 	task         ParallelJobTask
 	weight       int
+	size         int64
+	files        int
+	priority     int
 	progressUnit progress.Units
+	taskType     string
+	startTime    time.Time
+	lastEvent    time.Time // time of the last progress event
 	canceled     bool
+	ctx          context.Context // carries the span of the job while the task runs
 	mutex        sync.Mutex
 }
 
-func newParallelJob(manager *ParallelJobManager, name string, task ParallelJobTask, weight int, progressUnit progress.Units) *ParallelJob {
+func newParallelJob(manager *ParallelJobManager, name string, task ParallelJobTask, weight int, progressUnit progress.Units, options ParallelJobOptions) *ParallelJob {
+	files := options.Files
+	if files <= 0 {
+		files = 1
+	}
+
 	return &ParallelJob{
 		manager:      manager,
 		index:        manager.getNextJobIndex(),
 		name:         name,
 		task:         task,
 		weight:       weight,
+		size:         options.Size,
+		files:        files,
+		priority:     options.Priority,
 		progressUnit: progressUnit,
 	}
 }
@@ -41,17 +62,102 @@ func (job *ParallelJob) GetManager() *ParallelJobManager {
 }
 
 func (job *ParallelJob) Progress(taskType string, processed int64, total int64, errored bool) {
+	now := time.Now()
+
+	job.mutex.Lock()
+	job.taskType = taskType
+
+	// progress events are throttled, except the first and the last
+	emitEvent := errored || processed >= total || job.lastEvent.IsZero() || now.Sub(job.lastEvent) >= progressEventInterval
+	if emitEvent {
+		job.lastEvent = now
+	}
+	job.mutex.Unlock()
+
+	if emitEvent {
+		job.manager.writeJobEvent(job, ProgressEventProgress, &ProgressEvent{
+			Time:      now,
+			Task:      taskType,
+			Processed: processed,
+			Total:     total,
+		})
+	}
+
+	// bytes transferred are counted in the aggregate progress
+	if processed >= 0 && (taskType == "upload" || taskType == "download") {
+		if processed > job.size {
+			processed = job.size
+		}
+		atomic.StoreInt64(&job.processedBytes, processed)
+	}
+
 	job.manager.progress(taskType, job.name, processed, total, job.progressUnit, errored)
 }
 
+// getProgress returns the current task type, bytes transferred, and the time the job started
+func (job *ParallelJob) getProgress() (string, int64, time.Time) {
+	job.mutex.Lock()
+	defer job.mutex.Unlock()
+
+	return job.taskType, atomic.LoadInt64(&job.processedBytes), job.startTime
+}
+
+func (job *ParallelJob) setStarted() {
+	job.mutex.Lock()
+	defer job.mutex.Unlock()
+
+	job.startTime = time.Now()
+}
+
+// GetContext returns the context the manager is started with, tasks should stop when it is done
+// spans started with the context are children of the span of the job
+func (job *ParallelJob) GetContext() context.Context {
+	job.mutex.Lock()
+	ctx := job.ctx
+	job.mutex.Unlock()
+
+	if ctx != nil {
+		return ctx
+	}
+
+	return job.manager.getContext()
+}
+
+func (job *ParallelJob) setContext(ctx context.Context) {
+	job.mutex.Lock()
+	defer job.mutex.Unlock()
+
+	job.ctx = ctx
+}
+
+func (job *ParallelJob) GetIndex() int64 {
+	return job.index
+}
+
 func (job *ParallelJob) GetName() string {
 	return job.name
 }
 
+// GetLogFields returns log fields identifying the job, tasks add them to their log lines
+func (job *ParallelJob) GetLogFields() log.Fields {
+	return log.Fields{
+		"job_index": job.index,
+		"job_name":  job.name,
+	}
+}
+
 func (job *ParallelJob) GetWeight() int {
 	return job.weight
 }
 
+func (job *ParallelJob) GetSize() int64 {
+	return job.size
+}
+
+func (job *ParallelJob) GetPriority() int {
+	return job.priority
+}
+
 func (job *ParallelJob) SetCanceled() {
 	job.mutex.Lock()
 	defer job.mutex.Unlock()
@@ -66,25 +172,50 @@ func (job *ParallelJob) IsCanceled() bool {
 	return job.canceled
 }
 
+// JobMetrics counts jobs finished with their status, done, failed, or canceled
+type JobMetrics interface {
+	AddJob(status string)
+}
+
+// ParallelJobCounts has the number of jobs in each state
+type ParallelJobCounts struct {
+	Total    int64
+	Done     int64
+	Canceled int64
+	Errored  int64
+}
+
 type ParallelJobManager struct {
 	// moved to top to avoid 64bit alignment issue
 	jobsDoneCounter     int64
 	jobsErroredCounter  int64
 	jobsCanceledCounter int64
+	doneFilesCounter    int64
+	doneBytesCounter    int64
 
 	nextJobIndex            int64
 	pendingJobs             *list.List             // list of *ParallelJob
 	runningJobs             map[int64]*ParallelJob // map of job index to *ParallelJob
 	totalJobs               int
+	totalFiles              int64
+	totalBytes              int64
 	weightCapacity          int
 	currentWeight           int
+	schedulePolicy          SchedulePolicy
 	showProgress            bool
 	showFullPath            bool
+	startTime               time.Time
+	progressEventWriter     *ProgressEventWriter
+	metrics                 JobMetrics
+	progressMode            ProgressMode
+	progressSlowJobNum      int
 	progressWriter          progress.Writer
+	aggregateProgress       *aggregateProgress
 	progressTrackers        map[string]*progress.Tracker
 	progressTrackerCallback terminal.ProgressTrackerCallback
 	jobErrors               []error
 	stopOnError             bool
+	ctx                     context.Context
 	canceled                bool // if the job manager is canceled
 	mutex                   sync.RWMutex
 	waitCond                *sync.Cond // condition variable for waiting on weight capacity
@@ -101,13 +232,20 @@ func NewParallelJobManager(weightCapacity int, showProgress bool, showFullPath b
 		totalJobs:               0,
 		weightCapacity:          weightCapacity,
 		currentWeight:           0,
+		schedulePolicy:          SchedulePolicyFIFO,
 		showProgress:            showProgress,
 		showFullPath:            showFullPath,
+		progressEventWriter:     nil,
+		metrics:                 nil,
+		progressMode:            ProgressModeFull,
+		progressSlowJobNum:      DefaultProgressSlowJobNum,
 		progressWriter:          nil,
+		aggregateProgress:       nil,
 		progressTrackers:        map[string]*progress.Tracker{},
 		progressTrackerCallback: nil,
 		jobErrors:               nil,
 		stopOnError:             stopOnError,
+		ctx:                     context.Background(),
 		canceled:                false,
 		mutex:                   sync.RWMutex{},
 		processWait:             sync.WaitGroup{},
@@ -121,6 +259,131 @@ func NewParallelJobManager(weightCapacity int, showProgress bool, showFullPath b
 	return manager
 }
 
+// SetSchedulePolicy sets the order pending jobs are started
+// must be called before Start
+func (manager *ParallelJobManager) SetSchedulePolicy(policy SchedulePolicy) {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+
+	manager.schedulePolicy = policy
+}
+
+// SetProgressMode sets how progress of jobs is displayed
+// must be called before Start
+func (manager *ParallelJobManager) SetProgressMode(mode ProgressMode) {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+
+	manager.progressMode = mode
+}
+
+// SetProgressSlowJobNum sets the number of the slowest jobs displayed in compact mode
+// must be called before Start
+func (manager *ParallelJobManager) SetProgressSlowJobNum(num int) {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+
+	manager.progressSlowJobNum = num
+}
+
+// SetProgressEventWriter sets the writer to write progress events of jobs, as newline-delimited JSON
+// must be called before Schedule
+func (manager *ParallelJobManager) SetProgressEventWriter(writer *ProgressEventWriter) {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+
+	manager.progressEventWriter = writer
+}
+
+// SetMetrics sets the metrics counting jobs done, failed, and canceled
+// must be called before Start
+func (manager *ParallelJobManager) SetMetrics(metrics JobMetrics) {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+
+	manager.metrics = metrics
+}
+
+// addJobMetrics counts a job finished with the status if metrics are set
+func (manager *ParallelJobManager) addJobMetrics(status ProgressEventType) {
+	if manager.metrics == nil {
+		return
+	}
+
+	manager.metrics.AddJob(string(status))
+}
+
+func (manager *ParallelJobManager) writeJobEvent(job *ParallelJob, eventType ProgressEventType, event *ProgressEvent) {
+	if manager.progressEventWriter == nil {
+		return
+	}
+
+	event.Event = eventType
+	event.JobID = job.index + 1
+	event.JobName = job.name
+	if event.Size == 0 {
+		event.Size = job.size
+	}
+
+	manager.progressEventWriter.Write(event)
+}
+
+func (manager *ParallelJobManager) writeAggregateEvent() {
+	if manager.progressEventWriter == nil {
+		return
+	}
+
+	stats := manager.getProgressStats()
+	counts := manager.GetJobCounts()
+
+	manager.progressEventWriter.Write(&ProgressEvent{
+		Event:        ProgressEventAggregate,
+		TotalJobs:    counts.Total,
+		DoneJobs:     counts.Done,
+		FailedJobs:   counts.Errored,
+		CanceledJobs: counts.Canceled,
+		TotalFiles:   stats.totalFiles,
+		DoneFiles:    stats.doneFiles,
+		TotalBytes:   stats.totalBytes,
+		DoneBytes:    stats.doneBytes,
+		ElapsedSec:   time.Since(manager.startTime).Seconds(),
+	})
+}
+
+// startAggregateEvents writes aggregate events periodically until the returned function is called
+func (manager *ParallelJobManager) startAggregateEvents() func() {
+	if manager.progressEventWriter == nil {
+		return func() {}
+	}
+
+	stopChan := make(chan bool)
+	stopWait := sync.WaitGroup{}
+	stopWait.Add(1)
+
+	go func() {
+		defer stopWait.Done()
+
+		ticker := time.NewTicker(aggregateProgressEventInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stopChan:
+				return
+			case <-ticker.C:
+				manager.writeAggregateEvent()
+			}
+		}
+	}()
+
+	return func() {
+		close(stopChan)
+		stopWait.Wait()
+
+		manager.writeAggregateEvent()
+	}
+}
+
 func (manager *ParallelJobManager) getNextJobIndex() int64 {
 	idx := manager.nextJobIndex
 	manager.nextJobIndex++
@@ -190,6 +453,60 @@ func (manager *ParallelJobManager) CancelJobs() {
 	manager.canceled = true
 }
 
+// WaitJobs waits until all scheduled jobs return, returns false if they do not return within the timeout
+func (manager *ParallelJobManager) WaitJobs(timeout time.Duration) bool {
+	waitDone := make(chan struct{})
+	go func() {
+		manager.processWait.Wait()
+		close(waitDone)
+	}()
+
+	select {
+	case <-waitDone:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// WaitJobManagers waits until all jobs of the managers return, nil managers are skipped
+// returns false if they do not return within the timeout
+func WaitJobManagers(timeout time.Duration, managers ...*ParallelJobManager) bool {
+	deadline := time.Now().Add(timeout)
+	for _, manager := range managers {
+		if manager == nil {
+			continue
+		}
+
+		if !manager.WaitJobs(time.Until(deadline)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// GetJobCounts returns the number of jobs in each state, jobs pending or running are not counted in Done, Canceled, and Errored
+func (manager *ParallelJobManager) GetJobCounts() ParallelJobCounts {
+	manager.mutex.RLock()
+	total := manager.totalJobs
+	manager.mutex.RUnlock()
+
+	return ParallelJobCounts{
+		Total:    int64(total),
+		Done:     atomic.LoadInt64(&manager.jobsDoneCounter),
+		Canceled: atomic.LoadInt64(&manager.jobsCanceledCounter),
+		Errored:  atomic.LoadInt64(&manager.jobsErroredCounter),
+	}
+}
+
+func (manager *ParallelJobManager) getContext() context.Context {
+	manager.mutex.RLock()
+	defer manager.mutex.RUnlock()
+
+	return manager.ctx
+}
+
 func (manager *ParallelJobManager) IsJobCanceled() bool {
 	manager.mutex.RLock()
 	defer manager.mutex.RUnlock()
@@ -216,6 +533,24 @@ func (manager *ParallelJobManager) popNextPendingTask() *ParallelJob {
 	return nil
 }
 
+// orderPendingJobs reorders pending jobs by priority and schedule policy
+func (manager *ParallelJobManager) orderPendingJobs() {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+
+	jobs := make([]*ParallelJob, 0, manager.pendingJobs.Len())
+	for elem := manager.pendingJobs.Front(); elem != nil; elem = elem.Next() {
+		if job, ok := elem.Value.(*ParallelJob); ok {
+			jobs = append(jobs, job)
+		}
+	}
+
+	manager.pendingJobs.Init()
+	for _, job := range orderJobs(jobs, manager.schedulePolicy) {
+		manager.pendingJobs.PushBack(job)
+	}
+}
+
 func (manager *ParallelJobManager) removeRunningJob(job *ParallelJob) {
 	manager.mutex.Lock()
 	defer manager.mutex.Unlock()
@@ -223,26 +558,97 @@ func (manager *ParallelJobManager) removeRunningJob(job *ParallelJob) {
 	delete(manager.runningJobs, job.index)
 }
 
+func (manager *ParallelJobManager) getRunningJobs() []*ParallelJob {
+	manager.mutex.RLock()
+	defer manager.mutex.RUnlock()
+
+	jobs := make([]*ParallelJob, 0, len(manager.runningJobs))
+	for _, job := range manager.runningJobs {
+		jobs = append(jobs, job)
+	}
+
+	return jobs
+}
+
+type progressStats struct {
+	totalFiles int64
+	doneFiles  int64
+	totalBytes int64
+	doneBytes  int64
+}
+
+// getProgressStats returns files and bytes done, including bytes transferred by running jobs
+func (manager *ParallelJobManager) getProgressStats() progressStats {
+	manager.mutex.RLock()
+	stats := progressStats{
+		totalFiles: manager.totalFiles,
+		totalBytes: manager.totalBytes,
+	}
+
+	runningBytes := int64(0)
+	for _, job := range manager.runningJobs {
+		runningBytes += atomic.LoadInt64(&job.processedBytes)
+	}
+	manager.mutex.RUnlock()
+
+	stats.doneFiles = atomic.LoadInt64(&manager.doneFilesCounter)
+	stats.doneBytes = atomic.LoadInt64(&manager.doneBytesCounter) + runningBytes
+
+	return stats
+}
+
 // Schedule schedules a new job to run in parallel
 // must be called before Start
 func (manager *ParallelJobManager) Schedule(name string, task ParallelJobTask, weight int, progressUnit progress.Units) {
+	manager.ScheduleWithOptions(name, task, weight, progressUnit, ParallelJobOptions{})
+}
+
+// ScheduleWithOptions schedules a new job to run in parallel, with size and priority used for ordering jobs
+// must be called before Start
+func (manager *ParallelJobManager) ScheduleWithOptions(name string, task ParallelJobTask, weight int, progressUnit progress.Units, options ParallelJobOptions) {
 	manager.mutex.Lock()
 	defer manager.mutex.Unlock()
 
-	job := newParallelJob(manager, name, task, weight, progressUnit)
+	job := newParallelJob(manager, name, task, weight, progressUnit, options)
 
 	manager.pendingJobs.PushBack(job)
 	manager.processWait.Add(1)
 	manager.totalJobs++
+	manager.totalFiles += int64(job.files)
+	manager.totalBytes += job.size
+
+	manager.writeJobEvent(job, ProgressEventScheduled, &ProgressEvent{
+		Files: job.files,
+	})
 }
 
 // Start starts the job manager to run the scheduled jobs in parallel
 func (manager *ParallelJobManager) Start() error {
+	return manager.StartWithContext(context.Background())
+}
+
+// StartWithContext starts the job manager to run the scheduled jobs in parallel
+// pending jobs are canceled when the ctx is done, running jobs can stop by checking the ctx given by ParallelJob.GetContext
+func (manager *ParallelJobManager) StartWithContext(ctx context.Context) error {
 	logger := log.WithFields(log.Fields{})
 
+	manager.mutex.Lock()
+	manager.ctx = ctx
+	manager.mutex.Unlock()
+
+	stopCancel := context.AfterFunc(ctx, manager.CancelJobs)
+	defer stopCancel()
+
+	manager.orderPendingJobs()
+
+	manager.startTime = time.Now()
+
 	manager.startProgress()
 	defer manager.endProgress()
 
+	stopAggregateEvents := manager.startAggregateEvents()
+	defer stopAggregateEvents()
+
 	for {
 		job := manager.popNextPendingTask()
 		if job == nil {
@@ -260,17 +666,47 @@ func (manager *ParallelJobManager) Start() error {
 		}
 
 		manager.waitForWeight(job.weight)
+		job.setStarted()
+
+		manager.writeJobEvent(job, ProgressEventStarted, &ProgressEvent{
+			Files: job.files,
+		})
 
 		logger.Debugf("Run job id %d, name %q, canceled %t", job.index, job.name, job.canceled)
 
 		go func() {
-			taskLogger := log.WithFields(log.Fields{
-				"job_index": job.index,
-				"job_name":  job.name,
-				"canceled":  job.canceled,
+			taskLogger := log.WithFields(job.GetLogFields()).WithFields(log.Fields{
+				"canceled": job.canceled,
 			})
 
+			jobCtx, jobSpan := tracing.StartSpan(manager.getContext(), "job",
+				tracing.String("gocmd.job.name", job.name),
+				tracing.Int64("gocmd.job.size", job.size),
+				tracing.Int64("gocmd.job.files", int64(job.files)),
+			)
+			job.setContext(jobCtx)
+
 			err := job.task(job)
+			done := err == nil && !job.IsCanceled()
+
+			if err != nil {
+				manager.writeJobEvent(job, ProgressEventFailed, &ProgressEvent{
+					Error: err.Error(),
+				})
+				manager.addJobMetrics(ProgressEventFailed)
+				jobSpan.SetAttributes(tracing.String("gocmd.job.status", string(ProgressEventFailed)))
+			} else if done {
+				manager.writeJobEvent(job, ProgressEventDone, &ProgressEvent{})
+				manager.addJobMetrics(ProgressEventDone)
+				jobSpan.SetAttributes(tracing.String("gocmd.job.status", string(ProgressEventDone)))
+			} else {
+				manager.writeJobEvent(job, ProgressEventCanceled, &ProgressEvent{})
+				manager.addJobMetrics(ProgressEventCanceled)
+				jobSpan.SetAttributes(tracing.String("gocmd.job.status", string(ProgressEventCanceled)))
+			}
+
+			jobSpan.End(err)
+
 			if err != nil {
 				// increase jobs errored counter
 				atomic.AddInt64(&manager.jobsErroredCounter, 1)
@@ -292,6 +728,12 @@ func (manager *ParallelJobManager) Start() error {
 			}
 
 			manager.removeRunningJob(job)
+
+			if done {
+				atomic.AddInt64(&manager.doneFilesCounter, int64(job.files))
+				atomic.AddInt64(&manager.doneBytesCounter, job.size)
+			}
+
 			manager.processWait.Done()
 
 			manager.decWeight(job.weight)
@@ -313,11 +755,22 @@ func (manager *ParallelJobManager) startProgress() {
 
 		go manager.progressWriter.Render()
 
+		// aggregate progress of all jobs is displayed first
+		stats := manager.getProgressStats()
+		manager.aggregateProgress = newAggregateProgress(manager, stats.totalFiles, stats.totalBytes)
+		manager.progressWriter.AppendTracker(manager.aggregateProgress.tracker)
+		manager.aggregateProgress.start()
+
 		// add progress tracker callback
 		manager.progressTrackerCallback = func(taskType string, taskName string, processed int64, total int64, progressUnit progress.Units, errored bool) {
 			manager.mutex.Lock()
 			defer manager.mutex.Unlock()
 
+			if manager.progressMode == ProgressModeCompact {
+				// slowest jobs are displayed by the aggregate progress
+				return
+			}
+
 			trackerName := terminal.GetTrackerName(taskType, taskName)
 
 			var tracker *progress.Tracker
@@ -356,8 +809,20 @@ func (manager *ParallelJobManager) startProgress() {
 func (manager *ParallelJobManager) endProgress() {
 	if manager.showProgress {
 		if manager.progressWriter != nil {
+			if manager.aggregateProgress != nil {
+				manager.aggregateProgress.stop()
+			}
+
 			manager.mutex.Lock()
 
+			if manager.aggregateProgress != nil {
+				if len(manager.jobErrors) == 0 {
+					manager.aggregateProgress.tracker.MarkAsDone()
+				} else {
+					manager.aggregateProgress.tracker.MarkAsErrored()
+				}
+			}
+
 			for _, tracker := range manager.progressTrackers {
 				if len(manager.jobErrors) == 0 {
 					tracker.MarkAsDone()
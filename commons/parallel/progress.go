@@ -0,0 +1,216 @@
+package parallel
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/cyverse/gocommands/commons/terminal"
+	"github.com/jedib0t/go-pretty/v6/progress"
+)
+
+// ProgressMode determines how progress of jobs is displayed
+type ProgressMode string
+
+const (
+	// ProgressModeFull displays the aggregate progress and progress of every job
+	ProgressModeFull ProgressMode = "full"
+	// ProgressModeCompact displays the aggregate progress and the slowest active jobs only
+	ProgressModeCompact ProgressMode = "compact"
+)
+
+const (
+	// DefaultProgressSlowJobNum is the default number of the slowest jobs displayed in compact mode
+	DefaultProgressSlowJobNum int = 5
+
+	aggregateProgressUpdateInterval time.Duration = 500 * time.Millisecond
+	aggregateProgressRateWindow     time.Duration = 5 * time.Second
+)
+
+// GetProgressMode returns ProgressMode from string
+func GetProgressMode(mode string) (ProgressMode, error) {
+	switch strings.ToLower(mode) {
+	case string(ProgressModeFull), "":
+		return ProgressModeFull, nil
+	case string(ProgressModeCompact):
+		return ProgressModeCompact, nil
+	default:
+		return ProgressModeFull, errors.Errorf("unknown progress mode %q", mode)
+	}
+}
+
+type progressSample struct {
+	time  time.Time
+	value int64
+}
+
+// aggregateProgress tracks progress of all jobs of a manager, with the current throughput and ETA
+type aggregateProgress struct {
+	manager  *ParallelJobManager
+	tracker  *progress.Tracker
+	useBytes bool
+	samples  []progressSample
+	stopChan chan bool
+	stopWait sync.WaitGroup
+}
+
+func newAggregateProgress(manager *ParallelJobManager, totalFiles int64, totalBytes int64) *aggregateProgress {
+	// jobs without size are tracked by the number of files
+	useBytes := totalBytes > 0
+
+	tracker := &progress.Tracker{
+		Message: fmt.Sprintf("[Total] 0/%d files", totalFiles),
+		Total:   totalFiles,
+		Units:   progress.UnitsDefault,
+	}
+
+	if useBytes {
+		tracker.Total = totalBytes
+		tracker.Units = progress.UnitsBytes
+	}
+
+	return &aggregateProgress{
+		manager:  manager,
+		tracker:  tracker,
+		useBytes: useBytes,
+		samples:  []progressSample{},
+		stopChan: make(chan bool),
+	}
+}
+
+func (aggregate *aggregateProgress) start() {
+	aggregate.stopWait.Add(1)
+
+	go func() {
+		defer aggregate.stopWait.Done()
+
+		ticker := time.NewTicker(aggregateProgressUpdateInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-aggregate.stopChan:
+				return
+			case <-ticker.C:
+				aggregate.update()
+			}
+		}
+	}()
+}
+
+func (aggregate *aggregateProgress) stop() {
+	close(aggregate.stopChan)
+	aggregate.stopWait.Wait()
+
+	aggregate.update()
+}
+
+func (aggregate *aggregateProgress) update() {
+	manager := aggregate.manager
+	stats := manager.getProgressStats()
+
+	value := stats.doneFiles
+	total := stats.totalFiles
+	if aggregate.useBytes {
+		value = stats.doneBytes
+		total = stats.totalBytes
+	}
+
+	// current rate over the recent window
+	now := time.Now()
+	aggregate.samples = append(aggregate.samples, progressSample{
+		time:  now,
+		value: value,
+	})
+
+	for len(aggregate.samples) > 2 && now.Sub(aggregate.samples[0].time) > aggregateProgressRateWindow {
+		aggregate.samples = aggregate.samples[1:]
+	}
+
+	rate := float64(0)
+	oldest := aggregate.samples[0]
+	if elapsed := now.Sub(oldest.time).Seconds(); elapsed > 0 {
+		rate = float64(value-oldest.value) / elapsed
+	}
+
+	eta := "-"
+	if rate > 0 && total > value {
+		eta = time.Duration(float64(total-value) / rate * float64(time.Second)).Round(time.Second).String()
+	} else if total <= value {
+		eta = "0s"
+	}
+
+	rateString := fmt.Sprintf("%.1f files/s", rate)
+	if aggregate.useBytes {
+		rateString = fmt.Sprintf("%s/s", progress.UnitsBytes.Sprint(int64(rate)))
+	}
+
+	aggregate.tracker.UpdateMessage(fmt.Sprintf("[Total] %d/%d files", stats.doneFiles, stats.totalFiles))
+	aggregate.tracker.SetValue(value)
+
+	pinnedMessages := []string{
+		fmt.Sprintf("Total: %d/%d files, %s/%s, %s, ETA %s", stats.doneFiles, stats.totalFiles, progress.UnitsBytes.Sprint(stats.doneBytes), progress.UnitsBytes.Sprint(stats.totalBytes), rateString, eta),
+	}
+
+	if manager.progressMode == ProgressModeCompact {
+		pinnedMessages = append(pinnedMessages, aggregate.getSlowJobMessages(now)...)
+	}
+
+	manager.progressWriter.SetPinnedMessages(pinnedMessages...)
+}
+
+// getSlowJobMessages returns messages for the slowest active jobs
+func (aggregate *aggregateProgress) getSlowJobMessages(now time.Time) []string {
+	manager := aggregate.manager
+	messageWidth := terminal.GetProgressMessageWidth(manager.showFullPath)
+
+	type jobSpeed struct {
+		job       *ParallelJob
+		taskType  string
+		processed int64
+		speed     float64
+	}
+
+	jobSpeeds := []jobSpeed{}
+	for _, job := range manager.getRunningJobs() {
+		taskType, processed, startTime := job.getProgress()
+		if startTime.IsZero() {
+			continue
+		}
+
+		speed := float64(0)
+		if elapsed := now.Sub(startTime).Seconds(); elapsed > 0 {
+			speed = float64(processed) / elapsed
+		}
+
+		jobSpeeds = append(jobSpeeds, jobSpeed{
+			job:       job,
+			taskType:  taskType,
+			processed: processed,
+			speed:     speed,
+		})
+	}
+
+	sort.SliceStable(jobSpeeds, func(i int, j int) bool {
+		return jobSpeeds[i].speed < jobSpeeds[j].speed
+	})
+
+	messages := []string{}
+	for idx, jobSpeed := range jobSpeeds {
+		if idx >= manager.progressSlowJobNum {
+			break
+		}
+
+		name := terminal.GetTrackerName(jobSpeed.taskType, jobSpeed.job.name)
+		if !manager.showFullPath {
+			name = terminal.GetShortTrackerMessage(jobSpeed.taskType, jobSpeed.job.name, messageWidth)
+		}
+
+		messages = append(messages, fmt.Sprintf("  %s %s/%s, %s/s", name, progress.UnitsBytes.Sprint(jobSpeed.processed), progress.UnitsBytes.Sprint(jobSpeed.job.size), progress.UnitsBytes.Sprint(int64(jobSpeed.speed))))
+	}
+
+	return messages
+}
@@ -0,0 +1,232 @@
+package parallel
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/errors"
+)
+
+// ProgressEventType is a type of progress event
+type ProgressEventType string
+
+const (
+	// ProgressEventScheduled is emitted when a job is scheduled
+	ProgressEventScheduled ProgressEventType = "scheduled"
+	// ProgressEventStarted is emitted when a job starts
+	ProgressEventStarted ProgressEventType = "started"
+	// ProgressEventProgress is emitted when a job makes progress
+	ProgressEventProgress ProgressEventType = "progress"
+	// ProgressEventDone is emitted when a job completes
+	ProgressEventDone ProgressEventType = "done"
+	// ProgressEventFailed is emitted when a job fails
+	ProgressEventFailed ProgressEventType = "failed"
+	// ProgressEventCanceled is emitted when a job is canceled
+	ProgressEventCanceled ProgressEventType = "canceled"
+	// ProgressEventAggregate is emitted periodically with totals of all jobs
+	ProgressEventAggregate ProgressEventType = "aggregate"
+)
+
+const (
+	progressEventInterval          time.Duration = 250 * time.Millisecond
+	aggregateProgressEventInterval time.Duration = time.Second
+
+	progressEventFDPrefix string = "fd:"
+)
+
+// ProgressEvent is a progress event written as a line of JSON
+// job events and aggregate events are written with their own fields, see MarshalJSON
+type ProgressEvent struct {
+	Time  time.Time         `json:"time"`
+	Event ProgressEventType `json:"event"`
+
+	// job events, JobID starts from 1
+	JobID     int64  `json:"job_id"`
+	JobName   string `json:"job_name"`
+	Task      string `json:"task"`
+	Processed int64  `json:"processed"`
+	Total     int64  `json:"total"`
+	Size      int64  `json:"size"`
+	Files     int    `json:"files"`
+	Error     string `json:"error"`
+
+	// aggregate events
+	TotalJobs    int64   `json:"total_jobs"`
+	DoneJobs     int64   `json:"done_jobs"`
+	FailedJobs   int64   `json:"failed_jobs"`
+	CanceledJobs int64   `json:"canceled_jobs"`
+	TotalFiles   int64   `json:"total_files"`
+	DoneFiles    int64   `json:"done_files"`
+	TotalBytes   int64   `json:"total_bytes"`
+	DoneBytes    int64   `json:"done_bytes"`
+	ElapsedSec   float64 `json:"elapsed_sec"`
+}
+
+// jobProgressEvent is the JSON form of job events
+// processed and total are written for progress events only, even if they are zero
+type jobProgressEvent struct {
+	Time      time.Time         `json:"time"`
+	Event     ProgressEventType `json:"event"`
+	JobID     int64             `json:"job_id"`
+	JobName   string            `json:"job_name"`
+	Task      string            `json:"task,omitempty"`
+	Processed *int64            `json:"processed,omitempty"`
+	Total     *int64            `json:"total,omitempty"`
+	Size      int64             `json:"size"`
+	Files     int               `json:"files,omitempty"`
+	Error     string            `json:"error,omitempty"`
+}
+
+// aggregateProgressEvent is the JSON form of aggregate events, all counters are written even if they are zero
+type aggregateProgressEvent struct {
+	Time         time.Time         `json:"time"`
+	Event        ProgressEventType `json:"event"`
+	TotalJobs    int64             `json:"total_jobs"`
+	DoneJobs     int64             `json:"done_jobs"`
+	FailedJobs   int64             `json:"failed_jobs"`
+	CanceledJobs int64             `json:"canceled_jobs"`
+	TotalFiles   int64             `json:"total_files"`
+	DoneFiles    int64             `json:"done_files"`
+	TotalBytes   int64             `json:"total_bytes"`
+	DoneBytes    int64             `json:"done_bytes"`
+	ElapsedSec   float64           `json:"elapsed_sec"`
+}
+
+// MarshalJSON encodes the event with the fields of its event type
+func (event ProgressEvent) MarshalJSON() ([]byte, error) {
+	if event.Event == ProgressEventAggregate {
+		return json.Marshal(aggregateProgressEvent{
+			Time:         event.Time,
+			Event:        event.Event,
+			TotalJobs:    event.TotalJobs,
+			DoneJobs:     event.DoneJobs,
+			FailedJobs:   event.FailedJobs,
+			CanceledJobs: event.CanceledJobs,
+			TotalFiles:   event.TotalFiles,
+			DoneFiles:    event.DoneFiles,
+			TotalBytes:   event.TotalBytes,
+			DoneBytes:    event.DoneBytes,
+			ElapsedSec:   event.ElapsedSec,
+		})
+	}
+
+	jobEvent := jobProgressEvent{
+		Time:    event.Time,
+		Event:   event.Event,
+		JobID:   event.JobID,
+		JobName: event.JobName,
+		Size:    event.Size,
+		Files:   event.Files,
+		Error:   event.Error,
+	}
+
+	if event.Event == ProgressEventProgress {
+		jobEvent.Task = event.Task
+		jobEvent.Processed = &event.Processed
+		jobEvent.Total = &event.Total
+	}
+
+	return json.Marshal(jobEvent)
+}
+
+// ProgressEventWriter writes progress events as newline-delimited JSON, or passes them to a callback
+type ProgressEventWriter struct {
+	writer   io.Writer
+	closer   io.Closer
+	callback func(event ProgressEvent)
+	mutex    sync.Mutex
+}
+
+// NewProgressEventWriter creates a new ProgressEventWriter writing to the writer
+func NewProgressEventWriter(writer io.Writer) *ProgressEventWriter {
+	return &ProgressEventWriter{
+		writer: writer,
+		closer: nil,
+	}
+}
+
+// NewProgressEventCallbackWriter creates a new ProgressEventWriter passing events to the callback, the callback is not called concurrently
+func NewProgressEventCallbackWriter(callback func(event ProgressEvent)) *ProgressEventWriter {
+	return &ProgressEventWriter{
+		callback: callback,
+	}
+}
+
+// OpenProgressEventWriter creates a new ProgressEventWriter writing to a file descriptor in 'fd:N' form (e.g., fd:3) or a file path
+// standard input, output, and error (fd:0 to fd:2) are not accepted
+func OpenProgressEventWriter(target string) (*ProgressEventWriter, error) {
+	if fdInput, ok := strings.CutPrefix(target, progressEventFDPrefix); ok {
+		fd, err := strconv.ParseUint(fdInput, 10, 32)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid file descriptor %q", target)
+		}
+
+		if fd <= 2 {
+			return nil, errors.Errorf("invalid file descriptor %q, standard input, output, and error are not allowed", target)
+		}
+
+		file := os.NewFile(uintptr(fd), "fd"+fdInput)
+		if file == nil {
+			return nil, errors.Errorf("invalid file descriptor %q", target)
+		}
+
+		// the descriptor is owned by the caller of the process
+		return NewProgressEventWriter(file), nil
+	}
+
+	file, err := os.Create(target)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create progress event file %q", target)
+	}
+
+	return &ProgressEventWriter{
+		writer: file,
+		closer: file,
+	}, nil
+}
+
+// Write writes an event
+func (writer *ProgressEventWriter) Write(event *ProgressEvent) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	if writer.callback != nil {
+		writer.mutex.Lock()
+		defer writer.mutex.Unlock()
+
+		writer.callback(*event)
+		return
+	}
+
+	line, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+
+	line = append(line, '\n')
+
+	writer.mutex.Lock()
+	defer writer.mutex.Unlock()
+
+	writer.writer.Write(line) //nolint
+}
+
+// Close closes the file written, file descriptors given are not closed
+func (writer *ProgressEventWriter) Close() error {
+	writer.mutex.Lock()
+	defer writer.mutex.Unlock()
+
+	if writer.closer != nil {
+		err := writer.closer.Close()
+		writer.closer = nil
+		return err
+	}
+
+	return nil
+}
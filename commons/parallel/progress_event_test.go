@@ -0,0 +1,228 @@
+package parallel
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/jedib0t/go-pretty/v6/progress"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProgressEvent(t *testing.T) {
+	t.Run("test MarshalProgressEvent", testMarshalProgressEvent)
+	t.Run("test OpenProgressEventWriter", testOpenProgressEventWriter)
+	t.Run("test ProgressEventSequence", testProgressEventSequence)
+}
+
+func testMarshalProgressEvent(t *testing.T) {
+	eventTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		event    ProgressEvent
+		expected map[string]interface{}
+	}{
+		{
+			name:  "progress at zero",
+			event: ProgressEvent{Time: eventTime, Event: ProgressEventProgress, JobID: 1, JobName: "a", Task: "upload", Processed: 0, Total: 0, Size: 0},
+			expected: map[string]interface{}{
+				"time": "2024-01-02T03:04:05Z", "event": "progress", "job_id": 1.0, "job_name": "a", "task": "upload", "processed": 0.0, "total": 0.0, "size": 0.0,
+			},
+		},
+		{
+			name:  "scheduled",
+			event: ProgressEvent{Time: eventTime, Event: ProgressEventScheduled, JobID: 2, JobName: "b", Size: 10, Files: 1},
+			expected: map[string]interface{}{
+				"time": "2024-01-02T03:04:05Z", "event": "scheduled", "job_id": 2.0, "job_name": "b", "size": 10.0, "files": 1.0,
+			},
+		},
+		{
+			name:  "failed",
+			event: ProgressEvent{Time: eventTime, Event: ProgressEventFailed, JobID: 3, JobName: "c", Size: 10, Error: "failed"},
+			expected: map[string]interface{}{
+				"time": "2024-01-02T03:04:05Z", "event": "failed", "job_id": 3.0, "job_name": "c", "size": 10.0, "error": "failed",
+			},
+		},
+		{
+			name:  "aggregate at zero",
+			event: ProgressEvent{Time: eventTime, Event: ProgressEventAggregate, TotalJobs: 2},
+			expected: map[string]interface{}{
+				"time": "2024-01-02T03:04:05Z", "event": "aggregate", "total_jobs": 2.0, "done_jobs": 0.0, "failed_jobs": 0.0, "canceled_jobs": 0.0,
+				"total_files": 0.0, "done_files": 0.0, "total_bytes": 0.0, "done_bytes": 0.0, "elapsed_sec": 0.0,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		buffer := bytes.Buffer{}
+		writer := NewProgressEventWriter(&buffer)
+		writer.Write(&test.event)
+
+		assert.True(t, strings.HasSuffix(buffer.String(), "\n"), test.name)
+
+		decoded := map[string]interface{}{}
+		err := json.Unmarshal(buffer.Bytes(), &decoded)
+		assert.NoError(t, err, test.name)
+		assert.Equal(t, test.expected, decoded, test.name)
+	}
+}
+
+func testOpenProgressEventWriter(t *testing.T) {
+	tempDir := t.TempDir()
+
+	file, err := os.Create(filepath.Join(tempDir, "fd.jsonl"))
+	assert.NoError(t, err)
+	defer file.Close()
+
+	tests := []struct {
+		target   string
+		hasError bool
+	}{
+		{filepath.Join(tempDir, "events.jsonl"), false},
+		{fmt.Sprintf("fd:%d", file.Fd()), false},
+		{"fd:0", true},
+		{"fd:1", true},
+		{"fd:2", true},
+		{"fd:", true},
+		{"fd:three", true},
+		{filepath.Join(tempDir, "missing", "events.jsonl"), true},
+	}
+
+	for _, test := range tests {
+		writer, err := OpenProgressEventWriter(test.target)
+		if test.hasError {
+			assert.Error(t, err, test.target)
+			continue
+		}
+
+		assert.NoError(t, err, test.target)
+
+		writer.Write(&ProgressEvent{Event: ProgressEventDone, JobID: 1, JobName: test.target})
+		err = writer.Close()
+		assert.NoError(t, err, test.target)
+	}
+
+	// the file descriptor given is written but not closed
+	_, err = file.Stat()
+	assert.NoError(t, err)
+
+	for _, path := range []string{filepath.Join(tempDir, "events.jsonl"), filepath.Join(tempDir, "fd.jsonl")} {
+		data, err := os.ReadFile(path)
+		assert.NoError(t, err, path)
+		assert.Contains(t, string(data), `"event":"done"`, path)
+	}
+}
+
+func testProgressEventSequence(t *testing.T) {
+	events := []ProgressEvent{}
+	writer := NewProgressEventCallbackWriter(func(event ProgressEvent) {
+		events = append(events, event)
+	})
+
+	// runs one job at a time
+	manager := NewParallelJobManager(1, false, false, false)
+	manager.SetProgressEventWriter(writer)
+
+	manager.ScheduleWithOptions("done", func(job *ParallelJob) error {
+		job.Progress("upload", 0, 10, false)
+		job.Progress("upload", 5, 10, false) // throttled
+		job.Progress("upload", 10, 10, false)
+		return nil
+	}, 1, progress.UnitsBytes, ParallelJobOptions{Size: 10})
+
+	manager.ScheduleWithOptions("slow", func(job *ParallelJob) error {
+		job.Progress("upload", 0, 20, false)
+		job.Progress("upload", 5, 20, false) // throttled
+		time.Sleep(progressEventInterval + 50*time.Millisecond)
+		job.Progress("upload", 6, 20, false)
+		job.Progress("upload", 20, 20, false)
+		return nil
+	}, 1, progress.UnitsBytes, ParallelJobOptions{Size: 20})
+
+	manager.ScheduleWithOptions("failed", func(job *ParallelJob) error {
+		job.Progress("upload", 0, 30, false)
+		job.Progress("upload", 1, 30, true) // errors are not throttled
+		return errors.New("upload failed")
+	}, 1, progress.UnitsBytes, ParallelJobOptions{Size: 30})
+
+	manager.ScheduleWithOptions("canceled", func(job *ParallelJob) error {
+		job.SetCanceled()
+		return nil
+	}, 1, progress.UnitsBytes, ParallelJobOptions{Size: 40, Files: 2})
+
+	err := manager.Start()
+	assert.Error(t, err)
+
+	type jobEvent struct {
+		jobID     int64
+		event     ProgressEventType
+		processed int64
+	}
+
+	expected := []jobEvent{
+		{1, ProgressEventScheduled, 0},
+		{2, ProgressEventScheduled, 0},
+		{3, ProgressEventScheduled, 0},
+		{4, ProgressEventScheduled, 0},
+		{1, ProgressEventStarted, 0},
+		{1, ProgressEventProgress, 0},
+		{1, ProgressEventProgress, 10},
+		{1, ProgressEventDone, 0},
+		{2, ProgressEventStarted, 0},
+		{2, ProgressEventProgress, 0},
+		{2, ProgressEventProgress, 6},
+		{2, ProgressEventProgress, 20},
+		{2, ProgressEventDone, 0},
+		{3, ProgressEventStarted, 0},
+		{3, ProgressEventProgress, 0},
+		{3, ProgressEventProgress, 1},
+		{3, ProgressEventFailed, 0},
+		{4, ProgressEventStarted, 0},
+		{4, ProgressEventCanceled, 0},
+	}
+
+	jobEvents := []jobEvent{}
+	var lastAggregate *ProgressEvent
+	for idx := range events {
+		event := events[idx]
+		assert.False(t, event.Time.IsZero())
+
+		if event.Event == ProgressEventAggregate {
+			lastAggregate = &events[idx]
+			continue
+		}
+
+		jobEvents = append(jobEvents, jobEvent{event.JobID, event.Event, event.Processed})
+
+		switch event.Event {
+		case ProgressEventFailed:
+			assert.Equal(t, "failed", event.JobName)
+			assert.Equal(t, "upload failed", event.Error)
+		case ProgressEventProgress:
+			assert.Equal(t, "upload", event.Task)
+			assert.Equal(t, event.Size, event.Total)
+		}
+	}
+
+	assert.Equal(t, expected, jobEvents)
+
+	// the last event has the totals
+	if assert.NotNil(t, lastAggregate) {
+		assert.Equal(t, &events[len(events)-1], lastAggregate)
+		assert.Equal(t, int64(4), lastAggregate.TotalJobs)
+		assert.Equal(t, int64(2), lastAggregate.DoneJobs)
+		assert.Equal(t, int64(1), lastAggregate.FailedJobs)
+		assert.Equal(t, int64(1), lastAggregate.CanceledJobs)
+		assert.Equal(t, int64(5), lastAggregate.TotalFiles)
+		assert.Equal(t, int64(2), lastAggregate.DoneFiles)
+		assert.Equal(t, int64(100), lastAggregate.TotalBytes)
+		assert.Equal(t, int64(30), lastAggregate.DoneBytes)
+	}
+}
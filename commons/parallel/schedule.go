@@ -0,0 +1,113 @@
+package parallel
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+)
+
+// SchedulePolicy determines the order pending jobs are started
+type SchedulePolicy string
+
+const (
+	// SchedulePolicyFIFO starts jobs in the order they are scheduled
+	SchedulePolicyFIFO SchedulePolicy = "fifo"
+	// SchedulePolicySmallFirst starts smaller jobs first, to get results early
+	SchedulePolicySmallFirst SchedulePolicy = "small_first"
+	// SchedulePolicyLargeFirst starts larger jobs first, to shorten total time
+	SchedulePolicyLargeFirst SchedulePolicy = "large_first"
+	// SchedulePolicyInterleave alternates the smallest and the largest jobs, so large jobs do not block small jobs
+	SchedulePolicyInterleave SchedulePolicy = "interleave"
+)
+
+// GetSchedulePolicy returns SchedulePolicy from string
+func GetSchedulePolicy(policy string) (SchedulePolicy, error) {
+	switch strings.ToLower(policy) {
+	case string(SchedulePolicyFIFO), "":
+		return SchedulePolicyFIFO, nil
+	case string(SchedulePolicySmallFirst), "small-first", "smallest":
+		return SchedulePolicySmallFirst, nil
+	case string(SchedulePolicyLargeFirst), "large-first", "largest":
+		return SchedulePolicyLargeFirst, nil
+	case string(SchedulePolicyInterleave):
+		return SchedulePolicyInterleave, nil
+	default:
+		return SchedulePolicyFIFO, errors.Errorf("unknown schedule policy %q", policy)
+	}
+}
+
+// GetPriority returns the priority of the file at the path, a pattern given earlier has a higher priority, 0 if no patterns match
+func GetPriority(priorityPatterns []string, p string) int {
+	name := filepath.Base(p)
+	for idx, pattern := range priorityPatterns {
+		if matched, _ := filepath.Match(pattern, name); matched {
+			return len(priorityPatterns) - idx
+		}
+	}
+
+	return 0
+}
+
+// ParallelJobOptions has optional attributes of a job used for scheduling and displaying progress
+type ParallelJobOptions struct {
+	// Size is the size of data the job processes, used by size-based schedule policies and the aggregate progress
+	Size int64
+	// Files is the number of files the job processes, 1 if not set
+	Files int
+	// Priority is the priority of the job, jobs with higher priority start before others regardless of the policy
+	Priority int
+}
+
+// orderJobs returns jobs in the order to start, jobs are sorted by priority first, then by the policy
+// jobs having the same priority and size keep the scheduled order
+func orderJobs(jobs []*ParallelJob, policy SchedulePolicy) []*ParallelJob {
+	ordered := make([]*ParallelJob, len(jobs))
+	copy(ordered, jobs)
+
+	sort.SliceStable(ordered, func(i int, j int) bool {
+		if ordered[i].priority != ordered[j].priority {
+			return ordered[i].priority > ordered[j].priority
+		}
+
+		switch policy {
+		case SchedulePolicySmallFirst, SchedulePolicyInterleave:
+			return ordered[i].size < ordered[j].size
+		case SchedulePolicyLargeFirst:
+			return ordered[i].size > ordered[j].size
+		default:
+			return false
+		}
+	})
+
+	if policy != SchedulePolicyInterleave {
+		return ordered
+	}
+
+	// take the smallest and the largest alternately in each priority group
+	interleaved := make([]*ParallelJob, 0, len(ordered))
+	groupStart := 0
+	for groupStart < len(ordered) {
+		groupEnd := groupStart
+		for groupEnd < len(ordered) && ordered[groupEnd].priority == ordered[groupStart].priority {
+			groupEnd++
+		}
+
+		small := groupStart
+		large := groupEnd - 1
+		for small <= large {
+			interleaved = append(interleaved, ordered[small])
+			small++
+
+			if small <= large {
+				interleaved = append(interleaved, ordered[large])
+				large--
+			}
+		}
+
+		groupStart = groupEnd
+	}
+
+	return interleaved
+}
@@ -0,0 +1,104 @@
+package parallel
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSchedule(t *testing.T) {
+	t.Run("test GetSchedulePolicy", testGetSchedulePolicy)
+	t.Run("test OrderJobs", testOrderJobs)
+}
+
+func testGetSchedulePolicy(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected SchedulePolicy
+		hasError bool
+	}{
+		{"", SchedulePolicyFIFO, false},
+		{"FIFO", SchedulePolicyFIFO, false},
+		{"small-first", SchedulePolicySmallFirst, false},
+		{"largest", SchedulePolicyLargeFirst, false},
+		{"interleave", SchedulePolicyInterleave, false},
+		{"random", SchedulePolicyFIFO, true},
+	}
+
+	for _, test := range tests {
+		policy, err := GetSchedulePolicy(test.input)
+		if test.hasError {
+			assert.Error(t, err, test.input)
+		} else {
+			assert.NoError(t, err, test.input)
+		}
+
+		assert.Equal(t, test.expected, policy, test.input)
+	}
+}
+
+type testScheduleJob struct {
+	name     string
+	size     int64
+	priority int
+}
+
+func testOrderJobs(t *testing.T) {
+	// scheduled order
+	jobs := []testScheduleJob{
+		{"a", 30, 0},
+		{"b", 10, 0},
+		{"c", 50, 0},
+		{"d", 20, 0},
+		{"e", 40, 0},
+	}
+
+	prioritizedJobs := []testScheduleJob{
+		{"a", 30, 0},
+		{"b", 10, 1},
+		{"c", 50, 0},
+		{"d", 20, 1},
+		{"e", 40, 0},
+		{"f", 60, 1},
+		{"g", 5, 0},
+	}
+
+	tests := []struct {
+		name     string
+		jobs     []testScheduleJob
+		policy   SchedulePolicy
+		expected []string
+	}{
+		{"fifo", jobs, SchedulePolicyFIFO, []string{"a", "b", "c", "d", "e"}},
+		{"small first", jobs, SchedulePolicySmallFirst, []string{"b", "d", "a", "e", "c"}},
+		{"large first", jobs, SchedulePolicyLargeFirst, []string{"c", "e", "a", "d", "b"}},
+		{"interleave odd", jobs, SchedulePolicyInterleave, []string{"b", "c", "d", "e", "a"}},
+		{"interleave even", jobs[:4], SchedulePolicyInterleave, []string{"b", "c", "d", "a"}},
+		{"interleave single", jobs[:1], SchedulePolicyInterleave, []string{"a"}},
+		{"interleave empty", nil, SchedulePolicyInterleave, []string{}},
+		{"same size keeps scheduled order", []testScheduleJob{{"a", 10, 0}, {"b", 10, 0}, {"c", 5, 0}}, SchedulePolicyLargeFirst, []string{"a", "b", "c"}},
+		{"fifo with priority groups", prioritizedJobs, SchedulePolicyFIFO, []string{"b", "d", "f", "a", "c", "e", "g"}},
+		{"small first with priority groups", prioritizedJobs, SchedulePolicySmallFirst, []string{"b", "d", "f", "g", "a", "e", "c"}},
+		{"interleave within priority groups", prioritizedJobs, SchedulePolicyInterleave, []string{"b", "f", "d", "g", "c", "a", "e"}},
+	}
+
+	for _, test := range tests {
+		parallelJobs := make([]*ParallelJob, 0, len(test.jobs))
+		for _, job := range test.jobs {
+			parallelJobs = append(parallelJobs, &ParallelJob{
+				name:     job.name,
+				size:     job.size,
+				priority: job.priority,
+			})
+		}
+
+		ordered := orderJobs(parallelJobs, test.policy)
+
+		names := make([]string, 0, len(ordered))
+		for _, job := range ordered {
+			names = append(names, job.name)
+		}
+
+		assert.Equal(t, test.expected, names, test.name)
+	}
+}
@@ -0,0 +1,266 @@
+package tracing
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/cyverse/gocommands/commons"
+)
+
+const (
+	otlpTracesPath       string        = "/v1/traces"
+	otlpExportTimeout    time.Duration = 10 * time.Second
+	otlpSpanKindInternal int           = 1 // SPAN_KIND_INTERNAL
+	otlpStatusError      int           = 2 // STATUS_CODE_ERROR
+	serviceName          string        = "gocmd"
+)
+
+// Exporter exports ended spans
+type Exporter interface {
+	Export(spans []*Span) error
+	Close() error
+}
+
+// NewExporter returns an exporter writing spans in OTLP/JSON
+// spans are sent to a collector if the target is an http(s) URL, or appended to a file otherwise
+func NewExporter(target string) (Exporter, error) {
+	if strings.HasPrefix(target, "http://") || strings.HasPrefix(target, "https://") {
+		return newHTTPExporter(target), nil
+	}
+
+	return newFileExporter(target)
+}
+
+// fileExporter appends a line of OTLP/JSON (ExportTraceServiceRequest) per batch, readable by the otlpjsonfile receiver of the collector
+type fileExporter struct {
+	file  *os.File
+	mutex sync.Mutex
+}
+
+func newFileExporter(path string) (*fileExporter, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open trace file %q", path)
+	}
+
+	return &fileExporter{
+		file: file,
+	}, nil
+}
+
+func (exporter *fileExporter) Export(spans []*Span) error {
+	payload, err := encodeSpans(spans)
+	if err != nil {
+		return err
+	}
+
+	exporter.mutex.Lock()
+	defer exporter.mutex.Unlock()
+
+	_, err = exporter.file.Write(append(payload, '\n'))
+	if err != nil {
+		return errors.Wrapf(err, "failed to write spans to %q", exporter.file.Name())
+	}
+
+	return nil
+}
+
+func (exporter *fileExporter) Close() error {
+	return exporter.file.Close()
+}
+
+// httpExporter posts OTLP/JSON to the traces endpoint of a collector
+type httpExporter struct {
+	url    string
+	client *http.Client
+}
+
+func newHTTPExporter(url string) *httpExporter {
+	url = strings.TrimSuffix(url, "/")
+	if !strings.HasSuffix(url, otlpTracesPath) {
+		url += otlpTracesPath
+	}
+
+	return &httpExporter{
+		url: url,
+		client: &http.Client{
+			Timeout: otlpExportTimeout,
+		},
+	}
+}
+
+func (exporter *httpExporter) Export(spans []*Span) error {
+	payload, err := encodeSpans(spans)
+	if err != nil {
+		return err
+	}
+
+	resp, err := exporter.client.Post(exporter.url, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return errors.Wrapf(err, "failed to send spans to %q", exporter.url)
+	}
+	defer resp.Body.Close()
+
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return errors.Errorf("failed to send spans to %q, received %d error", exporter.url, resp.StatusCode)
+	}
+
+	return nil
+}
+
+func (exporter *httpExporter) Close() error {
+	exporter.client.CloseIdleConnections()
+	return nil
+}
+
+// OTLP/JSON structures, see opentelemetry-proto ExportTraceServiceRequest
+type otlpAnyValue struct {
+	StringValue *string `json:"stringValue,omitempty"`
+	IntValue    *string `json:"intValue,omitempty"` // int64 is encoded as a string
+	BoolValue   *bool   `json:"boolValue,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              int            `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Status            otlpStatus     `json:"status"`
+}
+
+type otlpScope struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpTraceRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+func encodeAttributes(attributes []Attribute) []otlpKeyValue {
+	keyValues := make([]otlpKeyValue, 0, len(attributes))
+	for _, attribute := range attributes {
+		keyValue := otlpKeyValue{
+			Key: attribute.Key,
+		}
+
+		switch value := attribute.Value.(type) {
+		case string:
+			keyValue.Value.StringValue = &value
+		case int64:
+			intValue := strconv.FormatInt(value, 10)
+			keyValue.Value.IntValue = &intValue
+		case bool:
+			keyValue.Value.BoolValue = &value
+		default:
+			stringValue := fmt.Sprintf("%v", value)
+			keyValue.Value.StringValue = &stringValue
+		}
+
+		keyValues = append(keyValues, keyValue)
+	}
+
+	return keyValues
+}
+
+func encodeSpans(spans []*Span) ([]byte, error) {
+	otlpSpans := make([]otlpSpan, 0, len(spans))
+	for _, span := range spans {
+		span.mutex.Lock()
+		encodedSpan := otlpSpan{
+			TraceID:           span.traceID,
+			SpanID:            span.spanID,
+			ParentSpanID:      span.parentSpanID,
+			Name:              span.name,
+			Kind:              otlpSpanKindInternal,
+			StartTimeUnixNano: strconv.FormatInt(span.startTime.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(span.endTime.UnixNano(), 10),
+			Attributes:        encodeAttributes(span.attributes),
+		}
+
+		if len(span.errorMessage) > 0 {
+			encodedSpan.Status = otlpStatus{
+				Code:    otlpStatusError,
+				Message: span.errorMessage,
+			}
+		}
+		span.mutex.Unlock()
+
+		otlpSpans = append(otlpSpans, encodedSpan)
+	}
+
+	version := commons.GetClientVersion()
+
+	resourceAttributes := []Attribute{
+		String("service.name", serviceName),
+	}
+
+	if len(version) > 0 {
+		resourceAttributes = append(resourceAttributes, String("service.version", version))
+	}
+
+	request := otlpTraceRequest{
+		ResourceSpans: []otlpResourceSpans{
+			{
+				Resource: otlpResource{
+					Attributes: encodeAttributes(resourceAttributes),
+				},
+				ScopeSpans: []otlpScopeSpans{
+					{
+						Scope: otlpScope{
+							Name:    "github.com/cyverse/gocommands",
+							Version: version,
+						},
+						Spans: otlpSpans,
+					},
+				},
+			},
+		},
+	}
+
+	payload, err := json.Marshal(request)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to encode spans")
+	}
+
+	return payload, nil
+}
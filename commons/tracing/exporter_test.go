@@ -0,0 +1,218 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"testing"
+
+	"github.com/cockroachdb/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// recordingExporter keeps spans exported
+type recordingExporter struct {
+	spans []*Span
+}
+
+func (exporter *recordingExporter) Export(spans []*Span) error {
+	exporter.spans = append(exporter.spans, spans...)
+	return nil
+}
+
+func (exporter *recordingExporter) Close() error {
+	return nil
+}
+
+func TestExporter(t *testing.T) {
+	t.Run("test StartSpan", testStartSpan)
+	t.Run("test EncodeSpans", testEncodeSpans)
+}
+
+func testStartSpan(t *testing.T) {
+	// no tracer
+	ctx, span := StartSpan(context.Background(), "put")
+	assert.Nil(t, span)
+	assert.Equal(t, context.Background(), ctx)
+
+	span.SetAttributes(String("key", "value"))
+	span.End(nil)
+
+	exporter := &recordingExporter{}
+	tracer := NewTracer(exporter)
+
+	ctx = ContextWithTracer(context.Background(), tracer)
+
+	rootCtx, root := StartSpan(ctx, "put")
+	childCtx, child := StartSpan(rootCtx, "upload")
+	_, grandchild := StartSpan(childCtx, "checksum")
+	_, sibling := StartSpan(rootCtx, "upload")
+	_, otherRoot := StartSpan(ctx, "get")
+
+	tests := []struct {
+		name     string
+		span     *Span
+		parent   *Span
+		sameRoot bool
+	}{
+		{"root", root, nil, true},
+		{"child", child, root, true},
+		{"grandchild", grandchild, child, true},
+		{"sibling", sibling, root, true},
+		{"other root", otherRoot, nil, false},
+	}
+
+	for _, test := range tests {
+		assert.Len(t, test.span.traceID, 32, test.name)
+		assert.Len(t, test.span.spanID, 16, test.name)
+
+		if test.parent != nil {
+			assert.Equal(t, test.parent.spanID, test.span.parentSpanID, test.name)
+		} else {
+			assert.Empty(t, test.span.parentSpanID, test.name)
+		}
+
+		if test.sameRoot {
+			assert.Equal(t, root.traceID, test.span.traceID, test.name)
+		} else {
+			assert.NotEqual(t, root.traceID, test.span.traceID, test.name)
+		}
+	}
+
+	assert.NotEqual(t, child.spanID, sibling.spanID)
+
+	// spans are exported once when they end
+	for _, span := range []*Span{grandchild, child, sibling, root, otherRoot} {
+		span.End(nil)
+	}
+	root.End(errors.New("ended twice"))
+
+	err := tracer.Close()
+	assert.NoError(t, err)
+
+	assert.Len(t, exporter.spans, 5)
+	assert.Empty(t, root.errorMessage)
+}
+
+func testEncodeSpans(t *testing.T) {
+	exporter := &recordingExporter{}
+	tracer := NewTracer(exporter)
+
+	ctx := ContextWithTracer(context.Background(), tracer)
+
+	rootCtx, root := StartSpan(ctx, "put",
+		String("gocmd.target_path", "/zone/home/user"),
+		Int64("gocmd.files", 2),
+	)
+	_, upload := StartSpan(rootCtx, "upload",
+		Int64("gocmd.size", 9007199254740993), // larger than float64 can hold exactly
+		Bool("gocmd.webdav", true),
+	)
+
+	upload.End(errors.New("connection reset"))
+	root.End(nil)
+
+	err := tracer.Close()
+	assert.NoError(t, err)
+
+	payload, err := encodeSpans(exporter.spans)
+	assert.NoError(t, err)
+
+	request := otlpTraceRequest{}
+	err = json.Unmarshal(payload, &request)
+	assert.NoError(t, err)
+
+	if !assert.Len(t, request.ResourceSpans, 1) || !assert.Len(t, request.ResourceSpans[0].ScopeSpans, 1) {
+		return
+	}
+
+	resourceAttributes := request.ResourceSpans[0].Resource.Attributes
+	if assert.NotEmpty(t, resourceAttributes) {
+		assert.Equal(t, "service.name", resourceAttributes[0].Key)
+		assert.Equal(t, serviceName, *resourceAttributes[0].Value.StringValue)
+	}
+
+	spans := request.ResourceSpans[0].ScopeSpans[0].Spans
+	if !assert.Len(t, spans, 2) {
+		return
+	}
+
+	// spans are encoded in the order they end
+	encodedUpload := spans[0]
+	encodedRoot := spans[1]
+
+	tests := []struct {
+		name         string
+		encoded      otlpSpan
+		span         *Span
+		parentSpanID string
+		status       otlpStatus
+		attributes   map[string]string
+	}{
+		{
+			name:         "root",
+			encoded:      encodedRoot,
+			span:         root,
+			parentSpanID: "",
+			status:       otlpStatus{},
+			attributes:   map[string]string{"gocmd.target_path": "/zone/home/user", "gocmd.files": "2"},
+		},
+		{
+			name:         "upload",
+			encoded:      encodedUpload,
+			span:         upload,
+			parentSpanID: root.spanID,
+			status:       otlpStatus{Code: otlpStatusError, Message: "connection reset"},
+			attributes:   map[string]string{"gocmd.size": "9007199254740993", "gocmd.webdav": "true"},
+		},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.span.name, test.encoded.Name, test.name)
+		assert.Equal(t, root.traceID, test.encoded.TraceID, test.name)
+		assert.Equal(t, test.span.spanID, test.encoded.SpanID, test.name)
+		assert.Equal(t, test.parentSpanID, test.encoded.ParentSpanID, test.name)
+		assert.Equal(t, otlpSpanKindInternal, test.encoded.Kind, test.name)
+		assert.Equal(t, test.status, test.encoded.Status, test.name)
+		assert.Equal(t, strconv.FormatInt(test.span.startTime.UnixNano(), 10), test.encoded.StartTimeUnixNano, test.name)
+		assert.Equal(t, strconv.FormatInt(test.span.endTime.UnixNano(), 10), test.encoded.EndTimeUnixNano, test.name)
+
+		attributes := map[string]string{}
+		for _, attribute := range test.encoded.Attributes {
+			switch {
+			case attribute.Value.StringValue != nil:
+				attributes[attribute.Key] = *attribute.Value.StringValue
+			case attribute.Value.IntValue != nil:
+				attributes[attribute.Key] = *attribute.Value.IntValue
+			case attribute.Value.BoolValue != nil:
+				attributes[attribute.Key] = strconv.FormatBool(*attribute.Value.BoolValue)
+			}
+		}
+		assert.Equal(t, test.attributes, attributes, test.name)
+	}
+
+	// int64 values and times are JSON strings, so they are not rounded by JSON decoders
+	decoded := map[string]interface{}{}
+	err = json.Unmarshal(payload, &decoded)
+	assert.NoError(t, err)
+
+	decodedUpload := decoded["resourceSpans"].([]interface{})[0].(map[string]interface{})["scopeSpans"].([]interface{})[0].(map[string]interface{})["spans"].([]interface{})[0].(map[string]interface{})
+	assert.IsType(t, "", decodedUpload["startTimeUnixNano"])
+	assert.IsType(t, "", decodedUpload["endTimeUnixNano"])
+
+	for _, attribute := range decodedUpload["attributes"].([]interface{}) {
+		keyValue := attribute.(map[string]interface{})
+		value := keyValue["value"].(map[string]interface{})
+
+		switch keyValue["key"] {
+		case "gocmd.size":
+			assert.Equal(t, map[string]interface{}{"intValue": "9007199254740993"}, value)
+		case "gocmd.webdav":
+			assert.Equal(t, map[string]interface{}{"boolValue": true}, value)
+		}
+	}
+
+	status := decodedUpload["status"].(map[string]interface{})
+	assert.Equal(t, 2.0, status["code"])
+	assert.Equal(t, "connection reset", status["message"])
+}
@@ -0,0 +1,217 @@
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	exportBatchSize     int           = 256
+	exportFlushInterval time.Duration = 5 * time.Second
+)
+
+// Attribute is a key-value pair tagged to a span
+type Attribute struct {
+	Key   string
+	Value interface{} // string, int64, or bool
+}
+
+// String returns a string attribute
+func String(key string, value string) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Int64 returns an integer attribute
+func Int64(key string, value int64) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Bool returns a boolean attribute
+func Bool(key string, value bool) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Span is a timed operation in a trace
+// all methods are no-op on nil, which is returned when tracing is disabled
+type Span struct {
+	tracer       *Tracer
+	traceID      string
+	spanID       string
+	parentSpanID string
+	name         string
+	startTime    time.Time
+	endTime      time.Time
+	attributes   []Attribute
+	errorMessage string
+	ended        bool
+	mutex        sync.Mutex
+}
+
+// SetAttributes adds attributes to the span
+func (span *Span) SetAttributes(attributes ...Attribute) {
+	if span == nil {
+		return
+	}
+
+	span.mutex.Lock()
+	defer span.mutex.Unlock()
+
+	span.attributes = append(span.attributes, attributes...)
+}
+
+// End ends the span, the span is marked as failed if err is not nil
+func (span *Span) End(err error) {
+	if span == nil {
+		return
+	}
+
+	span.mutex.Lock()
+	if span.ended {
+		span.mutex.Unlock()
+		return
+	}
+
+	span.ended = true
+	span.endTime = time.Now()
+	if err != nil {
+		span.errorMessage = err.Error()
+	}
+	span.mutex.Unlock()
+
+	span.tracer.addEndedSpan(span)
+}
+
+// Tracer collects ended spans and exports them in batches
+type Tracer struct {
+	exporter Exporter
+	spans    []*Span
+	mutex    sync.Mutex
+
+	stopChan  chan bool
+	waitGroup sync.WaitGroup
+	closeOnce sync.Once
+}
+
+// NewTracer creates a new Tracer exporting spans with the exporter
+func NewTracer(exporter Exporter) *Tracer {
+	tracer := &Tracer{
+		exporter: exporter,
+		spans:    []*Span{},
+
+		stopChan: make(chan bool),
+	}
+
+	tracer.waitGroup.Add(1)
+	go func() {
+		defer tracer.waitGroup.Done()
+
+		ticker := time.NewTicker(exportFlushInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-tracer.stopChan:
+				return
+			case <-ticker.C:
+				tracer.flush()
+			}
+		}
+	}()
+
+	return tracer
+}
+
+func (tracer *Tracer) addEndedSpan(span *Span) {
+	tracer.mutex.Lock()
+	tracer.spans = append(tracer.spans, span)
+	full := len(tracer.spans) >= exportBatchSize
+	tracer.mutex.Unlock()
+
+	if full {
+		tracer.flush()
+	}
+}
+
+func (tracer *Tracer) flush() {
+	tracer.mutex.Lock()
+	spans := tracer.spans
+	tracer.spans = []*Span{}
+	tracer.mutex.Unlock()
+
+	if len(spans) == 0 {
+		return
+	}
+
+	err := tracer.exporter.Export(spans)
+	if err != nil {
+		log.WithError(err).Warnf("failed to export %d spans", len(spans))
+	}
+}
+
+// Close exports remaining spans and releases the exporter
+func (tracer *Tracer) Close() error {
+	if tracer == nil {
+		return nil
+	}
+
+	var err error
+
+	tracer.closeOnce.Do(func() {
+		close(tracer.stopChan)
+		tracer.waitGroup.Wait()
+
+		tracer.flush()
+		err = tracer.exporter.Close()
+	})
+
+	return err
+}
+
+type tracerKey struct{}
+type spanKey struct{}
+
+// ContextWithTracer returns a copy of the ctx carrying the tracer, spans are started with StartSpan
+func ContextWithTracer(ctx context.Context, tracer *Tracer) context.Context {
+	if tracer == nil {
+		return ctx
+	}
+
+	return context.WithValue(ctx, tracerKey{}, tracer)
+}
+
+// StartSpan starts a span being a child of the span in the ctx, and returns a copy of the ctx carrying the new span
+// returns nil span if the ctx does not carry a tracer
+func StartSpan(ctx context.Context, name string, attributes ...Attribute) (context.Context, *Span) {
+	tracer, ok := ctx.Value(tracerKey{}).(*Tracer)
+	if !ok || tracer == nil {
+		return ctx, nil
+	}
+
+	span := &Span{
+		tracer:     tracer,
+		spanID:     newID(8),
+		name:       name,
+		startTime:  time.Now(),
+		attributes: attributes,
+	}
+
+	if parent, ok := ctx.Value(spanKey{}).(*Span); ok && parent != nil {
+		span.traceID = parent.traceID
+		span.parentSpanID = parent.spanID
+	} else {
+		span.traceID = newID(16)
+	}
+
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+func newID(size int) string {
+	id := make([]byte, size)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
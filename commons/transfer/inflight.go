@@ -0,0 +1,80 @@
+package transfer
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/cockroachdb/errors"
+)
+
+// InFlightTransfers tracks files being written, to remove incomplete files when transfers are aborted
+type InFlightTransfers struct {
+	cleanUpFuncs map[string]func() error
+	mutex        sync.Mutex
+	cleanUpMutex sync.Mutex // held while cleaning up, so callers return after files are removed
+}
+
+// NewInFlightTransfers creates a new InFlightTransfers
+func NewInFlightTransfers() *InFlightTransfers {
+	return &InFlightTransfers{
+		cleanUpFuncs: map[string]func() error{},
+		mutex:        sync.Mutex{},
+		cleanUpMutex: sync.Mutex{},
+	}
+}
+
+// Start records that the file at the path is being written, cleanUp removes the incomplete file
+func (transfers *InFlightTransfers) Start(path string, cleanUp func() error) {
+	transfers.mutex.Lock()
+	defer transfers.mutex.Unlock()
+
+	transfers.cleanUpFuncs[path] = cleanUp
+}
+
+// Finish records that the file at the path is complete or removed
+func (transfers *InFlightTransfers) Finish(path string) {
+	transfers.mutex.Lock()
+	defer transfers.mutex.Unlock()
+
+	delete(transfers.cleanUpFuncs, path)
+}
+
+// Count returns the number of files being written
+func (transfers *InFlightTransfers) Count() int {
+	transfers.mutex.Lock()
+	defer transfers.mutex.Unlock()
+
+	return len(transfers.cleanUpFuncs)
+}
+
+// CleanUp removes incomplete files being written, returns paths removed
+// if another CleanUp is in progress, it waits until that completes
+func (transfers *InFlightTransfers) CleanUp() ([]string, error) {
+	transfers.cleanUpMutex.Lock()
+	defer transfers.cleanUpMutex.Unlock()
+
+	transfers.mutex.Lock()
+	cleanUpFuncs := transfers.cleanUpFuncs
+	transfers.cleanUpFuncs = map[string]func() error{}
+	transfers.mutex.Unlock()
+
+	paths := make([]string, 0, len(cleanUpFuncs))
+	for path := range cleanUpFuncs {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	removedPaths := []string{}
+	cleanUpErrors := []error{}
+	for _, path := range paths {
+		err := cleanUpFuncs[path]()
+		if err != nil {
+			cleanUpErrors = append(cleanUpErrors, errors.Wrapf(err, "failed to remove incomplete file %q", path))
+			continue
+		}
+
+		removedPaths = append(removedPaths, path)
+	}
+
+	return removedPaths, errors.Join(cleanUpErrors...)
+}
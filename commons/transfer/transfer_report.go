@@ -11,6 +11,7 @@ import (
 
 	"github.com/cockroachdb/errors"
 	irodsclient_fs "github.com/cyverse/go-irodsclient/fs"
+	"github.com/cyverse/gocommands/commons/config"
 	"github.com/cyverse/gocommands/commons/terminal"
 	"github.com/jedib0t/go-pretty/v6/table"
 )
@@ -29,12 +30,15 @@ const (
 	TransferMethodCopy TransferMethod = "COPY"
 	// TransferMethodDelete is for delete command
 	TransferMethodDelete TransferMethod = "DELETE"
+	// TransferMethodReencrypt is for reencrypt command
+	TransferMethodReencrypt TransferMethod = "REENCRYPT"
 	// TransferMethodUnknown is for unknown command
 	TransferMethodUnknown TransferMethod = ""
 )
 
 type TransferReportFile struct {
-	Method TransferMethod `json:"method"` // get, put, bput ...
+	Method        TransferMethod `json:"method"`         // get, put, bput ...
+	CorrelationID string         `json:"correlation_id"` // ID of the run, set when added
 
 	StartAt time.Time `json:"start_time"`
 	EndAt   time.Time `json:"end_at"`
@@ -65,6 +69,8 @@ func GetTransferMethod(method string) TransferMethod {
 		return TransferMethodCopy
 	case string(TransferMethodDelete), "DEL":
 		return TransferMethodDelete
+	case string(TransferMethodReencrypt):
+		return TransferMethodReencrypt
 	default:
 		return TransferMethodUnknown
 	}
@@ -157,8 +163,11 @@ func NewTransferReportManager(report bool, reportPath string, reportToStdout boo
 	return manager, nil
 }
 
-// Release releases resources
+// Release releases resources, reports are flushed to the report file
 func (manager *TransferReportManager) Release() {
+	manager.lock.Lock()
+	defer manager.lock.Unlock()
+
 	if manager.writer != nil {
 		if !manager.reportToStdout {
 			manager.writer.Close()
@@ -174,12 +183,16 @@ func (manager *TransferReportManager) AddFile(file *TransferReportFile) error {
 		return nil
 	}
 
+	manager.lock.Lock()
+	defer manager.lock.Unlock()
+
 	if manager.writer == nil {
 		return nil
 	}
 
-	manager.lock.Lock()
-	defer manager.lock.Unlock()
+	if len(file.CorrelationID) == 0 {
+		file.CorrelationID = config.GetCorrelationID()
+	}
 
 	lineOutput := ""
 	if manager.reportToStdout {
@@ -198,6 +211,7 @@ func (manager *TransferReportManager) AddFile(file *TransferReportFile) error {
 
 		t.AppendRows([]table.Row{
 			{"Method", file.Method},
+			{"Correlation ID", file.CorrelationID},
 			{"Start Time", file.StartAt.Format("2006-01-02 15:04:05 MST")},
 			{"End Time", file.EndAt.Format("2006-01-02 15:04:05 MST")},
 			{"Source Path", file.SourcePath},
@@ -2,6 +2,7 @@ package types
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/cockroachdb/errors"
 )
@@ -103,3 +104,104 @@ func IsWebDAVError(err error) bool {
 	var webDAVErr *WebDAVError
 	return errors.As(err, &webDAVErr)
 }
+
+type IntegrityError struct {
+	Path   string
+	Reason string
+}
+
+func NewIntegrityError(path string, reason string) error {
+	return &IntegrityError{
+		Path:   path,
+		Reason: reason,
+	}
+}
+
+// Error returns error message
+func (err *IntegrityError) Error() string {
+	if len(err.Path) == 0 {
+		return fmt.Sprintf("integrity check failed, %s", err.Reason)
+	}
+	return fmt.Sprintf("integrity check failed for %q, %s", err.Path, err.Reason)
+}
+
+// Is tests type of error
+func (err *IntegrityError) Is(other error) bool {
+	_, ok := other.(*IntegrityError)
+	return ok
+}
+
+// ToString stringifies the object
+func (err *IntegrityError) ToString() string {
+	return fmt.Sprintf("IntegrityError: %q (%s)", err.Path, err.Reason)
+}
+
+// IsIntegrityError evaluates if the given error is IntegrityError
+func IsIntegrityError(err error) bool {
+	var integrityErr *IntegrityError
+	return errors.As(err, &integrityErr)
+}
+
+type InterruptedError struct {
+	Signal string
+}
+
+func NewInterruptedError(signal string) error {
+	return &InterruptedError{
+		Signal: signal,
+	}
+}
+
+// Error returns error message
+func (err *InterruptedError) Error() string {
+	return fmt.Sprintf("interrupted by signal %q", err.Signal)
+}
+
+// Is tests type of error
+func (err *InterruptedError) Is(other error) bool {
+	_, ok := other.(*InterruptedError)
+	return ok
+}
+
+// ToString stringifies the object
+func (err *InterruptedError) ToString() string {
+	return fmt.Sprintf("InterruptedError: %q", err.Signal)
+}
+
+// IsInterruptedError evaluates if the given error is InterruptedError
+func IsInterruptedError(err error) bool {
+	var interruptedErr *InterruptedError
+	return errors.As(err, &interruptedErr)
+}
+
+type DeadlineExceededError struct {
+	Deadline time.Duration
+}
+
+func NewDeadlineExceededError(deadline time.Duration) error {
+	return &DeadlineExceededError{
+		Deadline: deadline,
+	}
+}
+
+// Error returns error message
+func (err *DeadlineExceededError) Error() string {
+	return fmt.Sprintf("deadline %s exceeded", err.Deadline)
+}
+
+// Is tests type of error
+func (err *DeadlineExceededError) Is(other error) bool {
+	_, ok := other.(*DeadlineExceededError)
+	return ok
+}
+
+// ToString stringifies the object
+func (err *DeadlineExceededError) ToString() string {
+	return fmt.Sprintf("DeadlineExceededError: %s", err.Deadline)
+}
+
+// IsDeadlineExceededError evaluates if the given error is DeadlineExceededError
+func IsDeadlineExceededError(err error) bool {
+	var deadlineExceededErr *DeadlineExceededError
+	return errors.As(err, &deadlineExceededErr)
+}
@@ -1,24 +1,32 @@
 package webdav
 
 import (
+	"context"
 	"io"
 )
 
 type ProgressCallback func(writeSize int)
 
+// WriterWithProgress reports the size written to the callback, writes fail once the ctx is done
 type WriterWithProgress struct {
+	ctx        context.Context
 	baseWriter io.WriteCloser
 	callback   ProgressCallback
 }
 
-func NewWriterWithProgress(baseWriter io.WriteCloser, callback ProgressCallback) *WriterWithProgress {
+func NewWriterWithProgress(ctx context.Context, baseWriter io.WriteCloser, callback ProgressCallback) *WriterWithProgress {
 	return &WriterWithProgress{
+		ctx:        ctx,
 		baseWriter: baseWriter,
 		callback:   callback,
 	}
 }
 
 func (w *WriterWithProgress) Write(p []byte) (n int, err error) {
+	if ctxErr := w.ctx.Err(); ctxErr != nil {
+		return 0, ctxErr
+	}
+
 	n, err = w.baseWriter.Write(p)
 	if n > 0 {
 		w.callback(n)
@@ -30,19 +38,26 @@ func (w *WriterWithProgress) Close() error {
 	return w.baseWriter.Close()
 }
 
+// ReaderWithProgress reports the size read to the callback, reads fail once the ctx is done
 type ReaderWithProgress struct {
+	ctx        context.Context
 	baseReader io.ReadCloser
 	callback   ProgressCallback
 }
 
-func NewReaderWithProgress(baseReader io.ReadCloser, callback ProgressCallback) *ReaderWithProgress {
+func NewReaderWithProgress(ctx context.Context, baseReader io.ReadCloser, callback ProgressCallback) *ReaderWithProgress {
 	return &ReaderWithProgress{
+		ctx:        ctx,
 		baseReader: baseReader,
 		callback:   callback,
 	}
 }
 
 func (r *ReaderWithProgress) Read(p []byte) (n int, err error) {
+	if ctxErr := r.ctx.Err(); ctxErr != nil {
+		return 0, ctxErr
+	}
+
 	n, err = r.baseReader.Read(p)
 	if n > 0 {
 		r.callback(n)
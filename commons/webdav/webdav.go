@@ -2,9 +2,11 @@ package webdav
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
@@ -41,14 +43,33 @@ func NewWebDAVClient(filesystem *irodsclient_fs.FileSystem, baseURL string, user
 		webdav: nil,
 	}
 
-	err := client.initWebDAV()
+	err := client.initWebDAV(true)
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to connect to WebDAV server")
 	}
 	return client, nil
 }
 
-func (client *WebDAVClient) initWebDAV() error {
+// NewWebDAVClientForTicket creates a WebDAV client for ticket access without probing the WebDAV root
+// as anonymous users may not be able to access the root, errors are reported when accessing paths with the ticket
+func NewWebDAVClientForTicket(filesystem *irodsclient_fs.FileSystem, baseURL string, username string, password string) (*WebDAVClient, error) {
+	client := &WebDAVClient{
+		filesystem: filesystem,
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		username:   username,
+		password:   password,
+
+		webdav: nil,
+	}
+
+	err := client.initWebDAV(false)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create WebDAV client")
+	}
+	return client, nil
+}
+
+func (client *WebDAVClient) initWebDAV(probe bool) error {
 	webdav := gowebdav.NewClient(client.baseURL, client.username, client.password)
 
 	tlsConfig := &tls.Config{
@@ -88,13 +109,16 @@ func (client *WebDAVClient) initWebDAV() error {
 	}
 
 	webdav.SetTransport(transport)
-	err := webdav.Connect()
-	if err != nil {
-		if httpStatusErr, ok := client.getWebDAVErrorCode(err); ok {
-			return types.NewWebDAVError(client.baseURL, int(httpStatusErr))
-		}
 
-		return types.NewWebDAVError(client.baseURL, http.StatusServiceUnavailable)
+	if probe {
+		err := webdav.Connect()
+		if err != nil {
+			if httpStatusErr, ok := client.getWebDAVErrorCode(err); ok {
+				return types.NewWebDAVError(client.baseURL, int(httpStatusErr))
+			}
+
+			return types.NewWebDAVError(client.baseURL, http.StatusServiceUnavailable)
+		}
 	}
 
 	client.webdav = webdav
@@ -120,11 +144,23 @@ func (client *WebDAVClient) getWebDavError(url string, err error) error {
 	return err
 }
 
+// GetTicketURL returns an URL to access the iRODS path with the ticket via WebDAV
+// each path segment and the ticket are escaped
+func GetTicketURL(baseURL string, irodsPath string, ticket string) string {
+	segments := strings.Split(irodsPath, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+
+	return strings.TrimSuffix(baseURL, "/") + strings.Join(segments, "/") + "?ticket=" + url.QueryEscape(ticket)
+}
+
 func (client *WebDAVClient) getPathForTicket(irodsPath string, ticket string) string {
 	return client.baseURL + irodsPath + "?ticket=" + ticket
 }
 
-func (client *WebDAVClient) DownloadFile(sourceEntry *irodsclient_fs.Entry, localPath string, ticket string, verifyChecksum bool, callback irodsclient_common.TransferTrackerCallback) (*irodsclient_fs.FileTransferResult, error) {
+// DownloadFile downloads a data object to a local file, the download stops when the ctx is done
+func (client *WebDAVClient) DownloadFile(ctx context.Context, sourceEntry *irodsclient_fs.Entry, localPath string, ticket string, verifyChecksum bool, callback irodsclient_common.TransferTrackerCallback) (*irodsclient_fs.FileTransferResult, error) {
 	logger := log.WithFields(log.Fields{
 		"irods_source_path": sourceEntry.Path,
 		"local_path":        localPath,
@@ -196,7 +232,7 @@ func (client *WebDAVClient) DownloadFile(sourceEntry *irodsclient_fs.Entry, loca
 
 	logger.Debugf("downloading file %s (offset %d, length %d) from WebDAV server", irodsSrcPath, offset, readSize)
 
-	newOffset, downloadErr := client.downloadToLocalWithTrackerCallBack(irodsSrcPath, localFilePath, ticket, offset, readSize, sourceEntry.Size, callback)
+	newOffset, downloadErr := client.downloadToLocalWithTrackerCallBack(ctx, irodsSrcPath, localFilePath, ticket, offset, readSize, sourceEntry.Size, callback)
 	if downloadErr != nil {
 		logger.WithError(downloadErr).Debugf("failed to download file %q (offset %d, length %d) from WebDAV server", irodsSrcPath, offset, readSize)
 		return fileTransferResult, errors.Wrapf(downloadErr, "failed to download file %q (offset %d, length %d) from WebDAV server", irodsSrcPath, offset, readSize)
@@ -227,7 +263,8 @@ func (client *WebDAVClient) DownloadFile(sourceEntry *irodsclient_fs.Entry, loca
 	return fileTransferResult, nil
 }
 
-func (client *WebDAVClient) UploadFile(localPath string, irodsPath string, ticket string, verifyChecksum bool, callback irodsclient_common.TransferTrackerCallback) (*irodsclient_fs.FileTransferResult, error) {
+// UploadFile uploads a local file to a data object, the upload stops when the ctx is done
+func (client *WebDAVClient) UploadFile(ctx context.Context, localPath string, irodsPath string, ticket string, verifyChecksum bool, callback irodsclient_common.TransferTrackerCallback) (*irodsclient_fs.FileTransferResult, error) {
 	logger := log.WithFields(log.Fields{
 		"local_source_path": localPath,
 		"irods_path":        irodsPath,
@@ -283,7 +320,7 @@ func (client *WebDAVClient) UploadFile(localPath string, irodsPath string, ticke
 
 	logger.Debugf("uploading file %s (length %d) to WebDAV server", localSrcPath, writeSize)
 
-	_, uploadErr := client.uploadToIrodsWithTrackerCallBack(localSrcPath, irodsFilePath, ticket, writeSize, callback)
+	_, uploadErr := client.uploadToIrodsWithTrackerCallBack(ctx, localSrcPath, irodsFilePath, ticket, writeSize, callback)
 	if uploadErr != nil {
 		logger.WithError(uploadErr).Debugf("failed to upload file %q (length %d) to WebDAV server", localSrcPath, writeSize)
 		return fileTransferResult, errors.Wrapf(uploadErr, "failed to upload file %q (length %d) to WebDAV server", localSrcPath, writeSize)
@@ -341,7 +378,7 @@ func (client *WebDAVClient) calculateLocalFileHash(localPath string, algorithm i
 	return hashBytes, nil
 }
 
-func (client *WebDAVClient) downloadToLocalWithTrackerCallBack(irodsPath string, localPath string, ticket string, offset int64, readLength int64, fileSize int64, callback irodsclient_common.TransferTrackerCallback) (int64, error) {
+func (client *WebDAVClient) downloadToLocalWithTrackerCallBack(ctx context.Context, irodsPath string, localPath string, ticket string, offset int64, readLength int64, fileSize int64, callback irodsclient_common.TransferTrackerCallback) (int64, error) {
 	webdavPath := client.getPathForTicket(irodsPath, ticket)
 
 	reader, readErr := client.webdav.ReadStreamRange(webdavPath, offset, readLength)
@@ -379,7 +416,7 @@ func (client *WebDAVClient) downloadToLocalWithTrackerCallBack(irodsPath string,
 		}
 	}
 
-	progressWriter := NewWriterWithProgress(f, progress)
+	progressWriter := NewWriterWithProgress(ctx, f, progress)
 	defer progressWriter.Close()
 
 	if callback != nil {
@@ -402,7 +439,7 @@ func (client *WebDAVClient) downloadToLocalWithTrackerCallBack(irodsPath string,
 	return offset + actualWrite, nil
 }
 
-func (client *WebDAVClient) uploadToIrodsWithTrackerCallBack(localPath string, irodsPath string, ticket string, fileSize int64, callback irodsclient_common.TransferTrackerCallback) (int64, error) {
+func (client *WebDAVClient) uploadToIrodsWithTrackerCallBack(ctx context.Context, localPath string, irodsPath string, ticket string, fileSize int64, callback irodsclient_common.TransferTrackerCallback) (int64, error) {
 	webdavPath := client.getPathForTicket(irodsPath, ticket)
 
 	reader, readErr := os.Open(localPath)
@@ -424,7 +461,7 @@ func (client *WebDAVClient) uploadToIrodsWithTrackerCallBack(localPath string, i
 		}
 	}
 
-	progressReader := NewReaderWithProgress(reader, progress)
+	progressReader := NewReaderWithProgress(ctx, reader, progress)
 	defer progressReader.Close()
 
 	err := client.webdav.WriteStreamWithLength(webdavPath, progressReader, fileSize, 0)
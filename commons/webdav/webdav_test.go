@@ -1,6 +1,7 @@
 package webdav
 
 import (
+	"context"
 	"encoding/hex"
 	"os"
 	"testing"
@@ -12,9 +13,28 @@ import (
 )
 
 func TestWebDAV(t *testing.T) {
+	t.Run("test GetTicketURL", testGetTicketURL)
 	t.Run("test DownloadFileFromWebDAV", testDownloadFileFromWebDAV)
 }
 
+func testGetTicketURL(t *testing.T) {
+	tests := []struct {
+		name     string
+		baseURL  string
+		path     string
+		ticket   string
+		expected string
+	}{
+		{"plain", "https://data.cyverse.org/dav/", "/iplant/home/user/file.txt", "abc123", "https://data.cyverse.org/dav/iplant/home/user/file.txt?ticket=abc123"},
+		{"space and hash", "https://data.cyverse.org/dav", "/iplant/home/user/my file#1.txt", "abc123", "https://data.cyverse.org/dav/iplant/home/user/my%20file%231.txt?ticket=abc123"},
+		{"question mark and ampersand", "https://data.cyverse.org/dav", "/iplant/home/user/a?b&c", "t&k=1", "https://data.cyverse.org/dav/iplant/home/user/a%3Fb&c?ticket=t%26k%3D1"},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, GetTicketURL(test.baseURL, test.path, test.ticket), test.name)
+	}
+}
+
 func testDownloadFileFromWebDAV(t *testing.T) {
 	checksumBytes, _ := hex.DecodeString("713133e1a59ef6d1e42aa5405beae0de")
 	sourceEntry := &irodsclient_fs.Entry{
@@ -39,7 +59,7 @@ func testDownloadFileFromWebDAV(t *testing.T) {
 	webdav, err := NewWebDAVClient(nil, "https://data.cyverse.org/dav", "username", "password")
 	assert.NoError(t, err)
 
-	transferResult, err := webdav.DownloadFile(sourceEntry, localPath, "", true, callback)
+	transferResult, err := webdav.DownloadFile(context.Background(), sourceEntry, localPath, "", true, callback)
 	assert.NoError(t, err)
 
 	os.Remove(localPath) // Clean up the test file
@@ -1,6 +1,7 @@
 package wildcard
 
 import (
+	"context"
 	"sort"
 
 	"github.com/cockroachdb/errors"
@@ -11,7 +12,8 @@ import (
 	"github.com/cyverse/gocommands/commons/path"
 )
 
-func ExpandWildcards(fs *irodsclient_fs.FileSystem, account *types.IRODSAccount, targetPaths []string, expandCollections bool, expandDataobjects bool) ([]string, error) {
+// ExpandWildcards expands wildcards in target paths to matching collections or data objects, it stops when the ctx is done
+func ExpandWildcards(ctx context.Context, fs *irodsclient_fs.FileSystem, account *types.IRODSAccount, targetPaths []string, expandCollections bool, expandDataobjects bool) ([]string, error) {
 	if !expandCollections && !expandDataobjects {
 		return nil, errors.New("Need to enable data objects or collections (or both) for wildcard expansion.")
 	}
@@ -19,6 +21,10 @@ func ExpandWildcards(fs *irodsclient_fs.FileSystem, account *types.IRODSAccount,
 	outputPaths := []string{}
 
 	for _, targetPath := range targetPaths {
+		if err := ctx.Err(); err != nil {
+			return nil, errors.Wrapf(err, "failed to expand wildcards")
+		}
+
 		if irodsclient_util.HasWildcards(targetPath) {
 			// First convert targetPath to absolute path
 			cwd := config.GetCWD()
@@ -0,0 +1,2271 @@
+package gocommands
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/avast/retry-go"
+	"github.com/cockroachdb/errors"
+	irodsclient_fs "github.com/cyverse/go-irodsclient/fs"
+	irodsclient_types "github.com/cyverse/go-irodsclient/irods/types"
+	irodsclient_util "github.com/cyverse/go-irodsclient/irods/util"
+	"github.com/cyverse/gocommands/commons/bundle"
+	"github.com/cyverse/gocommands/commons/config"
+	"github.com/cyverse/gocommands/commons/encryption"
+	"github.com/cyverse/gocommands/commons/irods"
+	"github.com/cyverse/gocommands/commons/parallel"
+	commons_path "github.com/cyverse/gocommands/commons/path"
+	"github.com/cyverse/gocommands/commons/tracing"
+	"github.com/cyverse/gocommands/commons/transfer"
+	"github.com/cyverse/gocommands/commons/types"
+	"github.com/cyverse/gocommands/commons/webdav"
+	"github.com/jedib0t/go-pretty/v6/progress"
+	log "github.com/sirupsen/logrus"
+)
+
+// BundleOptions has options of bundles of small files
+type BundleOptions struct {
+	// LocalTempPath is the local directory bundle files are created in, the system temp directory if empty
+	LocalTempPath string
+	// IRODSTempPath is the iRODS collection bundle files are uploaded to, a staging collection in the target if empty
+	IRODSTempPath string
+	// ClearOld removes stale bundle files in temp directories before uploading
+	ClearOld bool
+	// MinFileNumInBundle is the minimum number of files in a bundle, the default if 0
+	MinFileNumInBundle int
+	// MaxFileNumInBundle is the maximum number of files in a bundle, the default if 0
+	MaxFileNumInBundle int
+	// MaxBundleFileSize is the maximum size of a bundle file, the default if 0
+	MaxBundleFileSize int64
+	// NoBulkRegistration registers extracted files one by one
+	NoBulkRegistration bool
+	// AdaptiveBundles adjusts bundle size and concurrency to the throughput observed
+	AdaptiveBundles bool
+}
+
+// BputOptions has options of Bput
+type BputOptions struct {
+	TransferOptions
+	EncryptionOptions
+	BundleOptions
+
+	// SourcePaths are local files or directories to upload
+	SourcePaths []string
+	// TargetPath is the iRODS collection to upload to, the working collection if empty
+	TargetPath string
+}
+
+// Bput bundles local files or directories and uploads them to iRODS
+func Bput(ctx context.Context, options BputOptions) (*TransferResult, error) {
+	bput, err := NewBputTransfer(options)
+	if err != nil {
+		return nil, err
+	}
+
+	err = bput.Run(ctx)
+	return bput.GetResult(), err
+}
+
+// BputTransfer bundles local files or directories and uploads them to iRODS
+type BputTransfer struct {
+	options BputOptions
+
+	account      *irodsclient_types.IRODSAccount
+	filesystem   *irodsclient_fs.FileSystem
+	webdavClient *webdav.WebDAVClient
+
+	// working and home collections relative iRODS paths are resolved against
+	cwd  string
+	home string
+
+	sourcePaths []string
+	targetPath  string
+
+	stagingPath    string
+	stagingDirMade bool
+
+	parallelTransferJobManager    *parallel.ParallelJobManager
+	parallelPostProcessJobManager *parallel.ParallelJobManager
+	bundleManager                 *bundle.BundleManager
+	adaptiveBundleController      *bundle.AdaptiveBundleController
+	progressEventWriter           *parallel.ProgressEventWriter
+	transferReportManager         *transfer.TransferReportManager
+	updatedPathMap                map[string]bool
+	mutex                         sync.RWMutex // mutex for updatedPathMap and job managers replaced at every round
+
+	inFlightTransfers *transfer.InFlightTransfers
+	removedPaths      []string // incomplete files removed after the run is aborted
+
+	// context of the run, carrying the span of the operation
+	ctx context.Context
+
+	// canceled by CancelPending
+	pendingCtx    context.Context
+	cancelPending context.CancelFunc
+
+	// canceled by Abort
+	run runContext
+
+	events *eventNotifier
+
+	totalScheduledFiles int
+	totalUploadedFiles  int
+	totalUploadedBytes  int64
+	startTime           time.Time
+}
+
+// NewBputTransfer creates a new BputTransfer, options are validated
+func NewBputTransfer(options BputOptions) (*BputTransfer, error) {
+	err := options.validate()
+	if err != nil {
+		return nil, err
+	}
+
+	options.setDefaults()
+
+	bput := &BputTransfer{
+		options: options,
+
+		account:     options.Account,
+		sourcePaths: options.SourcePaths,
+		targetPath:  options.TargetPath,
+
+		updatedPathMap:     map[string]bool{},
+		inFlightTransfers:  transfer.NewInFlightTransfers(),
+		totalUploadedFiles: 0,
+		totalUploadedBytes: 0,
+		startTime:          time.Now(),
+	}
+
+	bput.cwd, bput.home = options.getWorkingDirs()
+	bput.pendingCtx, bput.cancelPending = context.WithCancel(context.Background())
+	bput.events = newEventNotifier(options.OnEvent)
+
+	return bput, nil
+}
+
+func (options *BputOptions) validate() error {
+	err := options.TransferOptions.validate()
+	if err != nil {
+		return err
+	}
+
+	err = options.BundleOptions.validate()
+	if err != nil {
+		return err
+	}
+
+	if len(options.SourcePaths) == 0 {
+		return errors.New("source path is not given")
+	}
+
+	if options.NoRoot && len(options.SourcePaths) > 1 {
+		return errors.New("failed to put multiple source collections without creating root directory")
+	}
+
+	return nil
+}
+
+func (options *BputOptions) setDefaults() {
+	options.TransferOptions.setDefaults()
+	options.EncryptionOptions.setDefaults()
+	options.BundleOptions.setDefaults()
+
+	if len(options.TargetPath) == 0 {
+		options.TargetPath = "./"
+	}
+}
+
+func (options *BundleOptions) validate() error {
+	if options.MinFileNumInBundle < 0 || options.MaxFileNumInBundle < 0 || options.MaxBundleFileSize < 0 {
+		return errors.New("bundle limits must not be negative")
+	}
+
+	if options.MaxFileNumInBundle > 0 && options.MinFileNumInBundle > options.MaxFileNumInBundle {
+		return errors.Errorf("minimum number of files in a bundle %d is larger than maximum %d", options.MinFileNumInBundle, options.MaxFileNumInBundle)
+	}
+
+	return nil
+}
+
+// setDefaults sets default values to options not given
+func (options *BundleOptions) setDefaults() {
+	if len(options.LocalTempPath) == 0 {
+		options.LocalTempPath = os.TempDir()
+	}
+
+	if options.MinFileNumInBundle == 0 {
+		options.MinFileNumInBundle = config.MinFileNumInBundleDefault
+	}
+
+	if options.MaxFileNumInBundle == 0 {
+		options.MaxFileNumInBundle = config.MaxFileNumInBundleDefault
+	}
+
+	if options.MaxBundleFileSize == 0 {
+		options.MaxBundleFileSize = config.MaxBundleFileSizeDefault
+	}
+}
+
+// CancelPending cancels pending bundles, bundles running are completed
+func (bput *BputTransfer) CancelPending() {
+	bput.cancelPending()
+}
+
+// GetResult returns statistics of the upload
+func (bput *BputTransfer) GetResult() *TransferResult {
+	return &TransferResult{
+		Files:          bput.totalUploadedFiles,
+		Bytes:          bput.totalUploadedBytes,
+		NotTransferred: int64(bput.totalScheduledFiles - bput.totalUploadedFiles),
+		RemovedPaths:   bput.removedPaths,
+		Duration:       time.Since(bput.startTime),
+	}
+}
+
+// GetAdaptiveBundleDecisions returns decisions made at every round of adaptive bundles, nil if AdaptiveBundles is not set
+func (bput *BputTransfer) GetAdaptiveBundleDecisions() []bundle.AdaptiveBundleDecision {
+	if bput.adaptiveBundleController == nil {
+		return nil
+	}
+
+	return bput.adaptiveBundleController.GetDecisions()
+}
+
+// Abort cancels Run, waits for running uploads to return, and removes incomplete tarballs and data objects
+// It is for callers exiting before Run returns, the result has the data objects removed
+func (bput *BputTransfer) Abort() *TransferResult {
+	logger := log.WithFields(log.Fields{})
+
+	// stop running jobs, and wait for them to return before removing files they write
+	bput.run.abort()
+	transferJobManager, postProcessJobManager := bput.getJobManagers()
+	if !parallel.WaitJobManagers(AbortWaitTimeout, transferJobManager, postProcessJobManager) {
+		logger.Warnf("running jobs did not return in %s, removing incomplete files", AbortWaitTimeout)
+	}
+
+	removedPaths, err := bput.inFlightTransfers.CleanUp()
+	if err != nil {
+		logger.WithError(err).Warn("failed to remove incomplete files")
+	}
+
+	if bput.stagingDirMade {
+		bput.filesystem.RemoveDir(bput.stagingPath, true, true)
+	}
+
+	if bput.transferReportManager != nil {
+		bput.transferReportManager.Release()
+	}
+
+	result := bput.GetResult()
+	result.RemovedPaths = removedPaths
+	return result
+}
+
+// getJobManagers returns job managers of the current round
+func (bput *BputTransfer) getJobManagers() (*parallel.ParallelJobManager, *parallel.ParallelJobManager) {
+	bput.mutex.RLock()
+	defer bput.mutex.RUnlock()
+
+	return bput.parallelTransferJobManager, bput.parallelPostProcessJobManager
+}
+
+// Run uploads files in bundles
+// when the ctx is done, pending bundles are canceled and running WebDAV uploads are aborted, incomplete tarballs and data objects are removed
+func (bput *BputTransfer) Run(ctx context.Context) (err error) {
+	logger := log.WithFields(log.Fields{})
+
+	ctx, cancel := bput.run.start(ctx)
+	defer cancel()
+
+	ctx, span := tracing.StartSpan(ctx, "bput",
+		tracing.String("gocmd.source_paths", strings.Join(bput.sourcePaths, ",")),
+		tracing.String("gocmd.target_path", bput.targetPath),
+		tracing.String("irods.host", bput.account.Host),
+		tracing.String("irods.zone", bput.account.ClientZone),
+		tracing.String("irods.resource", bput.account.DefaultResource),
+	)
+	defer func() {
+		span.End(err)
+	}()
+
+	bput.ctx = ctx
+
+	// Create a file system
+	bput.filesystem, err = irods.GetIRODSFSClientForLargeFileIO(bput.account, bput.options.ThreadNum, bput.options.TCPBufferSize, true, bput.options.getTimeoutSeconds())
+	if err != nil {
+		return errors.Wrap(err, "failed to get iRODS FS Client")
+	}
+	defer bput.filesystem.Release()
+
+	if bput.options.WebDAV && len(bput.options.WebDAVBaseURL) > 0 {
+		webdavClient, err := webdav.NewWebDAVClient(bput.filesystem, bput.options.WebDAVBaseURL, bput.account.ProxyUser, bput.account.Password)
+		if err != nil {
+			return errors.Wrap(err, "failed to create WebDAV client")
+		}
+
+		bput.webdavClient = webdavClient
+	}
+
+	// transfer report
+	bput.transferReportManager, err = bput.options.newTransferReportManager()
+	if err != nil {
+		return errors.Wrap(err, "failed to create transfer report manager")
+	}
+	defer bput.transferReportManager.Release()
+
+	// set default key for encryption
+	if len(bput.options.EncryptionKey) == 0 {
+		bput.options.EncryptionKey = bput.account.Password
+	}
+
+	// parallel job manager
+	ioSession := bput.filesystem.GetIOSession()
+	bput.progressEventWriter = bput.events.getProgressEventWriter()
+	bput.parallelTransferJobManager, bput.parallelPostProcessJobManager = bput.options.newParallelJobManagers(ioSession.GetMaxConnections(), bput.progressEventWriter)
+
+	// cancel pending jobs when CancelPending is called, job manager is replaced at every round in adaptive mode
+	stopCancel := context.AfterFunc(bput.pendingCtx, func() {
+		transferJobManager, postProcessJobManager := bput.getJobManagers()
+		transferJobManager.CancelJobs()
+		postProcessJobManager.CancelJobs()
+	})
+	defer stopCancel()
+
+	// run
+	if len(bput.sourcePaths) >= 2 {
+		// multi-source, target must be a dir
+		err = bput.ensureTargetIsDir(bput.targetPath)
+		if err != nil {
+			return errors.Wrapf(err, "target path %q is not a directory", bput.targetPath)
+		}
+	}
+
+	// bundle manager
+	bput.stagingPath = bput.options.IRODSTempPath
+	if len(bput.stagingPath) == 0 {
+		bput.stagingPath = bundle.GetStagingDirInTargetPath(bput.filesystem, bput.targetPath)
+	}
+
+	stagingDirMade, stagingDirErr := bundle.EnsureStagingDirPath(bput.filesystem, bput.stagingPath)
+	if stagingDirErr != nil {
+		return errors.Wrapf(stagingDirErr, "failed to prepare staging path %q", bput.stagingPath)
+	}
+
+	if stagingDirMade {
+		// delete the staging directory if it was created, Abort deletes it if Run does not return
+		bput.stagingDirMade = true
+		defer bput.filesystem.RemoveDir(bput.stagingPath, true, true)
+	}
+
+	bput.bundleManager = bundle.NewBundleManager(bput.options.MinFileNumInBundle, bput.options.MaxFileNumInBundle, bput.options.MaxBundleFileSize, bput.options.LocalTempPath, bput.stagingPath)
+
+	if bput.options.AdaptiveBundles {
+		bput.adaptiveBundleController = bundle.NewAdaptiveBundleController(bput.options.MaxFileNumInBundle, bput.options.MaxBundleFileSize, ioSession.GetMaxConnections())
+	}
+
+	// clear local bundles
+	if bput.options.ClearOld {
+		logger.Debugf("clearing a local temp directory %q", bput.options.LocalTempPath)
+		clearErr := bput.bundleManager.ClearLocalBundles()
+		if err != nil {
+			return errors.Wrapf(clearErr, "failed to clear local bundle files")
+		}
+
+		logger.Debugf("clearing an irods temp directory %q", bput.stagingPath)
+		err = bput.bundleManager.ClearIRODSBundles(bput.filesystem, false)
+		if err != nil {
+			return errors.Wrapf(err, "failed to clear irods bundle files in %q", bput.stagingPath)
+		}
+	}
+
+	// this only schedules jobs, does not run them
+	_, walkSpan := tracing.StartSpan(ctx, "walk",
+		tracing.String("gocmd.source_paths", strings.Join(bput.sourcePaths, ",")),
+	)
+
+	for _, sourcePath := range bput.sourcePaths {
+		err = bput.putOne(sourcePath, bput.targetPath)
+		if err != nil {
+			walkSpan.End(err)
+			return errors.Wrapf(err, "failed to bundle-put %q to %q", sourcePath, bput.targetPath)
+		}
+	}
+
+	walkSpan.End(nil)
+
+	// process bput
+	if bput.adaptiveBundleController == nil {
+		err = bput.bput()
+		if err != nil {
+			return errors.Wrap(err, "failed to bundle-put files")
+		}
+	} else {
+		// bundles are rebuilt and scheduled at every round
+		bput.bundleManager.DoneScheduling()
+	}
+
+	// delete on success
+	if bput.options.DeleteOnSuccess {
+		for _, sourcePath := range bput.sourcePaths {
+			logger.Infof("deleting source file or directory under %q after upload", sourcePath)
+
+			err = bput.deleteOnSuccessOne(sourcePath)
+			if err != nil {
+				return errors.Wrapf(err, "failed to delete source %q after upload", sourcePath)
+			}
+		}
+	}
+
+	// delete extra
+	if bput.options.Delete {
+		logger.Infof("deleting extra data objects and collections under %q", bput.targetPath)
+
+		err = bput.deleteExtraOne(bput.targetPath)
+		if err != nil {
+			return errors.Wrap(err, "failed to delete extra data objects or collections")
+		}
+	}
+
+	logger.Info("done scheduling jobs, starting jobs")
+
+	var transferErr error
+	if bput.adaptiveBundleController == nil {
+		transferErr = bput.parallelTransferJobManager.StartWithContext(ctx)
+	} else {
+		transferErr = bput.startAdaptiveBundleTransfer()
+	}
+
+	if transferErr != nil {
+		// error occurred while transferring files
+		bput.parallelPostProcessJobManager.CancelJobs()
+	}
+
+	postProcessErr := bput.parallelPostProcessJobManager.StartWithContext(ctx)
+
+	if ctx.Err() != nil {
+		// remove incomplete tarballs and data objects of aborted uploads
+		removedPaths, cleanUpErr := bput.inFlightTransfers.CleanUp()
+		if cleanUpErr != nil {
+			logger.WithError(cleanUpErr).Warn("failed to remove incomplete files")
+		}
+		bput.removedPaths = removedPaths
+
+		return errors.Wrap(context.Cause(ctx), "upload canceled")
+	}
+
+	if transferErr != nil {
+		return errors.Wrap(transferErr, "failed to perform transfer jobs")
+	}
+
+	if postProcessErr != nil {
+		return errors.Wrap(postProcessErr, "failed to perform post process jobs")
+	}
+
+	return nil
+}
+
+// startAdaptiveBundleTransfer transfers bundles in rounds, adjusting bundle size and concurrency after every round
+func (bput *BputTransfer) startAdaptiveBundleTransfer() error {
+	logger := log.WithFields(log.Fields{})
+
+	// entries are already grouped by directory
+	pendingEntries := []bundle.BundleEntry{}
+	for _, bun := range bput.bundleManager.GetBundles() {
+		pendingEntries = append(pendingEntries, bun.GetEntries()...)
+	}
+
+	ioSession := bput.filesystem.GetIOSession()
+	controller := bput.adaptiveBundleController
+
+	transferErrors := []error{}
+
+	for len(pendingEntries) > 0 {
+		if bput.pendingCtx.Err() != nil || bput.ctx.Err() != nil {
+			// remaining entries are reported as canceled
+			break
+		}
+
+		roundBundleManager := bundle.NewBundleManager(bput.options.MinFileNumInBundle, controller.GetMaxFileNumInBundle(), controller.GetMaxBundleFileSize(), bput.options.LocalTempPath, bput.stagingPath)
+		bundlesPerRound := controller.GetBundlesPerRound()
+
+		roundFileNum := 0
+		roundSize := int64(0)
+		for len(pendingEntries) > 0 {
+			entry := pendingEntries[0]
+
+			bundles := roundBundleManager.GetBundles()
+			if len(bundles) >= bundlesPerRound {
+				lastBundle := bundles[len(bundles)-1]
+				if lastBundle.IsFull() || !lastBundle.IsSameDir(entry) {
+					// the entry requires a new bundle, leave it for next round
+					break
+				}
+			}
+
+			err := roundBundleManager.Add(entry)
+			if err != nil {
+				return errors.Wrapf(err, "failed to add %q to bundle", entry.LocalPath)
+			}
+
+			pendingEntries = pendingEntries[1:]
+			roundFileNum++
+			roundSize += entry.Size
+		}
+
+		logger.Debugf("scheduling %d files in %d bundles for next round", roundFileNum, len(roundBundleManager.GetBundles()))
+
+		bput.bundleManager = roundBundleManager
+
+		bput.mutex.Lock()
+		bput.parallelTransferJobManager = bput.options.newParallelTransferJobManager(ioSession.GetMaxConnections(), bput.progressEventWriter)
+		bput.mutex.Unlock()
+
+		err := bput.bput()
+		if err != nil {
+			return errors.Wrap(err, "failed to bundle-put files")
+		}
+
+		controller.StartRound()
+		roundErr := bput.parallelTransferJobManager.StartWithContext(bput.ctx)
+		decision := controller.EndRound(roundFileNum, roundSize)
+
+		bput.reportAdaptiveBundleDecision(decision)
+
+		if roundErr != nil {
+			transferErrors = append(transferErrors, roundErr)
+
+			if bput.options.StopOnError {
+				break
+			}
+		}
+	}
+
+	// report entries not transferred due to error or interrupt
+	for _, entry := range pendingEntries {
+		now := time.Now()
+		reportFile := &transfer.TransferReportFile{
+			Method:     transfer.TransferMethodBput,
+			StartAt:    now,
+			EndAt:      now,
+			SourcePath: entry.LocalPath,
+			SourceSize: entry.Size,
+			DestPath:   entry.IRODSPath,
+			Notes:      []string{"bput", "adaptive", "canceled"},
+		}
+
+		bput.transferReportManager.AddFile(reportFile)
+	}
+
+	return errors.Join(transferErrors...)
+}
+
+func (bput *BputTransfer) reportAdaptiveBundleDecision(decision bundle.AdaptiveBundleDecision) {
+	reportFile := &transfer.TransferReportFile{
+		Method:     transfer.TransferMethodBput,
+		StartAt:    decision.StartTime,
+		EndAt:      decision.EndTime,
+		SourceSize: decision.Size,
+		DestPath:   bput.targetPath,
+		Notes:      append([]string{"bput"}, decision.ToNotes()...),
+	}
+
+	bput.transferReportManager.AddFile(reportFile)
+}
+
+func (bput *BputTransfer) bput() error {
+	// seal incomplete bundle
+	bput.bundleManager.DoneScheduling()
+
+	// process bundles
+	bundles := bput.bundleManager.GetBundles()
+	for _, bundle := range bundles {
+		if bundle.IsEmpty() {
+			continue
+		}
+
+		if !bundle.IsSealed() {
+			return errors.Errorf("bundle %d (%q) is not sealed, cannot process", bundle.GetID(), bundle.GetBundleFilename())
+		}
+
+		if bundle.RequireTar() {
+			bput.scheduleBundleTransfer(bundle)
+		} else {
+			for _, bundleEntry := range bundle.GetEntries() {
+				bput.scheduleBundleEntryTransfer(&bundleEntry)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (bput *BputTransfer) ensureTargetIsDir(targetPath string) error {
+	cwd := bput.cwd
+	home := bput.home
+	zone := bput.account.ClientZone
+	targetPath = commons_path.MakeIRODSPath(cwd, home, zone, targetPath)
+
+	targetEntry, err := bput.filesystem.Stat(targetPath)
+	if err != nil {
+		if irodsclient_types.IsFileNotFoundError(err) {
+			// not exist
+			return types.NewNotDirError(targetPath)
+		}
+
+		return errors.Wrapf(err, "failed to stat %q", targetPath)
+	}
+
+	if !targetEntry.IsDir() {
+		return types.NewNotDirError(targetPath)
+	}
+
+	return nil
+}
+
+func (bput *BputTransfer) getEncryptionMode(targetPath string, parentEncryptionMode encryption.EncryptionMode) encryption.EncryptionMode {
+	if bput.options.Encryption {
+		return bput.options.EncryptionMode
+	}
+
+	if bput.options.NoEncryption {
+		return encryption.EncryptionModeNone
+	}
+
+	if !bput.options.EncryptionIgnoreMeta {
+		// load encryption config from meta
+		targetDir := targetPath
+
+		targetEntry, err := bput.filesystem.Stat(targetPath)
+		if err != nil {
+			if irodsclient_types.IsFileNotFoundError(err) {
+				targetDir = path.Dir(targetPath)
+			} else {
+				return parentEncryptionMode
+			}
+		} else {
+			if !targetEntry.IsDir() {
+				targetDir = path.Dir(targetEntry.Path)
+			}
+		}
+
+		encryptionConfig := encryption.GetEncryptionConfigFromMeta(bput.filesystem, targetDir)
+		return encryptionConfig.Mode
+	}
+
+	return parentEncryptionMode
+}
+
+func (bput *BputTransfer) putOne(sourcePath string, targetPath string) error {
+	cwd := bput.cwd
+	home := bput.home
+	zone := bput.account.ClientZone
+	sourcePath = commons_path.MakeLocalPath(sourcePath)
+	targetPath = commons_path.MakeIRODSPath(cwd, home, zone, targetPath)
+
+	sourceStat, err := os.Stat(sourcePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return irodsclient_types.NewFileNotFoundError(sourcePath)
+		}
+
+		return errors.Wrapf(err, "failed to stat %q", sourcePath)
+	}
+
+	if sourceStat.IsDir() {
+		// dir
+		if !bput.options.NoRoot {
+			targetPath = commons_path.MakeIRODSTargetFilePath(bput.filesystem, sourcePath, targetPath)
+		}
+
+		return bput.putDir(sourceStat, sourcePath, targetPath, encryption.EncryptionModeNone)
+	}
+
+	// file
+	encryptionMode := bput.getEncryptionMode(targetPath, encryption.EncryptionModeNone)
+	if encryptionMode != encryption.EncryptionModeNone {
+		// encrypt filename
+		tempPath, err := bput.getLocalPathForEncryption(sourcePath, path.Dir(targetPath))
+		if err != nil {
+			return errors.Wrapf(err, "failed to get encryption path for %q", sourcePath)
+		}
+
+		newTargetPath := path.Join(path.Dir(targetPath), path.Base(tempPath))
+
+		return bput.putFile(sourceStat, sourcePath, tempPath, newTargetPath, encryptionMode)
+	}
+
+	targetPath = commons_path.MakeIRODSTargetFilePath(bput.filesystem, sourcePath, targetPath)
+	return bput.putFile(sourceStat, sourcePath, "", targetPath, encryption.EncryptionModeNone)
+}
+
+func (bput *BputTransfer) deleteOnSuccessOne(sourcePath string) error {
+	sourcePath = commons_path.MakeLocalPath(sourcePath)
+
+	sourceStat, err := os.Stat(sourcePath)
+	if err != nil {
+		return errors.Wrapf(err, "failed to stat %q", sourcePath)
+	}
+
+	if sourceStat.IsDir() {
+		// dir
+		return bput.deleteDirOnSuccess(sourcePath)
+	}
+
+	// file
+	return bput.deleteFileOnSuccess(sourcePath)
+}
+
+func (bput *BputTransfer) deleteExtraOne(targetPath string) error {
+	cwd := bput.cwd
+	home := bput.home
+	zone := bput.account.ClientZone
+	targetPath = commons_path.MakeIRODSPath(cwd, home, zone, targetPath)
+
+	targetEntry, err := bput.filesystem.Stat(targetPath)
+	if err != nil {
+		return errors.Wrapf(err, "failed to stat %q", targetPath)
+	}
+
+	if targetEntry.IsDir() {
+		// dir
+		return bput.deleteExtraDir(targetPath)
+	}
+
+	// file
+	return bput.deleteExtraFile(targetPath)
+}
+
+func (bput *BputTransfer) scheduleBundleTransfer(bun *bundle.Bundle) {
+	tarballPath := path.Join(bput.bundleManager.GetLocalTempDirPath(), bun.GetBundleFilename())
+	stagingTargetPath := path.Join(bput.bundleManager.GetIRODSStagingDirPath(), bun.GetBundleFilename())
+
+	logger := log.WithFields(log.Fields{
+		"bundle_id":          bun.GetID(),
+		"bundle_name":        bun.GetBundleFilename(),
+		"target_path":        bun.GetIRODSDir(),
+		"local_tarball_path": tarballPath,
+		"staging_path":       stagingTargetPath,
+	})
+
+	defaultNotes := []string{"bput"}
+
+	reportSimple := func(err error, additionalNotes ...string) {
+		now := time.Now()
+		newNotes := append(defaultNotes, additionalNotes...)
+		newNotes = append(newNotes, "file", bun.GetBundleFilename())
+
+		for _, bundleEntry := range bun.GetEntries() {
+			reportFile := &transfer.TransferReportFile{
+				Method:     transfer.TransferMethodBput,
+				StartAt:    now,
+				EndAt:      now,
+				SourcePath: bundleEntry.LocalPath,
+				SourceSize: bundleEntry.Size,
+				DestPath:   bundleEntry.IRODSPath,
+				Error:      err,
+				Notes:      newNotes,
+			}
+
+			bput.transferReportManager.AddFile(reportFile)
+		}
+	}
+
+	reportTransfer := func(result *irodsclient_fs.FileTransferResult, err error, additionalNotes ...string) {
+		newNotes := append(defaultNotes, additionalNotes...)
+		newNotes = append(newNotes, "file", bun.GetBundleFilename())
+
+		bput.transferReportManager.AddTransfer(result, transfer.TransferMethodBput, err, newNotes)
+
+		startTime := result.StartTime
+		endTime := result.EndTime
+
+		for _, bundleEntry := range bun.GetEntries() {
+			reportFile := &transfer.TransferReportFile{
+				Method:     transfer.TransferMethodBput,
+				StartAt:    startTime,
+				EndAt:      endTime,
+				SourcePath: bundleEntry.LocalPath,
+				SourceSize: bundleEntry.Size,
+				DestPath:   bundleEntry.IRODSPath,
+				Error:      err,
+				Notes:      newNotes,
+			}
+
+			bput.transferReportManager.AddFile(reportFile)
+		}
+	}
+
+	transferMode, threadsRequired := bput.determineTransferMethodForBundle(bun)
+
+	weight := threadsRequired
+	if bput.adaptiveBundleController != nil {
+		// weight controls the number of bundles transferred concurrently
+		weight = bput.adaptiveBundleController.GetThreadsPerBundle(bput.filesystem.GetIOSession().GetMaxConnections())
+		if threadsRequired > weight {
+			threadsRequired = weight
+		}
+	}
+
+	// task for bundling and uploading
+	bundleTask := func(job *parallel.ParallelJob) error {
+		logger := logger.WithFields(job.GetLogFields())
+
+		if job.IsCanceled() {
+			// job is canceled, do not run
+			job.Progress("upload", -1, bun.GetSize(), true)
+
+			reportSimple(nil, "canceled")
+			logger.Debug("canceled a task for bundle transfer")
+			return nil
+		}
+
+		logger.Debug("creating a tarball")
+
+		notes := []string{}
+
+		// create a bundle file
+		job.Progress("bundle", 0, bun.GetSize(), false)
+		tarStartTime := time.Now()
+		_, tarSpan := tracing.StartSpan(job.GetContext(), "tar",
+			tracing.Int64("gocmd.bundle_id", bun.GetID()),
+			tracing.Int64("gocmd.files", int64(bun.GetEntryNumber())),
+			tracing.Int64("gocmd.size", bun.GetSize()),
+			tracing.String("gocmd.tarball_path", tarballPath),
+		)
+		tarball := bundle.NewTar(bun.GetIRODSDir())
+
+		defer func() {
+			// remove encrypted temp files
+			for _, bundleEntry := range bun.GetEntries() {
+				if len(bundleEntry.TempPath) > 0 {
+					os.Remove(bundleEntry.TempPath)
+					bput.inFlightTransfers.Finish(bundleEntry.TempPath)
+				}
+			}
+		}()
+
+		for _, bundleEntry := range bun.GetEntries() {
+			// encrypt if needed
+			if bundleEntry.EncryptionMode != encryption.EncryptionModeNone {
+				notes = append(notes, "encrypt")
+
+				tempPath := bundleEntry.TempPath
+				bput.inFlightTransfers.Start(tempPath, func() error {
+					return os.Remove(tempPath)
+				})
+
+				_, encryptErr := bput.encryptFile(bundleEntry.LocalPath, bundleEntry.TempPath, bundleEntry.IRODSPath, bundleEntry.EncryptionMode)
+				if encryptErr != nil {
+					tarSpan.End(encryptErr)
+					job.Progress("bundle", -1, bun.GetSize(), true)
+
+					reportSimple(encryptErr, notes...)
+					return errors.Wrapf(encryptErr, "failed to encrypt file %s", bundleEntry.LocalPath)
+				}
+
+				tarball.AddEntry(bundleEntry.TempPath, bundleEntry.IRODSPath)
+			} else {
+				tarball.AddEntry(bundleEntry.LocalPath, bundleEntry.IRODSPath)
+			}
+		}
+
+		bput.inFlightTransfers.Start(tarballPath, func() error {
+			return os.Remove(tarballPath)
+		})
+
+		defer func() {
+			os.Remove(tarballPath)
+			bput.inFlightTransfers.Finish(tarballPath)
+		}()
+
+		tarErr := tarball.CreateTarball(tarballPath, nil)
+		tarSpan.End(tarErr)
+		if tarErr != nil {
+			job.Progress("bundle", -1, bun.GetSize(), true)
+
+			reportSimple(tarErr, "tar")
+			return errors.Wrapf(tarErr, "failed to create a tarball %q for bundle %d", tarballPath, bun.GetID())
+		}
+
+		tarDuration := time.Since(tarStartTime)
+		bput.options.Metrics.AddBundleStage("tar", tarDuration)
+
+		job.Progress("bundle", bun.GetSize(), bun.GetSize(), false)
+		logger.Debug("created a tarball")
+
+		tarballStat, tarErr := os.Stat(tarballPath)
+		if tarErr != nil {
+			job.Progress("bundle", -1, bun.GetSize(), true)
+
+			reportSimple(tarErr, "tar")
+			return errors.Wrapf(tarErr, "failed to create a tarball %q for bundle %d", tarballPath, bun.GetID())
+		}
+
+		// tarball size
+		job.Progress("upload", 0, tarballStat.Size(), false)
+
+		parentTargetPath := path.Dir(bun.GetIRODSDir())
+		_, statErr := bput.filesystem.Stat(parentTargetPath)
+		if statErr != nil {
+			// must exist, mkdir is performed at putDir
+			job.Progress("upload", -1, tarballStat.Size(), true)
+
+			reportSimple(statErr)
+			return errors.Wrapf(statErr, "failed to stat %q", parentTargetPath)
+		}
+
+		notes = append(notes, fmt.Sprintf("staging path %q", stagingTargetPath))
+
+		progressCallbackPut := func(taskType string, processed int64, total int64) {
+			job.Progress(taskType, processed, total, false)
+		}
+
+		var uploadErr error
+		var uploadResult *irodsclient_fs.FileTransferResult
+
+		notes = append(notes, string(transferMode), fmt.Sprintf("%d threads", threadsRequired))
+
+		retryNum := bput.options.RetryNum
+		retryInterval := bput.options.RetryInterval
+
+		uploadStartTime := time.Now()
+		_, uploadSpan := tracing.StartSpan(job.GetContext(), "upload",
+			tracing.String("gocmd.source_path", tarballPath),
+			tracing.String("gocmd.target_path", stagingTargetPath),
+			tracing.Int64("gocmd.size", tarballStat.Size()),
+			tracing.String("gocmd.transfer_mode", string(transferMode)),
+			tracing.Int64("gocmd.threads", int64(threadsRequired)),
+		)
+
+		// the tarball in staging is removed if aborted, extracted files are kept
+		bput.inFlightTransfers.Start(stagingTargetPath, func() error {
+			return bput.filesystem.RemoveFile(stagingTargetPath, true)
+		})
+		defer bput.inFlightTransfers.Finish(stagingTargetPath)
+
+		bundleAttempt := 0
+		bundleRetryErr := retry.Do(func() error {
+			bundleAttempt++
+			if bundleAttempt > 1 {
+				logger.Debugf("retrying bundle upload attempt %d/%d for %q", bundleAttempt, retryNum+1, tarballPath)
+				bput.options.Metrics.AddRetry(string(transfer.TransferMethodBput))
+			}
+
+			switch transferMode {
+			case transfer.TransferModeWebDAV:
+				uploadResult, uploadErr = bput.webdavClient.UploadFile(job.GetContext(), tarballPath, stagingTargetPath, "", bput.options.VerifyChecksum, progressCallbackPut)
+			case transfer.TransferModeICAT:
+				fallthrough
+			default:
+				uploadResult, uploadErr = bput.filesystem.UploadFileParallel(tarballPath, stagingTargetPath, "", threadsRequired, false, bput.options.VerifyChecksum, progressCallbackPut)
+			}
+			return uploadErr
+		}, retry.Attempts(uint(retryNum+1)), retry.Delay(retryInterval), retry.LastErrorOnly(true), retry.Context(job.GetContext()))
+
+		uploadSpan.SetAttributes(tracing.Int64("gocmd.attempts", int64(bundleAttempt)))
+		uploadSpan.End(bundleRetryErr)
+
+		if bundleRetryErr != nil {
+			job.Progress("upload", -1, tarballStat.Size(), true)
+			job.Progress("checksum", -1, tarballStat.Size(), true)
+
+			reportTransfer(uploadResult, bundleRetryErr, notes...)
+			return errors.Wrapf(bundleRetryErr, "failed to upload bundle %q to %q after %d attempts", tarballPath, stagingTargetPath, retryNum+1)
+		}
+
+		uploadDuration := time.Since(uploadStartTime)
+		bput.options.Metrics.AddBundleStage("upload", uploadDuration)
+
+		reportTransfer(uploadResult, nil, notes...)
+
+		logger.Debug("uploaded a tarball")
+
+		// extract the bundle in iRODS
+		logger.Debug("extracting a tarball")
+
+		extractStartTime := time.Now()
+
+		job.Progress("extract", 0, tarballStat.Size(), false)
+
+		// data objects are registered in bulk by the server while extracting, unless disabled
+		_, extractSpan := tracing.StartSpan(job.GetContext(), "extract",
+			tracing.String("gocmd.tarball_path", stagingTargetPath),
+			tracing.String("gocmd.target_path", bun.GetIRODSDir()),
+			tracing.Int64("gocmd.files", int64(bun.GetEntryNumber())),
+			tracing.Bool("gocmd.bulk_registration", !bput.options.NoBulkRegistration),
+		)
+
+		extractErr := bput.filesystem.ExtractStructFile(stagingTargetPath, bun.GetIRODSDir(), "", irodsclient_types.TAR_FILE_DT, bput.options.Force, !bput.options.NoBulkRegistration)
+		extractSpan.End(extractErr)
+		if extractErr != nil {
+			job.Progress("extract", -1, tarballStat.Size(), true)
+
+			reportSimple(extractErr, "extract")
+			return errors.Wrapf(extractErr, "failed to extract a tarball %q to %q", stagingTargetPath, bun.GetIRODSDir())
+		}
+
+		logger.Debug("extracted a tarball")
+
+		// remove the tarball
+		logger.Debug("removing a tarball")
+		removeErr := bput.filesystem.RemoveFile(stagingTargetPath, true)
+		if removeErr != nil {
+			job.Progress("extract", -1, tarballStat.Size(), true)
+			reportSimple(removeErr, "remove")
+			return errors.Wrapf(removeErr, "failed to remove a tarball %q", stagingTargetPath)
+		}
+
+		job.Progress("extract", tarballStat.Size(), tarballStat.Size(), false)
+
+		logger.Debug("removed a tarball")
+
+		extractDuration := time.Since(extractStartTime)
+		bput.options.Metrics.AddBundleStage("extract", extractDuration)
+
+		if bput.adaptiveBundleController != nil {
+			bput.adaptiveBundleController.RecordBundle(bundle.BundleStats{
+				BundleID:        bun.GetID(),
+				FileNum:         bun.GetEntryNumber(),
+				Size:            bun.GetSize(),
+				TarDuration:     tarDuration,
+				UploadDuration:  uploadDuration,
+				ExtractDuration: extractDuration,
+			})
+		}
+
+		bput.totalUploadedFiles += bun.GetEntryNumber()
+		bput.totalUploadedBytes += bun.GetSize()
+		bput.options.Metrics.AddTransfer(string(transfer.TransferMethodBput), string(transferMode), bun.GetEntryNumber(), bun.GetSize())
+
+		return nil
+	}
+
+	// a bundle has the highest priority of its files
+	priority := 0
+	for _, bundleEntry := range bun.GetEntries() {
+		entryPriority := bput.options.getPriority(bundleEntry.LocalPath)
+		if entryPriority > priority {
+			priority = entryPriority
+		}
+	}
+
+	bput.parallelTransferJobManager.ScheduleWithOptions(bun.GetBundleFilename(), bundleTask, weight, progress.UnitsBytes, parallel.ParallelJobOptions{
+		Size:     bun.GetSize(),
+		Files:    bun.GetEntryNumber(),
+		Priority: priority,
+	})
+	logger.Debugf("scheduled a bundle file upload (with %d files), %d threads", bun.GetEntryNumber(), threadsRequired)
+}
+
+func (bput *BputTransfer) scheduleBundleEntryTransfer(bundleEntry *bundle.BundleEntry) {
+	logger := log.WithFields(log.Fields{
+		"bundle_entry": bundleEntry.IRODSPath,
+		"local_path":   bundleEntry.LocalPath,
+		"temp_path":    bundleEntry.TempPath,
+		"encryption":   bundleEntry.EncryptionMode,
+	})
+
+	defaultNotes := []string{"bput", "no bundle"}
+
+	reportSimple := func(err error, additionalNotes ...string) {
+		now := time.Now()
+		newNotes := append(defaultNotes, additionalNotes...)
+		newNotes = append(newNotes, "file")
+
+		reportFile := &transfer.TransferReportFile{
+			Method:     transfer.TransferMethodPut,
+			StartAt:    now,
+			EndAt:      now,
+			SourcePath: bundleEntry.LocalPath,
+			SourceSize: bundleEntry.Size,
+			DestPath:   bundleEntry.IRODSPath,
+			Error:      err,
+			Notes:      newNotes,
+		}
+
+		bput.transferReportManager.AddFile(reportFile)
+	}
+
+	reportTransfer := func(result *irodsclient_fs.FileTransferResult, err error, additionalNotes ...string) {
+		newNotes := append(defaultNotes, additionalNotes...)
+
+		bput.transferReportManager.AddTransfer(result, transfer.TransferMethodPut, err, newNotes)
+	}
+
+	_, threadsRequired := bput.determineTransferMethod(bundleEntry.Size)
+
+	putTask := func(job *parallel.ParallelJob) error {
+		logger := logger.WithFields(job.GetLogFields())
+
+		if job.IsCanceled() {
+			// job is canceled, do not run
+			job.Progress("upload", -1, bundleEntry.Size, true)
+
+			reportSimple(nil, "canceled")
+			logger.Debug("canceled a task for uploading")
+			return nil
+		}
+
+		logger.Debug("uploading a file")
+
+		progressCallbackPut := func(taskType string, processed int64, total int64) {
+			job.Progress(taskType, processed, total, false)
+		}
+
+		job.Progress("upload", 0, bundleEntry.Size, false)
+
+		notes := []string{}
+
+		// encrypt
+		if bundleEntry.EncryptionMode != encryption.EncryptionModeNone {
+			notes = append(notes, "encrypt")
+
+			bput.inFlightTransfers.Start(bundleEntry.TempPath, func() error {
+				return os.Remove(bundleEntry.TempPath)
+			})
+
+			defer func() {
+				if len(bundleEntry.TempPath) > 0 {
+					// remove temp file
+					logger.Debug("removing a temporary file")
+					os.Remove(bundleEntry.TempPath)
+				}
+
+				bput.inFlightTransfers.Finish(bundleEntry.TempPath)
+			}()
+
+			_, encryptErr := bput.encryptFile(bundleEntry.LocalPath, bundleEntry.TempPath, bundleEntry.IRODSPath, bundleEntry.EncryptionMode)
+			if encryptErr != nil {
+				job.Progress("upload", -1, bundleEntry.Size, true)
+
+				reportSimple(encryptErr, notes...)
+				return errors.Wrapf(encryptErr, "failed to encrypt file %s", bundleEntry.LocalPath)
+			}
+		}
+
+		uploadSourcePath := bundleEntry.LocalPath
+		if len(bundleEntry.TempPath) > 0 {
+			uploadSourcePath = bundleEntry.TempPath
+		}
+
+		parentTargetPath := path.Dir(bundleEntry.IRODSPath)
+		_, statErr := bput.filesystem.Stat(parentTargetPath)
+		if statErr != nil {
+			// must exist, mkdir is performed at putDir
+			job.Progress("upload", -1, bundleEntry.Size, true)
+
+			reportSimple(statErr)
+			return errors.Wrapf(statErr, "failed to stat %q", parentTargetPath)
+		}
+
+		notes = append(notes, "icat", fmt.Sprintf("%d threads", threadsRequired))
+
+		var uploadResult *irodsclient_fs.FileTransferResult
+		var uploadErr error
+
+		entryRetryNum := bput.options.RetryNum
+		entryRetryInterval := bput.options.RetryInterval
+
+		// incomplete data object is removed if aborted
+		bput.inFlightTransfers.Start(bundleEntry.IRODSPath, func() error {
+			return bput.filesystem.RemoveFile(bundleEntry.IRODSPath, true)
+		})
+
+		_, uploadSpan := tracing.StartSpan(job.GetContext(), "upload",
+			tracing.String("gocmd.source_path", bundleEntry.LocalPath),
+			tracing.String("gocmd.target_path", bundleEntry.IRODSPath),
+			tracing.Int64("gocmd.size", bundleEntry.Size),
+			tracing.String("gocmd.transfer_mode", string(transfer.TransferModeICAT)),
+			tracing.Int64("gocmd.threads", int64(threadsRequired)),
+		)
+
+		entryAttempt := 0
+		entryRetryErr := retry.Do(func() error {
+			entryAttempt++
+			if entryAttempt > 1 {
+				logger.Debugf("retrying upload attempt %d/%d for %q", entryAttempt, entryRetryNum+1, bundleEntry.LocalPath)
+				bput.options.Metrics.AddRetry(string(transfer.TransferMethodBput))
+			}
+			uploadResult, uploadErr = bput.filesystem.UploadFileParallel(uploadSourcePath, bundleEntry.IRODSPath, "", threadsRequired, false, bput.options.VerifyChecksum, progressCallbackPut)
+			return uploadErr
+		}, retry.Attempts(uint(entryRetryNum+1)), retry.Delay(entryRetryInterval), retry.LastErrorOnly(true), retry.Context(job.GetContext()))
+
+		bput.inFlightTransfers.Finish(bundleEntry.IRODSPath)
+
+		uploadSpan.SetAttributes(tracing.Int64("gocmd.attempts", int64(entryAttempt)))
+		uploadSpan.End(entryRetryErr)
+
+		if entryRetryErr != nil {
+			job.Progress("upload", -1, bundleEntry.Size, true)
+			job.Progress("checksum", -1, bundleEntry.Size, true)
+
+			reportTransfer(uploadResult, entryRetryErr, notes...)
+			return errors.Wrapf(entryRetryErr, "failed to upload %q to %q after %d attempts", bundleEntry.LocalPath, bundleEntry.IRODSPath, entryRetryNum+1)
+		}
+
+		reportTransfer(uploadResult, nil, notes...)
+
+		bput.totalUploadedFiles++
+		bput.totalUploadedBytes += bundleEntry.Size
+		bput.options.Metrics.AddTransfer(string(transfer.TransferMethodBput), string(transfer.TransferModeICAT), 1, bundleEntry.Size)
+
+		logger.Debug("uploaded a file")
+
+		return nil
+	}
+
+	bput.parallelTransferJobManager.ScheduleWithOptions(bundleEntry.LocalPath, putTask, threadsRequired, progress.UnitsBytes, parallel.ParallelJobOptions{
+		Size:     bundleEntry.Size,
+		Priority: bput.options.getPriority(bundleEntry.LocalPath),
+	})
+	logger.Debugf("scheduled a file upload, %d threads", threadsRequired)
+}
+
+func (bput *BputTransfer) schedulePut(sourceStat fs.FileInfo, sourcePath string, tempPath string, targetPath string, encryptionMode encryption.EncryptionMode) error {
+	// add to bundle
+	bundleEntry := bundle.BundleEntry{
+		LocalPath:      sourcePath,
+		TempPath:       tempPath,
+		IRODSPath:      targetPath,
+		Size:           sourceStat.Size(),
+		EncryptionMode: encryptionMode,
+	}
+
+	bundleErr := bput.bundleManager.Add(bundleEntry)
+	if bundleErr != nil {
+		return errors.Wrapf(bundleErr, "failed to add %q to bundle", sourcePath)
+	}
+
+	bput.totalScheduledFiles++
+
+	return nil
+}
+
+func (bput *BputTransfer) scheduleDeleteFileOnSuccess(sourcePath string) {
+	logger := log.WithFields(log.Fields{
+		"source_path": sourcePath,
+	})
+
+	defaultNotes := []string{"bput", "delete on success", "file"}
+
+	report := func(startTime time.Time, endTime time.Time, err error, additionalNotes ...string) {
+		newNotes := append(defaultNotes, additionalNotes...)
+
+		reportFile := &transfer.TransferReportFile{
+			Method:     transfer.TransferMethodDelete,
+			StartAt:    startTime,
+			EndAt:      endTime,
+			SourcePath: sourcePath,
+			Error:      err,
+			Notes:      newNotes,
+		}
+
+		bput.transferReportManager.AddFile(reportFile)
+	}
+
+	reportSimple := func(err error, additionalNotes ...string) {
+		now := time.Now()
+		report(now, now, err, additionalNotes...)
+	}
+
+	deleteTask := func(job *parallel.ParallelJob) error {
+		logger := logger.WithFields(job.GetLogFields())
+
+		if job.IsCanceled() {
+			// job is canceled, do not run
+			job.Progress("delete", -1, 1, true)
+
+			reportSimple(nil, "canceled")
+			logger.Debug("canceled a task for deleting empty directory")
+			return nil
+		}
+
+		logger.Debug("deleting a file")
+
+		job.Progress("delete", 0, 1, false)
+
+		removeErr := os.Remove(sourcePath)
+		reportSimple(removeErr)
+
+		if removeErr != nil {
+			job.Progress("delete", -1, 1, true)
+			return errors.Wrapf(removeErr, "failed to delete %q", sourcePath)
+		}
+
+		logger.Debug("deleted a file")
+		job.Progress("delete", 1, 1, false)
+		return nil
+	}
+
+	bput.parallelPostProcessJobManager.Schedule("removing - "+sourcePath, deleteTask, 1, progress.UnitsDefault)
+	logger.Debug("scheduled a file deletion")
+}
+
+func (bput *BputTransfer) scheduleDeleteDirOnSuccess(sourcePath string) {
+	logger := log.WithFields(log.Fields{
+		"source_path": sourcePath,
+	})
+
+	defaultNotes := []string{"bput", "delete on success", "directory"}
+
+	report := func(startTime time.Time, endTime time.Time, err error, additionalNotes ...string) {
+		newNotes := append(defaultNotes, additionalNotes...)
+
+		reportFile := &transfer.TransferReportFile{
+			Method:     transfer.TransferMethodDelete,
+			StartAt:    startTime,
+			EndAt:      endTime,
+			SourcePath: sourcePath,
+			Error:      err,
+			Notes:      newNotes,
+		}
+
+		bput.transferReportManager.AddFile(reportFile)
+	}
+
+	reportSimple := func(err error, additionalNotes ...string) {
+		now := time.Now()
+		report(now, now, err, additionalNotes...)
+	}
+
+	deleteTask := func(job *parallel.ParallelJob) error {
+		logger := logger.WithFields(job.GetLogFields())
+
+		if job.IsCanceled() {
+			// job is canceled, do not run
+			job.Progress("delete", -1, 1, true)
+
+			reportSimple(nil, "canceled")
+			logger.Debug("canceled a task for deleting an empty directory")
+			return nil
+		}
+
+		logger.Debug("deleting an empty directory")
+
+		job.Progress("delete", 0, 1, false)
+
+		removeErr := os.Remove(sourcePath)
+		reportSimple(removeErr)
+
+		if removeErr != nil {
+			job.Progress("delete", -1, 1, true)
+			return errors.Wrapf(removeErr, "failed to delete %q", sourcePath)
+		}
+
+		logger.Debug("deleted an empty directory")
+		job.Progress("delete", 1, 1, false)
+		return nil
+	}
+
+	bput.parallelPostProcessJobManager.Schedule("removing - "+sourcePath, deleteTask, 1, progress.UnitsDefault)
+	logger.Debug("scheduled an empty directory deletion")
+}
+
+func (bput *BputTransfer) scheduleDeleteExtraFile(targetPath string) {
+	logger := log.WithFields(log.Fields{
+		"target_path": targetPath,
+	})
+
+	defaultNotes := []string{"bput", "extra", "file"}
+
+	report := func(startTime time.Time, endTime time.Time, err error, additionalNotes ...string) {
+		newNotes := append(defaultNotes, additionalNotes...)
+
+		reportFile := &transfer.TransferReportFile{
+			Method:   transfer.TransferMethodDelete,
+			StartAt:  startTime,
+			EndAt:    endTime,
+			DestPath: targetPath,
+			Error:    err,
+			Notes:    newNotes,
+		}
+
+		bput.transferReportManager.AddFile(reportFile)
+	}
+
+	reportSimple := func(err error, additionalNotes ...string) {
+		now := time.Now()
+		report(now, now, err, additionalNotes...)
+	}
+
+	deleteTask := func(job *parallel.ParallelJob) error {
+		logger := logger.WithFields(job.GetLogFields())
+
+		if job.IsCanceled() {
+			// job is canceled, do not run
+			job.Progress("delete", -1, 1, true)
+
+			reportSimple(nil, "canceled")
+			logger.Debug("canceled a task for deleting an extra data object")
+			return nil
+		}
+
+		logger.Debug("deleting an extra data object")
+
+		job.Progress("delete", 0, 1, false)
+
+		startTime := time.Now()
+		removeErr := bput.filesystem.RemoveFile(targetPath, true)
+		endTime := time.Now()
+		report(startTime, endTime, removeErr)
+
+		if removeErr != nil {
+			job.Progress("delete", -1, 1, true)
+			return errors.Wrapf(removeErr, "failed to delete %q", targetPath)
+		}
+
+		logger.Debug("deleted an extra data object")
+		job.Progress("delete", 1, 1, false)
+		return nil
+	}
+
+	bput.parallelPostProcessJobManager.Schedule(targetPath, deleteTask, 1, progress.UnitsDefault)
+	logger.Debug("scheduled an extra data object deletion")
+}
+
+func (bput *BputTransfer) scheduleDeleteExtraDir(targetPath string) {
+	logger := log.WithFields(log.Fields{
+		"target_path": targetPath,
+	})
+
+	defaultNotes := []string{"bput", "extra", "directory"}
+
+	report := func(startTime time.Time, endTime time.Time, err error, additionalNotes ...string) {
+		newNotes := append(defaultNotes, additionalNotes...)
+
+		reportFile := &transfer.TransferReportFile{
+			Method:   transfer.TransferMethodDelete,
+			StartAt:  startTime,
+			EndAt:    endTime,
+			DestPath: targetPath,
+			Error:    err,
+			Notes:    newNotes,
+		}
+
+		bput.transferReportManager.AddFile(reportFile)
+	}
+
+	reportSimple := func(err error, additionalNotes ...string) {
+		now := time.Now()
+		report(now, now, err, additionalNotes...)
+	}
+
+	deleteTask := func(job *parallel.ParallelJob) error {
+		logger := logger.WithFields(job.GetLogFields())
+
+		if job.IsCanceled() {
+			// job is canceled, do not run
+			job.Progress("delete", -1, 1, true)
+
+			reportSimple(nil, "canceled")
+			logger.Debug("canceled a task for deleting an extra collection")
+			return nil
+		}
+
+		logger.Debug("deleting an extra collection")
+
+		job.Progress("delete", 0, 1, false)
+
+		startTime := time.Now()
+		removeErr := bput.filesystem.RemoveDir(targetPath, false, false)
+		endTime := time.Now()
+		report(startTime, endTime, removeErr)
+
+		if removeErr != nil {
+			job.Progress("delete", -1, 1, true)
+			return errors.Wrapf(removeErr, "failed to delete %q", targetPath)
+		}
+
+		logger.Debug("deleted an extra collection")
+		job.Progress("delete", 1, 1, false)
+		return nil
+	}
+
+	bput.parallelPostProcessJobManager.Schedule(targetPath, deleteTask, 1, progress.UnitsDefault)
+	logger.Debug("scheduled an extra collection deletion")
+}
+
+func (bput *BputTransfer) putFile(sourceStat fs.FileInfo, sourcePath string, tempPath string, targetPath string, encryptionMode encryption.EncryptionMode) error {
+	logger := log.WithFields(log.Fields{
+		"source_path":     sourcePath,
+		"temp_path":       tempPath,
+		"target_path":     targetPath,
+		"encryption_mode": encryptionMode,
+	})
+
+	defaultNotes := []string{"bput"}
+
+	reportSimple := func(err error, additionalNotes ...string) {
+		now := time.Now()
+		newNotes := append(defaultNotes, additionalNotes...)
+		newNotes = append(newNotes, "file")
+
+		reportFile := &transfer.TransferReportFile{
+			Method:     transfer.TransferMethodBput,
+			StartAt:    now,
+			EndAt:      now,
+			SourcePath: sourcePath,
+			SourceSize: sourceStat.Size(),
+			DestPath:   targetPath,
+			Error:      err,
+			Notes:      newNotes,
+		}
+
+		bput.transferReportManager.AddFile(reportFile)
+	}
+
+	reportOverwrite := func(startTime time.Time, endTime time.Time, err error, additionalNotes ...string) {
+		newNotes := append(defaultNotes, additionalNotes...)
+		newNotes = append(newNotes, "overwrite")
+
+		reportFile := &transfer.TransferReportFile{
+			Method:   transfer.TransferMethodDelete,
+			StartAt:  startTime,
+			EndAt:    endTime,
+			DestPath: targetPath,
+			Error:    err,
+			Notes:    newNotes,
+		}
+
+		bput.transferReportManager.AddFile(reportFile)
+	}
+
+	bput.mutex.Lock()
+	commons_path.MarkIRODSPathMap(bput.updatedPathMap, targetPath)
+	bput.mutex.Unlock()
+
+	if bput.options.ExcludeHidden {
+		// exclude hidden
+		if strings.HasPrefix(sourceStat.Name(), ".") {
+			// skip
+			reportSimple(nil, "hidden", "skipped")
+			bput.events.notifySkipped(sourcePath, targetPath, "skip uploading a file %q to %q. The file is hidden!", sourcePath, targetPath)
+			logger.Debug("skip uploading a file. The file is hidden!")
+			return nil
+		}
+	}
+
+	if bput.options.Age > 0 {
+		// exclude old
+		age := time.Since(sourceStat.ModTime())
+		maxAge := bput.options.Age
+		if age > maxAge {
+			// skip
+			reportSimple(nil, "age", "skipped")
+			bput.events.notifySkipped(sourcePath, targetPath, "skip uploading a file %q to %q. The file is too old (%s > %s)!", sourcePath, targetPath, age, maxAge)
+			logger.Debugf("skip uploading a file. The file is too old (age %s > max_age %s)!", age, maxAge)
+			return nil
+		}
+	}
+
+	targetEntry, err := bput.filesystem.Stat(targetPath)
+	if err != nil {
+		if irodsclient_types.IsFileNotFoundError(err) {
+			// target does not exist
+			// target must be a file with new name
+			bput.schedulePut(sourceStat, sourcePath, tempPath, targetPath, encryptionMode)
+			return nil
+		}
+
+		return errors.Wrapf(err, "failed to stat %q", targetPath)
+	}
+
+	// target exists
+	// target must be a file
+	if targetEntry.IsDir() {
+		if bput.options.Sync {
+			// if it is sync, remove
+			overwrite := bput.options.confirm(fmt.Sprintf("Overwriting a data object %q, but collection exists. Overwrite?", targetPath))
+
+			if overwrite {
+				startTime := time.Now()
+				removeErr := bput.filesystem.RemoveDir(targetPath, true, true)
+				endTime := time.Now()
+				reportOverwrite(startTime, endTime, removeErr, "directory")
+
+				if removeErr != nil {
+					return removeErr
+				}
+
+				// fallthrough to put
+			} else {
+				overwriteErr := types.NewNotFileError(targetPath)
+
+				now := time.Now()
+				reportOverwrite(now, now, overwriteErr, "directory", "declined", "skipped")
+				bput.events.notifySkipped(sourcePath, targetPath, "skip uploading a file %q to %q. Collection exists with the same name!", sourcePath, targetPath)
+				logger.Debug("skip uploading a file. Collection exists with the same name!")
+				return nil
+			}
+		} else {
+			notFileErr := types.NewNotFileError(targetPath)
+			now := time.Now()
+			reportOverwrite(now, now, notFileErr, "directory")
+			return notFileErr
+		}
+	}
+
+	if bput.options.Differential {
+		if bput.options.NoHash {
+			if targetEntry.Size == sourceStat.Size() {
+				// skip
+				now := time.Now()
+				reportFile := &transfer.TransferReportFile{
+					Method:                transfer.TransferMethodBput,
+					StartAt:               now,
+					EndAt:                 now,
+					SourcePath:            sourcePath,
+					SourceSize:            sourceStat.Size(),
+					DestPath:              targetEntry.Path,
+					DestSize:              targetEntry.Size,
+					DestChecksumAlgorithm: string(targetEntry.CheckSumAlgorithm),
+
+					Notes: []string{"bput", "file", "differential", "no_hash", "same size", "skipped"},
+				}
+
+				bput.transferReportManager.AddFile(reportFile)
+
+				bput.events.notifySkipped(sourcePath, targetPath, "skip uploading a file %q to %q. The file already exists!", sourcePath, targetPath)
+				logger.Debug("skip uploading a file. The file already exists!")
+				return nil
+			}
+		} else {
+			if targetEntry.Size == sourceStat.Size() {
+				// compare hash
+				if len(targetEntry.CheckSum) > 0 {
+					_, checksumSpan := tracing.StartSpan(bput.ctx, "checksum",
+						tracing.String("gocmd.local_path", sourcePath),
+						tracing.Int64("gocmd.size", sourceStat.Size()),
+						tracing.String("gocmd.checksum_algorithm", string(targetEntry.CheckSumAlgorithm)),
+					)
+
+					localChecksum, err := irodsclient_util.HashLocalFile(sourcePath, string(targetEntry.CheckSumAlgorithm), nil)
+					checksumSpan.End(err)
+					if err != nil {
+						reportSimple(err, "differential")
+						return errors.Wrapf(err, "failed to get hash for %q", sourcePath)
+					}
+
+					if bytes.Equal(localChecksum, targetEntry.CheckSum) {
+						// skip
+						now := time.Now()
+						reportFile := &transfer.TransferReportFile{
+							Method:                  transfer.TransferMethodBput,
+							StartAt:                 now,
+							EndAt:                   now,
+							SourcePath:              sourcePath,
+							SourceSize:              sourceStat.Size(),
+							SourceChecksumAlgorithm: string(targetEntry.CheckSumAlgorithm),
+							SourceChecksum:          hex.EncodeToString(localChecksum),
+							DestPath:                targetEntry.Path,
+							DestSize:                targetEntry.Size,
+							DestChecksum:            hex.EncodeToString(targetEntry.CheckSum),
+							DestChecksumAlgorithm:   string(targetEntry.CheckSumAlgorithm),
+
+							Notes: []string{"bput", "file", "differential", "same checksum", "skipped"},
+						}
+
+						bput.transferReportManager.AddFile(reportFile)
+
+						bput.events.notifySkipped(sourcePath, targetPath, "skip uploading a file %q to %q. The data object with the same hash already exists!", sourcePath, targetPath)
+						logger.Debug("skip uploading a file. The data object with the same hash already exists!")
+						return nil
+					}
+				}
+			}
+		}
+	} else {
+		overwrite := bput.options.confirm(fmt.Sprintf("Data object %q already exists. Overwrite?", targetPath))
+
+		if !overwrite {
+			// skip
+			now := time.Now()
+			reportFile := &transfer.TransferReportFile{
+				Method:                transfer.TransferMethodBput,
+				StartAt:               now,
+				EndAt:                 now,
+				SourcePath:            sourcePath,
+				SourceSize:            sourceStat.Size(),
+				DestPath:              targetEntry.Path,
+				DestSize:              targetEntry.Size,
+				DestChecksum:          hex.EncodeToString(targetEntry.CheckSum),
+				DestChecksumAlgorithm: string(targetEntry.CheckSumAlgorithm),
+
+				Notes: []string{"bput", "file", "overwrite", "declined", "skipped"},
+			}
+
+			bput.transferReportManager.AddFile(reportFile)
+
+			bput.events.notifySkipped(sourcePath, targetPath, "skip uploading a file %q to %q. The data object already exists!", sourcePath, targetPath)
+			logger.Debug("skip uploading a file. The data object already exists!")
+			return nil
+		}
+	}
+
+	// schedule
+	bput.schedulePut(sourceStat, sourcePath, tempPath, targetPath, encryptionMode)
+	return nil
+}
+
+func (bput *BputTransfer) putDir(sourceStat fs.FileInfo, sourcePath string, targetPath string, parentEncryptionMode encryption.EncryptionMode) error {
+	logger := log.WithFields(log.Fields{
+		"source_path":            sourcePath,
+		"target_path":            targetPath,
+		"parent_encryption_mode": parentEncryptionMode,
+	})
+
+	defaultNotes := []string{"bput", "directory"}
+
+	report := func(startTime time.Time, endTime time.Time, err error, additionalNotes ...string) {
+		newNotes := append(defaultNotes, additionalNotes...)
+
+		reportFile := &transfer.TransferReportFile{
+			Method:     transfer.TransferMethodBput,
+			StartAt:    startTime,
+			EndAt:      endTime,
+			SourcePath: sourcePath,
+			SourceSize: sourceStat.Size(),
+			DestPath:   targetPath,
+			Error:      err,
+			Notes:      newNotes,
+		}
+
+		bput.transferReportManager.AddFile(reportFile)
+	}
+
+	reportSimple := func(err error, additionalNotes ...string) {
+		now := time.Now()
+		report(now, now, err, additionalNotes...)
+	}
+
+	reportOverwrite := func(startTime time.Time, endTime time.Time, err error, additionalNotes ...string) {
+		newNotes := append(defaultNotes, additionalNotes...)
+		newNotes = append(newNotes, "overwrite")
+
+		reportFile := &transfer.TransferReportFile{
+			Method:   transfer.TransferMethodDelete,
+			StartAt:  startTime,
+			EndAt:    endTime,
+			DestPath: targetPath,
+			Error:    err,
+			Notes:    newNotes,
+		}
+
+		bput.transferReportManager.AddFile(reportFile)
+	}
+
+	bput.mutex.Lock()
+	commons_path.MarkIRODSPathMap(bput.updatedPathMap, targetPath)
+	bput.mutex.Unlock()
+
+	if bput.options.ExcludeHidden {
+		// exclude hidden
+		if strings.HasPrefix(sourceStat.Name(), ".") {
+			// skip
+			reportSimple(nil, "hidden", "skipped")
+			bput.events.notifySkipped(sourcePath, targetPath, "skip uploading a directory %q to %q. The directory is hidden!", sourcePath, targetPath)
+			logger.Debug("skip uploading a directory. The directory is hidden!")
+			return nil
+		}
+	}
+
+	targetEntry, err := bput.filesystem.Stat(targetPath)
+	if err != nil {
+		if irodsclient_types.IsFileNotFoundError(err) {
+			// target does not exist
+			// target must be a directory with new name
+			startTime := time.Now()
+			err = bput.filesystem.MakeDir(targetPath, true)
+			endTime := time.Now()
+			report(startTime, endTime, err)
+			if err != nil {
+				return errors.Wrapf(err, "failed to make a collection %q", targetPath)
+			}
+
+			// fallthrough to put entries
+		} else {
+			reportSimple(err)
+			return errors.Wrapf(err, "failed to stat %q", targetPath)
+		}
+	} else {
+		// target exists
+		if !targetEntry.IsDir() {
+			if bput.options.Sync {
+				// if it is sync, remove
+				overwrite := bput.options.confirm(fmt.Sprintf("Overwriting a collection %q, but data object exists. Overwrite?", targetPath))
+
+				if overwrite {
+					startTime := time.Now()
+					removeErr := bput.filesystem.RemoveFile(targetPath, true)
+					endTime := time.Now()
+
+					reportOverwrite(startTime, endTime, removeErr)
+
+					if removeErr != nil {
+						return removeErr
+					}
+
+					// fallthrough to put entries
+				} else {
+					overwriteErr := types.NewNotDirError(targetPath)
+
+					now := time.Now()
+					reportOverwrite(now, now, overwriteErr, "declined", "skipped")
+					bput.events.notifySkipped(sourcePath, targetPath, "skip uploading a directory %q to %q. The data object already exists!", sourcePath, targetPath)
+					logger.Debug("skip uploading a directory. The data object already exists!")
+					return nil
+				}
+			} else {
+				notDirErr := types.NewNotDirError(targetPath)
+				now := time.Now()
+				reportOverwrite(now, now, notDirErr)
+				return notDirErr
+			}
+		} else {
+			// target is a directory
+			// cache target dir entries first for performance, because multiple files are uploaded to the same target dir
+			_, err = bput.filesystem.List(targetPath)
+			if err != nil {
+				reportSimple(err)
+				return errors.Wrapf(err, "failed to list a directory %q", targetPath)
+			}
+		}
+	}
+
+	// load encryption config
+	encryptionMode := bput.getEncryptionMode(targetPath, parentEncryptionMode)
+
+	// get entries
+	entries, err := os.ReadDir(sourcePath)
+	if err != nil {
+		reportSimple(err)
+		return errors.Wrapf(err, "failed to list a directory %q", sourcePath)
+	}
+
+	for _, entry := range entries {
+		newEntryPath := commons_path.MakeIRODSTargetFilePath(bput.filesystem, entry.Name(), targetPath)
+
+		entryPath := filepath.Join(sourcePath, entry.Name())
+		entryStat, err := os.Stat(entryPath)
+		if err != nil {
+			if os.IsNotExist(err) {
+				reportSimple(err)
+				return irodsclient_types.NewFileNotFoundError(entryPath)
+			}
+
+			return errors.Wrapf(err, "failed to stat %q", entryPath)
+		}
+
+		if entryStat.IsDir() {
+			// dir
+			err = bput.putDir(entryStat, entryPath, newEntryPath, encryptionMode)
+			if err != nil {
+				return err
+			}
+		} else {
+			// file
+			if encryptionMode != encryption.EncryptionModeNone {
+				// encrypt filename
+				tempPath, err := bput.getLocalPathForEncryption(entryPath, path.Dir(newEntryPath))
+				if err != nil {
+					reportSimple(err)
+					return errors.Wrapf(err, "failed to get encryption path for %q", entryPath)
+				}
+
+				newTargetPath := path.Join(path.Dir(newEntryPath), path.Base(tempPath))
+
+				err = bput.putFile(entryStat, entryPath, tempPath, newTargetPath, encryptionMode)
+				if err != nil {
+					return err
+				}
+			} else {
+				err = bput.putFile(entryStat, entryPath, "", newEntryPath, encryptionMode)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func (bput *BputTransfer) deleteFileOnSuccess(sourcePath string) error {
+	logger := log.WithFields(log.Fields{
+		"source_path": sourcePath,
+	})
+
+	defaultNotes := []string{"bput", "delete on success", "file"}
+
+	reportSimple := func(err error, additionalNotes ...string) {
+		now := time.Now()
+		newNotes := append(defaultNotes, additionalNotes...)
+
+		reportFile := &transfer.TransferReportFile{
+			Method:     transfer.TransferMethodDelete,
+			StartAt:    now,
+			EndAt:      now,
+			SourcePath: sourcePath,
+			Error:      err,
+			Notes:      newNotes,
+		}
+
+		bput.transferReportManager.AddFile(reportFile)
+	}
+
+	logger.Debug("removing a file after upload")
+
+	overwrite := bput.options.confirm(fmt.Sprintf("Removing a file %q after upload. Remove?", sourcePath))
+
+	if overwrite {
+		bput.scheduleDeleteFileOnSuccess(sourcePath)
+		return nil
+	} else {
+		// do not remove
+		reportSimple(nil, "declined", "skipped")
+		return nil
+	}
+}
+
+func (bput *BputTransfer) deleteDirOnSuccess(sourcePath string) error {
+	logger := log.WithFields(log.Fields{
+		"source_path": sourcePath,
+	})
+
+	defaultNotes := []string{"bput", "delete on success", "directory"}
+
+	reportSimple := func(err error, additionalNotes ...string) {
+		now := time.Now()
+		newNotes := append(defaultNotes, additionalNotes...)
+
+		reportFile := &transfer.TransferReportFile{
+			Method:     transfer.TransferMethodDelete,
+			StartAt:    now,
+			EndAt:      now,
+			SourcePath: sourcePath,
+			Error:      err,
+			Notes:      newNotes,
+		}
+
+		bput.transferReportManager.AddFile(reportFile)
+	}
+
+	logger.Debug("removing a directory after upload")
+
+	// scan recursively
+	entries, err := os.ReadDir(sourcePath)
+	if err != nil {
+		reportSimple(err)
+		return errors.Wrapf(err, "failed to list a directory %q", sourcePath)
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			// dir
+			newSourcePath := filepath.Join(sourcePath, entry.Name())
+			err = bput.deleteDirOnSuccess(newSourcePath)
+			if err != nil {
+				return err
+			}
+		} else {
+			// file
+			newSourcePath := filepath.Join(sourcePath, entry.Name())
+			err = bput.deleteFileOnSuccess(newSourcePath)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// delete the directory itself
+	overwrite := bput.options.confirm(fmt.Sprintf("Removing a directory %q after upload. Remove?", sourcePath))
+
+	if overwrite {
+		bput.scheduleDeleteDirOnSuccess(sourcePath)
+		return nil
+	} else {
+		// do not remove
+		reportSimple(nil, "declined", "skipped")
+		return nil
+	}
+}
+
+func (bput *BputTransfer) deleteExtraFile(targetPath string) error {
+	logger := log.WithFields(log.Fields{
+		"target_path": targetPath,
+	})
+
+	defaultNotes := []string{"bput", "extra", "file"}
+
+	report := func(startTime time.Time, endTime time.Time, err error, additionalNotes ...string) {
+		newNotes := append(defaultNotes, additionalNotes...)
+
+		reportFile := &transfer.TransferReportFile{
+			Method:   transfer.TransferMethodDelete,
+			StartAt:  startTime,
+			EndAt:    endTime,
+			DestPath: targetPath,
+			Error:    err,
+			Notes:    newNotes,
+		}
+
+		bput.transferReportManager.AddFile(reportFile)
+	}
+
+	reportSimple := func(err error, additionalNotes ...string) {
+		now := time.Now()
+		report(now, now, err, additionalNotes...)
+	}
+
+	bput.mutex.RLock()
+	isExtra := false
+	if _, ok := bput.updatedPathMap[targetPath]; !ok {
+		isExtra = true
+	}
+	bput.mutex.RUnlock()
+
+	if isExtra {
+		// extra file
+		logger.Debug("removing an extra data object")
+
+		overwrite := bput.options.confirm(fmt.Sprintf("Removing an extra data object %q. Remove?", targetPath))
+
+		if overwrite {
+			bput.scheduleDeleteExtraFile(targetPath)
+			return nil
+		} else {
+			// do not remove
+			reportSimple(nil, "declined", "skipped")
+			return nil
+		}
+	}
+
+	return nil
+}
+
+func (bput *BputTransfer) deleteExtraDir(targetPath string) error {
+	logger := log.WithFields(log.Fields{
+		"target_path": targetPath,
+	})
+
+	defaultNotes := []string{"bput", "extra", "directory"}
+
+	reportSimple := func(err error, additionalNotes ...string) {
+		now := time.Now()
+		newNotes := append(defaultNotes, additionalNotes...)
+
+		reportFile := &transfer.TransferReportFile{
+			Method:   transfer.TransferMethodDelete,
+			StartAt:  now,
+			EndAt:    now,
+			DestPath: targetPath,
+			Error:    err,
+			Notes:    newNotes,
+		}
+
+		bput.transferReportManager.AddFile(reportFile)
+	}
+
+	// scan recursively
+	entries, err := bput.filesystem.List(targetPath)
+	if err != nil {
+		reportSimple(err)
+		return errors.Wrapf(err, "failed to list a collection %q", targetPath)
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			// dir
+			err = bput.deleteExtraDir(entry.Path)
+			if err != nil {
+				return err
+			}
+		} else {
+			// file
+			err = bput.deleteExtraFile(entry.Path)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// delete the directory itself
+	bput.mutex.RLock()
+	isExtra := false
+	if _, ok := bput.updatedPathMap[targetPath]; !ok {
+		isExtra = true
+	}
+	bput.mutex.RUnlock()
+
+	if isExtra {
+		// extra dir
+		logger.Debug("removing an extra collection")
+
+		overwrite := bput.options.confirm(fmt.Sprintf("Removing an extra collection %q. Remove?", targetPath))
+
+		if overwrite {
+			bput.scheduleDeleteExtraDir(targetPath)
+			return nil
+		} else {
+			// do not remove
+			reportSimple(nil, "declined", "skipped")
+			return nil
+		}
+	}
+
+	return nil
+}
+
+func (bput *BputTransfer) getEncryptionManagerForEncryption(mode encryption.EncryptionMode, targetDir string) *encryption.EncryptionManager {
+	manager := encryption.NewEncryptionManager(mode)
+
+	switch mode {
+	case encryption.EncryptionModeWinSCP, encryption.EncryptionModeAESGCM, encryption.EncryptionModePGP:
+		manager.SetKey([]byte(bput.options.EncryptionKey))
+	case encryption.EncryptionModeSSH:
+		manager.SetPublicKeyRing(bput.getPublicKeyRing(targetDir))
+	case encryption.EncryptionModeAge:
+		manager.SetKey([]byte(bput.options.EncryptionKey))
+		manager.SetAgeRecipients(bput.options.EncryptionRecipients)
+	}
+
+	return manager
+}
+
+// getPublicKeyRing returns public keys of recipients, keys in collection metadata are used if keys are not given
+func (bput *BputTransfer) getPublicKeyRing(targetDir string) []string {
+	if len(bput.options.EncryptionPublicKeyPaths) == 0 && !bput.options.EncryptionIgnoreMeta {
+		encryptionConfig := encryption.GetEncryptionConfigFromMeta(bput.filesystem, targetDir)
+		if len(encryptionConfig.PublicKeys) > 0 {
+			return encryptionConfig.PublicKeys
+		}
+	}
+
+	return bput.options.getPublicKeyPaths()
+}
+
+func (bput *BputTransfer) getLocalPathForEncryption(sourcePath string, targetDir string) (string, error) {
+	if bput.options.EncryptionMode != encryption.EncryptionModeNone {
+		encryptManager := bput.getEncryptionManagerForEncryption(bput.options.EncryptionMode, targetDir)
+		sourceFilename := filepath.Base(sourcePath)
+
+		encryptedFilename, err := encryptManager.EncryptFilename(sourceFilename)
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to encrypt filename %q", sourcePath)
+		}
+
+		tempFilePath := commons_path.MakeLocalTargetFilePath(encryptedFilename, bput.options.EncryptionTempPath)
+
+		return tempFilePath, nil
+	}
+
+	return "", nil
+}
+
+func (bput *BputTransfer) encryptFile(sourcePath string, encryptedFilePath string, targetPath string, encryptionMode encryption.EncryptionMode) (bool, error) {
+	logger := log.WithFields(log.Fields{
+		"source_path":     sourcePath,
+		"encrypted_path":  encryptedFilePath,
+		"target_path":     targetPath,
+		"encryption_mode": encryptionMode,
+	})
+
+	if encryptionMode != encryption.EncryptionModeNone {
+		logger.Debug("encrypt a file")
+
+		encryptManager := bput.getEncryptionManagerForEncryption(encryptionMode, path.Dir(targetPath))
+
+		err := encryptManager.EncryptFile(sourcePath, encryptedFilePath)
+		if err != nil {
+			return false, errors.Wrapf(err, "failed to encrypt %q to %q", sourcePath, encryptedFilePath)
+		}
+
+		return true, nil
+	}
+
+	return false, nil
+}
+
+func (bput *BputTransfer) determineTransferMethod(size int64) (transfer.TransferMode, int) {
+	logger := log.WithFields(log.Fields{})
+
+	threads := parallel.CalculateThreadForTransferJob(size, bput.options.ThreadNumPerFile)
+
+	// determine how to upload
+	if bput.options.SingleThread || bput.options.ThreadNum <= 2 || bput.options.ThreadNumPerFile == 1 || !bput.filesystem.SupportParallelUpload() {
+		threads = 1
+	}
+
+	if bput.options.ICAT {
+		logger.Info("using ICAT transfer for uploading a data object")
+		return transfer.TransferModeICAT, threads
+	} else if bput.options.WebDAV {
+		if bput.webdavClient == nil {
+			// fallback
+			logger.Info("WebDAV is not configured. Using ICAT transfer for uploading a data object")
+			return transfer.TransferModeICAT, threads
+		}
+
+		logger.Info("using WebDAV for uploading a data object")
+		return transfer.TransferModeWebDAV, 1
+	}
+
+	// sysconfig
+	systemConfig := config.GetSystemConfig()
+	if systemConfig != nil && systemConfig.AdditionalConfig != nil {
+		mode := transfer.GetTransferMode(systemConfig.AdditionalConfig.TransferMode)
+		if mode.Valid() {
+			return mode, threads
+		}
+	}
+
+	logger.Info("using ICAT transfer for uploading a data object")
+	return transfer.TransferModeICAT, threads
+}
+
+func (bput *BputTransfer) determineTransferMethodForBundle(bun *bundle.Bundle) (transfer.TransferMode, int) {
+	logger := log.WithFields(log.Fields{})
+
+	threads := parallel.CalculateThreadForTransferJob(bun.GetSize(), bput.options.ThreadNumPerFile)
+
+	// determine how to upload
+	if bput.options.SingleThread || bput.options.ThreadNum <= 2 || bput.options.ThreadNumPerFile == 1 || !bput.filesystem.SupportParallelUpload() {
+		threads = 1
+	}
+
+	if bput.options.ICAT {
+		logger.Info("using ICAT transfer for uploading a data object")
+		return transfer.TransferModeICAT, threads
+	} else if bput.options.WebDAV {
+		if bput.webdavClient == nil {
+			// fallback
+			logger.Info("WebDAV is not configured. Using ICAT transfer for uploading a data object")
+			return transfer.TransferModeICAT, threads
+		}
+
+		logger.Info("using WebDAV for uploading a data object")
+		return transfer.TransferModeWebDAV, 1
+	}
+
+	// sysconfig
+	systemConfig := config.GetSystemConfig()
+	if systemConfig != nil && systemConfig.AdditionalConfig != nil {
+		mode := transfer.GetTransferMode(systemConfig.AdditionalConfig.TransferMode)
+		if mode.Valid() {
+			return mode, threads
+		}
+	}
+
+	logger.Info("using ICAT transfer for uploading a data object")
+	return transfer.TransferModeICAT, threads
+}
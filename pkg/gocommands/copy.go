@@ -0,0 +1,1120 @@
+package gocommands
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/avast/retry-go"
+	"github.com/cockroachdb/errors"
+	irodsclient_fs "github.com/cyverse/go-irodsclient/fs"
+	irodsclient_types "github.com/cyverse/go-irodsclient/irods/types"
+	"github.com/cyverse/gocommands/commons/irods"
+	"github.com/cyverse/gocommands/commons/parallel"
+	"github.com/cyverse/gocommands/commons/path"
+	"github.com/cyverse/gocommands/commons/tracing"
+	"github.com/cyverse/gocommands/commons/transfer"
+	"github.com/cyverse/gocommands/commons/types"
+	"github.com/cyverse/gocommands/commons/wildcard"
+	"github.com/jedib0t/go-pretty/v6/progress"
+	log "github.com/sirupsen/logrus"
+)
+
+// CopyOptions has options of Copy
+type CopyOptions struct {
+	TransferOptions
+
+	// SourcePaths are iRODS data objects or collections to copy
+	SourcePaths []string
+	// TargetPath is the iRODS data object or collection to copy to, required
+	TargetPath string
+
+	// WildcardSearch expands wildcards in SourcePaths
+	WildcardSearch bool
+	// PreserveMetadata copies AVUs of sources to targets
+	PreserveMetadata bool
+	// PreserveACL copies access control lists of sources to targets
+	PreserveACL bool
+	// PreserveModifyTime keeps modification times of sources at targets
+	PreserveModifyTime bool
+}
+
+// Copy copies iRODS data objects or collections to another iRODS path
+func Copy(ctx context.Context, options CopyOptions) (*TransferResult, error) {
+	cp, err := NewCopyTransfer(options)
+	if err != nil {
+		return nil, err
+	}
+
+	err = cp.Run(ctx)
+	return cp.GetResult(), err
+}
+
+// CopyTransfer copies iRODS data objects or collections to another iRODS path
+type CopyTransfer struct {
+	options CopyOptions
+
+	account    *irodsclient_types.IRODSAccount
+	filesystem *irodsclient_fs.FileSystem
+
+	// working and home collections relative iRODS paths are resolved against
+	cwd  string
+	home string
+
+	sourcePaths []string
+	targetPath  string
+
+	parallelTransferJobManager    *parallel.ParallelJobManager
+	parallelPostProcessJobManager *parallel.ParallelJobManager
+
+	transferReportManager *transfer.TransferReportManager
+	updatedPathMap        map[string]bool
+	mutex                 sync.RWMutex // mutex for updatedPathMap
+
+	// canceled by CancelPending
+	pendingCtx    context.Context
+	cancelPending context.CancelFunc
+
+	// canceled by Abort
+	run runContext
+
+	events *eventNotifier
+
+	totalCopiedFiles int
+	totalCopiedBytes int64
+	startTime        time.Time
+}
+
+// NewCopyTransfer creates a new CopyTransfer, options are validated
+func NewCopyTransfer(options CopyOptions) (*CopyTransfer, error) {
+	err := options.validate()
+	if err != nil {
+		return nil, err
+	}
+
+	options.setDefaults()
+
+	cp := &CopyTransfer{
+		options: options,
+
+		account:     options.Account,
+		sourcePaths: options.SourcePaths,
+		targetPath:  options.TargetPath,
+
+		updatedPathMap:   map[string]bool{},
+		totalCopiedFiles: 0,
+		totalCopiedBytes: 0,
+		startTime:        time.Now(),
+	}
+
+	cp.cwd, cp.home = options.getWorkingDirs()
+	cp.pendingCtx, cp.cancelPending = context.WithCancel(context.Background())
+	cp.events = newEventNotifier(options.OnEvent)
+
+	return cp, nil
+}
+
+func (options *CopyOptions) validate() error {
+	err := options.TransferOptions.validate()
+	if err != nil {
+		return err
+	}
+
+	if len(options.SourcePaths) == 0 {
+		return errors.New("source path is not given")
+	}
+
+	if len(options.TargetPath) == 0 {
+		return errors.New("target path is not given")
+	}
+
+	if options.NoRoot && len(options.SourcePaths) > 1 {
+		return errors.New("failed to copy multiple source collections without creating root directory")
+	}
+
+	return nil
+}
+
+func (options *CopyOptions) setDefaults() {
+	options.TransferOptions.setDefaults()
+}
+
+// CancelPending cancels pending copies, copies running are completed
+func (cp *CopyTransfer) CancelPending() {
+	cp.cancelPending()
+}
+
+// GetResult returns statistics of the copy
+func (cp *CopyTransfer) GetResult() *TransferResult {
+	notCopied := int64(0)
+	if cp.parallelTransferJobManager != nil {
+		counts := cp.parallelTransferJobManager.GetJobCounts()
+		notCopied = counts.Total - int64(cp.totalCopiedFiles)
+	}
+
+	return &TransferResult{
+		Files:          cp.totalCopiedFiles,
+		Bytes:          cp.totalCopiedBytes,
+		NotTransferred: notCopied,
+		Duration:       time.Since(cp.startTime),
+	}
+}
+
+// Abort cancels Run and waits for running copies to return
+// It is for callers exiting before Run returns, copies are done by the server so no incomplete files are left locally
+func (cp *CopyTransfer) Abort() *TransferResult {
+	logger := log.WithFields(log.Fields{})
+
+	cp.run.abort()
+	if !parallel.WaitJobManagers(AbortWaitTimeout, cp.parallelTransferJobManager, cp.parallelPostProcessJobManager) {
+		logger.Warnf("running jobs did not return in %s", AbortWaitTimeout)
+	}
+
+	if cp.transferReportManager != nil {
+		cp.transferReportManager.Release()
+	}
+
+	return cp.GetResult()
+}
+
+// Run copies data objects
+// when the ctx is done, pending copies are canceled
+func (cp *CopyTransfer) Run(ctx context.Context) (err error) {
+	logger := log.WithFields(log.Fields{})
+
+	ctx, cancel := cp.run.start(ctx)
+	defer cancel()
+
+	ctx, span := tracing.StartSpan(ctx, "cp",
+		tracing.String("gocmd.source_paths", strings.Join(cp.sourcePaths, ",")),
+		tracing.String("gocmd.target_path", cp.targetPath),
+		tracing.String("irods.host", cp.account.Host),
+		tracing.String("irods.zone", cp.account.ClientZone),
+		tracing.String("irods.resource", cp.account.DefaultResource),
+	)
+	defer func() {
+		span.End(err)
+	}()
+
+	// Create a file system
+	cp.filesystem, err = irods.GetIRODSFSClient(cp.account, false, cp.options.getTimeoutSeconds())
+	if err != nil {
+		return errors.Wrapf(err, "failed to get iRODS FS Client")
+	}
+	defer cp.filesystem.Release()
+
+	// transfer report
+	cp.transferReportManager, err = cp.options.newTransferReportManager()
+	if err != nil {
+		return errors.Wrapf(err, "failed to create transfer report manager")
+	}
+	defer cp.transferReportManager.Release()
+
+	// parallel job manager
+	metaSession := cp.filesystem.GetMetadataSession()
+	cp.parallelTransferJobManager, cp.parallelPostProcessJobManager = cp.options.newParallelJobManagers(metaSession.GetMaxConnections(), cp.events.getProgressEventWriter())
+
+	// cancel pending jobs when CancelPending is called
+	stopCancel := context.AfterFunc(cp.pendingCtx, func() {
+		cp.parallelTransferJobManager.CancelJobs()
+		cp.parallelPostProcessJobManager.CancelJobs()
+	})
+	defer stopCancel()
+
+	// Expand wildcards
+	if cp.options.WildcardSearch {
+		cp.sourcePaths, err = wildcard.ExpandWildcards(ctx, cp.filesystem, cp.account, cp.sourcePaths, true, true)
+		if err != nil {
+			return errors.Wrapf(err, "failed to expand wildcards")
+		}
+	}
+
+	// run
+	if len(cp.sourcePaths) >= 2 {
+		// multi-source, target must be a dir
+		err = cp.ensureTargetIsDir(cp.targetPath)
+		if err != nil {
+			return errors.Wrapf(err, "target path %q is not a directory", cp.targetPath)
+		}
+	}
+
+	for _, sourcePath := range cp.sourcePaths {
+		err = cp.copyOne(sourcePath, cp.targetPath)
+		if err != nil {
+			return errors.Wrapf(err, "failed to copy %q to %q", sourcePath, cp.targetPath)
+		}
+	}
+
+	// delete extra
+	if cp.options.Delete {
+		logger.Infof("deleting extra data objects and collections under %q", cp.targetPath)
+
+		err = cp.deleteExtraOne(cp.targetPath)
+		if err != nil {
+			return errors.Wrapf(err, "failed to delete extra data objects or collections")
+		}
+	}
+
+	logger.Info("done scheduling jobs, starting jobs")
+
+	transferErr := cp.parallelTransferJobManager.StartWithContext(ctx)
+	if transferErr != nil {
+		// error occurred while transferring files
+		cp.parallelPostProcessJobManager.CancelJobs()
+	}
+
+	postProcessErr := cp.parallelPostProcessJobManager.StartWithContext(ctx)
+
+	if ctx.Err() != nil {
+		return errors.Wrap(context.Cause(ctx), "copy canceled")
+	}
+
+	if transferErr != nil {
+		return errors.Wrapf(transferErr, "failed to perform transfer jobs")
+	}
+
+	if postProcessErr != nil {
+		return errors.Wrapf(postProcessErr, "failed to perform post process jobs")
+	}
+
+	return nil
+}
+
+func (cp *CopyTransfer) ensureTargetIsDir(targetPath string) error {
+	cwd := cp.cwd
+	home := cp.home
+	zone := cp.account.ClientZone
+	targetPath = path.MakeIRODSPath(cwd, home, zone, targetPath)
+
+	targetEntry, err := cp.filesystem.Stat(targetPath)
+	if err != nil {
+		if irodsclient_types.IsFileNotFoundError(err) {
+			// not exist
+			return types.NewNotDirError(targetPath)
+		}
+
+		return errors.Wrapf(err, "failed to stat %q", targetPath)
+	}
+
+	if !targetEntry.IsDir() {
+		return types.NewNotDirError(targetPath)
+	}
+
+	return nil
+}
+
+func (cp *CopyTransfer) copyOne(sourcePath string, targetPath string) error {
+	cwd := cp.cwd
+	home := cp.home
+	zone := cp.account.ClientZone
+	sourcePath = path.MakeIRODSPath(cwd, home, zone, sourcePath)
+	targetPath = path.MakeIRODSPath(cwd, home, zone, targetPath)
+
+	sourceEntry, err := cp.filesystem.Stat(sourcePath)
+	if err != nil {
+		return errors.Wrapf(err, "failed to stat %q", sourcePath)
+	}
+
+	if sourceEntry.IsDir() {
+		// dir
+		if !cp.options.Recursive {
+			return errors.New("cannot copy a collection, turn on 'recurse' option")
+		}
+
+		if !cp.options.NoRoot {
+			targetPath = path.MakeIRODSTargetFilePath(cp.filesystem, sourcePath, targetPath)
+		}
+
+		return cp.copyDir(sourceEntry, targetPath)
+	}
+
+	// file
+	targetPath = path.MakeIRODSTargetFilePath(cp.filesystem, sourcePath, targetPath)
+	return cp.copyFile(sourceEntry, targetPath)
+}
+
+func (cp *CopyTransfer) deleteExtraOne(targetPath string) error {
+	cwd := cp.cwd
+	home := cp.home
+	zone := cp.account.ClientZone
+	targetPath = path.MakeIRODSPath(cwd, home, zone, targetPath)
+
+	targetEntry, err := cp.filesystem.Stat(targetPath)
+	if err != nil {
+		return errors.Wrapf(err, "failed to stat %q", targetPath)
+	}
+
+	if targetEntry.IsDir() {
+		// dir
+		return cp.deleteExtraDir(targetEntry)
+	}
+
+	// file
+	return cp.deleteExtraFile(targetEntry)
+}
+
+func (cp *CopyTransfer) scheduleCopy(sourceEntry *irodsclient_fs.Entry, targetPath string, targetEntry *irodsclient_fs.Entry) {
+	logger := log.WithFields(log.Fields{
+		"source_path": sourceEntry.Path,
+		"target_path": targetPath,
+	})
+
+	defaultNotes := []string{"cp", "file"}
+
+	reportSimple := func(err error, additionalNotes ...string) {
+		now := time.Now()
+		newNotes := append(defaultNotes, additionalNotes...)
+
+		reportFile := &transfer.TransferReportFile{
+			Method:     transfer.TransferMethodCopy,
+			StartAt:    now,
+			EndAt:      now,
+			SourcePath: sourceEntry.Path,
+			SourceSize: sourceEntry.Size,
+			DestPath:   targetPath,
+			Error:      err,
+			Notes:      newNotes,
+		}
+
+		cp.transferReportManager.AddFile(reportFile)
+	}
+
+	copyTask := func(job *parallel.ParallelJob) error {
+		logger := logger.WithFields(job.GetLogFields())
+
+		if job.IsCanceled() {
+			// job is canceled, do not run
+			job.Progress("copy", -1, 1, true)
+
+			reportSimple(nil, "canceled")
+			logger.Debug("canceled a task for copying")
+			return nil
+		}
+
+		logger.Debug("copying a data object")
+
+		job.Progress("copy", 0, 1, false)
+
+		retryNum := cp.options.RetryNum
+		retryInterval := cp.options.RetryInterval
+
+		_, copySpan := tracing.StartSpan(job.GetContext(), "copy",
+			tracing.String("gocmd.source_path", sourceEntry.Path),
+			tracing.String("gocmd.target_path", targetPath),
+			tracing.Int64("gocmd.size", sourceEntry.Size),
+		)
+
+		var startTime, endTime time.Time
+		attempt := 0
+		retryErr := retry.Do(func() error {
+			attempt++
+			if attempt > 1 {
+				logger.Debugf("retrying copy attempt %d/%d for %q", attempt, retryNum, sourceEntry.Path)
+				cp.options.Metrics.AddRetry(string(transfer.TransferMethodCopy))
+			}
+			startTime = time.Now()
+			err := cp.filesystem.CopyFileToFile(sourceEntry.Path, targetPath, true)
+			endTime = time.Now()
+			return err
+		}, retry.Attempts(uint(retryNum+1)), retry.Delay(retryInterval), retry.LastErrorOnly(true), retry.Context(job.GetContext()))
+
+		copySpan.SetAttributes(tracing.Int64("gocmd.attempts", int64(attempt)))
+		copySpan.End(retryErr)
+
+		if retryErr != nil {
+			job.Progress("copy", -1, 1, true)
+
+			reportSimple(retryErr)
+			return errors.Wrapf(retryErr, "failed to copy %q to %q after %d attempts", sourceEntry.Path, targetPath, retryNum+1)
+		}
+
+		preserveErr := cp.preserveAttributes(sourceEntry, targetPath)
+		if preserveErr != nil {
+			job.Progress("copy", -1, 1, true)
+
+			reportSimple(preserveErr, "preserve")
+			return errors.Wrapf(preserveErr, "failed to preserve attributes of %q to %q", sourceEntry.Path, targetPath)
+		}
+
+		reportFile := &transfer.TransferReportFile{
+			Method:                  transfer.TransferMethodCopy,
+			StartAt:                 startTime,
+			EndAt:                   endTime,
+			SourcePath:              sourceEntry.Path,
+			SourceSize:              sourceEntry.Size,
+			SourceChecksumAlgorithm: string(sourceEntry.CheckSumAlgorithm),
+			SourceChecksum:          hex.EncodeToString(sourceEntry.CheckSum),
+			DestPath:                targetPath,
+
+			Notes: defaultNotes,
+		}
+
+		if targetEntry != nil {
+			reportFile.DestSize = targetEntry.Size
+			reportFile.DestChecksumAlgorithm = string(targetEntry.CheckSumAlgorithm)
+			reportFile.DestChecksum = hex.EncodeToString(targetEntry.CheckSum)
+		}
+
+		cp.transferReportManager.AddFile(reportFile)
+
+		cp.totalCopiedFiles++
+		cp.totalCopiedBytes += sourceEntry.Size
+		cp.options.Metrics.AddTransfer(string(transfer.TransferMethodCopy), string(transfer.TransferModeICAT), 1, sourceEntry.Size)
+
+		logger.Debug("copied a data object")
+		job.Progress("copy", 1, 1, false)
+
+		return nil
+	}
+
+	cp.parallelTransferJobManager.ScheduleWithOptions(sourceEntry.Path, copyTask, 1, progress.UnitsDefault, parallel.ParallelJobOptions{
+		Size:     sourceEntry.Size,
+		Priority: cp.options.getPriority(sourceEntry.Path),
+	})
+	logger.Debug("scheduled a data object copy")
+}
+
+func (cp *CopyTransfer) scheduleDeleteExtraFile(targetEntry *irodsclient_fs.Entry) {
+	logger := log.WithFields(log.Fields{
+		"target_path": targetEntry.Path,
+	})
+
+	defaultNotes := []string{"cp", "extra", "file"}
+
+	report := func(startTime time.Time, endTime time.Time, err error, additionalNotes ...string) {
+		newNotes := append(defaultNotes, additionalNotes...)
+
+		reportFile := &transfer.TransferReportFile{
+			Method:   transfer.TransferMethodDelete,
+			StartAt:  startTime,
+			EndAt:    endTime,
+			DestPath: targetEntry.Path,
+			Error:    err,
+			Notes:    newNotes,
+		}
+
+		cp.transferReportManager.AddFile(reportFile)
+	}
+
+	reportSimple := func(err error, additionalNotes ...string) {
+		now := time.Now()
+		report(now, now, err, additionalNotes...)
+	}
+
+	deleteTask := func(job *parallel.ParallelJob) error {
+		logger := logger.WithFields(job.GetLogFields())
+
+		if job.IsCanceled() {
+			// job is canceled, do not run
+			job.Progress("delete", -1, 1, true)
+
+			reportSimple(nil, "canceled")
+			logger.Debug("canceled a task for deleting")
+			return nil
+		}
+
+		logger.Debug("deleting a data object")
+
+		job.Progress("delete", 0, 1, false)
+
+		startTime := time.Now()
+		removeErr := cp.filesystem.RemoveFile(targetEntry.Path, true)
+		endTime := time.Now()
+
+		report(startTime, endTime, removeErr)
+
+		if removeErr != nil {
+			job.Progress("delete", -1, 1, true)
+			return errors.Wrapf(removeErr, "failed to delete %q", targetEntry.Path)
+		}
+
+		logger.Debug("deleted a data object")
+		job.Progress("delete", 1, 1, false)
+		return nil
+	}
+
+	cp.parallelPostProcessJobManager.Schedule(targetEntry.Path, deleteTask, 1, progress.UnitsDefault)
+	logger.Debug("scheduled a data object deletion")
+}
+
+func (cp *CopyTransfer) scheduleDeleteExtraDir(targetEntry *irodsclient_fs.Entry) {
+	logger := log.WithFields(log.Fields{
+		"target_path": targetEntry.Path,
+	})
+
+	defaultNotes := []string{"cp", "extra", "directory"}
+
+	report := func(startTime time.Time, endTime time.Time, err error, additionalNotes ...string) {
+		newNotes := append(defaultNotes, additionalNotes...)
+
+		reportFile := &transfer.TransferReportFile{
+			Method:   transfer.TransferMethodDelete,
+			StartAt:  startTime,
+			EndAt:    endTime,
+			DestPath: targetEntry.Path,
+			Error:    err,
+			Notes:    newNotes,
+		}
+
+		cp.transferReportManager.AddFile(reportFile)
+	}
+
+	reportSimple := func(err error, additionalNotes ...string) {
+		now := time.Now()
+		report(now, now, err, additionalNotes...)
+	}
+
+	deleteTask := func(job *parallel.ParallelJob) error {
+		logger := logger.WithFields(job.GetLogFields())
+
+		if job.IsCanceled() {
+			// job is canceled, do not run
+			job.Progress("delete", -1, 1, true)
+
+			reportSimple(nil, "canceled")
+			logger.Debug("canceled a task for deleting extra collection")
+			return nil
+		}
+
+		logger.Debug("deleting an extra collection")
+
+		job.Progress("delete", 0, 1, false)
+
+		startTime := time.Now()
+		err := cp.filesystem.RemoveDir(targetEntry.Path, true, true)
+		endTime := time.Now()
+
+		report(startTime, endTime, err)
+
+		if err != nil {
+			job.Progress("delete", -1, 1, true)
+			return errors.Wrapf(err, "failed to delete %q", targetEntry.Path)
+		}
+
+		logger.Debug("deleted an extra collection")
+		job.Progress("delete", 1, 1, false)
+		return nil
+	}
+
+	cp.parallelPostProcessJobManager.Schedule(targetEntry.Path, deleteTask, 1, progress.UnitsDefault)
+	logger.Debugf("scheduled a collection deletion %q", targetEntry.Path)
+}
+
+func (cp *CopyTransfer) copyFile(sourceEntry *irodsclient_fs.Entry, targetPath string) error {
+	logger := log.WithFields(log.Fields{
+		"source_path": sourceEntry.Path,
+		"target_path": targetPath,
+	})
+
+	defaultNotes := []string{"cp"}
+
+	reportSimple := func(err error, additionalNotes ...string) {
+		now := time.Now()
+		newNotes := append(defaultNotes, additionalNotes...)
+		newNotes = append(newNotes, "file")
+
+		reportFile := &transfer.TransferReportFile{
+			Method:     transfer.TransferMethodCopy,
+			StartAt:    now,
+			EndAt:      now,
+			SourcePath: sourceEntry.Path,
+			SourceSize: sourceEntry.Size,
+			DestPath:   targetPath,
+			Error:      err,
+			Notes:      newNotes,
+		}
+
+		cp.transferReportManager.AddFile(reportFile)
+	}
+
+	reportOverwrite := func(startTime time.Time, endTime time.Time, err error, additionalNotes ...string) {
+		newNotes := append(defaultNotes, additionalNotes...)
+		newNotes = append(newNotes, "overwrite")
+
+		reportFile := &transfer.TransferReportFile{
+			Method:   transfer.TransferMethodDelete,
+			StartAt:  startTime,
+			EndAt:    endTime,
+			DestPath: targetPath,
+			Error:    err,
+			Notes:    newNotes,
+		}
+
+		cp.transferReportManager.AddFile(reportFile)
+	}
+
+	cp.mutex.Lock()
+	path.MarkIRODSPathMap(cp.updatedPathMap, targetPath)
+	cp.mutex.Unlock()
+
+	if cp.options.ExcludeHidden {
+		// exclude hidden
+		if strings.HasPrefix(sourceEntry.Name, ".") {
+			// skip
+			reportSimple(nil, "hidden", "skipped")
+			cp.events.notifySkipped(sourceEntry.Path, targetPath, "skip copying a data object %q to %q. The data object is hidden!", sourceEntry.Path, targetPath)
+			logger.Debug("skip copying a data object. The data object is hidden!", sourceEntry)
+			return nil
+		}
+	}
+
+	if cp.options.Age > 0 {
+		// check age
+		age := time.Since(sourceEntry.ModifyTime)
+		maxAge := cp.options.Age
+		if age > maxAge {
+			// skip
+			reportSimple(nil, "age", "skipped")
+			cp.events.notifySkipped(sourceEntry.Path, targetPath, "skip copying a data object %q to %q. The data object is too old (%s > %s)!", sourceEntry.Path, targetPath, age, maxAge)
+			logger.Debugf("skip copying a data object. The data object is too old (%s > %s)!", age, maxAge)
+			return nil
+		}
+	}
+
+	targetEntry, err := cp.filesystem.Stat(targetPath)
+	if err != nil {
+		if irodsclient_types.IsFileNotFoundError(err) {
+			// target does not exist
+			// target must be a file with new name
+			cp.scheduleCopy(sourceEntry, targetPath, nil)
+			return nil
+		}
+
+		reportSimple(err)
+		return errors.Wrapf(err, "failed to stat %q", targetPath)
+	}
+
+	// target exists
+	// target must be a file
+	if targetEntry.IsDir() {
+		if cp.options.Sync {
+			// if it is sync, remove
+			overwrite := cp.options.confirm(fmt.Sprintf("Overwriting a data object %q, but collection exists. Overwrite?", targetPath))
+
+			if overwrite {
+				startTime := time.Now()
+				removeErr := cp.filesystem.RemoveDir(targetPath, true, true)
+				endTime := time.Now()
+
+				reportOverwrite(startTime, endTime, removeErr, "directory")
+
+				if removeErr != nil {
+					return removeErr
+				}
+
+				// fallthrough to copy
+			} else {
+				overwriteErr := types.NewNotFileError(targetPath)
+				now := time.Now()
+
+				reportOverwrite(now, now, overwriteErr, "directory", "declined")
+				return overwriteErr
+			}
+		} else {
+			notFileErr := types.NewNotFileError(targetPath)
+			now := time.Now()
+
+			reportOverwrite(now, now, notFileErr, "directory")
+			return notFileErr
+		}
+	}
+
+	if cp.options.Differential {
+		if cp.options.NoHash {
+			if targetEntry.Size == sourceEntry.Size {
+				// skip
+				now := time.Now()
+				reportFile := &transfer.TransferReportFile{
+					Method:                  transfer.TransferMethodCopy,
+					StartAt:                 now,
+					EndAt:                   now,
+					SourcePath:              sourceEntry.Path,
+					SourceSize:              sourceEntry.Size,
+					SourceChecksumAlgorithm: string(sourceEntry.CheckSumAlgorithm),
+					SourceChecksum:          hex.EncodeToString(sourceEntry.CheckSum),
+					DestPath:                targetPath,
+					DestSize:                targetEntry.Size,
+					DestChecksumAlgorithm:   string(targetEntry.CheckSumAlgorithm),
+					DestChecksum:            hex.EncodeToString(targetEntry.CheckSum),
+
+					Notes: []string{"cp", "file", "differential", "no hash", "same size", "skipped"},
+				}
+
+				cp.transferReportManager.AddFile(reportFile)
+
+				cp.events.notifySkipped(sourceEntry.Path, targetPath, "skip copying a data object %q to %q. The data object already exists!", sourceEntry.Path, targetPath)
+				logger.Debugf("skip copying a data object. The data object already exists!")
+				return nil
+			}
+		} else {
+			if targetEntry.Size == sourceEntry.Size {
+				// compare hash
+				if len(sourceEntry.CheckSum) > 0 && bytes.Equal(sourceEntry.CheckSum, targetEntry.CheckSum) {
+					now := time.Now()
+					reportFile := &transfer.TransferReportFile{
+						Method:                  transfer.TransferMethodCopy,
+						StartAt:                 now,
+						EndAt:                   now,
+						SourcePath:              sourceEntry.Path,
+						SourceSize:              sourceEntry.Size,
+						SourceChecksumAlgorithm: string(sourceEntry.CheckSumAlgorithm),
+						SourceChecksum:          hex.EncodeToString(sourceEntry.CheckSum),
+						DestPath:                targetPath,
+						DestSize:                targetEntry.Size,
+						DestChecksum:            hex.EncodeToString(targetEntry.CheckSum),
+						DestChecksumAlgorithm:   string(targetEntry.CheckSumAlgorithm),
+
+						Notes: []string{"cp", "file", "differential", "same checksum", "skipped"},
+					}
+
+					cp.transferReportManager.AddFile(reportFile)
+
+					cp.events.notifySkipped(sourceEntry.Path, targetPath, "skip copying a data object %q to %q. The data object with the same hash already exists!", sourceEntry.Path, targetPath)
+					logger.Debugf("skip copying a data object %q to %q. The data object with the same hash already exists!", sourceEntry.Path, targetPath)
+					return nil
+				}
+			}
+		}
+	} else {
+		overwrite := cp.options.confirm(fmt.Sprintf("Data object %q already exists. Overwrite?", targetPath))
+
+		if !overwrite {
+			now := time.Now()
+			reportFile := &transfer.TransferReportFile{
+				Method:                  transfer.TransferMethodCopy,
+				StartAt:                 now,
+				EndAt:                   now,
+				SourcePath:              sourceEntry.Path,
+				SourceSize:              sourceEntry.Size,
+				SourceChecksumAlgorithm: string(sourceEntry.CheckSumAlgorithm),
+				SourceChecksum:          hex.EncodeToString(sourceEntry.CheckSum),
+				DestPath:                targetPath,
+				DestSize:                targetEntry.Size,
+				DestChecksum:            hex.EncodeToString(targetEntry.CheckSum),
+				DestChecksumAlgorithm:   string(targetEntry.CheckSumAlgorithm),
+
+				Notes: []string{"cp", "file", "overwrite", "declined", "skipped"},
+			}
+
+			cp.transferReportManager.AddFile(reportFile)
+
+			cp.events.notifySkipped(sourceEntry.Path, targetPath, "skip copying a data object %q to %q. The data object already exists!", sourceEntry.Path, targetPath)
+			logger.Debugf("skip copying a data object %q to %q. The data object already exists!", sourceEntry.Path, targetPath)
+			return nil
+		}
+	}
+
+	// schedule
+	cp.scheduleCopy(sourceEntry, targetPath, targetEntry)
+	return nil
+}
+
+func (cp *CopyTransfer) copyDir(sourceEntry *irodsclient_fs.Entry, targetPath string) error {
+	logger := log.WithFields(log.Fields{
+		"source_path": sourceEntry.Path,
+		"target_path": targetPath,
+	})
+
+	defaultNotes := []string{"cp", "directory"}
+
+	reportSimple := func(err error, additionalNotes ...string) {
+		now := time.Now()
+		newNotes := append(defaultNotes, additionalNotes...)
+
+		reportFile := &transfer.TransferReportFile{
+			Method:     transfer.TransferMethodCopy,
+			StartAt:    now,
+			EndAt:      now,
+			SourcePath: sourceEntry.Path,
+			SourceSize: sourceEntry.Size,
+			DestPath:   targetPath,
+			Error:      err,
+			Notes:      newNotes,
+		}
+
+		cp.transferReportManager.AddFile(reportFile)
+	}
+
+	reportOverwrite := func(startTime time.Time, endTime time.Time, err error, additionalNotes ...string) {
+		newNotes := append(defaultNotes, additionalNotes...)
+		newNotes = append(newNotes, "overwrite")
+
+		reportFile := &transfer.TransferReportFile{
+			Method:   transfer.TransferMethodDelete,
+			StartAt:  startTime,
+			EndAt:    endTime,
+			DestPath: targetPath,
+			Error:    err,
+			Notes:    newNotes,
+		}
+
+		cp.transferReportManager.AddFile(reportFile)
+	}
+
+	cp.mutex.Lock()
+	path.MarkIRODSPathMap(cp.updatedPathMap, targetPath)
+	cp.mutex.Unlock()
+
+	if cp.options.ExcludeHidden {
+		// exclude hidden
+		if strings.HasPrefix(sourceEntry.Name, ".") {
+			// skip
+			reportSimple(nil, "hidden", "skipped")
+			cp.events.notifySkipped(sourceEntry.Path, targetPath, "skip copying a collection %q to %q. The collection is hidden!", sourceEntry.Path, targetPath)
+			logger.Debug("skip copying a collection. The collection is hidden!")
+			return nil
+		}
+	}
+
+	targetEntry, err := cp.filesystem.Stat(targetPath)
+	if err != nil {
+		if irodsclient_types.IsFileNotFoundError(err) {
+			// target does not exist
+			// target must be a directory with new name
+			err = cp.filesystem.MakeDir(targetPath, true)
+			reportSimple(err)
+			if err != nil {
+				return errors.Wrapf(err, "failed to make a collection %q", targetPath)
+			}
+
+			// fallthrough to copy entries
+		} else {
+			reportSimple(err)
+			return errors.Wrapf(err, "failed to stat %q", targetPath)
+		}
+	} else {
+		// target exists
+		if !targetEntry.IsDir() {
+			if cp.options.Sync {
+				// if it is sync, remove
+				overwrite := cp.options.confirm(fmt.Sprintf("Overwriting a collection %q, but data object exists. Overwrite?", targetPath))
+
+				if overwrite {
+					startTime := time.Now()
+					removeErr := cp.filesystem.RemoveFile(targetPath, true)
+					endTime := time.Now()
+
+					reportOverwrite(startTime, endTime, removeErr)
+
+					if removeErr != nil {
+						return removeErr
+					}
+
+					// fallthrough to copy entries
+				} else {
+					overwriteErr := types.NewNotDirError(targetPath)
+					now := time.Now()
+
+					reportOverwrite(now, now, overwriteErr, "declined")
+					cp.events.notifySkipped(sourceEntry.Path, targetPath, "skip copying a collection %q to %q. The data object already exists!", sourceEntry.Path, targetPath)
+					logger.Debug("skip copying a collection. The data object already exists!")
+					return nil
+				}
+			} else {
+				notDirErr := types.NewNotDirError(targetPath)
+				now := time.Now()
+
+				reportOverwrite(now, now, notDirErr)
+				return notDirErr
+			}
+		}
+	}
+
+	err = cp.preserveAttributes(sourceEntry, targetPath)
+	if err != nil {
+		reportSimple(err, "preserve")
+		return errors.Wrapf(err, "failed to preserve attributes of %q to %q", sourceEntry.Path, targetPath)
+	}
+
+	// copy entries
+	entries, err := cp.filesystem.List(sourceEntry.Path)
+	if err != nil {
+		reportSimple(err)
+		return errors.Wrapf(err, "failed to list a directory %q", sourceEntry.Path)
+	}
+
+	for _, entry := range entries {
+		newEntryPath := path.MakeIRODSTargetFilePath(cp.filesystem, entry.Path, targetPath)
+
+		if entry.IsDir() {
+			// dir
+			err = cp.copyDir(entry, newEntryPath)
+			if err != nil {
+				return err
+			}
+		} else {
+			// file
+			err = cp.copyFile(entry, newEntryPath)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// preserveAttributes copies metadata, ACLs, and modification time of the source to the target as requested
+func (cp *CopyTransfer) preserveAttributes(sourceEntry *irodsclient_fs.Entry, targetPath string) error {
+	logger := log.WithFields(log.Fields{
+		"source_path": sourceEntry.Path,
+		"target_path": targetPath,
+	})
+
+	if cp.options.PreserveMetadata {
+		logger.Debug("preserve metadata")
+
+		err := irods.CopyMetadata(cp.filesystem, sourceEntry.Path, targetPath)
+		if err != nil {
+			return err
+		}
+	}
+
+	if cp.options.PreserveACL {
+		logger.Debug("preserve ACLs")
+
+		protectedUser := cp.account.ClientUser + "#" + cp.account.ClientZone
+		err := irods.CopyACLs(cp.filesystem, sourceEntry.Path, targetPath, sourceEntry.IsDir(), protectedUser)
+		if err != nil {
+			return err
+		}
+	}
+
+	// modification time of collections is managed by the server
+	if cp.options.PreserveModifyTime && !sourceEntry.IsDir() {
+		logger.Debug("preserve modification time")
+
+		err := irods.CopyModifyTime(cp.filesystem, sourceEntry.Path, targetPath)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (cp *CopyTransfer) deleteExtraFile(targetEntry *irodsclient_fs.Entry) error {
+	logger := log.WithFields(log.Fields{
+		"target_path": targetEntry.Path,
+	})
+
+	defaultNotes := []string{"cp", "extra", "file"}
+
+	reportSimple := func(err error, additionalNotes ...string) {
+		now := time.Now()
+		newNotes := append(defaultNotes, additionalNotes...)
+
+		reportFile := &transfer.TransferReportFile{
+			Method:   transfer.TransferMethodDelete,
+			StartAt:  now,
+			EndAt:    now,
+			DestPath: targetEntry.Path,
+			Error:    err,
+			Notes:    newNotes,
+		}
+
+		cp.transferReportManager.AddFile(reportFile)
+	}
+
+	cp.mutex.RLock()
+	isExtra := false
+	if _, ok := cp.updatedPathMap[targetEntry.Path]; !ok {
+		isExtra = true
+	}
+	cp.mutex.RUnlock()
+
+	if isExtra {
+		// extra file
+		logger.Debug("removing an extra data object")
+
+		overwrite := cp.options.confirm(fmt.Sprintf("Removing an extra data object %q. Remove?", targetEntry.Path))
+
+		if overwrite {
+			cp.scheduleDeleteExtraFile(targetEntry)
+			return nil
+		} else {
+			// do not remove
+			reportSimple(nil, "declined")
+			return nil
+		}
+	}
+
+	return nil
+}
+
+func (cp *CopyTransfer) deleteExtraDir(targetEntry *irodsclient_fs.Entry) error {
+	logger := log.WithFields(log.Fields{
+		"target_path": targetEntry.Path,
+	})
+
+	defaultNotes := []string{"cp", "extra", "directory"}
+
+	reportSimple := func(err error, additionalNotes ...string) {
+		now := time.Now()
+		newNotes := append(defaultNotes, additionalNotes...)
+
+		reportFile := &transfer.TransferReportFile{
+			Method:   transfer.TransferMethodDelete,
+			StartAt:  now,
+			EndAt:    now,
+			DestPath: targetEntry.Path,
+			Error:    err,
+			Notes:    newNotes,
+		}
+
+		cp.transferReportManager.AddFile(reportFile)
+	}
+
+	// delete the directory itself
+	cp.mutex.RLock()
+	isExtra := false
+	if _, ok := cp.updatedPathMap[targetEntry.Path]; !ok {
+		isExtra = true
+	}
+	cp.mutex.RUnlock()
+
+	if isExtra {
+		// extra dir
+		logger.Debug("removing an extra collection")
+
+		overwrite := cp.options.confirm(fmt.Sprintf("Removing an extra directory %q. Remove?", targetEntry.Path))
+
+		if overwrite {
+			cp.scheduleDeleteExtraDir(targetEntry)
+			return nil
+		} else {
+			// do not remove
+			reportSimple(nil, "declined")
+			return nil
+		}
+	}
+
+	// scan recursively
+	entries, err := cp.filesystem.List(targetEntry.Path)
+	if err != nil {
+		reportSimple(err)
+		return errors.Wrapf(err, "failed to list a collection %q", targetEntry.Path)
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			// dir
+			err = cp.deleteExtraDir(entry)
+			if err != nil {
+				return err
+			}
+		} else {
+			// file
+			err = cp.deleteExtraFile(entry)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
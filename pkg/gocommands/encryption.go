@@ -0,0 +1,87 @@
+package gocommands
+
+import (
+	"os"
+
+	"github.com/cyverse/gocommands/commons/encryption"
+)
+
+// EncryptionOptions has options of encryption of uploaded files
+type EncryptionOptions struct {
+	// Encryption encrypts files with EncryptionMode, files are also encrypted if the target collection is configured for encryption
+	Encryption bool
+	// NoEncryption never encrypts files
+	NoEncryption bool
+	// EncryptionIgnoreMeta ignores encryption configured in metadata of target collections
+	EncryptionIgnoreMeta bool
+	// EncryptionMode is the encryption mode, 'ssh' if empty
+	EncryptionMode encryption.EncryptionMode
+	// EncryptionKey is the key for 'winscp', 'aesgcm', and 'pgp' mode, the password of the account if empty
+	EncryptionKey string
+	// EncryptionPublicKeyPaths are public keys or key ring files for 'ssh' mode, keys in metadata of target collections or the default key if empty
+	EncryptionPublicKeyPaths []string
+	// EncryptionRecipients are age recipients or recipient files for 'age' mode, the default recipient file if empty
+	EncryptionRecipients []string
+	// EncryptionTempPath is the directory for encrypted files, the system temp directory if empty
+	EncryptionTempPath string
+}
+
+// DecryptionOptions has options of decryption of downloaded files
+type DecryptionOptions struct {
+	// NoDecryption never decrypts files, encrypted files are decrypted otherwise
+	NoDecryption bool
+	// DecryptionKey is the key for 'winscp', 'aesgcm', and 'pgp' mode, the password of the account if empty
+	DecryptionKey string
+	// DecryptionPrivateKeyPath is the private key for 'ssh' mode, the default key if empty
+	DecryptionPrivateKeyPath string
+	// DecryptionIdentityPath is the identity file for 'age' mode, the default identity if empty
+	DecryptionIdentityPath string
+	// DecryptionTempPath is the directory for encrypted files, the system temp directory if empty
+	DecryptionTempPath string
+}
+
+// setDefaults sets default values to options not given
+func (options *EncryptionOptions) setDefaults() {
+	if options.NoEncryption {
+		options.Encryption = false
+	}
+
+	if len(options.EncryptionMode) == 0 {
+		options.EncryptionMode = encryption.EncryptionModeSSH
+	}
+
+	if len(options.EncryptionRecipients) == 0 {
+		recipientPath := encryption.GetDefaultAgeRecipientPath()
+		if len(recipientPath) > 0 {
+			options.EncryptionRecipients = []string{recipientPath}
+		}
+	}
+
+	if len(options.EncryptionTempPath) == 0 {
+		options.EncryptionTempPath = os.TempDir()
+	}
+}
+
+// getPublicKeyPaths returns public keys given, the default key if not given
+func (options *EncryptionOptions) getPublicKeyPaths() []string {
+	if len(options.EncryptionPublicKeyPaths) == 0 {
+		return []string{encryption.GetDefaultPublicKeyPath()}
+	}
+
+	return options.EncryptionPublicKeyPaths
+}
+
+// setDefaults sets default values to options not given
+func (options *DecryptionOptions) setDefaults() {
+	if len(options.DecryptionPrivateKeyPath) == 0 {
+		options.DecryptionPrivateKeyPath = encryption.GetDefaultPrivateKeyPath()
+	}
+
+	if len(options.DecryptionIdentityPath) == 0 {
+		options.DecryptionIdentityPath = encryption.GetDefaultAgeIdentityPath()
+	}
+
+	if len(options.DecryptionTempPath) == 0 {
+		options.DecryptionTempPath = os.TempDir()
+	}
+}
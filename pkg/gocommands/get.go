@@ -0,0 +1,1619 @@
+package gocommands
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/avast/retry-go"
+	"github.com/cockroachdb/errors"
+	irodsclient_fs "github.com/cyverse/go-irodsclient/fs"
+	irodsclient_irodsfs "github.com/cyverse/go-irodsclient/irods/fs"
+	irodsclient_types "github.com/cyverse/go-irodsclient/irods/types"
+	irodsclient_util "github.com/cyverse/go-irodsclient/irods/util"
+	"github.com/cyverse/gocommands/commons/config"
+	"github.com/cyverse/gocommands/commons/encryption"
+	"github.com/cyverse/gocommands/commons/irods"
+	"github.com/cyverse/gocommands/commons/parallel"
+	commons_path "github.com/cyverse/gocommands/commons/path"
+	"github.com/cyverse/gocommands/commons/tracing"
+	"github.com/cyverse/gocommands/commons/transfer"
+	"github.com/cyverse/gocommands/commons/types"
+	"github.com/cyverse/gocommands/commons/webdav"
+	"github.com/cyverse/gocommands/commons/wildcard"
+	"github.com/jedib0t/go-pretty/v6/progress"
+	log "github.com/sirupsen/logrus"
+)
+
+// GetOptions has options of Get
+type GetOptions struct {
+	TransferOptions
+	DecryptionOptions
+
+	// SourcePaths are iRODS data objects or collections to download
+	SourcePaths []string
+	// TargetPath is the local file or directory to download to, the current directory if empty
+	TargetPath string
+
+	// WildcardSearch expands wildcards in SourcePaths
+	WildcardSearch bool
+}
+
+// Get downloads iRODS data objects or collections to local
+func Get(ctx context.Context, options GetOptions) (*TransferResult, error) {
+	get, err := NewGetTransfer(options)
+	if err != nil {
+		return nil, err
+	}
+
+	err = get.Run(ctx)
+	return get.GetResult(), err
+}
+
+// GetTransfer downloads iRODS data objects or collections to local
+type GetTransfer struct {
+	options GetOptions
+
+	account      *irodsclient_types.IRODSAccount
+	filesystem   *irodsclient_fs.FileSystem
+	webdavClient *webdav.WebDAVClient
+
+	// working and home collections relative iRODS paths are resolved against
+	cwd  string
+	home string
+
+	sourcePaths []string
+	targetPath  string
+
+	parallelTransferJobManager    *parallel.ParallelJobManager
+	parallelPostProcessJobManager *parallel.ParallelJobManager
+
+	transferReportManager *transfer.TransferReportManager
+	updatedPathMap        map[string]bool
+	mutex                 sync.RWMutex // mutex for updatedPathMap
+
+	inFlightTransfers *transfer.InFlightTransfers
+	removedPaths      []string // incomplete files removed after the run is aborted
+
+	// context of the run, carrying the span of the operation
+	ctx context.Context
+
+	// canceled by CancelPending
+	pendingCtx    context.Context
+	cancelPending context.CancelFunc
+
+	// canceled by Abort
+	run runContext
+
+	events *eventNotifier
+
+	totalDownloadedFiles int
+	totalDownloadedBytes int64
+	startTime            time.Time
+}
+
+// NewGetTransfer creates a new GetTransfer, options are validated
+func NewGetTransfer(options GetOptions) (*GetTransfer, error) {
+	err := options.validate()
+	if err != nil {
+		return nil, err
+	}
+
+	options.setDefaults()
+
+	get := &GetTransfer{
+		options: options,
+
+		account:     options.Account,
+		sourcePaths: options.SourcePaths,
+		targetPath:  options.TargetPath,
+
+		updatedPathMap:       map[string]bool{},
+		inFlightTransfers:    transfer.NewInFlightTransfers(),
+		totalDownloadedFiles: 0,
+		totalDownloadedBytes: 0,
+		startTime:            time.Now(),
+	}
+
+	get.cwd, get.home = options.getWorkingDirs()
+	get.pendingCtx, get.cancelPending = context.WithCancel(context.Background())
+	get.events = newEventNotifier(options.OnEvent)
+
+	return get, nil
+}
+
+func (options *GetOptions) validate() error {
+	err := options.TransferOptions.validate()
+	if err != nil {
+		return err
+	}
+
+	if len(options.SourcePaths) == 0 {
+		return errors.New("source path is not given")
+	}
+
+	if options.NoRoot && len(options.SourcePaths) > 1 {
+		return errors.New("failed to get multiple source collections without creating root directory")
+	}
+
+	return nil
+}
+
+func (options *GetOptions) setDefaults() {
+	options.TransferOptions.setDefaults()
+	options.DecryptionOptions.setDefaults()
+
+	if len(options.TargetPath) == 0 {
+		options.TargetPath = "./"
+	}
+}
+
+// CancelPending cancels pending downloads, downloads running are completed
+func (get *GetTransfer) CancelPending() {
+	get.cancelPending()
+}
+
+// GetResult returns statistics of the download
+func (get *GetTransfer) GetResult() *TransferResult {
+	notDownloaded := int64(0)
+	if get.parallelTransferJobManager != nil {
+		counts := get.parallelTransferJobManager.GetJobCounts()
+		notDownloaded = counts.Total - int64(get.totalDownloadedFiles)
+	}
+
+	return &TransferResult{
+		Files:          get.totalDownloadedFiles,
+		Bytes:          get.totalDownloadedBytes,
+		NotTransferred: notDownloaded,
+		RemovedPaths:   get.removedPaths,
+		Duration:       time.Since(get.startTime),
+	}
+}
+
+// Abort cancels Run, waits for running downloads to return, and removes incomplete files
+// It is for callers exiting before Run returns, the result has the files removed
+func (get *GetTransfer) Abort() *TransferResult {
+	logger := log.WithFields(log.Fields{})
+
+	// stop running jobs, and wait for them to return before removing files they write
+	get.run.abort()
+	if !parallel.WaitJobManagers(AbortWaitTimeout, get.parallelTransferJobManager, get.parallelPostProcessJobManager) {
+		logger.Warnf("running jobs did not return in %s, removing incomplete files", AbortWaitTimeout)
+	}
+
+	removedPaths, err := get.inFlightTransfers.CleanUp()
+	if err != nil {
+		logger.WithError(err).Warn("failed to remove incomplete files")
+	}
+
+	if get.transferReportManager != nil {
+		get.transferReportManager.Release()
+	}
+
+	result := get.GetResult()
+	result.RemovedPaths = removedPaths
+	return result
+}
+
+// Run downloads files
+// when the ctx is done, pending downloads are canceled and running WebDAV downloads are aborted, incomplete files are removed
+func (get *GetTransfer) Run(ctx context.Context) (err error) {
+	logger := log.WithFields(log.Fields{})
+
+	ctx, cancel := get.run.start(ctx)
+	defer cancel()
+
+	ctx, span := tracing.StartSpan(ctx, "get",
+		tracing.String("gocmd.source_paths", strings.Join(get.sourcePaths, ",")),
+		tracing.String("gocmd.target_path", get.targetPath),
+		tracing.String("irods.host", get.account.Host),
+		tracing.String("irods.zone", get.account.ClientZone),
+		tracing.String("irods.resource", get.account.DefaultResource),
+	)
+	defer func() {
+		span.End(err)
+	}()
+
+	get.ctx = ctx
+
+	// Create a file system
+	get.filesystem, err = irods.GetIRODSFSClientForLargeFileIO(get.account, get.options.ThreadNum, get.options.TCPBufferSize, true, get.options.getTimeoutSeconds())
+	if err != nil {
+		return errors.Wrap(err, "failed to get iRODS FS Client")
+	}
+	defer get.filesystem.Release()
+
+	if get.options.WebDAV && len(get.options.WebDAVBaseURL) > 0 {
+		newWebDAVClient := webdav.NewWebDAVClient
+		if get.options.isAnonymous() {
+			// anonymous user may not be able to access the WebDAV root, access paths with the ticket directly
+			newWebDAVClient = webdav.NewWebDAVClientForTicket
+		}
+
+		webdavClient, err := newWebDAVClient(get.filesystem, get.options.WebDAVBaseURL, get.account.ProxyUser, get.account.Password)
+		if err != nil {
+			return errors.Wrap(err, "failed to create WebDAV client")
+		}
+
+		get.webdavClient = webdavClient
+	}
+
+	// transfer report
+	get.transferReportManager, err = get.options.newTransferReportManager()
+	if err != nil {
+		return errors.Wrap(err, "failed to create transfer report manager")
+	}
+	defer get.transferReportManager.Release()
+
+	// set default key for decryption
+	if len(get.options.DecryptionKey) == 0 {
+		get.options.DecryptionKey = get.account.Password
+	}
+
+	// parallel job manager
+	ioSession := get.filesystem.GetIOSession()
+	get.parallelTransferJobManager, get.parallelPostProcessJobManager = get.options.newParallelJobManagers(ioSession.GetMaxConnections(), get.events.getProgressEventWriter())
+
+	// cancel pending jobs when CancelPending is called
+	stopCancel := context.AfterFunc(get.pendingCtx, func() {
+		get.parallelTransferJobManager.CancelJobs()
+		get.parallelPostProcessJobManager.CancelJobs()
+	})
+	defer stopCancel()
+
+	// Expand wildcards
+	if get.options.WildcardSearch {
+		get.sourcePaths, err = wildcard.ExpandWildcards(ctx, get.filesystem, get.account, get.sourcePaths, true, true)
+		if err != nil {
+			return errors.Wrap(err, "failed to expand wildcards")
+		}
+	}
+
+	// run
+	if len(get.sourcePaths) >= 2 {
+		// multi-source, target must be a dir
+		err = get.ensureTargetIsDir(get.targetPath)
+		if err != nil {
+			return errors.Wrapf(err, "target path %q is not a directory", get.targetPath)
+		}
+	}
+
+	for _, sourcePath := range get.sourcePaths {
+		err = get.getOne(sourcePath, get.targetPath)
+		if err != nil {
+			return errors.Wrapf(err, "failed to get %q to %q", sourcePath, get.targetPath)
+		}
+	}
+
+	// delete sources on success
+	if get.options.DeleteOnSuccess {
+		for _, sourcePath := range get.sourcePaths {
+			logger.Infof("deleting source data objects and collections under %q after download", sourcePath)
+
+			err = get.deleteOnSuccessOne(sourcePath)
+			if err != nil {
+				return errors.Wrapf(err, "failed to delete %q after download", sourcePath)
+			}
+		}
+	}
+
+	// delete extra
+	if get.options.Delete {
+		logger.Infof("deleting extra files and directories under %q", get.targetPath)
+
+		err := get.deleteExtraOne(get.targetPath)
+		if err != nil {
+			return errors.Wrap(err, "failed to delete extra files or directories")
+		}
+	}
+
+	logger.Info("done scheduling jobs, starting jobs")
+
+	transferErr := get.parallelTransferJobManager.StartWithContext(ctx)
+	if transferErr != nil {
+		// error occurred while transferring files
+		get.parallelPostProcessJobManager.CancelJobs()
+	}
+
+	postProcessErr := get.parallelPostProcessJobManager.StartWithContext(ctx)
+
+	if ctx.Err() != nil {
+		// remove incomplete files of aborted downloads
+		removedPaths, cleanUpErr := get.inFlightTransfers.CleanUp()
+		if cleanUpErr != nil {
+			logger.WithError(cleanUpErr).Warn("failed to remove incomplete files")
+		}
+		get.removedPaths = removedPaths
+
+		return errors.Wrap(context.Cause(ctx), "download canceled")
+	}
+
+	if transferErr != nil {
+		return errors.Wrap(transferErr, "failed to perform transfer jobs")
+	}
+
+	if postProcessErr != nil {
+		return errors.Wrap(postProcessErr, "failed to perform post process jobs")
+	}
+
+	return nil
+}
+
+func (get *GetTransfer) ensureTargetIsDir(targetPath string) error {
+	targetPath = commons_path.MakeLocalPath(targetPath)
+
+	targetStat, err := os.Stat(targetPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			// not exist
+			return types.NewNotDirError(targetPath)
+		}
+
+		return errors.Wrapf(err, "failed to stat %q", targetPath)
+	}
+
+	if !targetStat.IsDir() {
+		return types.NewNotDirError(targetPath)
+	}
+
+	return nil
+}
+
+func (get *GetTransfer) requireDecryption(sourcePath string) bool {
+	if get.options.NoDecryption {
+		return false
+	}
+
+	mode := encryption.DetectEncryptionMode(sourcePath)
+	return mode != encryption.EncryptionModeNone
+}
+
+func (get *GetTransfer) hasTransferStatusFile(targetPath string) bool {
+	// check transfer status file
+	trxStatusFilePath := irodsclient_irodsfs.GetDataObjectTransferStatusFilePath(targetPath)
+	_, err := os.Stat(trxStatusFilePath)
+	return err == nil
+}
+
+func (get *GetTransfer) getOne(sourcePath string, targetPath string) error {
+	cwd := get.cwd
+	home := get.home
+	zone := get.account.ClientZone
+	sourcePath = commons_path.MakeIRODSPath(cwd, home, zone, sourcePath)
+	targetPath = commons_path.MakeLocalPath(targetPath)
+
+	sourceEntry, err := get.filesystem.Stat(sourcePath)
+	if err != nil {
+		return errors.Wrapf(err, "failed to stat %q", sourcePath)
+	}
+
+	if sourceEntry.IsDir() {
+		// dir
+		if !get.options.NoRoot {
+			targetPath = commons_path.MakeLocalTargetFilePath(sourcePath, targetPath)
+		}
+
+		return get.getDir(sourceEntry, targetPath)
+	}
+
+	// file
+	if get.requireDecryption(sourceEntry.Path) {
+		// decrypt filename
+		tempPath, newTargetPath, err := get.getPathsForDecryption(sourceEntry.Path, targetPath)
+		if err != nil {
+			return errors.Wrapf(err, "failed to get decryption path for %q", sourceEntry.Path)
+		}
+
+		return get.getFile(sourceEntry, tempPath, newTargetPath)
+	}
+
+	targetPath = commons_path.MakeLocalTargetFilePath(sourcePath, targetPath)
+	return get.getFile(sourceEntry, "", targetPath)
+}
+
+func (get *GetTransfer) deleteOnSuccessOne(sourcePath string) error {
+	cwd := get.cwd
+	home := get.home
+	zone := get.account.ClientZone
+	sourcePath = commons_path.MakeIRODSPath(cwd, home, zone, sourcePath)
+
+	sourceEntry, err := get.filesystem.Stat(sourcePath)
+	if err != nil {
+		return errors.Wrapf(err, "failed to stat %q", sourcePath)
+	}
+
+	if sourceEntry.IsDir() {
+		// dir
+		return get.deleteDirOnSuccess(sourcePath)
+	}
+
+	// file
+	return get.deleteFileOnSuccess(sourcePath)
+}
+
+func (get *GetTransfer) deleteExtraOne(targetPath string) error {
+	targetPath = commons_path.MakeLocalPath(targetPath)
+
+	targetStat, err := os.Stat(targetPath)
+	if err != nil {
+		return errors.Wrapf(err, "failed to stat %q", targetPath)
+	}
+
+	if targetStat.IsDir() {
+		// dir
+		return get.deleteExtraDir(targetPath)
+	}
+
+	// file
+	return get.deleteExtraFile(targetPath)
+}
+
+func (get *GetTransfer) scheduleGet(sourceEntry *irodsclient_fs.Entry, tempPath string, targetPath string) {
+	logger := log.WithFields(log.Fields{
+		"source_path": sourceEntry.Path,
+		"temp_path":   tempPath,
+		"target_path": targetPath,
+	})
+
+	defaultNotes := []string{"get"}
+
+	reportSimple := func(err error, additionalNotes ...string) {
+		now := time.Now()
+		newNotes := append(defaultNotes, additionalNotes...)
+		newNotes = append(newNotes, "file")
+
+		reportFile := &transfer.TransferReportFile{
+			Method:     transfer.TransferMethodGet,
+			StartAt:    now,
+			EndAt:      now,
+			SourcePath: sourceEntry.Path,
+			SourceSize: sourceEntry.Size,
+			DestPath:   targetPath,
+			Error:      err,
+			Notes:      newNotes,
+		}
+
+		get.transferReportManager.AddFile(reportFile)
+	}
+
+	reportTransfer := func(result *irodsclient_fs.FileTransferResult, err error, additionalNotes ...string) {
+		newNotes := append(defaultNotes, additionalNotes...)
+
+		get.transferReportManager.AddTransfer(result, transfer.TransferMethodGet, err, newNotes)
+	}
+
+	transferMode, threadsRequired := get.determineTransferMethod(sourceEntry.Size)
+
+	getTask := func(job *parallel.ParallelJob) error {
+		logger := logger.WithFields(job.GetLogFields())
+
+		if job.IsCanceled() {
+			// job is canceled, do not run
+			job.Progress("download", -1, sourceEntry.Size, true)
+
+			reportSimple(nil, "canceled")
+			logger.Debug("canceled a task for downloading a data object")
+			return nil
+		}
+
+		logger.Debug("downloading a data object")
+
+		progressCallbackGet := func(taskType string, processed int64, total int64) {
+			job.Progress(taskType, processed, total, false)
+		}
+
+		job.Progress("download", 0, sourceEntry.Size, false)
+
+		downloadPath := targetPath
+		if len(tempPath) > 0 {
+			downloadPath = tempPath
+		}
+
+		parentDownloadPath := filepath.Dir(downloadPath)
+		_, statErr := os.Stat(parentDownloadPath)
+		if statErr != nil {
+			// must exist, mkdir is performed at getDir
+			job.Progress("download", -1, sourceEntry.Size, true)
+
+			reportSimple(statErr)
+			return errors.Wrapf(statErr, "failed to stat %q", parentDownloadPath)
+		}
+
+		notes := []string{string(transferMode), fmt.Sprintf("%d threads", threadsRequired)}
+		if get.requireDecryption(sourceEntry.Path) {
+			notes = append(notes, "decrypt")
+		}
+
+		var downloadErr error
+		var downloadResult *irodsclient_fs.FileTransferResult
+
+		retryNum := get.options.RetryNum
+		retryInterval := get.options.RetryInterval
+
+		// incomplete file and its transfer status file are removed if aborted
+		get.inFlightTransfers.Start(downloadPath, func() error {
+			os.Remove(irodsclient_irodsfs.GetDataObjectTransferStatusFilePath(downloadPath))
+			return os.Remove(downloadPath)
+		})
+
+		_, transferSpan := tracing.StartSpan(job.GetContext(), "download",
+			tracing.String("gocmd.source_path", sourceEntry.Path),
+			tracing.String("gocmd.target_path", targetPath),
+			tracing.Int64("gocmd.size", sourceEntry.Size),
+			tracing.String("gocmd.transfer_mode", string(transferMode)),
+			tracing.Int64("gocmd.threads", int64(threadsRequired)),
+		)
+
+		attempt := 0
+		retryErr := retry.Do(func() error {
+			attempt++
+			if attempt > 1 {
+				logger.Debugf("retrying download attempt %d/%d for %q", attempt, retryNum+1, sourceEntry.Path)
+				get.options.Metrics.AddRetry(string(transfer.TransferMethodGet))
+			}
+
+			switch transferMode {
+			case transfer.TransferModeWebDAV:
+				downloadResult, downloadErr = get.webdavClient.DownloadFile(job.GetContext(), sourceEntry, downloadPath, get.account.Ticket, get.options.VerifyChecksum, progressCallbackGet)
+				notes = append(notes, "webdav")
+			case transfer.TransferModeICAT:
+				fallthrough
+			default:
+				downloadResult, downloadErr = get.filesystem.DownloadFileParallelResumable(sourceEntry.Path, "", downloadPath, threadsRequired, get.options.VerifyChecksum, progressCallbackGet)
+				notes = append(notes, "icat", fmt.Sprintf("%d threads", threadsRequired))
+			}
+			return downloadErr
+		}, retry.Attempts(uint(retryNum+1)), retry.Delay(retryInterval), retry.LastErrorOnly(true), retry.Context(job.GetContext()))
+
+		transferSpan.SetAttributes(tracing.Int64("gocmd.attempts", int64(attempt)))
+		transferSpan.End(retryErr)
+
+		if retryErr == nil || job.GetContext().Err() == nil {
+			// keep the incomplete file to be removed if aborted
+			get.inFlightTransfers.Finish(downloadPath)
+		}
+
+		if retryErr != nil {
+			job.Progress("download", -1, sourceEntry.Size, true)
+			job.Progress("checksum", -1, sourceEntry.Size, true)
+
+			reportTransfer(downloadResult, retryErr, notes...)
+			return errors.Wrapf(retryErr, "failed to download %q to %q after %d attempts", sourceEntry.Path, targetPath, retryNum+1)
+		}
+
+		get.totalDownloadedFiles++
+		get.totalDownloadedBytes += sourceEntry.Size
+		get.options.Metrics.AddTransfer(string(transfer.TransferMethodGet), string(transferMode), 1, sourceEntry.Size)
+
+		// decrypt
+		if get.requireDecryption(sourceEntry.Path) {
+			job.Progress("decrypt", 0, sourceEntry.Size, false)
+
+			get.inFlightTransfers.Start(targetPath, func() error {
+				return os.Remove(targetPath)
+			})
+
+			_, decryptErr := get.decryptFile(sourceEntry.Path, tempPath, targetPath)
+			get.inFlightTransfers.Finish(targetPath)
+			if decryptErr != nil {
+				job.Progress("decrypt", -1, sourceEntry.Size, true)
+
+				if types.IsIntegrityError(decryptErr) {
+					notes = append(notes, "integrity_error")
+				}
+
+				reportTransfer(downloadResult, decryptErr, notes...)
+				return errors.Wrap(decryptErr, "failed to decrypt file")
+			}
+
+			job.Progress("decrypt", sourceEntry.Size, sourceEntry.Size, false)
+		}
+
+		if get.options.MetadataSidecar {
+			exportErr := irods.ExportMetadataToSidecar(get.filesystem, sourceEntry.Path, targetPath)
+			if exportErr != nil {
+				reportTransfer(downloadResult, exportErr, append(notes, "meta_sidecar")...)
+				return errors.Wrapf(exportErr, "failed to export metadata of %q", sourceEntry.Path)
+			}
+		}
+
+		reportTransfer(downloadResult, nil, notes...)
+
+		logger.Debugf("downloaded a data object %q to %q", sourceEntry.Path, targetPath)
+
+		return nil
+	}
+
+	get.parallelTransferJobManager.ScheduleWithOptions(sourceEntry.Path, getTask, threadsRequired, progress.UnitsBytes, parallel.ParallelJobOptions{
+		Size:     sourceEntry.Size,
+		Priority: get.options.getPriority(sourceEntry.Path),
+	})
+	logger.Debugf("scheduled a data object download, %d threads", threadsRequired)
+}
+
+func (get *GetTransfer) scheduleDeleteFileOnSuccess(sourcePath string) {
+	logger := log.WithFields(log.Fields{
+		"source_path": sourcePath,
+	})
+
+	defaultNotes := []string{"get", "delete on success", "file"}
+
+	report := func(startTime time.Time, endTime time.Time, err error, additionalNotes ...string) {
+		newNotes := append(defaultNotes, additionalNotes...)
+
+		reportFile := &transfer.TransferReportFile{
+			Method:     transfer.TransferMethodDelete,
+			StartAt:    startTime,
+			EndAt:      endTime,
+			SourcePath: sourcePath,
+			Error:      err,
+			Notes:      newNotes,
+		}
+
+		get.transferReportManager.AddFile(reportFile)
+	}
+
+	reportSimple := func(err error, additionalNotes ...string) {
+		now := time.Now()
+		report(now, now, err, additionalNotes...)
+	}
+
+	deleteTask := func(job *parallel.ParallelJob) error {
+		logger := logger.WithFields(job.GetLogFields())
+
+		if job.IsCanceled() {
+			// job is canceled, do not run
+			job.Progress("delete", -1, 1, true)
+
+			reportSimple(nil, "canceled")
+			logger.Debug("canceled a task for deleting a data object")
+			return nil
+		}
+
+		logger.Debug("deleting a data object")
+
+		job.Progress("delete", 0, 1, false)
+
+		startTime := time.Now()
+		removeErr := get.filesystem.RemoveFile(sourcePath, true)
+		endTime := time.Now()
+		report(startTime, endTime, removeErr)
+
+		if removeErr != nil {
+			job.Progress("delete", -1, 1, true)
+			return errors.Wrapf(removeErr, "failed to delete %q", sourcePath)
+		}
+
+		logger.Debug("deleted a data object")
+		job.Progress("delete", 1, 1, false)
+		return nil
+	}
+
+	get.parallelPostProcessJobManager.Schedule("removing - "+sourcePath, deleteTask, 1, progress.UnitsDefault)
+	logger.Debug("scheduled a data object deletion")
+}
+
+func (get *GetTransfer) scheduleDeleteDirOnSuccess(sourcePath string) {
+	logger := log.WithFields(log.Fields{
+		"source_path": sourcePath,
+	})
+
+	defaultNotes := []string{"get", "delete on success", "directory"}
+
+	report := func(startTime time.Time, endTime time.Time, err error, additionalNotes ...string) {
+		newNotes := append(defaultNotes, additionalNotes...)
+
+		reportFile := &transfer.TransferReportFile{
+			Method:     transfer.TransferMethodDelete,
+			StartAt:    startTime,
+			EndAt:      endTime,
+			SourcePath: sourcePath,
+			Error:      err,
+			Notes:      newNotes,
+		}
+
+		get.transferReportManager.AddFile(reportFile)
+	}
+
+	reportSimple := func(err error, additionalNotes ...string) {
+		now := time.Now()
+		report(now, now, err, additionalNotes...)
+	}
+
+	deleteTask := func(job *parallel.ParallelJob) error {
+		logger := logger.WithFields(job.GetLogFields())
+
+		if job.IsCanceled() {
+			// job is canceled, do not run
+			job.Progress("delete", -1, 1, true)
+
+			reportSimple(nil, "canceled")
+			logger.Debug("canceled a task for deleting an empty collection")
+			return nil
+		}
+
+		logger.Debug("deleting an empty collection")
+
+		job.Progress("delete", 0, 1, false)
+
+		startTime := time.Now()
+		removeErr := get.filesystem.RemoveDir(sourcePath, false, false)
+		endTime := time.Now()
+		report(startTime, endTime, removeErr)
+
+		if removeErr != nil {
+			job.Progress("delete", -1, 1, true)
+			return errors.Wrapf(removeErr, "failed to delete %q", sourcePath)
+		}
+
+		logger.Debug("deleted an empty collection")
+		job.Progress("delete", 1, 1, false)
+		return nil
+	}
+
+	get.parallelPostProcessJobManager.Schedule("removing - "+sourcePath, deleteTask, 1, progress.UnitsDefault)
+	logger.Debug("scheduled an empty collection deletion")
+}
+
+func (get *GetTransfer) scheduleDeleteExtraFile(targetPath string) {
+	logger := log.WithFields(log.Fields{
+		"target_path": targetPath,
+	})
+
+	defaultNotes := []string{"get", "extra", "file"}
+
+	report := func(startTime time.Time, endTime time.Time, err error, additionalNotes ...string) {
+		newNotes := append(defaultNotes, additionalNotes...)
+
+		reportFile := &transfer.TransferReportFile{
+			Method:   transfer.TransferMethodDelete,
+			StartAt:  startTime,
+			EndAt:    endTime,
+			DestPath: targetPath,
+			Error:    err,
+			Notes:    newNotes,
+		}
+
+		get.transferReportManager.AddFile(reportFile)
+	}
+
+	reportSimple := func(err error, additionalNotes ...string) {
+		now := time.Now()
+		report(now, now, err, additionalNotes...)
+	}
+
+	deleteTask := func(job *parallel.ParallelJob) error {
+		logger := logger.WithFields(job.GetLogFields())
+
+		if job.IsCanceled() {
+			// job is canceled, do not run
+			job.Progress("delete", -1, 1, true)
+
+			reportSimple(nil, "canceled")
+			logger.Debug("canceled a task for deleting an extra file")
+			return nil
+		}
+
+		logger.Debug("deleting an extra file")
+
+		job.Progress("delete", 0, 1, false)
+
+		removeErr := os.Remove(targetPath)
+		reportSimple(removeErr)
+
+		if removeErr != nil {
+			job.Progress("delete", -1, 1, true)
+			return errors.Wrapf(removeErr, "failed to delete %q", targetPath)
+		}
+
+		logger.Debug("deleted an extra file")
+		job.Progress("delete", 1, 1, false)
+		return nil
+	}
+
+	get.parallelPostProcessJobManager.Schedule(targetPath, deleteTask, 1, progress.UnitsDefault)
+	logger.Debug("scheduled an extra file deletion")
+}
+
+func (get *GetTransfer) scheduleDeleteExtraDir(targetPath string) {
+	logger := log.WithFields(log.Fields{
+		"target_path": targetPath,
+	})
+
+	defaultNotes := []string{"get", "extra", "directory"}
+
+	report := func(startTime time.Time, endTime time.Time, err error, additionalNotes ...string) {
+		newNotes := append(defaultNotes, additionalNotes...)
+
+		reportFile := &transfer.TransferReportFile{
+			Method:   transfer.TransferMethodDelete,
+			StartAt:  startTime,
+			EndAt:    endTime,
+			DestPath: targetPath,
+			Error:    err,
+			Notes:    newNotes,
+		}
+
+		get.transferReportManager.AddFile(reportFile)
+	}
+
+	reportSimple := func(err error, additionalNotes ...string) {
+		now := time.Now()
+		report(now, now, err, additionalNotes...)
+	}
+
+	deleteTask := func(job *parallel.ParallelJob) error {
+		logger := logger.WithFields(job.GetLogFields())
+
+		if job.IsCanceled() {
+			// job is canceled, do not run
+			job.Progress("delete", -1, 1, true)
+
+			reportSimple(nil, "canceled")
+			logger.Debug("canceled a task for deleting an extra directory")
+			return nil
+		}
+
+		logger.Debug("deleting an extra directory")
+
+		job.Progress("delete", 0, 1, false)
+
+		removeErr := os.RemoveAll(targetPath)
+		reportSimple(removeErr)
+
+		if removeErr != nil {
+			job.Progress("delete", -1, 1, true)
+			return errors.Wrapf(removeErr, "failed to delete %q", targetPath)
+		}
+
+		logger.Debug("deleted an extra directory")
+		job.Progress("delete", 1, 1, false)
+		return nil
+	}
+
+	get.parallelPostProcessJobManager.Schedule(targetPath, deleteTask, 1, progress.UnitsDefault)
+	logger.Debug("scheduled an extra directory deletion")
+}
+
+func (get *GetTransfer) getFile(sourceEntry *irodsclient_fs.Entry, tempPath string, targetPath string) error {
+	logger := log.WithFields(log.Fields{
+		"source_path": sourceEntry.Path,
+		"temp_path":   tempPath,
+		"target_path": targetPath,
+	})
+
+	logger.Debug("download a data object")
+
+	defaultNotes := []string{"get"}
+
+	reportSimple := func(err error, additionalNotes ...string) {
+		now := time.Now()
+		newNotes := append(defaultNotes, additionalNotes...)
+		newNotes = append(newNotes, "file")
+
+		reportFile := &transfer.TransferReportFile{
+			Method:     transfer.TransferMethodGet,
+			StartAt:    now,
+			EndAt:      now,
+			SourcePath: sourceEntry.Path,
+			SourceSize: sourceEntry.Size,
+			DestPath:   targetPath,
+			Error:      err,
+			Notes:      newNotes,
+		}
+
+		get.transferReportManager.AddFile(reportFile)
+	}
+
+	reportOverwrite := func(err error, additionalNotes ...string) {
+		now := time.Now()
+		newNotes := append(defaultNotes, additionalNotes...)
+		newNotes = append(newNotes, "overwrite")
+
+		reportFile := &transfer.TransferReportFile{
+			Method:   transfer.TransferMethodDelete,
+			StartAt:  now,
+			EndAt:    now,
+			DestPath: targetPath,
+			Error:    err,
+			Notes:    newNotes,
+		}
+
+		get.transferReportManager.AddFile(reportFile)
+	}
+
+	get.mutex.Lock()
+	commons_path.MarkLocalPathMap(get.updatedPathMap, targetPath)
+	get.mutex.Unlock()
+
+	if get.options.ExcludeHidden {
+		// exclude hidden
+		if strings.HasPrefix(sourceEntry.Name, ".") {
+			// skip
+			reportSimple(nil, "hidden", "skipped")
+			get.events.notifySkipped(sourceEntry.Path, targetPath, "skip downloading a data object %q to %q. The data object is hidden!", sourceEntry.Path, targetPath)
+			logger.Debug("skip downloading a data object. The data object is hidden!")
+			return nil
+		}
+	}
+
+	if get.options.Age > 0 {
+		// exclude old
+		age := time.Since(sourceEntry.ModifyTime)
+		maxAge := get.options.Age
+		if age > maxAge {
+			// skip
+			reportSimple(nil, "age", "skipped")
+			get.events.notifySkipped(sourceEntry.Path, targetPath, "skip downloading a data object %q to %q. The data object is too old (%s > %s)!", sourceEntry.Path, targetPath, age, maxAge)
+			logger.Debugf("skip downloading a data object. The data object is too old (%s > %s)!", age, maxAge)
+			return nil
+		}
+	}
+
+	targetStat, err := os.Stat(targetPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			// target does not exist
+			// target must be a file with new name
+			get.scheduleGet(sourceEntry, tempPath, targetPath)
+			return nil
+		}
+
+		reportSimple(err)
+		return errors.Wrapf(err, "failed to stat %q", targetPath)
+	}
+
+	// target exists
+	// target must be a file
+	if targetStat.IsDir() {
+		if get.options.Sync {
+			// if it is sync, remove
+			overwrite := get.options.confirm(fmt.Sprintf("Overwriting a file %q, but directory exists. Overwrite?", targetPath))
+
+			if overwrite {
+				removeErr := os.RemoveAll(targetPath)
+				reportOverwrite(removeErr, "directory")
+
+				if removeErr != nil {
+					return removeErr
+				}
+
+				// fallthrough to get
+			} else {
+				overwriteErr := types.NewNotFileError(targetPath)
+
+				reportOverwrite(overwriteErr, "directory", "declined", "skipped")
+				get.events.notifySkipped(sourceEntry.Path, targetPath, "skip downloading a data object %q to %q. Directory exists with the same name!", sourceEntry.Path, targetPath)
+				logger.Debug("skip downloading a data object. Directory exists with the same name!")
+				return nil
+			}
+		} else {
+			notFileErr := types.NewNotFileError(targetPath)
+			reportOverwrite(notFileErr, "directory")
+			return notFileErr
+		}
+	}
+
+	// check transfer status file
+	if get.hasTransferStatusFile(targetPath) {
+		// incomplete file - resume downloading
+		get.events.notifyResumed(sourceEntry.Path, targetPath, "resume downloading a data object %q", targetPath)
+		logger.Debug("resume downloading a data object")
+
+		get.scheduleGet(sourceEntry, tempPath, targetPath)
+		return nil
+	}
+
+	if get.options.Differential {
+		if get.options.NoHash {
+			if targetStat.Size() == sourceEntry.Size {
+				// skip
+				now := time.Now()
+				reportFile := &transfer.TransferReportFile{
+					Method:                  transfer.TransferMethodGet,
+					StartAt:                 now,
+					EndAt:                   now,
+					SourcePath:              sourceEntry.Path,
+					SourceSize:              sourceEntry.Size,
+					SourceChecksumAlgorithm: string(sourceEntry.CheckSumAlgorithm),
+					SourceChecksum:          hex.EncodeToString(sourceEntry.CheckSum),
+					DestPath:                targetPath,
+					DestSize:                targetStat.Size(),
+
+					Notes: []string{"get", "file", "differential", "no_hash", "same size", "skipped"},
+				}
+
+				get.transferReportManager.AddFile(reportFile)
+
+				get.events.notifySkipped(sourceEntry.Path, targetPath, "skip downloading a data object %q to %q. The file already exists!", sourceEntry.Path, targetPath)
+				logger.Debug("skip downloading a data object. The file already exists!")
+				return nil
+			}
+		} else {
+			if targetStat.Size() == sourceEntry.Size {
+				// compare hash
+				if len(sourceEntry.CheckSum) > 0 {
+					_, checksumSpan := tracing.StartSpan(get.ctx, "checksum",
+						tracing.String("gocmd.local_path", targetPath),
+						tracing.Int64("gocmd.size", sourceEntry.Size),
+						tracing.String("gocmd.checksum_algorithm", string(sourceEntry.CheckSumAlgorithm)),
+					)
+
+					localChecksum, err := irodsclient_util.HashLocalFile(targetPath, string(sourceEntry.CheckSumAlgorithm), nil)
+					checksumSpan.End(err)
+					if err != nil {
+						reportSimple(err, "differential")
+						return errors.Wrapf(err, "failed to get hash of %q", targetPath)
+					}
+
+					if bytes.Equal(sourceEntry.CheckSum, localChecksum) {
+						// skip
+						now := time.Now()
+						reportFile := &transfer.TransferReportFile{
+							Method:                  transfer.TransferMethodGet,
+							StartAt:                 now,
+							EndAt:                   now,
+							SourcePath:              sourceEntry.Path,
+							SourceSize:              sourceEntry.Size,
+							SourceChecksumAlgorithm: string(sourceEntry.CheckSumAlgorithm),
+							SourceChecksum:          hex.EncodeToString(sourceEntry.CheckSum),
+							DestPath:                targetPath,
+							DestSize:                targetStat.Size(),
+							DestChecksum:            hex.EncodeToString(localChecksum),
+							DestChecksumAlgorithm:   string(sourceEntry.CheckSumAlgorithm),
+
+							Notes: []string{"get", "file", "differential", "same checksum", "skipped"},
+						}
+
+						get.transferReportManager.AddFile(reportFile)
+
+						get.events.notifySkipped(sourceEntry.Path, targetPath, "skip downloading a data object %q to %q. The file with the same hash already exists!", sourceEntry.Path, targetPath)
+						logger.Debug("skip downloading a data object. The file with the same hash already exists!")
+						return nil
+					}
+				}
+			}
+		}
+	} else {
+		overwrite := get.options.confirm(fmt.Sprintf("File %q already exists. Overwrite?", targetPath))
+
+		if !overwrite {
+			// skip
+			now := time.Now()
+			reportFile := &transfer.TransferReportFile{
+				Method:                  transfer.TransferMethodGet,
+				StartAt:                 now,
+				EndAt:                   now,
+				SourcePath:              sourceEntry.Path,
+				SourceSize:              sourceEntry.Size,
+				SourceChecksumAlgorithm: string(sourceEntry.CheckSumAlgorithm),
+				SourceChecksum:          hex.EncodeToString(sourceEntry.CheckSum),
+				DestPath:                targetPath,
+				DestSize:                targetStat.Size(),
+
+				Notes: []string{"get", "file", "overwrite", "decliened", "skipped"},
+			}
+
+			get.transferReportManager.AddFile(reportFile)
+
+			get.events.notifySkipped(sourceEntry.Path, targetPath, "skip downloading a data object %q to %q. The file already exists!", sourceEntry.Path, targetPath)
+			logger.Debug("skip downloading a data object. The file already exists!")
+			return nil
+		}
+	}
+
+	// schedule
+	get.scheduleGet(sourceEntry, tempPath, targetPath)
+	return nil
+}
+
+func (get *GetTransfer) getDir(sourceEntry *irodsclient_fs.Entry, targetPath string) error {
+	logger := log.WithFields(log.Fields{
+		"source_path": sourceEntry.Path,
+		"target_path": targetPath,
+	})
+
+	logger.Debug("download a collection")
+
+	defaultNotes := []string{"get", "directory"}
+
+	reportSimple := func(err error, additionalNotes ...string) {
+		now := time.Now()
+		newNotes := append(defaultNotes, additionalNotes...)
+
+		reportFile := &transfer.TransferReportFile{
+			Method:     transfer.TransferMethodGet,
+			StartAt:    now,
+			EndAt:      now,
+			SourcePath: sourceEntry.Path,
+			SourceSize: sourceEntry.Size,
+			DestPath:   targetPath,
+			Error:      err,
+			Notes:      newNotes,
+		}
+
+		get.transferReportManager.AddFile(reportFile)
+	}
+
+	reportOverwrite := func(err error, additionalNotes ...string) {
+		now := time.Now()
+		newNotes := append(defaultNotes, additionalNotes...)
+		newNotes = append(newNotes, "overwrite")
+
+		reportFile := &transfer.TransferReportFile{
+			Method:   transfer.TransferMethodDelete,
+			StartAt:  now,
+			EndAt:    now,
+			DestPath: targetPath,
+			Error:    err,
+			Notes:    newNotes,
+		}
+
+		get.transferReportManager.AddFile(reportFile)
+	}
+
+	get.mutex.Lock()
+	commons_path.MarkLocalPathMap(get.updatedPathMap, targetPath)
+	get.mutex.Unlock()
+
+	if get.options.ExcludeHidden {
+		// exclude hidden
+		if strings.HasPrefix(sourceEntry.Name, ".") {
+			// skip
+			reportSimple(nil, "hidden", "skipped")
+			get.events.notifySkipped(sourceEntry.Path, targetPath, "skip downloading a collection %q to %q. The collection is hidden!", sourceEntry.Path, targetPath)
+			logger.Debug("skip downloading a collection. The collection is hidden!")
+			return nil
+		}
+	}
+
+	targetStat, err := os.Stat(targetPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			// target does not exist
+			// target must be a directorywith new name
+			err = os.MkdirAll(targetPath, 0766)
+			reportSimple(err)
+			if err != nil {
+				return errors.Wrapf(err, "failed to make a directory %q", targetPath)
+			}
+
+			// fallthrough to get entries
+		} else {
+			reportSimple(err)
+			return errors.Wrapf(err, "failed to stat %q", targetPath)
+		}
+	} else {
+		// target exists
+		if !targetStat.IsDir() {
+			if get.options.Sync {
+				// if it is sync, remove
+				overwrite := get.options.confirm(fmt.Sprintf("Overwriting a directory %q, but file exists. Overwrite?", targetPath))
+
+				if overwrite {
+					removeErr := os.Remove(targetPath)
+					reportOverwrite(removeErr)
+
+					if removeErr != nil {
+						return removeErr
+					}
+
+					// fallthrough to get entries
+				} else {
+					overwriteErr := types.NewNotDirError(targetPath)
+
+					reportOverwrite(overwriteErr, "declined", "skipped")
+					get.events.notifySkipped(sourceEntry.Path, targetPath, "skip downloading a collection %q to %q. File exists with the same name!", sourceEntry.Path, targetPath)
+					logger.Debug("skip downloading a collection. File exists with the same name!")
+					return nil
+				}
+			} else {
+				notDirErr := types.NewNotDirError(targetPath)
+				reportOverwrite(notDirErr)
+				return notDirErr
+			}
+		}
+	}
+
+	if get.options.MetadataSidecar {
+		err = irods.ExportMetadataToSidecar(get.filesystem, sourceEntry.Path, targetPath)
+		if err != nil {
+			reportSimple(err, "meta_sidecar")
+			return errors.Wrapf(err, "failed to export metadata of %q", sourceEntry.Path)
+		}
+	}
+
+	// load encryption config
+	requireDecryption := get.requireDecryption(sourceEntry.Path)
+
+	// get entries
+	entries, err := get.filesystem.List(sourceEntry.Path)
+	if err != nil {
+		reportSimple(err)
+		return errors.Wrapf(err, "failed to list a directory %q", sourceEntry.Path)
+	}
+
+	for _, entry := range entries {
+		newEntryPath := commons_path.MakeLocalTargetFilePath(entry.Path, targetPath)
+
+		if entry.IsDir() {
+			// dir
+			err = get.getDir(entry, newEntryPath)
+			if err != nil {
+				return err
+			}
+		} else {
+			// file
+			if requireDecryption {
+				// decrypt filename
+				tempPath, newTargetPath, err := get.getPathsForDecryption(entry.Path, targetPath)
+				if err != nil {
+					reportSimple(err)
+					return errors.Wrapf(err, "failed to get decryption path for %q", entry.Path)
+				}
+
+				err = get.getFile(entry, tempPath, newTargetPath)
+				if err != nil {
+					return err
+				}
+			} else {
+				err = get.getFile(entry, "", newEntryPath)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func (get *GetTransfer) deleteFileOnSuccess(sourcePath string) error {
+	logger := log.WithFields(log.Fields{
+		"source_path": sourcePath,
+	})
+
+	defaultNotes := []string{"get", "delete on success", "file"}
+
+	reportSimple := func(err error, additionalNotes ...string) {
+		now := time.Now()
+		newNotes := append(defaultNotes, additionalNotes...)
+
+		reportFile := &transfer.TransferReportFile{
+			Method:     transfer.TransferMethodDelete,
+			StartAt:    now,
+			EndAt:      now,
+			SourcePath: sourcePath,
+			Error:      err,
+			Notes:      newNotes,
+		}
+
+		get.transferReportManager.AddFile(reportFile)
+	}
+
+	logger.Debug("removing a data object after download")
+
+	overwrite := get.options.confirm(fmt.Sprintf("Removing a data object %q after download. Remove?", sourcePath))
+
+	if overwrite {
+		get.scheduleDeleteFileOnSuccess(sourcePath)
+		return nil
+	} else {
+		// do not remove
+		reportSimple(nil, "declined", "skipped")
+		return nil
+	}
+}
+
+func (get *GetTransfer) deleteDirOnSuccess(sourcePath string) error {
+	logger := log.WithFields(log.Fields{
+		"source_path": sourcePath,
+	})
+
+	defaultNotes := []string{"get", "delete on success", "directory"}
+
+	reportSimple := func(err error, additionalNotes ...string) {
+		now := time.Now()
+		newNotes := append(defaultNotes, additionalNotes...)
+
+		reportFile := &transfer.TransferReportFile{
+			Method:     transfer.TransferMethodDelete,
+			StartAt:    now,
+			EndAt:      now,
+			SourcePath: sourcePath,
+			Error:      err,
+			Notes:      newNotes,
+		}
+
+		get.transferReportManager.AddFile(reportFile)
+	}
+
+	logger.Debug("removing a collection after download")
+
+	// scan recursively
+	entries, err := get.filesystem.List(sourcePath)
+	if err != nil {
+		reportSimple(err)
+		return errors.Wrapf(err, "failed to list a collection %q", sourcePath)
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			// dir
+			err = get.deleteDirOnSuccess(entry.Path)
+			if err != nil {
+				return err
+			}
+		} else {
+			// file
+			err = get.deleteFileOnSuccess(entry.Path)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// delete the directory itself
+	overwrite := get.options.confirm(fmt.Sprintf("Removing a collection after download %q. Remove?", sourcePath))
+
+	if overwrite {
+		get.scheduleDeleteDirOnSuccess(sourcePath)
+		return nil
+	} else {
+		// do not remove
+		reportSimple(nil, "declined", "skipped")
+		return nil
+	}
+}
+
+func (get *GetTransfer) deleteExtraFile(targetPath string) error {
+	logger := log.WithFields(log.Fields{
+		"target_path": targetPath,
+	})
+
+	defaultNotes := []string{"get", "extra", "file"}
+
+	reportSimple := func(err error, additionalNotes ...string) {
+		now := time.Now()
+		newNotes := append(defaultNotes, additionalNotes...)
+
+		reportFile := &transfer.TransferReportFile{
+			Method:   transfer.TransferMethodDelete,
+			StartAt:  now,
+			EndAt:    now,
+			DestPath: targetPath,
+			Error:    err,
+			Notes:    newNotes,
+		}
+
+		get.transferReportManager.AddFile(reportFile)
+	}
+
+	updatedPath := targetPath
+	if get.options.MetadataSidecar && irods.IsMetadataSidecarPath(targetPath) {
+		// sidecar file is kept as long as its data is
+		updatedPath = strings.TrimSuffix(targetPath, irods.MetadataSidecarSuffix)
+	}
+
+	get.mutex.RLock()
+	isExtra := false
+	if _, ok := get.updatedPathMap[updatedPath]; !ok {
+		isExtra = true
+	}
+	get.mutex.RUnlock()
+
+	if isExtra {
+		// extra file
+		logger.Debug("removing an extra file")
+
+		overwrite := get.options.confirm(fmt.Sprintf("Removing an extra file %q. Remove?", targetPath))
+
+		if overwrite {
+			get.scheduleDeleteExtraFile(targetPath)
+			return nil
+		} else {
+			// do not remove
+			reportSimple(nil, "declined", "skipped")
+			return nil
+		}
+	}
+
+	return nil
+}
+
+func (get *GetTransfer) deleteExtraDir(targetPath string) error {
+	logger := log.WithFields(log.Fields{
+		"target_path": targetPath,
+	})
+
+	defaultNotes := []string{"get", "extra", "directory"}
+
+	reportSimple := func(err error, additionalNotes ...string) {
+		now := time.Now()
+		newNotes := append(defaultNotes, additionalNotes...)
+
+		reportFile := &transfer.TransferReportFile{
+			Method:   transfer.TransferMethodDelete,
+			StartAt:  now,
+			EndAt:    now,
+			DestPath: targetPath,
+			Error:    err,
+			Notes:    newNotes,
+		}
+
+		get.transferReportManager.AddFile(reportFile)
+	}
+
+	// scan recursively
+	entries, err := os.ReadDir(targetPath)
+	if err != nil {
+		reportSimple(err)
+		return errors.Wrapf(err, "failed to list a directory %q", targetPath)
+	}
+
+	for _, entry := range entries {
+		entryPath := filepath.Join(targetPath, entry.Name())
+
+		if entry.IsDir() {
+			// dir
+			err = get.deleteExtraDir(entryPath)
+			if err != nil {
+				return err
+			}
+		} else {
+			// file
+			err = get.deleteExtraFile(entryPath)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// delete the directory itself
+	get.mutex.RLock()
+	isExtra := false
+	if _, ok := get.updatedPathMap[targetPath]; !ok {
+		isExtra = true
+	}
+	get.mutex.RUnlock()
+
+	if isExtra {
+		// extra dir
+		logger.Debug("removing an extra directory")
+
+		overwrite := get.options.confirm(fmt.Sprintf("Removing an extra directory %q. Remove?", targetPath))
+
+		if overwrite {
+			get.scheduleDeleteExtraDir(targetPath)
+			return nil
+		} else {
+			// do not remove
+			reportSimple(nil, "declined", "skipped")
+			return nil
+		}
+	}
+
+	return nil
+}
+
+func (get *GetTransfer) getEncryptionManagerForDecryption(mode encryption.EncryptionMode) *encryption.EncryptionManager {
+	manager := encryption.NewEncryptionManager(mode)
+
+	switch mode {
+	case encryption.EncryptionModeWinSCP, encryption.EncryptionModeAESGCM, encryption.EncryptionModePGP:
+		manager.SetKey([]byte(get.options.DecryptionKey))
+	case encryption.EncryptionModeSSH:
+		manager.SetPublicPrivateKey(get.options.DecryptionPrivateKeyPath)
+	case encryption.EncryptionModeAge:
+		manager.SetKey([]byte(get.options.DecryptionKey))
+		manager.SetPublicPrivateKey(get.options.DecryptionIdentityPath)
+	}
+
+	return manager
+}
+
+func (get *GetTransfer) getPathsForDecryption(sourcePath string, targetPath string) (string, string, error) {
+	encryptionMode := encryption.DetectEncryptionMode(sourcePath)
+
+	if encryptionMode != encryption.EncryptionModeNone {
+		// encrypted file
+		sourceFilename := path.Base(sourcePath)
+		encryptManager := get.getEncryptionManagerForDecryption(encryptionMode)
+
+		tempFilePath := commons_path.MakeLocalTargetFilePath(sourcePath, get.options.DecryptionTempPath)
+
+		decryptedFilename, err := encryptManager.DecryptFilename(sourceFilename)
+		if err != nil && encryptionMode == encryption.EncryptionModeSSH {
+			// filename of multi-recipient file is encrypted with the first key in collection's key ring
+			encryptionConfig := encryption.GetEncryptionConfigFromMeta(get.filesystem, path.Dir(sourcePath))
+			if len(encryptionConfig.PublicKeys) > 0 {
+				encryptManager.SetPublicKeyRing(encryptionConfig.PublicKeys)
+				decryptedFilename, err = encryptManager.DecryptFilename(sourceFilename)
+			}
+		}
+
+		if err != nil {
+			return "", "", errors.Wrapf(err, "failed to decrypt filename %q", sourcePath)
+		}
+
+		targetFilePath := commons_path.MakeLocalTargetFilePath(decryptedFilename, targetPath)
+
+		return tempFilePath, targetFilePath, nil
+	}
+
+	targetFilePath := commons_path.MakeLocalTargetFilePath(sourcePath, targetPath)
+
+	return "", targetFilePath, nil
+}
+
+func (get *GetTransfer) decryptFile(sourcePath string, encryptedFilePath string, targetPath string) (bool, error) {
+	logger := log.WithFields(log.Fields{
+		"source_path": sourcePath,
+		"temp_path":   encryptedFilePath,
+		"target_path": targetPath,
+	})
+
+	encryptionMode := encryption.DetectEncryptionMode(sourcePath)
+
+	if encryptionMode != encryption.EncryptionModeNone {
+		logger.Debug("decrypt a data object")
+
+		encryptManager := get.getEncryptionManagerForDecryption(encryptionMode)
+
+		err := encryptManager.DecryptFile(encryptedFilePath, targetPath)
+		if err != nil {
+			return false, errors.Wrapf(err, "failed to decrypt %q to %q", encryptedFilePath, targetPath)
+		}
+
+		logger.Debug("removing a temp file")
+		os.Remove(encryptedFilePath)
+
+		return true, nil
+	}
+
+	return false, nil
+}
+
+func (get *GetTransfer) determineTransferMethod(size int64) (transfer.TransferMode, int) {
+	logger := log.WithFields(log.Fields{})
+
+	threads := parallel.CalculateThreadForTransferJob(size, get.options.ThreadNumPerFile)
+
+	// determine how to download
+	if get.options.SingleThread || get.options.ThreadNum <= 2 || get.options.ThreadNumPerFile == 1 {
+		threads = 1
+	}
+
+	if get.options.ICAT {
+		logger.Info("using ICAT transfer for downloading a data object")
+		return transfer.TransferModeICAT, threads
+	} else if get.options.WebDAV {
+		if get.webdavClient == nil {
+			// fallback
+			logger.Info("WebDAV is not configured. Using ICAT transfer for downloading a data object")
+			return transfer.TransferModeICAT, threads
+		}
+
+		logger.Info("using WebDAV for downloading a data object")
+		return transfer.TransferModeWebDAV, 1
+	}
+
+	// sysconfig
+	systemConfig := config.GetSystemConfig()
+	if systemConfig != nil && systemConfig.AdditionalConfig != nil {
+		mode := transfer.GetTransferMode(systemConfig.AdditionalConfig.TransferMode)
+		if mode.Valid() {
+			return mode, threads
+		}
+	}
+
+	logger.Info("using ICAT transfer for downloading a data object")
+	return transfer.TransferModeICAT, threads
+}
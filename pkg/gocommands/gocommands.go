@@ -0,0 +1,348 @@
+// Package gocommands provides upload, download, copy, and sync operations of gocmd for Go programs.
+//
+// Operations are configured with option structs, the gocmd commands map their command-line flags to the same options.
+// Existing files are overwritten and files are removed only when Force is set or Confirm returns true, otherwise they are skipped.
+// Canceling the context cancels pending transfers and aborts running WebDAV transfers and retries,
+// incomplete files are removed. Transfers over the iRODS protocol already running complete.
+package gocommands
+
+import (
+	"context"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	irodsclient_types "github.com/cyverse/go-irodsclient/irods/types"
+	"github.com/cyverse/gocommands/commons/config"
+	"github.com/cyverse/gocommands/commons/parallel"
+	"github.com/cyverse/gocommands/commons/transfer"
+)
+
+const (
+	// DefaultRetryInterval is the interval between retries when RetryInterval is not set
+	DefaultRetryInterval time.Duration = 5 * time.Second
+
+	// AbortWaitTimeout is how long Abort waits for running transfers to return before incomplete files are removed
+	AbortWaitTimeout time.Duration = 10 * time.Second
+)
+
+// SchedulePolicy is the order files are transferred
+type SchedulePolicy string
+
+const (
+	// SchedulePolicyFIFO transfers files in the order they are found
+	SchedulePolicyFIFO SchedulePolicy = "fifo"
+	// SchedulePolicySmallFirst transfers smaller files first
+	SchedulePolicySmallFirst SchedulePolicy = "small_first"
+	// SchedulePolicyLargeFirst transfers larger files first
+	SchedulePolicyLargeFirst SchedulePolicy = "large_first"
+	// SchedulePolicyInterleave alternates the smallest and the largest files
+	SchedulePolicyInterleave SchedulePolicy = "interleave"
+)
+
+// ProgressMode is how progress bars are shown
+type ProgressMode string
+
+const (
+	// ProgressModeFull shows the total progress and progress of every file
+	ProgressModeFull ProgressMode = "full"
+	// ProgressModeCompact shows the total progress and the slowest files only
+	ProgressModeCompact ProgressMode = "compact"
+)
+
+// MetricsSink counts jobs, transfers, retries, and bundle stages of operations
+// methods are called concurrently
+type MetricsSink interface {
+	// AddJob counts a job finished with the status, done, failed, or canceled
+	AddJob(status string)
+	// AddTransfer counts files and bytes transferred with the method and the mode
+	AddTransfer(method string, mode string, files int, size int64)
+	// AddRetry counts a retry of a transfer with the method
+	AddRetry(method string)
+	// AddBundleStage counts time taken by a stage of bundle transfers, tar, upload, or extract
+	AddBundleStage(stage string, duration time.Duration)
+}
+
+// noMetricsSink is used when Metrics is not set
+type noMetricsSink struct{}
+
+func (noMetricsSink) AddJob(status string)                                          {}
+func (noMetricsSink) AddTransfer(method string, mode string, files int, size int64) {}
+func (noMetricsSink) AddRetry(method string)                                        {}
+func (noMetricsSink) AddBundleStage(stage string, duration time.Duration)           {}
+
+// TransferOptions has options common to all operations
+type TransferOptions struct {
+	// Account is the iRODS account to connect with, required
+	Account *irodsclient_types.IRODSAccount
+	// WorkingDir is the collection relative iRODS paths are resolved against, the home collection if empty
+	WorkingDir string
+	// HomeDir is the home collection, "/<zone>/home/<user>" if empty
+	HomeDir string
+	// Timeout is the timeout of iRODS operations, the default if 0
+	Timeout time.Duration
+
+	// Force overwrites existing files and removes files without asking
+	Force bool
+	// Confirm is called to ask whether to overwrite or remove a file when Force is not set, the file is skipped if it returns false or is not set
+	Confirm func(message string) bool
+	// Recursive transfers directories and collections
+	Recursive bool
+	// NoRoot transfers the contents of a source directory without creating the directory at the target
+	NoRoot bool
+	// Differential transfers only files different from the target, compared by size and hash
+	Differential bool
+	// NoHash compares only sizes in differential transfers
+	NoHash bool
+	// VerifyChecksum verifies checksums after transfers
+	VerifyChecksum bool
+	// ExcludeHidden skips hidden files and directories
+	ExcludeHidden bool
+	// DeleteOnSuccess removes sources after they are transferred, requires Force or Confirm
+	DeleteOnSuccess bool
+	// MetadataSidecar stores metadata of data objects in sidecar files
+	MetadataSidecar bool
+
+	// Sync replaces targets of a different type, a file with a directory and vice versa, set by Sync
+	Sync bool
+	// Delete removes files in the target not existing in the sources, requires Force or Confirm
+	Delete bool
+	// Age excludes files older than the age, no files are excluded if 0
+	Age time.Duration
+
+	// ThreadNum is the total number of transfer threads, the default if 0
+	ThreadNum int
+	// ThreadNumPerFile is the number of transfer threads for each file, the default if 0
+	ThreadNumPerFile int
+	// TCPBufferSize is the TCP socket buffer size, the default if 0
+	TCPBufferSize int
+	// SingleThread transfers each file with a single thread
+	SingleThread bool
+	// ICAT transfers files through iCAT
+	ICAT bool
+	// WebDAV transfers files through WebDAV at WebDAVBaseURL, through iCAT if the URL is not set
+	WebDAV bool
+	// WebDAVBaseURL is the base URL of the WebDAV server
+	WebDAVBaseURL string
+	// StopOnError stops all transfers when one fails
+	StopOnError bool
+	// RetryNum is the number of retries of failed transfers
+	RetryNum int
+	// RetryInterval is the interval between retries, DefaultRetryInterval if 0
+	RetryInterval time.Duration
+
+	// SchedulePolicy is the order files are transferred, fifo if empty
+	SchedulePolicy SchedulePolicy
+	// PriorityPatterns are patterns of names of files transferred first, in descending order of priority
+	PriorityPatterns []string
+
+	// ReportPath writes a transfer report to the path if set, to stdout if "-"
+	ReportPath string
+
+	// ShowProgress shows progress bars on the terminal
+	ShowProgress bool
+	// ShowFullPath shows full paths of files in progress bars
+	ShowFullPath bool
+	// ProgressMode is how progress bars are shown, full if empty
+	ProgressMode ProgressMode
+	// ProgressSlowFileNum is the number of the slowest files shown in compact progress mode, the default if 0
+	ProgressSlowFileNum int
+
+	// OnEvent is called with progress events and files skipped, it is not called concurrently
+	OnEvent func(event ProgressEvent)
+	// Metrics counts jobs, bytes transferred, and retries if set
+	Metrics MetricsSink
+}
+
+// TransferResult has statistics of an operation
+type TransferResult struct {
+	// Files is the number of files transferred
+	Files int
+	// Bytes is the total size of files transferred
+	Bytes int64
+	// NotTransferred is the number of files not transferred due to errors or cancellation
+	NotTransferred int64
+	// RemovedPaths are incomplete files removed after the operation is canceled
+	RemovedPaths []string
+	// Duration is the time taken
+	Duration time.Duration
+}
+
+// Transfer is an operation started with Run
+type Transfer interface {
+	// Run runs the operation
+	Run(ctx context.Context) error
+	// CancelPending cancels pending transfers, transfers running are completed
+	CancelPending()
+	// Abort cancels Run, waits for running transfers to return up to AbortWaitTimeout, and removes incomplete files
+	Abort() *TransferResult
+	// GetResult returns statistics of the operation
+	GetResult() *TransferResult
+}
+
+func (options *TransferOptions) validate() error {
+	if options.Account == nil {
+		return errors.New("account is not given")
+	}
+
+	if options.ThreadNum < 0 || options.ThreadNumPerFile < 0 {
+		return errors.New("number of threads must not be negative")
+	}
+
+	if options.RetryNum < 0 {
+		return errors.New("number of retries must not be negative")
+	}
+
+	_, err := parallel.GetSchedulePolicy(string(options.SchedulePolicy))
+	if err != nil {
+		return err
+	}
+
+	_, err = parallel.GetProgressMode(string(options.ProgressMode))
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// setDefaults sets default values to options not given
+func (options *TransferOptions) setDefaults() {
+	if options.ThreadNum == 0 {
+		options.ThreadNum = config.GetDefaultTransferThreadNum()
+	}
+
+	if options.ThreadNumPerFile == 0 {
+		options.ThreadNumPerFile = config.GetDefaultTransferThreadNumPerFile()
+	}
+
+	if options.TCPBufferSize <= 0 {
+		options.TCPBufferSize = config.GetDefaultTCPBufferSize()
+	}
+
+	if options.RetryInterval <= 0 {
+		options.RetryInterval = DefaultRetryInterval
+	}
+
+	// validated, aliases are replaced
+	schedulePolicy, _ := parallel.GetSchedulePolicy(string(options.SchedulePolicy))
+	options.SchedulePolicy = SchedulePolicy(schedulePolicy)
+
+	progressMode, _ := parallel.GetProgressMode(string(options.ProgressMode))
+	options.ProgressMode = ProgressMode(progressMode)
+
+	if options.ProgressSlowFileNum <= 0 {
+		options.ProgressSlowFileNum = parallel.DefaultProgressSlowJobNum
+	}
+
+	if options.Metrics == nil {
+		options.Metrics = noMetricsSink{}
+	}
+}
+
+// getWorkingDirs returns the working and home collections
+func (options *TransferOptions) getWorkingDirs() (string, string) {
+	home := options.HomeDir
+	if len(home) == 0 {
+		home = path.Join("/", options.Account.ClientZone, "home", options.Account.ClientUser)
+	}
+
+	cwd := home
+	if len(options.WorkingDir) > 0 {
+		cwd = options.WorkingDir
+		if !path.IsAbs(cwd) {
+			cwd = path.Join(home, cwd)
+		}
+	}
+
+	return path.Clean(cwd), path.Clean(home)
+}
+
+// getTimeoutSeconds returns the timeout of iRODS operations in seconds, 0 for the default
+func (options *TransferOptions) getTimeoutSeconds() int {
+	return int(options.Timeout.Seconds())
+}
+
+// confirm returns true if the file in the message can be overwritten or removed
+func (options *TransferOptions) confirm(message string) bool {
+	if options.Force {
+		return true
+	}
+
+	if options.Confirm == nil {
+		return false
+	}
+
+	return options.Confirm(message)
+}
+
+// getPriority returns the priority of the file at the path
+func (options *TransferOptions) getPriority(p string) int {
+	return parallel.GetPriority(options.PriorityPatterns, p)
+}
+
+// isAnonymous returns true if the account is an anonymous user accessing with a ticket
+func (options *TransferOptions) isAnonymous() bool {
+	return options.Account.ClientUser == config.AnonymousUsername && len(options.Account.Ticket) > 0
+}
+
+// newTransferReportManager returns a manager writing a transfer report to ReportPath
+func (options *TransferOptions) newTransferReportManager() (*transfer.TransferReportManager, error) {
+	return transfer.NewTransferReportManager(len(options.ReportPath) > 0, options.ReportPath, options.ReportPath == "-")
+}
+
+// newParallelJobManagers returns job managers for transfers and post processes, displaying progress as configured
+func (options *TransferOptions) newParallelJobManagers(maxThreads int, progressEventWriter *parallel.ProgressEventWriter) (*parallel.ParallelJobManager, *parallel.ParallelJobManager) {
+	transferJobManager := options.newParallelTransferJobManager(maxThreads, progressEventWriter)
+
+	postProcessJobManager := parallel.NewParallelJobManager(1, options.ShowProgress, options.ShowFullPath, false)
+	postProcessJobManager.SetProgressEventWriter(progressEventWriter)
+
+	return transferJobManager, postProcessJobManager
+}
+
+// newParallelTransferJobManager returns a job manager for transfers, displaying progress as configured
+func (options *TransferOptions) newParallelTransferJobManager(maxThreads int, progressEventWriter *parallel.ProgressEventWriter) *parallel.ParallelJobManager {
+	manager := parallel.NewParallelJobManager(maxThreads, options.ShowProgress, options.ShowFullPath, options.StopOnError)
+	manager.SetSchedulePolicy(parallel.SchedulePolicy(options.SchedulePolicy))
+	manager.SetProgressMode(parallel.ProgressMode(options.ProgressMode))
+	manager.SetProgressSlowJobNum(options.ProgressSlowFileNum)
+	manager.SetProgressEventWriter(progressEventWriter)
+	manager.SetMetrics(options.Metrics)
+
+	return manager
+}
+
+// runContext is the context of a run, canceled when the run is aborted
+type runContext struct {
+	cancel  context.CancelFunc
+	aborted bool
+	mutex   sync.Mutex
+}
+
+// start returns a context of the run derived from the ctx, canceled if the run is already aborted
+// the cancel function returned is called when the run returns
+func (run *runContext) start(ctx context.Context) (context.Context, context.CancelFunc) {
+	run.mutex.Lock()
+	defer run.mutex.Unlock()
+
+	ctx, cancel := context.WithCancel(ctx)
+	if run.aborted {
+		cancel()
+	}
+
+	run.cancel = cancel
+	return ctx, cancel
+}
+
+// abort cancels the context of the run
+func (run *runContext) abort() {
+	run.mutex.Lock()
+	defer run.mutex.Unlock()
+
+	run.aborted = true
+	if run.cancel != nil {
+		run.cancel()
+	}
+}
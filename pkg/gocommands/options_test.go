@@ -0,0 +1,281 @@
+package gocommands
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	irodsclient_types "github.com/cyverse/go-irodsclient/irods/types"
+	"github.com/cyverse/gocommands/commons/config"
+	"github.com/cyverse/gocommands/commons/parallel"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOptions(t *testing.T) {
+	t.Run("test TransferOptions", testTransferOptions)
+	t.Run("test ValidateOptions", testValidateOptions)
+	t.Run("test Confirm", testConfirm)
+	t.Run("test GetSyncMode", testGetSyncMode)
+	t.Run("test NewSyncTransfer", testNewSyncTransfer)
+	t.Run("test EventNotifier", testEventNotifier)
+	t.Run("test RunContext", testRunContext)
+}
+
+func newTestAccount() *irodsclient_types.IRODSAccount {
+	return &irodsclient_types.IRODSAccount{
+		Host:       "localhost",
+		Port:       1247,
+		ClientUser: "user",
+		ClientZone: "zone",
+		ProxyUser:  "user",
+		ProxyZone:  "zone",
+	}
+}
+
+func testTransferOptions(t *testing.T) {
+	options := TransferOptions{
+		Account:    newTestAccount(),
+		WorkingDir: "data",
+	}
+
+	assert.NoError(t, options.validate())
+	options.setDefaults()
+
+	assert.Equal(t, config.GetDefaultTransferThreadNum(), options.ThreadNum)
+	assert.Equal(t, config.GetDefaultTransferThreadNumPerFile(), options.ThreadNumPerFile)
+	assert.Equal(t, DefaultRetryInterval, options.RetryInterval)
+	assert.Equal(t, SchedulePolicyFIFO, options.SchedulePolicy)
+	assert.Equal(t, ProgressModeFull, options.ProgressMode)
+	assert.Equal(t, parallel.DefaultProgressSlowJobNum, options.ProgressSlowFileNum)
+	assert.Equal(t, noMetricsSink{}, options.Metrics)
+
+	// aliases are replaced
+	options.SchedulePolicy = "smallest"
+	options.setDefaults()
+	assert.Equal(t, SchedulePolicySmallFirst, options.SchedulePolicy)
+
+	cwd, home := options.getWorkingDirs()
+	assert.Equal(t, "/zone/home/user/data", cwd)
+	assert.Equal(t, "/zone/home/user", home)
+
+	options.WorkingDir = "/zone/home/shared"
+	cwd, _ = options.getWorkingDirs()
+	assert.Equal(t, "/zone/home/shared", cwd)
+
+	options.Timeout = 90 * time.Second
+	assert.Equal(t, 90, options.getTimeoutSeconds())
+}
+
+func testValidateOptions(t *testing.T) {
+	account := newTestAccount()
+
+	tests := []struct {
+		name     string
+		validate func() error
+		hasError bool
+	}{
+		{"put", (&PutOptions{TransferOptions: TransferOptions{Account: account}, SourcePaths: []string{"a"}}).validate, false},
+		{"put without account", (&PutOptions{SourcePaths: []string{"a"}}).validate, true},
+		{"put without sources", (&PutOptions{TransferOptions: TransferOptions{Account: account}}).validate, true},
+		{"put multiple sources without root", (&PutOptions{TransferOptions: TransferOptions{Account: account, NoRoot: true}, SourcePaths: []string{"a", "b"}}).validate, true},
+		{"put negative threads", (&PutOptions{TransferOptions: TransferOptions{Account: account, ThreadNum: -1}, SourcePaths: []string{"a"}}).validate, true},
+		{"put negative retries", (&PutOptions{TransferOptions: TransferOptions{Account: account, RetryNum: -1}, SourcePaths: []string{"a"}}).validate, true},
+		{"put unknown schedule policy", (&PutOptions{TransferOptions: TransferOptions{Account: account, SchedulePolicy: "random"}, SourcePaths: []string{"a"}}).validate, true},
+		{"put unknown progress mode", (&PutOptions{TransferOptions: TransferOptions{Account: account, ProgressMode: "tiny"}, SourcePaths: []string{"a"}}).validate, true},
+		{"get", (&GetOptions{TransferOptions: TransferOptions{Account: account}, SourcePaths: []string{"a"}}).validate, false},
+		{"get multiple sources without root", (&GetOptions{TransferOptions: TransferOptions{Account: account, NoRoot: true}, SourcePaths: []string{"a", "b"}}).validate, true},
+		{"bput", (&BputOptions{TransferOptions: TransferOptions{Account: account}, SourcePaths: []string{"a"}}).validate, false},
+		{"bput negative bundle size", (&BputOptions{TransferOptions: TransferOptions{Account: account}, BundleOptions: BundleOptions{MaxBundleFileSize: -1}, SourcePaths: []string{"a"}}).validate, true},
+		{"bput min files larger than max", (&BputOptions{TransferOptions: TransferOptions{Account: account}, BundleOptions: BundleOptions{MinFileNumInBundle: 10, MaxFileNumInBundle: 5}, SourcePaths: []string{"a"}}).validate, true},
+		{"copy", (&CopyOptions{TransferOptions: TransferOptions{Account: account}, SourcePaths: []string{"a"}, TargetPath: "b"}).validate, false},
+		{"copy without target", (&CopyOptions{TransferOptions: TransferOptions{Account: account}, SourcePaths: []string{"a"}}).validate, true},
+	}
+
+	for _, test := range tests {
+		err := test.validate()
+		if test.hasError {
+			assert.Error(t, err, test.name)
+		} else {
+			assert.NoError(t, err, test.name)
+		}
+	}
+}
+
+func testConfirm(t *testing.T) {
+	confirmed := func(message string) bool {
+		return true
+	}
+
+	tests := []struct {
+		name     string
+		options  TransferOptions
+		expected bool
+	}{
+		{"not forced without confirm", TransferOptions{}, false},
+		{"forced", TransferOptions{Force: true}, true},
+		{"confirmed", TransferOptions{Confirm: confirmed}, true},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, test.options.confirm("overwrite?"), test.name)
+	}
+}
+
+func testGetSyncMode(t *testing.T) {
+	tests := []struct {
+		name            string
+		sourcePaths     []string
+		targetPath      string
+		expectedMode    SyncMode
+		expectedSources []string
+		expectedTarget  string
+		hasError        bool
+	}{
+		{"local to iRODS", []string{"/local/a", "/local/b"}, "i:/zone/home/user/dir", SyncModeLocalToIRODS, []string{"/local/a", "/local/b"}, "/zone/home/user/dir", false},
+		{"iRODS to local", []string{"i:dir"}, "/local/dir", SyncModeIRODSToLocal, []string{"dir"}, "/local/dir", false},
+		{"iRODS to iRODS", []string{"i:/zone/home/user/dir1"}, "i:/zone/home/user/dir2", SyncModeIRODSToIRODS, []string{"/zone/home/user/dir1"}, "/zone/home/user/dir2", false},
+		{"local to local", []string{"/local/a"}, "/local/b", "", nil, "", true},
+		{"mixed sources", []string{"/local/a", "i:dir"}, "i:dir2", "", nil, "", true},
+		{"no sources", []string{}, "i:dir", "", nil, "", true},
+	}
+
+	for _, test := range tests {
+		mode, sourcePaths, targetPath, err := GetSyncMode(test.sourcePaths, test.targetPath)
+		if test.hasError {
+			assert.Error(t, err, test.name)
+			continue
+		}
+
+		assert.NoError(t, err, test.name)
+		assert.Equal(t, test.expectedMode, mode, test.name)
+		assert.Equal(t, test.expectedSources, sourcePaths, test.name)
+		assert.Equal(t, test.expectedTarget, targetPath, test.name)
+	}
+}
+
+func testNewSyncTransfer(t *testing.T) {
+	account := newTestAccount()
+
+	// local to iRODS with bundles
+	sync, err := NewSyncTransfer(SyncOptions{
+		TransferOptions: TransferOptions{Account: account, Delete: true, Age: time.Hour},
+		BundleOptions:   BundleOptions{MaxFileNumInBundle: 10},
+		SourcePaths:     []string{"/local/dir"},
+		TargetPath:      "i:/zone/home/user/dir",
+		BulkUpload:      true,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, SyncModeLocalToIRODS, sync.GetMode())
+
+	bput, ok := sync.GetTransfer().(*BputTransfer)
+	if assert.True(t, ok) {
+		assert.True(t, bput.options.Recursive)
+		assert.True(t, bput.options.Differential)
+		assert.True(t, bput.options.Sync)
+		assert.True(t, bput.options.Delete)
+		assert.Equal(t, time.Hour, bput.options.Age)
+		assert.Equal(t, 10, bput.options.MaxFileNumInBundle)
+		assert.Equal(t, config.MinFileNumInBundleDefault, bput.options.MinFileNumInBundle)
+		assert.Equal(t, []string{"/local/dir"}, bput.sourcePaths)
+		assert.Equal(t, "/zone/home/user/dir", bput.targetPath)
+	}
+
+	// iRODS to local
+	sync, err = NewSyncTransfer(SyncOptions{
+		TransferOptions:   TransferOptions{Account: account},
+		DecryptionOptions: DecryptionOptions{NoDecryption: true},
+		SourcePaths:       []string{"i:dir"},
+		TargetPath:        "/local/dir",
+	})
+	assert.NoError(t, err)
+
+	get, ok := sync.GetTransfer().(*GetTransfer)
+	if assert.True(t, ok) {
+		assert.True(t, get.options.Recursive)
+		assert.True(t, get.options.NoDecryption)
+		assert.Equal(t, "/zone/home/user", get.cwd)
+	}
+
+	// iRODS to iRODS
+	sync, err = NewSyncTransfer(SyncOptions{
+		TransferOptions: TransferOptions{Account: account},
+		SourcePaths:     []string{"i:dir1"},
+		TargetPath:      "i:dir2",
+	})
+	assert.NoError(t, err)
+
+	_, ok = sync.GetTransfer().(*CopyTransfer)
+	assert.True(t, ok)
+
+	// options are validated
+	_, err = NewSyncTransfer(SyncOptions{
+		SourcePaths: []string{"i:dir1"},
+		TargetPath:  "i:dir2",
+	})
+	assert.Error(t, err)
+}
+
+func testEventNotifier(t *testing.T) {
+	events := []ProgressEvent{}
+	notifier := newEventNotifier(func(event ProgressEvent) {
+		events = append(events, event)
+	})
+
+	notifier.notifySkipped("a", "/zone/home/user/a", "skip uploading a file %q to %q. The file is hidden!", "a", "/zone/home/user/a")
+	notifier.notifyResumed("/zone/home/user/b", "b", "resume downloading a data object %q", "b")
+
+	writer := notifier.getProgressEventWriter()
+	writer.Write(&parallel.ProgressEvent{Event: parallel.ProgressEventFailed, JobID: 1, JobName: "c", Size: 10, Error: "failed"})
+	writer.Write(&parallel.ProgressEvent{Event: parallel.ProgressEventAggregate, TotalJobs: 1, FailedJobs: 1, TotalBytes: 10})
+
+	if assert.Len(t, events, 4) {
+		for _, event := range events {
+			assert.False(t, event.Time.IsZero())
+		}
+
+		assert.Equal(t, ProgressEventSkipped, events[0].Event)
+		assert.Equal(t, "a", events[0].SourcePath)
+		assert.Equal(t, "/zone/home/user/a", events[0].TargetPath)
+		assert.Equal(t, `skip uploading a file "a" to "/zone/home/user/a". The file is hidden!`, events[0].Message)
+
+		assert.Equal(t, ProgressEventResumed, events[1].Event)
+		assert.Equal(t, `resume downloading a data object "b"`, events[1].Message)
+
+		assert.Equal(t, ProgressEventFailed, events[2].Event)
+		assert.Equal(t, int64(1), events[2].JobID)
+		assert.Equal(t, "c", events[2].JobName)
+		assert.Equal(t, int64(10), events[2].Size)
+		assert.Equal(t, "failed", events[2].Error)
+
+		assert.Equal(t, ProgressEventAggregate, events[3].Event)
+		assert.Equal(t, int64(1), events[3].TotalJobs)
+		assert.Equal(t, int64(1), events[3].FailedJobs)
+		assert.Equal(t, int64(10), events[3].TotalBytes)
+	}
+
+	// events are dropped without OnEvent
+	notifier = newEventNotifier(nil)
+	assert.Nil(t, notifier.getProgressEventWriter())
+	notifier.notifySkipped("a", "b", "skip")
+}
+
+func testRunContext(t *testing.T) {
+	run := runContext{}
+
+	ctx, cancel := run.start(context.Background())
+	defer cancel()
+
+	assert.NoError(t, ctx.Err())
+
+	run.abort()
+	assert.ErrorIs(t, ctx.Err(), context.Canceled)
+
+	// a run started after abort is canceled
+	aborted := runContext{}
+	aborted.abort()
+
+	ctx, cancel = aborted.start(context.Background())
+	defer cancel()
+
+	assert.ErrorIs(t, ctx.Err(), context.Canceled)
+}
@@ -0,0 +1,152 @@
+package gocommands
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/cyverse/gocommands/commons/parallel"
+)
+
+// ProgressEventType is a type of progress event
+type ProgressEventType string
+
+const (
+	// ProgressEventScheduled is emitted when a transfer is scheduled
+	ProgressEventScheduled ProgressEventType = "scheduled"
+	// ProgressEventStarted is emitted when a transfer starts
+	ProgressEventStarted ProgressEventType = "started"
+	// ProgressEventProgress is emitted when a transfer makes progress
+	ProgressEventProgress ProgressEventType = "progress"
+	// ProgressEventDone is emitted when a transfer completes
+	ProgressEventDone ProgressEventType = "done"
+	// ProgressEventFailed is emitted when a transfer fails
+	ProgressEventFailed ProgressEventType = "failed"
+	// ProgressEventCanceled is emitted when a transfer is canceled
+	ProgressEventCanceled ProgressEventType = "canceled"
+	// ProgressEventAggregate is emitted periodically with totals of all transfers
+	ProgressEventAggregate ProgressEventType = "aggregate"
+	// ProgressEventSkipped is emitted when a file is not transferred, Message has the reason
+	ProgressEventSkipped ProgressEventType = "skipped"
+	// ProgressEventResumed is emitted when a transfer resumes from an incomplete file
+	ProgressEventResumed ProgressEventType = "resumed"
+)
+
+// ProgressEvent is an event of an operation passed to OnEvent callbacks
+type ProgressEvent struct {
+	Time  time.Time
+	Event ProgressEventType
+
+	// transfer events, JobID starts from 1
+	JobID     int64
+	JobName   string
+	Task      string
+	Processed int64
+	Total     int64
+	Size      int64
+	Files     int
+	Error     string
+
+	// aggregate events
+	TotalJobs    int64
+	DoneJobs     int64
+	FailedJobs   int64
+	CanceledJobs int64
+	TotalFiles   int64
+	DoneFiles    int64
+	TotalBytes   int64
+	DoneBytes    int64
+	ElapsedSec   float64
+
+	// skipped and resumed events
+	SourcePath string
+	TargetPath string
+	Message    string
+}
+
+// newProgressEvent returns a ProgressEvent of the event of job managers
+func newProgressEvent(event parallel.ProgressEvent) ProgressEvent {
+	return ProgressEvent{
+		Time:  event.Time,
+		Event: ProgressEventType(event.Event),
+
+		JobID:     event.JobID,
+		JobName:   event.JobName,
+		Task:      event.Task,
+		Processed: event.Processed,
+		Total:     event.Total,
+		Size:      event.Size,
+		Files:     event.Files,
+		Error:     event.Error,
+
+		TotalJobs:    event.TotalJobs,
+		DoneJobs:     event.DoneJobs,
+		FailedJobs:   event.FailedJobs,
+		CanceledJobs: event.CanceledJobs,
+		TotalFiles:   event.TotalFiles,
+		DoneFiles:    event.DoneFiles,
+		TotalBytes:   event.TotalBytes,
+		DoneBytes:    event.DoneBytes,
+		ElapsedSec:   event.ElapsedSec,
+	}
+}
+
+// eventNotifier passes events of an operation to OnEvent, one at a time
+type eventNotifier struct {
+	onEvent func(event ProgressEvent)
+	mutex   sync.Mutex
+}
+
+// newEventNotifier creates a new eventNotifier, events are dropped if onEvent is nil
+func newEventNotifier(onEvent func(event ProgressEvent)) *eventNotifier {
+	return &eventNotifier{
+		onEvent: onEvent,
+	}
+}
+
+// notify passes the event to OnEvent
+func (notifier *eventNotifier) notify(event ProgressEvent) {
+	if notifier.onEvent == nil {
+		return
+	}
+
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	notifier.mutex.Lock()
+	defer notifier.mutex.Unlock()
+
+	notifier.onEvent(event)
+}
+
+// notifySkipped passes an event of a file not transferred, with the message formatted
+func (notifier *eventNotifier) notifySkipped(sourcePath string, targetPath string, format string, args ...interface{}) {
+	notifier.notify(ProgressEvent{
+		Event:      ProgressEventSkipped,
+		SourcePath: sourcePath,
+		TargetPath: targetPath,
+		Message:    fmt.Sprintf(format, args...),
+	})
+}
+
+// notifyResumed passes an event of a transfer resumed, with the message formatted
+func (notifier *eventNotifier) notifyResumed(sourcePath string, targetPath string, format string, args ...interface{}) {
+	notifier.notify(ProgressEvent{
+		Event:      ProgressEventResumed,
+		SourcePath: sourcePath,
+		TargetPath: targetPath,
+		Message:    fmt.Sprintf(format, args...),
+	})
+}
+
+// getProgressEventWriter returns a writer passing events of job managers to OnEvent, nil if OnEvent is not set
+func (notifier *eventNotifier) getProgressEventWriter() *parallel.ProgressEventWriter {
+	if notifier.onEvent == nil {
+		return nil
+	}
+
+	return parallel.NewProgressEventCallbackWriter(func(event parallel.ProgressEvent) {
+		notifier.notify(newProgressEvent(event))
+	})
+}
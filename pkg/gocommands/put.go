@@ -0,0 +1,1809 @@
+package gocommands
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/avast/retry-go"
+	"github.com/cockroachdb/errors"
+	irodsclient_fs "github.com/cyverse/go-irodsclient/fs"
+	irodsclient_types "github.com/cyverse/go-irodsclient/irods/types"
+	irodsclient_util "github.com/cyverse/go-irodsclient/irods/util"
+	"github.com/cyverse/gocommands/commons/config"
+	"github.com/cyverse/gocommands/commons/encryption"
+	"github.com/cyverse/gocommands/commons/irods"
+	"github.com/cyverse/gocommands/commons/parallel"
+	commons_path "github.com/cyverse/gocommands/commons/path"
+	"github.com/cyverse/gocommands/commons/tracing"
+	"github.com/cyverse/gocommands/commons/transfer"
+	"github.com/cyverse/gocommands/commons/types"
+	"github.com/cyverse/gocommands/commons/webdav"
+	"github.com/jedib0t/go-pretty/v6/progress"
+	log "github.com/sirupsen/logrus"
+)
+
+// PutOptions has options of Put
+type PutOptions struct {
+	TransferOptions
+	EncryptionOptions
+
+	// SourcePaths are local files or directories to upload
+	SourcePaths []string
+	// TargetPath is the iRODS data object or collection to upload to, the working collection if empty
+	TargetPath string
+
+	// MetadataTemplatePath is the metadata template file to validate and add AVUs with
+	MetadataTemplatePath string
+	// MetadataTemplateValues are values of attributes of the metadata template
+	MetadataTemplateValues map[string]string
+}
+
+// Put uploads local files or directories to iRODS
+func Put(ctx context.Context, options PutOptions) (*TransferResult, error) {
+	put, err := NewPutTransfer(options)
+	if err != nil {
+		return nil, err
+	}
+
+	err = put.Run(ctx)
+	return put.GetResult(), err
+}
+
+// PutTransfer uploads local files or directories to iRODS
+type PutTransfer struct {
+	options PutOptions
+
+	account      *irodsclient_types.IRODSAccount
+	filesystem   *irodsclient_fs.FileSystem
+	webdavClient *webdav.WebDAVClient
+
+	// working and home collections relative iRODS paths are resolved against
+	cwd  string
+	home string
+
+	sourcePaths []string
+	targetPath  string
+
+	parallelTransferJobManager    *parallel.ParallelJobManager
+	parallelPostProcessJobManager *parallel.ParallelJobManager
+	transferReportManager         *transfer.TransferReportManager
+	updatedPathMap                map[string]bool
+	mutex                         sync.RWMutex // mutex for updatedPathMap
+
+	// AVUs resolved from the metadata template, key is local path
+	metadataTemplateMetas map[string][]*irodsclient_types.IRODSMeta
+
+	inFlightTransfers *transfer.InFlightTransfers
+	removedPaths      []string // incomplete files removed after the run is aborted
+
+	// context of the run, carrying the span of the operation
+	ctx context.Context
+
+	// canceled by CancelPending
+	pendingCtx    context.Context
+	cancelPending context.CancelFunc
+
+	// canceled by Abort
+	run runContext
+
+	events *eventNotifier
+
+	totalUploadedFiles int
+	totalUploadedBytes int64
+	startTime          time.Time
+}
+
+// NewPutTransfer creates a new PutTransfer, options are validated
+func NewPutTransfer(options PutOptions) (*PutTransfer, error) {
+	err := options.validate()
+	if err != nil {
+		return nil, err
+	}
+
+	options.setDefaults()
+
+	put := &PutTransfer{
+		options: options,
+
+		account:     options.Account,
+		sourcePaths: options.SourcePaths,
+		targetPath:  options.TargetPath,
+
+		updatedPathMap:     map[string]bool{},
+		totalUploadedFiles: 0,
+		totalUploadedBytes: 0,
+		startTime:          time.Now(),
+
+		metadataTemplateMetas: map[string][]*irodsclient_types.IRODSMeta{},
+
+		inFlightTransfers: transfer.NewInFlightTransfers(),
+	}
+
+	put.cwd, put.home = options.getWorkingDirs()
+	put.pendingCtx, put.cancelPending = context.WithCancel(context.Background())
+	put.events = newEventNotifier(options.OnEvent)
+
+	return put, nil
+}
+
+func (options *PutOptions) validate() error {
+	err := options.TransferOptions.validate()
+	if err != nil {
+		return err
+	}
+
+	if len(options.SourcePaths) == 0 {
+		return errors.New("source path is not given")
+	}
+
+	if options.NoRoot && len(options.SourcePaths) > 1 {
+		return errors.New("failed to put multiple source collections without creating root directory")
+	}
+
+	return nil
+}
+
+func (options *PutOptions) setDefaults() {
+	options.TransferOptions.setDefaults()
+	options.EncryptionOptions.setDefaults()
+
+	if len(options.TargetPath) == 0 {
+		options.TargetPath = "./"
+	}
+}
+
+// CancelPending cancels pending uploads, uploads running are completed
+func (put *PutTransfer) CancelPending() {
+	put.cancelPending()
+}
+
+// GetResult returns statistics of the upload
+func (put *PutTransfer) GetResult() *TransferResult {
+	notUploaded := int64(0)
+	if put.parallelTransferJobManager != nil {
+		counts := put.parallelTransferJobManager.GetJobCounts()
+		notUploaded = counts.Total - int64(put.totalUploadedFiles)
+	}
+
+	return &TransferResult{
+		Files:          put.totalUploadedFiles,
+		Bytes:          put.totalUploadedBytes,
+		NotTransferred: notUploaded,
+		RemovedPaths:   put.removedPaths,
+		Duration:       time.Since(put.startTime),
+	}
+}
+
+// Abort cancels Run, waits for running uploads to return, and removes incomplete data objects
+// It is for callers exiting before Run returns, the result has the data objects removed
+func (put *PutTransfer) Abort() *TransferResult {
+	logger := log.WithFields(log.Fields{})
+
+	// stop running jobs, and wait for them to return before removing files they write
+	put.run.abort()
+	if !parallel.WaitJobManagers(AbortWaitTimeout, put.parallelTransferJobManager, put.parallelPostProcessJobManager) {
+		logger.Warnf("running jobs did not return in %s, removing incomplete files", AbortWaitTimeout)
+	}
+
+	removedPaths, err := put.inFlightTransfers.CleanUp()
+	if err != nil {
+		logger.WithError(err).Warn("failed to remove incomplete files")
+	}
+
+	if put.transferReportManager != nil {
+		put.transferReportManager.Release()
+	}
+
+	result := put.GetResult()
+	result.RemovedPaths = removedPaths
+	return result
+}
+
+// Run uploads files
+// when the ctx is done, pending uploads are canceled and running WebDAV uploads are aborted, incomplete data objects are removed
+func (put *PutTransfer) Run(ctx context.Context) (err error) {
+	logger := log.WithFields(log.Fields{})
+
+	ctx, cancel := put.run.start(ctx)
+	defer cancel()
+
+	ctx, span := tracing.StartSpan(ctx, "put",
+		tracing.String("gocmd.source_paths", strings.Join(put.sourcePaths, ",")),
+		tracing.String("gocmd.target_path", put.targetPath),
+		tracing.String("irods.host", put.account.Host),
+		tracing.String("irods.zone", put.account.ClientZone),
+		tracing.String("irods.resource", put.account.DefaultResource),
+	)
+	defer func() {
+		span.End(err)
+	}()
+
+	put.ctx = ctx
+
+	// validate metadata template before transfer
+	if len(put.options.MetadataTemplatePath) > 0 {
+		err := put.resolveMetadataTemplate()
+		if err != nil {
+			return errors.Wrap(err, "failed to satisfy metadata template")
+		}
+	}
+
+	// Create a file system
+	put.filesystem, err = irods.GetIRODSFSClientForLargeFileIO(put.account, put.options.ThreadNum, put.options.TCPBufferSize, true, put.options.getTimeoutSeconds())
+	if err != nil {
+		return errors.Wrap(err, "failed to get iRODS FS Client")
+	}
+	defer put.filesystem.Release()
+
+	if put.options.WebDAV && len(put.options.WebDAVBaseURL) > 0 {
+		newWebDAVClient := webdav.NewWebDAVClient
+		if put.options.isAnonymous() {
+			// anonymous user may not be able to access the WebDAV root, access paths with the ticket directly
+			newWebDAVClient = webdav.NewWebDAVClientForTicket
+		}
+
+		webdavClient, err := newWebDAVClient(put.filesystem, put.options.WebDAVBaseURL, put.account.ProxyUser, put.account.Password)
+		if err != nil {
+			return errors.Wrap(err, "failed to create WebDAV client")
+		}
+
+		put.webdavClient = webdavClient
+	}
+
+	// transfer report
+	put.transferReportManager, err = put.options.newTransferReportManager()
+	if err != nil {
+		return errors.Wrap(err, "failed to create transfer report manager")
+	}
+	defer put.transferReportManager.Release()
+
+	// set default key for encryption
+	if len(put.options.EncryptionKey) == 0 {
+		put.options.EncryptionKey = put.account.Password
+	}
+
+	// parallel job manager
+	ioSession := put.filesystem.GetIOSession()
+	put.parallelTransferJobManager, put.parallelPostProcessJobManager = put.options.newParallelJobManagers(ioSession.GetMaxConnections(), put.events.getProgressEventWriter())
+
+	// cancel pending jobs when CancelPending is called
+	stopCancel := context.AfterFunc(put.pendingCtx, func() {
+		put.parallelTransferJobManager.CancelJobs()
+		put.parallelPostProcessJobManager.CancelJobs()
+	})
+	defer stopCancel()
+
+	// run
+	if len(put.sourcePaths) >= 2 {
+		// multi-source, target must be a dir
+		err = put.ensureTargetIsDir(put.targetPath)
+		if err != nil {
+			return errors.Wrapf(err, "target path %q is not a directory", put.targetPath)
+		}
+	}
+
+	for _, sourcePath := range put.sourcePaths {
+		err = put.putOne(sourcePath, put.targetPath)
+		if err != nil {
+			return errors.Wrapf(err, "failed to put %q to %q", sourcePath, put.targetPath)
+		}
+	}
+
+	// delete sources on success
+	if put.options.DeleteOnSuccess {
+		for _, sourcePath := range put.sourcePaths {
+			logger.Infof("deleting source file or directory under %q after upload", sourcePath)
+
+			err = put.deleteOnSuccessOne(sourcePath)
+			if err != nil {
+				return errors.Wrapf(err, "failed to delete source %q after upload", sourcePath)
+			}
+		}
+	}
+
+	// delete extra
+	if put.options.Delete {
+		logger.Infof("deleting extra data objects and collections under %q", put.targetPath)
+
+		err = put.deleteExtraOne(put.targetPath)
+		if err != nil {
+			return errors.Wrap(err, "failed to delete extra data objects or collections")
+		}
+	}
+
+	logger.Info("done scheduling jobs, starting jobs")
+
+	transferErr := put.parallelTransferJobManager.StartWithContext(ctx)
+	if transferErr != nil {
+		// error occurred while transferring files
+		put.parallelPostProcessJobManager.CancelJobs()
+	}
+
+	postProcessErr := put.parallelPostProcessJobManager.StartWithContext(ctx)
+
+	if ctx.Err() != nil {
+		// remove incomplete data objects of aborted uploads
+		removedPaths, cleanUpErr := put.inFlightTransfers.CleanUp()
+		if cleanUpErr != nil {
+			logger.WithError(cleanUpErr).Warn("failed to remove incomplete files")
+		}
+		put.removedPaths = removedPaths
+
+		return errors.Wrap(context.Cause(ctx), "upload canceled")
+	}
+
+	if transferErr != nil {
+		return errors.Wrap(transferErr, "failed to perform transfer jobs")
+	}
+
+	if postProcessErr != nil {
+		return errors.Wrap(postProcessErr, "failed to perform post process jobs")
+	}
+
+	return nil
+}
+
+func (put *PutTransfer) ensureTargetIsDir(targetPath string) error {
+	cwd := put.cwd
+	home := put.home
+	zone := put.account.ClientZone
+	targetPath = commons_path.MakeIRODSPath(cwd, home, zone, targetPath)
+
+	targetEntry, err := put.filesystem.Stat(targetPath)
+	if err != nil {
+		if irodsclient_types.IsFileNotFoundError(err) {
+			// not exist
+			return types.NewNotDirError(targetPath)
+		}
+
+		return errors.Wrapf(err, "failed to stat %q", targetPath)
+	}
+
+	if !targetEntry.IsDir() {
+		return types.NewNotDirError(targetPath)
+	}
+
+	return nil
+}
+
+func (put *PutTransfer) getEncryptionMode(targetPath string, parentEncryptionMode encryption.EncryptionMode) encryption.EncryptionMode {
+	if put.options.Encryption {
+		return put.options.EncryptionMode
+	}
+
+	if put.options.NoEncryption {
+		return encryption.EncryptionModeNone
+	}
+
+	if !put.options.EncryptionIgnoreMeta {
+		// load encryption config from meta
+		targetDir := targetPath
+
+		targetEntry, err := put.filesystem.Stat(targetPath)
+		if err != nil {
+			if irodsclient_types.IsFileNotFoundError(err) {
+				targetDir = path.Dir(targetPath)
+			} else {
+				return parentEncryptionMode
+			}
+		} else {
+			if !targetEntry.IsDir() {
+				targetDir = path.Dir(targetEntry.Path)
+			}
+		}
+
+		encryptionConfig := encryption.GetEncryptionConfigFromMeta(put.filesystem, targetDir)
+		return encryptionConfig.Mode
+	}
+
+	return parentEncryptionMode
+}
+
+func (put *PutTransfer) putOne(sourcePath string, targetPath string) error {
+	cwd := put.cwd
+	home := put.home
+	zone := put.account.ClientZone
+	sourcePath = commons_path.MakeLocalPath(sourcePath)
+	targetPath = commons_path.MakeIRODSPath(cwd, home, zone, targetPath)
+
+	sourceStat, err := os.Stat(sourcePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return irodsclient_types.NewFileNotFoundError(sourcePath)
+		}
+
+		return errors.Wrapf(err, "failed to stat %q", sourcePath)
+	}
+
+	if sourceStat.IsDir() {
+		// dir
+		if !put.options.NoRoot {
+			targetPath = commons_path.MakeIRODSTargetFilePath(put.filesystem, sourcePath, targetPath)
+		}
+
+		return put.putDir(sourceStat, sourcePath, targetPath, encryption.EncryptionModeNone)
+	}
+
+	// file
+	encryptionMode := put.getEncryptionMode(targetPath, encryption.EncryptionModeNone)
+	if encryptionMode != encryption.EncryptionModeNone {
+		// encrypt filename
+		tempPath, newTargetPath, err := put.getPathsForEncryption(sourcePath, targetPath)
+		if err != nil {
+			return errors.Wrapf(err, "failed to get encryption path for %q", sourcePath)
+		}
+
+		return put.putFile(sourceStat, sourcePath, tempPath, newTargetPath, encryptionMode)
+	}
+
+	targetPath = commons_path.MakeIRODSTargetFilePath(put.filesystem, sourcePath, targetPath)
+	return put.putFile(sourceStat, sourcePath, "", targetPath, encryption.EncryptionModeNone)
+}
+
+func (put *PutTransfer) deleteOnSuccessOne(sourcePath string) error {
+	sourcePath = commons_path.MakeLocalPath(sourcePath)
+
+	sourceStat, err := os.Stat(sourcePath)
+	if err != nil {
+		return errors.Wrapf(err, "failed to stat %q", sourcePath)
+	}
+
+	if sourceStat.IsDir() {
+		// dir
+		return put.deleteDirOnSuccess(sourcePath)
+	}
+
+	// file
+	return put.deleteFileOnSuccess(sourcePath)
+}
+
+func (put *PutTransfer) deleteExtraOne(targetPath string) error {
+	cwd := put.cwd
+	home := put.home
+	zone := put.account.ClientZone
+	targetPath = commons_path.MakeIRODSPath(cwd, home, zone, targetPath)
+
+	targetEntry, err := put.filesystem.Stat(targetPath)
+	if err != nil {
+		return errors.Wrapf(err, "failed to stat %q", targetPath)
+	}
+
+	if targetEntry.IsDir() {
+		// dir
+		return put.deleteExtraDir(targetPath)
+	}
+
+	// file
+	return put.deleteExtraFile(targetPath)
+}
+
+func (put *PutTransfer) schedulePut(sourceStat fs.FileInfo, sourcePath string, tempPath string, targetPath string, encryptionMode encryption.EncryptionMode) {
+	logger := log.WithFields(log.Fields{
+		"source_path":     sourcePath,
+		"temp_path":       tempPath,
+		"target_path":     targetPath,
+		"encryption_mode": encryptionMode,
+	})
+
+	defaultNotes := []string{"put"}
+
+	reportSimple := func(err error, additionalNotes ...string) {
+		now := time.Now()
+		newNotes := append(defaultNotes, additionalNotes...)
+		newNotes = append(newNotes, "file")
+
+		reportFile := &transfer.TransferReportFile{
+			Method:     transfer.TransferMethodPut,
+			StartAt:    now,
+			EndAt:      now,
+			SourcePath: sourcePath,
+			SourceSize: sourceStat.Size(),
+			DestPath:   targetPath,
+			Error:      err,
+			Notes:      newNotes,
+		}
+
+		put.transferReportManager.AddFile(reportFile)
+	}
+
+	reportTransfer := func(result *irodsclient_fs.FileTransferResult, err error, additionalNotes ...string) {
+		newNotes := append(defaultNotes, additionalNotes...)
+
+		put.transferReportManager.AddTransfer(result, transfer.TransferMethodPut, err, newNotes)
+	}
+
+	transferMode, threadsRequired := put.determineTransferMethod(sourceStat.Size())
+
+	putTask := func(job *parallel.ParallelJob) error {
+		logger := logger.WithFields(job.GetLogFields())
+
+		if job.IsCanceled() {
+			// job is canceled, do not run
+			job.Progress("upload", -1, sourceStat.Size(), true)
+
+			reportSimple(nil, "canceled")
+			logger.Debug("canceled a task for uploading a file")
+			return nil
+		}
+
+		logger.Debug("uploading a file")
+
+		notes := []string{}
+
+		// encrypt
+		if encryptionMode != encryption.EncryptionModeNone {
+			notes = append(notes, "encrypt")
+
+			job.Progress("encrypt", 0, sourceStat.Size(), false)
+
+			put.inFlightTransfers.Start(tempPath, func() error {
+				return os.Remove(tempPath)
+			})
+
+			defer func() {
+				if len(tempPath) > 0 {
+					// remove temp file
+					logger.Debug("removing a temporary file")
+					os.Remove(tempPath)
+				}
+
+				put.inFlightTransfers.Finish(tempPath)
+			}()
+
+			_, encryptErr := put.encryptFile(sourcePath, tempPath, targetPath, encryptionMode)
+			if encryptErr != nil {
+				job.Progress("encrypt", -1, sourceStat.Size(), true)
+
+				reportSimple(encryptErr, notes...)
+				return errors.Wrap(encryptErr, "failed to encrypt file")
+			}
+		}
+
+		progressCallbackPut := func(taskType string, processed int64, total int64) {
+			job.Progress(taskType, processed, total, false)
+		}
+
+		job.Progress("upload", 0, sourceStat.Size(), false)
+
+		uploadSourcePath := sourcePath
+		if len(tempPath) > 0 {
+			uploadSourcePath = tempPath
+		}
+
+		parentTargetPath := path.Dir(targetPath)
+		_, statErr := put.filesystem.Stat(parentTargetPath)
+		if statErr != nil {
+			// must exist, mkdir is performed at putDir
+			job.Progress("upload", -1, sourceStat.Size(), true)
+
+			reportSimple(statErr)
+			return errors.Wrapf(statErr, "failed to stat %q", parentTargetPath)
+		}
+
+		var uploadErr error
+		var uploadResult *irodsclient_fs.FileTransferResult
+
+		notes = append(notes, string(transferMode), fmt.Sprintf("%d threads", threadsRequired))
+
+		retryNum := put.options.RetryNum
+		retryInterval := put.options.RetryInterval
+
+		// incomplete data object is removed if aborted, only when this run creates it
+		// existing data objects are kept, and WebDAV servers discard incomplete uploads
+		if transferMode != transfer.TransferModeWebDAV && put.isNewDataObject(targetPath) {
+			put.inFlightTransfers.Start(targetPath, func() error {
+				return put.filesystem.RemoveFile(targetPath, true)
+			})
+		}
+
+		_, transferSpan := tracing.StartSpan(job.GetContext(), "upload",
+			tracing.String("gocmd.source_path", sourcePath),
+			tracing.String("gocmd.target_path", targetPath),
+			tracing.Int64("gocmd.size", sourceStat.Size()),
+			tracing.String("gocmd.transfer_mode", string(transferMode)),
+			tracing.Int64("gocmd.threads", int64(threadsRequired)),
+		)
+
+		attempt := 0
+		retryErr := retry.Do(func() error {
+			attempt++
+			if attempt > 1 {
+				logger.Debugf("retrying upload attempt %d/%d for %q", attempt, retryNum+1, sourcePath)
+				put.options.Metrics.AddRetry(string(transfer.TransferMethodPut))
+			}
+
+			switch transferMode {
+			case transfer.TransferModeWebDAV:
+				uploadResult, uploadErr = put.webdavClient.UploadFile(job.GetContext(), uploadSourcePath, targetPath, put.account.Ticket, put.options.VerifyChecksum, progressCallbackPut)
+			case transfer.TransferModeICAT:
+				fallthrough
+			default:
+				uploadResult, uploadErr = put.filesystem.UploadFileParallel(uploadSourcePath, targetPath, "", threadsRequired, false, put.options.VerifyChecksum, progressCallbackPut)
+			}
+			return uploadErr
+		}, retry.Attempts(uint(retryNum+1)), retry.Delay(retryInterval), retry.LastErrorOnly(true), retry.Context(job.GetContext()))
+
+		transferSpan.SetAttributes(tracing.Int64("gocmd.attempts", int64(attempt)))
+		transferSpan.End(retryErr)
+
+		if retryErr == nil || job.GetContext().Err() == nil {
+			// keep the incomplete data object to be removed if aborted
+			put.inFlightTransfers.Finish(targetPath)
+		}
+
+		if retryErr != nil {
+			job.Progress("upload", -1, sourceStat.Size(), true)
+			job.Progress("checksum", -1, sourceStat.Size(), true)
+
+			reportTransfer(uploadResult, retryErr, notes...)
+			return errors.Wrapf(retryErr, "failed to upload %q to %q after %d attempts", sourcePath, targetPath, retryNum+1)
+		}
+
+		put.totalUploadedFiles++
+		put.totalUploadedBytes += sourceStat.Size()
+		put.options.Metrics.AddTransfer(string(transfer.TransferMethodPut), string(transferMode), 1, sourceStat.Size())
+
+		if put.options.MetadataSidecar {
+			importErr := irods.ImportMetadataFromSidecar(put.filesystem, sourcePath, targetPath)
+			if importErr != nil {
+				reportTransfer(uploadResult, importErr, append(notes, "meta_sidecar")...)
+				return errors.Wrapf(importErr, "failed to import metadata to %q", targetPath)
+			}
+		}
+
+		applyErr := put.applyMetadataTemplate(sourcePath, targetPath)
+		if applyErr != nil {
+			reportTransfer(uploadResult, applyErr, append(notes, "meta_template")...)
+			return errors.Wrapf(applyErr, "failed to apply metadata template to %q", targetPath)
+		}
+
+		reportTransfer(uploadResult, nil, notes...)
+
+		logger.Debug("uploaded a file")
+
+		return nil
+	}
+
+	put.parallelTransferJobManager.ScheduleWithOptions(sourcePath, putTask, threadsRequired, progress.UnitsBytes, parallel.ParallelJobOptions{
+		Size:     sourceStat.Size(),
+		Priority: put.options.getPriority(sourcePath),
+	})
+	logger.Debugf("scheduled a file upload, %d threads", threadsRequired)
+}
+
+func (put *PutTransfer) scheduleDeleteFileOnSuccess(sourcePath string) {
+	logger := log.WithFields(log.Fields{
+		"source_path": sourcePath,
+	})
+
+	defaultNotes := []string{"put", "delete on success", "file"}
+
+	report := func(startTime time.Time, endTime time.Time, err error, additionalNotes ...string) {
+		newNotes := append(defaultNotes, additionalNotes...)
+
+		reportFile := &transfer.TransferReportFile{
+			Method:     transfer.TransferMethodDelete,
+			StartAt:    startTime,
+			EndAt:      endTime,
+			SourcePath: sourcePath,
+			Error:      err,
+			Notes:      newNotes,
+		}
+
+		put.transferReportManager.AddFile(reportFile)
+	}
+
+	reportSimple := func(err error, additionalNotes ...string) {
+		now := time.Now()
+		report(now, now, err, additionalNotes...)
+	}
+
+	deleteTask := func(job *parallel.ParallelJob) error {
+		logger := logger.WithFields(job.GetLogFields())
+
+		if job.IsCanceled() {
+			// job is canceled, do not run
+			job.Progress("delete", -1, 1, true)
+
+			reportSimple(nil, "canceled")
+			logger.Debug("canceled a task for deleting a file")
+			return nil
+		}
+
+		logger.Debug("deleting a file")
+
+		job.Progress("delete", 0, 1, false)
+
+		removeErr := os.Remove(sourcePath)
+		reportSimple(removeErr)
+
+		if removeErr != nil {
+			job.Progress("delete", -1, 1, true)
+			return errors.Wrapf(removeErr, "failed to delete %q", sourcePath)
+		}
+
+		logger.Debug("deleted a file")
+		job.Progress("delete", 1, 1, false)
+		return nil
+	}
+
+	put.parallelPostProcessJobManager.Schedule("removing - "+sourcePath, deleteTask, 1, progress.UnitsDefault)
+	logger.Debug("scheduled a file deletion")
+}
+
+func (put *PutTransfer) scheduleDeleteDirOnSuccess(sourcePath string) {
+	logger := log.WithFields(log.Fields{
+		"source_path": sourcePath,
+	})
+
+	defaultNotes := []string{"put", "delete on success", "directory"}
+
+	report := func(startTime time.Time, endTime time.Time, err error, additionalNotes ...string) {
+		newNotes := append(defaultNotes, additionalNotes...)
+
+		reportFile := &transfer.TransferReportFile{
+			Method:     transfer.TransferMethodDelete,
+			StartAt:    startTime,
+			EndAt:      endTime,
+			SourcePath: sourcePath,
+			Error:      err,
+			Notes:      newNotes,
+		}
+
+		put.transferReportManager.AddFile(reportFile)
+	}
+
+	reportSimple := func(err error, additionalNotes ...string) {
+		now := time.Now()
+		report(now, now, err, additionalNotes...)
+	}
+
+	deleteTask := func(job *parallel.ParallelJob) error {
+		logger := logger.WithFields(job.GetLogFields())
+
+		if job.IsCanceled() {
+			// job is canceled, do not run
+			job.Progress("delete", -1, 1, true)
+
+			reportSimple(nil, "canceled")
+			logger.Debug("canceled a task for deleting an empty directory")
+			return nil
+		}
+
+		logger.Debug("deleting an empty directory")
+
+		job.Progress("delete", 0, 1, false)
+
+		removeErr := os.Remove(sourcePath)
+		reportSimple(removeErr)
+
+		if removeErr != nil {
+			job.Progress("delete", -1, 1, true)
+			return errors.Wrapf(removeErr, "failed to delete %q", sourcePath)
+		}
+
+		logger.Debug("deleted an empty directory")
+		job.Progress("delete", 1, 1, false)
+		return nil
+	}
+
+	put.parallelPostProcessJobManager.Schedule("removing - "+sourcePath, deleteTask, 1, progress.UnitsDefault)
+	logger.Debug("scheduled an empty directory deletion")
+}
+
+func (put *PutTransfer) scheduleDeleteExtraFile(targetPath string) {
+	logger := log.WithFields(log.Fields{
+		"target_path": targetPath,
+	})
+
+	defaultNotes := []string{"put", "extra", "file"}
+
+	report := func(startTime time.Time, endTime time.Time, err error, additionalNotes ...string) {
+		newNotes := append(defaultNotes, additionalNotes...)
+
+		reportFile := &transfer.TransferReportFile{
+			Method:   transfer.TransferMethodDelete,
+			StartAt:  startTime,
+			EndAt:    endTime,
+			DestPath: targetPath,
+			Error:    err,
+			Notes:    newNotes,
+		}
+
+		put.transferReportManager.AddFile(reportFile)
+	}
+
+	reportSimple := func(err error, additionalNotes ...string) {
+		now := time.Now()
+		report(now, now, err, additionalNotes...)
+	}
+
+	deleteTask := func(job *parallel.ParallelJob) error {
+		logger := logger.WithFields(job.GetLogFields())
+
+		if job.IsCanceled() {
+			// job is canceled, do not run
+			job.Progress("delete", -1, 1, true)
+
+			reportSimple(nil, "canceled")
+			logger.Debug("canceled a task for deleting extra data object")
+			return nil
+		}
+
+		logger.Debug("deleting an extra data object")
+
+		job.Progress("delete", 0, 1, false)
+
+		startTime := time.Now()
+		removeErr := put.filesystem.RemoveFile(targetPath, true)
+		endTime := time.Now()
+		report(startTime, endTime, removeErr)
+
+		if removeErr != nil {
+			job.Progress("delete", -1, 1, true)
+			return errors.Wrapf(removeErr, "failed to delete %q", targetPath)
+		}
+
+		logger.Debug("deleted an extra data object")
+		job.Progress("delete", 1, 1, false)
+		return nil
+	}
+
+	put.parallelPostProcessJobManager.Schedule(targetPath, deleteTask, 1, progress.UnitsDefault)
+	logger.Debug("scheduled an extra data object deletion")
+}
+
+func (put *PutTransfer) scheduleDeleteExtraDir(targetPath string) {
+	logger := log.WithFields(log.Fields{
+		"target_path": targetPath,
+	})
+
+	defaultNotes := []string{"put", "extra", "directory"}
+
+	report := func(startTime time.Time, endTime time.Time, err error, additionalNotes ...string) {
+		newNotes := append(defaultNotes, additionalNotes...)
+
+		reportFile := &transfer.TransferReportFile{
+			Method:   transfer.TransferMethodDelete,
+			StartAt:  startTime,
+			EndAt:    endTime,
+			DestPath: targetPath,
+			Error:    err,
+			Notes:    newNotes,
+		}
+
+		put.transferReportManager.AddFile(reportFile)
+	}
+
+	reportSimple := func(err error, additionalNotes ...string) {
+		now := time.Now()
+		report(now, now, err, additionalNotes...)
+	}
+
+	deleteTask := func(job *parallel.ParallelJob) error {
+		logger := logger.WithFields(job.GetLogFields())
+
+		if job.IsCanceled() {
+			// job is canceled, do not run
+			job.Progress("delete", -1, 1, true)
+
+			reportSimple(nil, "canceled")
+			logger.Debug("canceled a task for deleting an extra collection")
+			return nil
+		}
+
+		logger.Debug("deleting an extra collection")
+
+		job.Progress("delete", 0, 1, false)
+
+		startTime := time.Now()
+		removeErr := put.filesystem.RemoveDir(targetPath, false, false)
+		endTime := time.Now()
+		report(startTime, endTime, removeErr)
+
+		if removeErr != nil {
+			job.Progress("delete", -1, 1, true)
+			return errors.Wrapf(removeErr, "failed to delete %q", targetPath)
+		}
+
+		logger.Debug("deleted an extra collection")
+		job.Progress("delete", 1, 1, false)
+		return nil
+	}
+
+	put.parallelPostProcessJobManager.Schedule(targetPath, deleteTask, 1, progress.UnitsDefault)
+	logger.Debug("scheduled an extra collection deletion")
+}
+
+func (put *PutTransfer) putFile(sourceStat fs.FileInfo, sourcePath string, tempPath string, targetPath string, encryptionMode encryption.EncryptionMode) error {
+	logger := log.WithFields(log.Fields{
+		"source_path":     sourcePath,
+		"temp_path":       tempPath,
+		"target_path":     targetPath,
+		"encryption_mode": encryptionMode,
+	})
+
+	defaultNotes := []string{"put"}
+
+	reportSimple := func(err error, additionalNotes ...string) {
+		now := time.Now()
+		newNotes := append(defaultNotes, additionalNotes...)
+		newNotes = append(newNotes, "file")
+
+		reportFile := &transfer.TransferReportFile{
+			Method:     transfer.TransferMethodPut,
+			StartAt:    now,
+			EndAt:      now,
+			SourcePath: sourcePath,
+			SourceSize: sourceStat.Size(),
+			DestPath:   targetPath,
+			Error:      err,
+			Notes:      newNotes,
+		}
+
+		put.transferReportManager.AddFile(reportFile)
+	}
+
+	reportOverwrite := func(startTime time.Time, endTime time.Time, err error, additionalNotes ...string) {
+		newNotes := append(defaultNotes, additionalNotes...)
+		newNotes = append(newNotes, "overwrite")
+
+		reportFile := &transfer.TransferReportFile{
+			Method:   transfer.TransferMethodDelete,
+			StartAt:  startTime,
+			EndAt:    endTime,
+			DestPath: targetPath,
+			Error:    err,
+			Notes:    newNotes,
+		}
+
+		put.transferReportManager.AddFile(reportFile)
+	}
+
+	put.mutex.Lock()
+	commons_path.MarkIRODSPathMap(put.updatedPathMap, targetPath)
+	put.mutex.Unlock()
+
+	if put.options.ExcludeHidden {
+		// exclude hidden
+		if strings.HasPrefix(sourceStat.Name(), ".") {
+			// skip
+			reportSimple(nil, "hidden", "skipped")
+			put.events.notifySkipped(sourcePath, targetPath, "skip uploading a file %q to %q. The file is hidden!", sourcePath, targetPath)
+			logger.Debug("skip uploading a file. The file is hidden!")
+			return nil
+		}
+	}
+
+	if put.options.Age > 0 {
+		// exclude old
+		age := time.Since(sourceStat.ModTime())
+		maxAge := put.options.Age
+		if age > maxAge {
+			// skip
+			reportSimple(nil, "age", "skipped")
+			put.events.notifySkipped(sourcePath, targetPath, "skip uploading a file %q to %q. The file is too old (%s > %s)!", sourcePath, targetPath, age, maxAge)
+			logger.Debugf("skip uploading a file. The file is too old (%s > %s)!", age, maxAge)
+			return nil
+		}
+	}
+
+	targetEntry, err := put.filesystem.Stat(targetPath)
+	if err != nil {
+		if irodsclient_types.IsFileNotFoundError(err) {
+			// target does not exist
+			// target must be a file with new name
+			put.schedulePut(sourceStat, sourcePath, tempPath, targetPath, encryptionMode)
+			return nil
+		}
+
+		reportSimple(err)
+		return errors.Wrapf(err, "failed to stat %q", targetPath)
+	}
+
+	// target exists
+	// target must be a file
+	if targetEntry.IsDir() {
+		if put.options.Sync {
+			// if it is sync, remove
+			overwrite := put.options.confirm(fmt.Sprintf("Overwriting a data object %q, but collection exists. Overwrite?", targetPath))
+
+			if overwrite {
+				startTime := time.Now()
+				removeErr := put.filesystem.RemoveDir(targetPath, true, true)
+				endTime := time.Now()
+				reportOverwrite(startTime, endTime, removeErr, "directory")
+
+				if removeErr != nil {
+					return removeErr
+				}
+
+				// fallthrough to put
+			} else {
+				overwriteErr := types.NewNotFileError(targetPath)
+
+				now := time.Now()
+				reportOverwrite(now, now, overwriteErr, "directory", "declined", "skipped")
+				put.events.notifySkipped(sourcePath, targetPath, "skip uploading a file %q to %q. Collection exists with the same name!", sourcePath, targetPath)
+				logger.Debug("skip uploading a file. Collection exists with the same name!")
+				return nil
+			}
+		} else {
+			notFileErr := types.NewNotFileError(targetPath)
+			now := time.Now()
+			reportOverwrite(now, now, notFileErr, "directory")
+			return notFileErr
+		}
+	}
+
+	if put.options.Differential {
+		if put.options.NoHash {
+			if targetEntry.Size == sourceStat.Size() {
+				// skip
+				now := time.Now()
+				reportFile := &transfer.TransferReportFile{
+					Method:                transfer.TransferMethodPut,
+					StartAt:               now,
+					EndAt:                 now,
+					SourcePath:            sourcePath,
+					SourceSize:            sourceStat.Size(),
+					DestPath:              targetEntry.Path,
+					DestSize:              targetEntry.Size,
+					DestChecksumAlgorithm: string(targetEntry.CheckSumAlgorithm),
+
+					Notes: []string{"put", "file", "differential", "no_hash", "same size", "skipped"},
+				}
+
+				put.transferReportManager.AddFile(reportFile)
+
+				put.events.notifySkipped(sourcePath, targetPath, "skip uploading a file %q to %q. The data object already exists!", sourcePath, targetPath)
+				logger.Debug("skip uploading a file. The data object already exists!")
+				return nil
+			}
+		} else {
+			if targetEntry.Size == sourceStat.Size() {
+				// compare hash
+				if len(targetEntry.CheckSum) > 0 {
+					_, checksumSpan := tracing.StartSpan(put.ctx, "checksum",
+						tracing.String("gocmd.local_path", sourcePath),
+						tracing.Int64("gocmd.size", sourceStat.Size()),
+						tracing.String("gocmd.checksum_algorithm", string(targetEntry.CheckSumAlgorithm)),
+					)
+
+					localChecksum, err := irodsclient_util.HashLocalFile(sourcePath, string(targetEntry.CheckSumAlgorithm), nil)
+					checksumSpan.End(err)
+					if err != nil {
+						reportSimple(err, "differential")
+						return errors.Wrapf(err, "failed to get hash for %q", sourcePath)
+					}
+
+					if bytes.Equal(localChecksum, targetEntry.CheckSum) {
+						// skip
+						now := time.Now()
+						reportFile := &transfer.TransferReportFile{
+							Method:                  transfer.TransferMethodPut,
+							StartAt:                 now,
+							EndAt:                   now,
+							SourcePath:              sourcePath,
+							SourceSize:              sourceStat.Size(),
+							SourceChecksumAlgorithm: string(targetEntry.CheckSumAlgorithm),
+							SourceChecksum:          hex.EncodeToString(localChecksum),
+							DestPath:                targetEntry.Path,
+							DestSize:                targetEntry.Size,
+							DestChecksum:            hex.EncodeToString(targetEntry.CheckSum),
+							DestChecksumAlgorithm:   string(targetEntry.CheckSumAlgorithm),
+
+							Notes: []string{"put", "file", "differential", "same checksum", "skipped"},
+						}
+
+						put.transferReportManager.AddFile(reportFile)
+
+						put.events.notifySkipped(sourcePath, targetPath, "skip uploading a file %q to %q. The data object with the same hash already exists!", sourcePath, targetPath)
+						logger.Debug("skip uploading a file. The data object with the same hash already exists!")
+						return nil
+					}
+				}
+			}
+		}
+	} else {
+		overwrite := put.options.confirm(fmt.Sprintf("Data object %q already exists. Overwrite?", targetPath))
+
+		if !overwrite {
+			// skip
+			now := time.Now()
+			reportFile := &transfer.TransferReportFile{
+				Method:                transfer.TransferMethodPut,
+				StartAt:               now,
+				EndAt:                 now,
+				SourcePath:            sourcePath,
+				SourceSize:            sourceStat.Size(),
+				DestPath:              targetEntry.Path,
+				DestSize:              targetEntry.Size,
+				DestChecksum:          hex.EncodeToString(targetEntry.CheckSum),
+				DestChecksumAlgorithm: string(targetEntry.CheckSumAlgorithm),
+
+				Notes: []string{"put", "file", "overwrite", "declined", "skipped"},
+			}
+
+			put.transferReportManager.AddFile(reportFile)
+
+			put.events.notifySkipped(sourcePath, targetPath, "skip uploading a file %q to %q. The data object already exists!", sourcePath, targetPath)
+			logger.Debug("skip uploading a file. The data object already exists!")
+			return nil
+		}
+	}
+
+	// schedule
+	put.schedulePut(sourceStat, sourcePath, tempPath, targetPath, encryptionMode)
+	return nil
+}
+
+func (put *PutTransfer) putDir(sourceStat fs.FileInfo, sourcePath string, targetPath string, parentEncryptionMode encryption.EncryptionMode) error {
+	logger := log.WithFields(log.Fields{
+		"source_path":            sourcePath,
+		"target_path":            targetPath,
+		"parent_encryption_mode": parentEncryptionMode,
+	})
+
+	defaultNotes := []string{"put", "directory"}
+
+	report := func(startTime time.Time, endTime time.Time, err error, additionalNotes ...string) {
+		newNotes := append(defaultNotes, additionalNotes...)
+
+		reportFile := &transfer.TransferReportFile{
+			Method:     transfer.TransferMethodPut,
+			StartAt:    startTime,
+			EndAt:      endTime,
+			SourcePath: sourcePath,
+			SourceSize: sourceStat.Size(),
+			DestPath:   targetPath,
+			Error:      err,
+			Notes:      newNotes,
+		}
+
+		put.transferReportManager.AddFile(reportFile)
+	}
+
+	reportSimple := func(err error, additionalNotes ...string) {
+		now := time.Now()
+		report(now, now, err, additionalNotes...)
+	}
+
+	reportOverwrite := func(startTime time.Time, endTime time.Time, err error, additionalNotes ...string) {
+		newNotes := append(defaultNotes, additionalNotes...)
+		newNotes = append(newNotes, "overwrite")
+
+		reportFile := &transfer.TransferReportFile{
+			Method:   transfer.TransferMethodDelete,
+			StartAt:  startTime,
+			EndAt:    endTime,
+			DestPath: targetPath,
+			Error:    err,
+			Notes:    newNotes,
+		}
+
+		put.transferReportManager.AddFile(reportFile)
+	}
+
+	put.mutex.Lock()
+	commons_path.MarkIRODSPathMap(put.updatedPathMap, targetPath)
+	put.mutex.Unlock()
+
+	if put.options.ExcludeHidden {
+		// exclude hidden
+		if strings.HasPrefix(sourceStat.Name(), ".") {
+			// skip
+			reportSimple(nil, "hidden", "skipped")
+			put.events.notifySkipped(sourcePath, targetPath, "skip uploading a directory %q to %q. The directory is hidden!", sourcePath, targetPath)
+			logger.Debug("skip uploading a directory. The directory is hidden!")
+			return nil
+		}
+	}
+
+	targetEntry, err := put.filesystem.Stat(targetPath)
+	if err != nil {
+		if irodsclient_types.IsFileNotFoundError(err) {
+			// target does not exist
+			// target must be a directory with new name
+			startTime := time.Now()
+			err = put.filesystem.MakeDir(targetPath, true)
+			endTime := time.Now()
+			report(startTime, endTime, err)
+			if err != nil {
+				return errors.Wrapf(err, "failed to make a collection %q", targetPath)
+			}
+
+			// fallthrough to put entries
+		} else {
+			reportSimple(err)
+			return errors.Wrapf(err, "failed to stat %q", targetPath)
+		}
+	} else {
+		// target exists
+		if !targetEntry.IsDir() {
+			if put.options.Sync {
+				// if it is sync, remove
+				overwrite := put.options.confirm(fmt.Sprintf("Overwriting a directory %q, but file exists. Overwrite?", targetPath))
+
+				if overwrite {
+					startTime := time.Now()
+					removeErr := put.filesystem.RemoveFile(targetPath, true)
+					endTime := time.Now()
+
+					reportOverwrite(startTime, endTime, removeErr)
+
+					if removeErr != nil {
+						return removeErr
+					}
+
+					// fallthrough to put entries
+				} else {
+					overwriteErr := types.NewNotDirError(targetPath)
+
+					now := time.Now()
+					reportOverwrite(now, now, overwriteErr, "declined", "skipped")
+					put.events.notifySkipped(sourcePath, targetPath, "skip uploading a directory %q to %q. Data object exists with the same name!", sourcePath, targetPath)
+					logger.Debug("skip uploading a directory. Data object exists with the same name!")
+					return nil
+				}
+			} else {
+				notDirErr := types.NewNotDirError(targetPath)
+				now := time.Now()
+				reportOverwrite(now, now, notDirErr)
+				return notDirErr
+			}
+		} else {
+			// target is a directory
+			// cache target dir entries first for performance, because multiple files are uploaded to the same target dir
+			_, err = put.filesystem.List(targetPath)
+			if err != nil {
+				reportSimple(err)
+				return errors.Wrapf(err, "failed to list a directory %q", targetPath)
+			}
+		}
+	}
+
+	if put.options.MetadataSidecar {
+		err = irods.ImportMetadataFromSidecar(put.filesystem, sourcePath, targetPath)
+		if err != nil {
+			reportSimple(err, "meta_sidecar")
+			return errors.Wrapf(err, "failed to import metadata to %q", targetPath)
+		}
+	}
+
+	err = put.applyMetadataTemplate(sourcePath, targetPath)
+	if err != nil {
+		reportSimple(err, "meta_template")
+		return errors.Wrapf(err, "failed to apply metadata template to %q", targetPath)
+	}
+
+	// load encryption config
+	encryptionMode := put.getEncryptionMode(targetPath, parentEncryptionMode)
+	logger.Infof("encryption mode for %q is %s", targetPath, encryptionMode)
+
+	// get entries
+	entries, err := os.ReadDir(sourcePath)
+	if err != nil {
+		reportSimple(err)
+		return errors.Wrapf(err, "failed to list a directory %q", sourcePath)
+	}
+
+	for _, entry := range entries {
+		newEntryPath := commons_path.MakeIRODSTargetFilePath(put.filesystem, entry.Name(), targetPath)
+
+		entryPath := filepath.Join(sourcePath, entry.Name())
+		entryStat, err := os.Stat(entryPath)
+		if err != nil {
+			if os.IsNotExist(err) {
+				reportSimple(err)
+				return irodsclient_types.NewFileNotFoundError(entryPath)
+			}
+
+			return errors.Wrapf(err, "failed to stat %q", entryPath)
+		}
+
+		if put.options.MetadataSidecar && !entryStat.IsDir() && irods.IsMetadataSidecarPath(entryPath) {
+			// sidecar files are imported as metadata, not uploaded
+			logger.Debugf("skip uploading a metadata sidecar file %q", entryPath)
+			continue
+		}
+
+		if entryStat.IsDir() {
+			// dir
+			err = put.putDir(entryStat, entryPath, newEntryPath, encryptionMode)
+			if err != nil {
+				return err
+			}
+		} else {
+			// file
+			if encryptionMode != encryption.EncryptionModeNone {
+				// encrypt filename
+				tempPath, newTargetPath, err := put.getPathsForEncryption(entryPath, targetPath)
+				if err != nil {
+					reportSimple(err)
+					return errors.Wrapf(err, "failed to get encryption path for %q", entryPath)
+				}
+
+				err = put.putFile(entryStat, entryPath, tempPath, newTargetPath, encryptionMode)
+				if err != nil {
+					return err
+				}
+			} else {
+				err = put.putFile(entryStat, entryPath, "", newEntryPath, encryptionMode)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func (put *PutTransfer) deleteFileOnSuccess(sourcePath string) error {
+	logger := log.WithFields(log.Fields{
+		"source_path": sourcePath,
+	})
+
+	defaultNotes := []string{"put", "delete on success", "file"}
+
+	reportSimple := func(err error, additionalNotes ...string) {
+		now := time.Now()
+		newNotes := append(defaultNotes, additionalNotes...)
+
+		reportFile := &transfer.TransferReportFile{
+			Method:     transfer.TransferMethodDelete,
+			StartAt:    now,
+			EndAt:      now,
+			SourcePath: sourcePath,
+			Error:      err,
+			Notes:      newNotes,
+		}
+
+		put.transferReportManager.AddFile(reportFile)
+	}
+
+	logger.Debug("removing a file after upload", sourcePath)
+
+	overwrite := put.options.confirm(fmt.Sprintf("Removing a file %q after upload. Remove?", sourcePath))
+
+	if overwrite {
+		put.scheduleDeleteFileOnSuccess(sourcePath)
+		return nil
+	} else {
+		// do not remove
+		reportSimple(nil, "declined", "skipped")
+		return nil
+	}
+}
+
+func (put *PutTransfer) deleteDirOnSuccess(sourcePath string) error {
+	logger := log.WithFields(log.Fields{
+		"source_path": sourcePath,
+	})
+
+	defaultNotes := []string{"put", "delete on success", "directory"}
+
+	reportSimple := func(err error, additionalNotes ...string) {
+		now := time.Now()
+		newNotes := append(defaultNotes, additionalNotes...)
+
+		reportFile := &transfer.TransferReportFile{
+			Method:     transfer.TransferMethodDelete,
+			StartAt:    now,
+			EndAt:      now,
+			SourcePath: sourcePath,
+			Error:      err,
+			Notes:      newNotes,
+		}
+
+		put.transferReportManager.AddFile(reportFile)
+	}
+
+	logger.Debug("removing a directory after upload", sourcePath)
+
+	// scan recursively
+	entries, err := os.ReadDir(sourcePath)
+	if err != nil {
+		reportSimple(err)
+		return errors.Wrapf(err, "failed to list a directory %q", sourcePath)
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			// dir
+			newSourcePath := filepath.Join(sourcePath, entry.Name())
+			err = put.deleteDirOnSuccess(newSourcePath)
+			if err != nil {
+				return err
+			}
+		} else {
+			// file
+			newSourcePath := filepath.Join(sourcePath, entry.Name())
+			err = put.deleteFileOnSuccess(newSourcePath)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// delete the directory itself
+	overwrite := put.options.confirm(fmt.Sprintf("Removing a directory %q after upload. Remove?", sourcePath))
+
+	if overwrite {
+		put.scheduleDeleteDirOnSuccess(sourcePath)
+		return nil
+	} else {
+		// do not remove
+		reportSimple(nil, "declined", "skipped")
+		return nil
+	}
+}
+
+func (put *PutTransfer) deleteExtraFile(targetPath string) error {
+	logger := log.WithFields(log.Fields{
+		"target_path": targetPath,
+	})
+
+	defaultNotes := []string{"put", "extra", "file"}
+
+	report := func(startTime time.Time, endTime time.Time, err error, additionalNotes ...string) {
+		newNotes := append(defaultNotes, additionalNotes...)
+
+		reportFile := &transfer.TransferReportFile{
+			Method:   transfer.TransferMethodDelete,
+			StartAt:  startTime,
+			EndAt:    endTime,
+			DestPath: targetPath,
+			Error:    err,
+			Notes:    newNotes,
+		}
+
+		put.transferReportManager.AddFile(reportFile)
+	}
+
+	reportSimple := func(err error, additionalNotes ...string) {
+		now := time.Now()
+		report(now, now, err, additionalNotes...)
+	}
+
+	put.mutex.RLock()
+	isExtra := false
+	if _, ok := put.updatedPathMap[targetPath]; !ok {
+		isExtra = true
+	}
+	put.mutex.RUnlock()
+
+	if isExtra {
+		// extra file
+		logger.Debug("removing an extra data object", targetPath)
+
+		overwrite := put.options.confirm(fmt.Sprintf("Removing an extra data object %q. Remove?", targetPath))
+
+		if overwrite {
+			put.scheduleDeleteExtraFile(targetPath)
+			return nil
+		} else {
+			// do not remove
+			reportSimple(nil, "declined", "skipped")
+			return nil
+		}
+	}
+
+	return nil
+}
+
+func (put *PutTransfer) deleteExtraDir(targetPath string) error {
+	logger := log.WithFields(log.Fields{
+		"target_path": targetPath,
+	})
+
+	defaultNotes := []string{"put", "extra", "directory"}
+
+	reportSimple := func(err error, additionalNotes ...string) {
+		now := time.Now()
+		newNotes := append(defaultNotes, additionalNotes...)
+
+		reportFile := &transfer.TransferReportFile{
+			Method:   transfer.TransferMethodDelete,
+			StartAt:  now,
+			EndAt:    now,
+			DestPath: targetPath,
+			Error:    err,
+			Notes:    newNotes,
+		}
+
+		put.transferReportManager.AddFile(reportFile)
+	}
+
+	// scan recursively
+	entries, err := put.filesystem.List(targetPath)
+	if err != nil {
+		reportSimple(err)
+		return errors.Wrapf(err, "failed to list a collection %q", targetPath)
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			// dir
+			err = put.deleteExtraDir(entry.Path)
+			if err != nil {
+				return err
+			}
+		} else {
+			// file
+			err = put.deleteExtraFile(entry.Path)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// delete the directory itself
+	put.mutex.RLock()
+	isExtra := false
+	if _, ok := put.updatedPathMap[targetPath]; !ok {
+		isExtra = true
+	}
+	put.mutex.RUnlock()
+
+	if isExtra {
+		// extra dir
+		logger.Debug("removing an extra collection")
+
+		overwrite := put.options.confirm(fmt.Sprintf("Removing an extra collection %q. Remove?", targetPath))
+
+		if overwrite {
+			put.scheduleDeleteExtraDir(targetPath)
+			return nil
+		} else {
+			// do not remove
+			reportSimple(nil, "declined", "skipped")
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// resolveMetadataTemplate resolves AVUs of the metadata template for all local files and directories to upload
+// It fails if any of them does not satisfy the template, so nothing is transferred
+func (put *PutTransfer) resolveMetadataTemplate() error {
+	template, err := irods.NewMetadataTemplateFromFile(put.options.MetadataTemplatePath)
+	if err != nil {
+		return err
+	}
+
+	failures := []string{}
+
+	for _, sourcePath := range put.sourcePaths {
+		sourcePath = commons_path.MakeLocalPath(sourcePath)
+
+		walkErr := filepath.WalkDir(sourcePath, func(entryPath string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if put.options.ExcludeHidden && entryPath != sourcePath && strings.HasPrefix(entry.Name(), ".") {
+				if entry.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+
+			if put.options.MetadataSidecar && !entry.IsDir() && irods.IsMetadataSidecarPath(entryPath) {
+				return nil
+			}
+
+			entryStat, err := os.Stat(entryPath)
+			if err != nil {
+				return errors.Wrapf(err, "failed to stat %q", entryPath)
+			}
+
+			metas, err := put.resolveMetadataTemplateForPath(template, entryPath, entryStat)
+			if err != nil {
+				failures = append(failures, fmt.Sprintf("%s: %s", entryPath, err.Error()))
+				return nil
+			}
+
+			put.metadataTemplateMetas[entryPath] = metas
+			return nil
+		})
+
+		if walkErr != nil {
+			return errors.Wrapf(walkErr, "failed to walk %q", sourcePath)
+		}
+	}
+
+	if len(failures) > 0 {
+		return errors.Errorf("%d files or directories do not satisfy the template\n%s", len(failures), strings.Join(failures, "\n"))
+	}
+
+	return nil
+}
+
+func (put *PutTransfer) resolveMetadataTemplateForPath(template *irods.MetadataTemplate, localPath string, stat fs.FileInfo) ([]*irodsclient_types.IRODSMeta, error) {
+	variables, err := template.MakeVariables(localPath, stat, put.account.ClientUser, put.startTime)
+	if err != nil {
+		return nil, err
+	}
+
+	values := map[string]string{}
+	if put.options.MetadataSidecar {
+		values, err = irods.ReadMetadataSidecarValues(localPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// values given in command-line override sidecar files
+	for attr, value := range put.options.MetadataTemplateValues {
+		values[attr] = value
+	}
+
+	metas, err := template.Resolve(variables, values, stat.IsDir())
+	if err != nil {
+		return nil, err
+	}
+
+	// apply values given in command-line even if they are not in the template
+	resolved := map[string]bool{}
+	for _, meta := range metas {
+		resolved[meta.Name] = true
+	}
+
+	for attr, value := range put.options.MetadataTemplateValues {
+		if !resolved[attr] && len(value) > 0 {
+			metas = append(metas, &irodsclient_types.IRODSMeta{
+				Name:  attr,
+				Value: value,
+			})
+		}
+	}
+
+	return metas, nil
+}
+
+// applyMetadataTemplate sets AVUs resolved from the metadata template to the uploaded data object or collection
+func (put *PutTransfer) applyMetadataTemplate(sourcePath string, targetPath string) error {
+	metas, ok := put.metadataTemplateMetas[sourcePath]
+	if !ok {
+		return nil
+	}
+
+	for _, meta := range metas {
+		err := irods.SetMetadataForTarget(put.filesystem, irods.MetadataTargetPath, targetPath, put.account.ClientZone, meta.Name, meta.Value, meta.Units)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// isNewDataObject returns true if the data object does not exist, false if it exists or its existence is unknown
+func (put *PutTransfer) isNewDataObject(targetPath string) bool {
+	_, err := put.filesystem.Stat(targetPath)
+	return err != nil && irodsclient_types.IsFileNotFoundError(err)
+}
+
+func (put *PutTransfer) getEncryptionManagerForEncryption(mode encryption.EncryptionMode, targetDir string) *encryption.EncryptionManager {
+	manager := encryption.NewEncryptionManager(mode)
+
+	switch mode {
+	case encryption.EncryptionModeWinSCP, encryption.EncryptionModeAESGCM, encryption.EncryptionModePGP:
+		manager.SetKey([]byte(put.options.EncryptionKey))
+	case encryption.EncryptionModeSSH:
+		manager.SetPublicKeyRing(put.getPublicKeyRing(targetDir))
+	case encryption.EncryptionModeAge:
+		manager.SetKey([]byte(put.options.EncryptionKey))
+		manager.SetAgeRecipients(put.options.EncryptionRecipients)
+	}
+
+	return manager
+}
+
+// getPublicKeyRing returns public keys of recipients, keys in collection metadata are used if keys are not given
+func (put *PutTransfer) getPublicKeyRing(targetDir string) []string {
+	if len(put.options.EncryptionPublicKeyPaths) == 0 && !put.options.EncryptionIgnoreMeta {
+		encryptionConfig := encryption.GetEncryptionConfigFromMeta(put.filesystem, targetDir)
+		if len(encryptionConfig.PublicKeys) > 0 {
+			return encryptionConfig.PublicKeys
+		}
+	}
+
+	return put.options.getPublicKeyPaths()
+}
+
+func (put *PutTransfer) getPathsForEncryption(sourcePath string, targetPath string) (string, string, error) {
+	if put.options.EncryptionMode != encryption.EncryptionModeNone {
+		targetDir := path.Dir(commons_path.MakeIRODSTargetFilePath(put.filesystem, sourcePath, targetPath))
+		encryptManager := put.getEncryptionManagerForEncryption(put.options.EncryptionMode, targetDir)
+		sourceFilename := filepath.Base(sourcePath)
+
+		encryptedFilename, err := encryptManager.EncryptFilename(sourceFilename)
+		if err != nil {
+			return "", "", errors.Wrapf(err, "failed to encrypt filename %q", sourcePath)
+		}
+
+		tempFilePath := commons_path.MakeLocalTargetFilePath(encryptedFilename, put.options.EncryptionTempPath)
+
+		targetFilePath := commons_path.MakeIRODSTargetFilePath(put.filesystem, encryptedFilename, targetPath)
+
+		return tempFilePath, targetFilePath, nil
+	}
+
+	targetFilePath := commons_path.MakeIRODSTargetFilePath(put.filesystem, sourcePath, targetPath)
+
+	return "", targetFilePath, nil
+}
+
+func (put *PutTransfer) encryptFile(sourcePath string, encryptedFilePath string, targetPath string, encryptionMode encryption.EncryptionMode) (bool, error) {
+	logger := log.WithFields(log.Fields{
+		"source_path":     sourcePath,
+		"temp_path":       encryptedFilePath,
+		"target_path":     targetPath,
+		"encryption_mode": encryptionMode,
+	})
+
+	if encryptionMode != encryption.EncryptionModeNone {
+		logger.Debug("encrypt a file")
+
+		encryptManager := put.getEncryptionManagerForEncryption(encryptionMode, path.Dir(targetPath))
+
+		err := encryptManager.EncryptFile(sourcePath, encryptedFilePath)
+		if err != nil {
+			return false, errors.Wrapf(err, "failed to encrypt %q to %q", sourcePath, encryptedFilePath)
+		}
+
+		return true, nil
+	}
+
+	return false, nil
+}
+
+func (put *PutTransfer) determineTransferMethod(size int64) (transfer.TransferMode, int) {
+	logger := log.WithFields(log.Fields{})
+
+	threads := parallel.CalculateThreadForTransferJob(size, put.options.ThreadNumPerFile)
+
+	// determine how to upload
+	if put.options.SingleThread || put.options.ThreadNum <= 2 || put.options.ThreadNumPerFile == 1 || !put.filesystem.SupportParallelUpload() {
+		threads = 1
+	}
+
+	if put.options.ICAT {
+		logger.Info("using ICAT transfer for uploading a data object")
+		return transfer.TransferModeICAT, threads
+	} else if put.options.WebDAV {
+		if put.webdavClient == nil {
+			// fallback to ICAT
+			logger.Info("WebDAV is not configured. Using ICAT transfer for uploading a data object")
+			return transfer.TransferModeICAT, threads
+		}
+
+		logger.Info("using WebDAV for uploading a data object")
+		return transfer.TransferModeWebDAV, 1
+	}
+
+	// sysconfig
+	systemConfig := config.GetSystemConfig()
+	if systemConfig != nil && systemConfig.AdditionalConfig != nil {
+		mode := transfer.GetTransferMode(systemConfig.AdditionalConfig.TransferMode)
+		if mode.Valid() {
+			return mode, threads
+		}
+	}
+
+	logger.Info("using ICAT transfer for uploading a data object")
+	return transfer.TransferModeICAT, threads
+}
@@ -0,0 +1,202 @@
+package gocommands
+
+import (
+	"context"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/cyverse/gocommands/commons/config"
+)
+
+// IRODSPathPrefix is the prefix of iRODS paths in sources and targets of Sync
+const IRODSPathPrefix string = "i:"
+
+// SyncMode is the direction of Sync
+type SyncMode string
+
+const (
+	// SyncModeLocalToIRODS uploads local directories to an iRODS collection
+	SyncModeLocalToIRODS SyncMode = "local_to_irods"
+	// SyncModeIRODSToLocal downloads iRODS collections to a local directory
+	SyncModeIRODSToLocal SyncMode = "irods_to_local"
+	// SyncModeIRODSToIRODS copies iRODS collections to another iRODS collection
+	SyncModeIRODSToIRODS SyncMode = "irods_to_irods"
+)
+
+// SyncOptions has options of Sync
+type SyncOptions struct {
+	TransferOptions
+	EncryptionOptions
+	DecryptionOptions
+	BundleOptions
+
+	// SourcePaths are local files or directories, or iRODS data objects or collections prefixed with "i:"
+	SourcePaths []string
+	// TargetPath is a local directory, or an iRODS collection prefixed with "i:"
+	TargetPath string
+
+	// BulkUpload uploads local files in bundles, also enabled by the bput_for_sync config
+	BulkUpload bool
+}
+
+// Sync synchronizes local directories to an iRODS collection, iRODS collections to a local directory, or iRODS collections to another iRODS collection
+// Directories are synchronized recursively, transferring only files different from the target
+func Sync(ctx context.Context, options SyncOptions) (*TransferResult, error) {
+	sync, err := NewSyncTransfer(options)
+	if err != nil {
+		return nil, err
+	}
+
+	err = sync.Run(ctx)
+	return sync.GetResult(), err
+}
+
+// SyncTransfer synchronizes files with put, bput, get, or copy depending on the mode
+type SyncTransfer struct {
+	mode     SyncMode
+	transfer Transfer
+}
+
+// NewSyncTransfer creates a new SyncTransfer, options are validated
+func NewSyncTransfer(options SyncOptions) (*SyncTransfer, error) {
+	mode, sourcePaths, targetPath, err := GetSyncMode(options.SourcePaths, options.TargetPath)
+	if err != nil {
+		return nil, err
+	}
+
+	transferOptions := options.TransferOptions
+	transferOptions.Recursive = true
+	transferOptions.Differential = true
+	transferOptions.Sync = true
+
+	var transfer Transfer
+	switch mode {
+	case SyncModeLocalToIRODS:
+		if options.useBput() {
+			transfer, err = NewBputTransfer(BputOptions{
+				TransferOptions:   transferOptions,
+				EncryptionOptions: options.EncryptionOptions,
+				BundleOptions:     options.BundleOptions,
+				SourcePaths:       sourcePaths,
+				TargetPath:        targetPath,
+			})
+		} else {
+			transfer, err = NewPutTransfer(PutOptions{
+				TransferOptions:   transferOptions,
+				EncryptionOptions: options.EncryptionOptions,
+				SourcePaths:       sourcePaths,
+				TargetPath:        targetPath,
+			})
+		}
+	case SyncModeIRODSToLocal:
+		transfer, err = NewGetTransfer(GetOptions{
+			TransferOptions:   transferOptions,
+			DecryptionOptions: options.DecryptionOptions,
+			SourcePaths:       sourcePaths,
+			TargetPath:        targetPath,
+		})
+	case SyncModeIRODSToIRODS:
+		transfer, err = NewCopyTransfer(CopyOptions{
+			TransferOptions: transferOptions,
+			SourcePaths:     sourcePaths,
+			TargetPath:      targetPath,
+		})
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &SyncTransfer{
+		mode:     mode,
+		transfer: transfer,
+	}, nil
+}
+
+// useBput returns true if local files are uploaded in bundles
+func (options *SyncOptions) useBput() bool {
+	if options.BulkUpload {
+		return true
+	}
+
+	systemConfig := config.GetSystemConfig()
+	if systemConfig != nil && systemConfig.AdditionalConfig != nil {
+		return systemConfig.AdditionalConfig.BputForSync
+	}
+
+	return false
+}
+
+// GetSyncMode returns the mode of sync, and source and target paths without the "i:" prefix
+func GetSyncMode(sourcePaths []string, targetPath string) (SyncMode, []string, string, error) {
+	localSourcePaths := []string{}
+	irodsSourcePaths := []string{}
+
+	for _, sourcePath := range sourcePaths {
+		if strings.HasPrefix(sourcePath, IRODSPathPrefix) {
+			irodsSourcePaths = append(irodsSourcePaths, strings.TrimPrefix(sourcePath, IRODSPathPrefix))
+		} else {
+			localSourcePaths = append(localSourcePaths, sourcePath)
+		}
+	}
+
+	if len(localSourcePaths) == 0 && len(irodsSourcePaths) == 0 {
+		return "", nil, "", errors.New("source path is not given")
+	}
+
+	if len(localSourcePaths) > 0 && len(irodsSourcePaths) > 0 {
+		return "", nil, "", errors.New("syncing local and iRODS sources together is not supported")
+	}
+
+	irodsTarget := strings.HasPrefix(targetPath, IRODSPathPrefix)
+	targetPath = strings.TrimPrefix(targetPath, IRODSPathPrefix)
+
+	if len(localSourcePaths) > 0 {
+		if !irodsTarget {
+			return "", nil, "", errors.New("syncing local to local is not supported")
+		}
+
+		return SyncModeLocalToIRODS, localSourcePaths, targetPath, nil
+	}
+
+	if irodsTarget {
+		return SyncModeIRODSToIRODS, irodsSourcePaths, targetPath, nil
+	}
+
+	return SyncModeIRODSToLocal, irodsSourcePaths, targetPath, nil
+}
+
+// GetMode returns the mode of sync
+func (sync *SyncTransfer) GetMode() SyncMode {
+	return sync.mode
+}
+
+// GetTransfer returns the put, bput, get, or copy transfer running the sync
+func (sync *SyncTransfer) GetTransfer() Transfer {
+	return sync.transfer
+}
+
+// Run synchronizes files
+func (sync *SyncTransfer) Run(ctx context.Context) error {
+	err := sync.transfer.Run(ctx)
+	if err != nil {
+		return errors.Wrapf(err, "failed to sync (%s)", strings.ReplaceAll(string(sync.mode), "_", " "))
+	}
+
+	return nil
+}
+
+// CancelPending cancels pending transfers, transfers running are completed
+func (sync *SyncTransfer) CancelPending() {
+	sync.transfer.CancelPending()
+}
+
+// Abort cancels Run, waits for running transfers to return, and removes incomplete files
+func (sync *SyncTransfer) Abort() *TransferResult {
+	return sync.transfer.Abort()
+}
+
+// GetResult returns statistics of the sync
+func (sync *SyncTransfer) GetResult() *TransferResult {
+	return sync.transfer.GetResult()
+}